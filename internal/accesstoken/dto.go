@@ -0,0 +1,52 @@
+package accesstoken
+
+import (
+	"time"
+)
+
+type RequestURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type CreateRequestBody struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scope     Scope      `json:"scope" binding:"required,oneof=read write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type AccessTokenResponse struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Prefix     string  `json:"prefix"`
+	Scope      Scope   `json:"scope"`
+	ExpiresAt  *string `json:"expires_at"`
+	LastUsedAt *string `json:"last_used_at"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+// CreateAccessTokenResponse includes the token, which is only returned when
+// it is created.
+type CreateAccessTokenResponse struct {
+	AccessTokenResponse
+	Token string `json:"token"`
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.UTC().Format(time.RFC3339)
+	return &formatted
+}
+
+func newAccessTokenResponse(token *AccessToken) *AccessTokenResponse {
+	return &AccessTokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scope:      token.Scope,
+		ExpiresAt:  formatTime(token.ExpiresAt),
+		LastUsedAt: formatTime(token.LastUsedAt),
+		CreatedAt:  token.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,59 @@
+package accesstoken
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) CreateToken(c *gin.Context) {
+	var body CreateRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := h.service.CreateToken(&body, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *Handler) GetTokens(c *gin.Context) {
+	userID := c.GetString("user_id")
+	response, err := h.service.GetTokens(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) DeleteToken(c *gin.Context) {
+	uri := &RequestURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if err := h.service.DeleteToken(uri.ID, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
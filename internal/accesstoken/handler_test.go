@@ -0,0 +1,191 @@
+package accesstoken
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) CreateToken(creates *CreateRequestBody, userID string) (*CreateAccessTokenResponse, error) {
+	args := m.Called(creates, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*CreateAccessTokenResponse), args.Error(1)
+}
+func (m *MockService) GetTokens(userID string) ([]AccessTokenResponse, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]AccessTokenResponse), args.Error(1)
+}
+func (m *MockService) DeleteToken(id string, userID string) error {
+	args := m.Called(id, userID)
+	return args.Error(0)
+}
+func (m *MockService) Authenticate(token string) (string, []string, error) {
+	args := m.Called(token)
+	if args.Get(1) == nil {
+		return args.String(0), nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).([]string), args.Error(2)
+}
+
+func TestHandler_NewHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+
+	// Act
+	h := NewHandler(mockService)
+
+	// Assert
+	assert.IsType(t, &Handler{}, h)
+	assert.Equal(t, mockService, h.service)
+}
+
+func TestHandler_CreateToken_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	body := CreateRequestBody{Name: "backup script", Scope: ScopeRead}
+	requestJSON, _ := json.Marshal(body)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", "1")
+
+	expected := &CreateAccessTokenResponse{
+		AccessTokenResponse: AccessTokenResponse{ID: tokenID, Name: body.Name, Prefix: "vdp_abcdefgh", Scope: ScopeRead},
+		Token:               "vdp_abcdefghsecret",
+	}
+	mockService.On("CreateToken", &body, "1").Return(expected, nil)
+
+	// Act
+	handler.CreateToken(c)
+
+	// Assert
+	require.Equal(t, http.StatusCreated, w.Code)
+	var response CreateAccessTokenResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expected, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_CreateToken_InvalidBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "missing name", body: `{"scope":"read"}`},
+		{name: "missing scope", body: `{"name":"cli"}`},
+		{name: "unknown scope", body: `{"name":"cli","scope":"admin"}`},
+		{name: "invalid expiry", body: `{"name":"cli","scope":"read","expires_at":"tomorrow"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := &MockService{}
+			handler := NewHandler(mockService)
+			c, w := test.SetupContext()
+
+			c.Request.Body = io.NopCloser(bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", "1")
+
+			// Act
+			handler.CreateToken(c)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			mockService.AssertNotCalled(t, "CreateToken", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandler_GetTokens_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Set("user_id", "1")
+
+	expected := []AccessTokenResponse{{ID: tokenID, Name: "cli", Prefix: "vdp_abcdefgh", Scope: ScopeWrite}}
+	mockService.On("GetTokens", "1").Return(expected, nil)
+
+	// Act
+	handler.GetTokens(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	var response []AccessTokenResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, expected, response)
+}
+
+func TestHandler_DeleteToken_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "id", Value: tokenID}}
+	c.Set("user_id", "1")
+
+	mockService.On("DeleteToken", tokenID, "1").Return(nil)
+
+	// Act
+	handler.DeleteToken(c)
+	c.Writer.WriteHeaderNow()
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_DeleteToken_InvalidURI(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "id", Value: "invalid"}}
+
+	// Act
+	handler.DeleteToken(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "DeleteToken", mock.Anything, mock.Anything)
+}
+func TestHandler_DeleteToken_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "id", Value: tokenID}}
+	c.Set("user_id", "1")
+
+	mockService.On("DeleteToken", tokenID, "1").Return(apperror.New(apperror.CodeAccessTokenNotFound, "Access token not found"))
+
+	// Act
+	handler.DeleteToken(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+}
@@ -0,0 +1,112 @@
+package accesstoken
+
+import (
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/middleware"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"
+	ScopeWrite Scope = "write"
+)
+
+// DriveScopes returns the route scopes granted by a token scope. Access
+// tokens are never granted drive:admin, so they cannot manage sharing or
+// create other tokens.
+func (s Scope) DriveScopes() []string {
+	if s == ScopeWrite {
+		return []string{middleware.ScopeDriveWrite}
+	}
+	return []string{middleware.ScopeDriveRead}
+}
+
+// AccessToken is a personal access token. Only the SHA-256 hash of the token
+// is stored, the token itself is shown once when it is created.
+type AccessToken struct {
+	ID         string     `gorm:"type:uuid;primary_key"`
+	UserID     string     `gorm:"type:varchar(255);not null;index"`
+	Name       string     `gorm:"type:varchar(100);not null"`
+	TokenHash  string     `gorm:"type:char(64);not null;uniqueIndex"`
+	Prefix     string     `gorm:"type:varchar(16);not null"`
+	Scope      Scope      `gorm:"type:varchar(10);not null;check:scope IN ('read','write')"`
+	ExpiresAt  *time.Time `gorm:"type:timestamptz"`
+	LastUsedAt *time.Time `gorm:"type:timestamptz"`
+	CreatedAt  time.Time  `gorm:"type:timestamptz;not null"`
+}
+
+func (AccessToken) TableName() string {
+	return "access_tokens"
+}
+
+func (t *AccessToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	t.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+type Repository interface {
+	Create(token *AccessToken) error
+	GetOne(id string) (*AccessToken, error)
+	GetByUser(userID string) ([]AccessToken, error)
+	GetByHash(tokenHash string) (*AccessToken, error)
+	Delete(id string) error
+	UpdateLastUsed(id string, lastUsedAt time.Time) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(token *AccessToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *repository) GetOne(id string) (*AccessToken, error) {
+	var token AccessToken
+	err := r.db.Where("id = ?", id).First(&token).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *repository) GetByUser(userID string) ([]AccessToken, error) {
+	var tokens []AccessToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *repository) GetByHash(tokenHash string) (*AccessToken, error) {
+	var token AccessToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *repository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&AccessToken{}).Error
+}
+
+func (r *repository) UpdateLastUsed(id string, lastUsedAt time.Time) error {
+	return r.db.Model(&AccessToken{}).Where("id = ?", id).Update("last_used_at", lastUsedAt).Error
+}
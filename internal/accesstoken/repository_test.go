@@ -0,0 +1,126 @@
+package accesstoken
+
+import (
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/db"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var d *gorm.DB
+
+func TestMain(m *testing.M) {
+	// Setup
+	dbURL, closeDB, err := test.SetupPostgresql()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	d, err = db.NewDatabase(&config.Config{DatabaseURL: dbURL})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = d.AutoMigrate(&AccessToken{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Run
+	code := m.Run()
+
+	// Teardown
+	closeDB()
+
+	os.Exit(code)
+}
+
+func TestRepository_NewRepository_Success(t *testing.T) {
+	// Act
+	repo := NewRepository(d)
+
+	// Assert
+	assert.NotNil(t, repo)
+	assert.IsType(t, &repository{}, repo)
+}
+
+func TestRepository_CreateAndGet_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	token := &AccessToken{UserID: "1", Name: "cli", TokenHash: hashToken("vdp_first"), Prefix: "vdp_first", Scope: ScopeWrite}
+	other := &AccessToken{UserID: "2", Name: "cli", TokenHash: hashToken("vdp_other"), Prefix: "vdp_other", Scope: ScopeRead}
+
+	// Act
+	err = repo.Create(token)
+	require.NoError(t, err)
+	err = repo.Create(other)
+	require.NoError(t, err)
+	byID, byIDErr := repo.GetOne(token.ID)
+	byUser, byUserErr := repo.GetByUser("1")
+	byHash, byHashErr := repo.GetByHash(hashToken("vdp_other"))
+	missing, missingErr := repo.GetByHash(hashToken("vdp_missing"))
+
+	// Assert
+	require.NoError(t, byIDErr)
+	require.NoError(t, byUserErr)
+	require.NoError(t, byHashErr)
+	require.NoError(t, missingErr)
+	assert.Equal(t, "cli", byID.Name)
+	require.Len(t, byUser, 1)
+	assert.Equal(t, token.ID, byUser[0].ID)
+	assert.Equal(t, other.ID, byHash.ID)
+	assert.Nil(t, missing)
+}
+
+func TestRepository_Delete_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	token := &AccessToken{UserID: "1", Name: "cli", TokenHash: hashToken("vdp_first"), Prefix: "vdp_first", Scope: ScopeWrite}
+	err = repo.Create(token)
+	require.NoError(t, err)
+
+	// Act
+	err = repo.Delete(token.ID)
+
+	// Assert
+	require.NoError(t, err)
+	deleted, err := repo.GetOne(token.ID)
+	require.NoError(t, err)
+	assert.Nil(t, deleted)
+}
+
+func TestRepository_UpdateLastUsed_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	token := &AccessToken{UserID: "1", Name: "cli", TokenHash: hashToken("vdp_first"), Prefix: "vdp_first", Scope: ScopeWrite}
+	err = repo.Create(token)
+	require.NoError(t, err)
+	lastUsedAt := time.Now().UTC().Truncate(time.Second)
+
+	// Act
+	err = repo.UpdateLastUsed(token.ID, lastUsedAt)
+
+	// Assert
+	require.NoError(t, err)
+	updated, err := repo.GetOne(token.ID)
+	require.NoError(t, err)
+	require.NotNil(t, updated.LastUsedAt)
+	assert.True(t, lastUsedAt.Equal(*updated.LastUsedAt))
+}
@@ -0,0 +1,20 @@
+package accesstoken
+
+import (
+	"github.com/vera/vera-drive-service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, h *Handler, authMiddleware middleware.AuthMiddleware) {
+	read := middleware.RequireScope(middleware.ScopeDriveRead)
+	admin := middleware.RequireScope(middleware.ScopeDriveAdmin)
+
+	g := r.Group("/access-tokens")
+	g.Use(gin.HandlerFunc(authMiddleware))
+	{
+		g.POST("", admin, h.CreateToken)
+		g.GET("", read, h.GetTokens)
+		g.DELETE("/:id", admin, h.DeleteToken)
+	}
+}
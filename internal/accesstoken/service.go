@@ -0,0 +1,115 @@
+package accesstoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/middleware"
+)
+
+// lastUsedResolution limits how often the last use of a token is written,
+// so that a script making many requests does not write on each of them.
+const lastUsedResolution = time.Minute
+
+// prefixLength is the number of characters of a token kept to tell tokens
+// apart in listings.
+const prefixLength = 12
+
+type Service interface {
+	CreateToken(creates *CreateRequestBody, userID string) (*CreateAccessTokenResponse, error)
+	GetTokens(userID string) ([]AccessTokenResponse, error)
+	DeleteToken(id string, userID string) error
+	// Authenticate returns the owner of a personal access token and the
+	// scopes granted to it.
+	Authenticate(token string) (string, []string, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return middleware.AccessTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func (s *service) CreateToken(creates *CreateRequestBody, userID string) (*CreateAccessTokenResponse, error) {
+	if creates.ExpiresAt != nil && !creates.ExpiresAt.After(time.Now()) {
+		return nil, apperror.New(apperror.CodeAccessTokenExpiryInvalid, "Expiry must be in the future | expires_at: "+creates.ExpiresAt.Format(time.RFC3339))
+	}
+	secret, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	token := &AccessToken{
+		UserID:    userID,
+		Name:      creates.Name,
+		TokenHash: hashToken(secret),
+		Prefix:    secret[:prefixLength],
+		Scope:     creates.Scope,
+		ExpiresAt: creates.ExpiresAt,
+	}
+	if err := s.repo.Create(token); err != nil {
+		return nil, err
+	}
+	return &CreateAccessTokenResponse{AccessTokenResponse: *newAccessTokenResponse(token), Token: secret}, nil
+}
+
+func (s *service) GetTokens(userID string) ([]AccessTokenResponse, error) {
+	tokens, err := s.repo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]AccessTokenResponse, len(tokens))
+	for i, token := range tokens {
+		responses[i] = *newAccessTokenResponse(&token)
+	}
+	return responses, nil
+}
+
+// DeleteToken revokes the token. Tokens of other users are reported as not
+// found.
+func (s *service) DeleteToken(id string, userID string) error {
+	token, err := s.repo.GetOne(id)
+	if err != nil {
+		return err
+	}
+	if token == nil || token.UserID != userID {
+		return apperror.New(apperror.CodeAccessTokenNotFound, "Access token not found | id: "+id)
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *service) Authenticate(secret string) (string, []string, error) {
+	token, err := s.repo.GetByHash(hashToken(secret))
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now().UTC()
+	if token == nil || (token.ExpiresAt != nil && !token.ExpiresAt.After(now)) {
+		return "", nil, apperror.New(apperror.CodeAccessTokenInvalid, "Access token is unknown, revoked or expired")
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		if err := s.repo.UpdateLastUsed(token.ID, now); err != nil {
+			return "", nil, err
+		}
+	}
+	return token.UserID, token.Scope.DriveScopes(), nil
+}
@@ -0,0 +1,248 @@
+package accesstoken
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Create(token *AccessToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+func (m *MockRepository) GetOne(id string) (*AccessToken, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*AccessToken), args.Error(1)
+}
+func (m *MockRepository) GetByUser(userID string) ([]AccessToken, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]AccessToken), args.Error(1)
+}
+func (m *MockRepository) GetByHash(tokenHash string) (*AccessToken, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*AccessToken), args.Error(1)
+}
+func (m *MockRepository) Delete(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+func (m *MockRepository) UpdateLastUsed(id string, lastUsedAt time.Time) error {
+	args := m.Called(id, lastUsedAt)
+	return args.Error(0)
+}
+
+const tokenID = "123e4567-e89b-12d3-a456-426614174000"
+
+func TestService_NewService_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+
+	// Act
+	s := NewService(mockRepo)
+
+	// Assert
+	assert.IsType(t, &service{}, s)
+	assert.Equal(t, mockRepo, s.(*service).repo)
+}
+
+func TestService_CreateToken_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	expiresAt := time.Now().Add(24 * time.Hour)
+	var created *AccessToken
+	mockRepo.On("Create", mock.AnythingOfType("*accesstoken.AccessToken")).Run(func(args mock.Arguments) {
+		created = args.Get(0).(*AccessToken)
+		created.ID = tokenID
+	}).Return(nil)
+
+	// Act
+	response, err := s.CreateToken(&CreateRequestBody{Name: "backup script", Scope: ScopeRead, ExpiresAt: &expiresAt}, "1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(response.Token, "vdp_"))
+	assert.Equal(t, response.Token[:prefixLength], response.Prefix)
+	assert.Equal(t, tokenID, response.ID)
+	assert.Equal(t, ScopeRead, response.Scope)
+	require.NotNil(t, response.ExpiresAt)
+	assert.Equal(t, "1", created.UserID)
+	assert.Equal(t, hashToken(response.Token), created.TokenHash)
+	assert.NotContains(t, created.TokenHash, response.Token)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_CreateToken_ExpiryInPast(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	expiresAt := time.Now().Add(-time.Minute)
+
+	// Act
+	response, err := s.CreateToken(&CreateRequestBody{Name: "backup script", Scope: ScopeRead, ExpiresAt: &expiresAt}, "1")
+
+	// Assert
+	assert.Nil(t, response)
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeAccessTokenExpiryInvalid, appErr.Code)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestService_GetTokens_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	lastUsedAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	mockRepo.On("GetByUser", "1").Return([]AccessToken{
+		{ID: tokenID, UserID: "1", Name: "cli", Prefix: "vdp_abcdefgh", Scope: ScopeWrite, LastUsedAt: &lastUsedAt, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}, nil)
+
+	// Act
+	response, err := s.GetTokens("1")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, response, 1)
+	assert.Equal(t, "cli", response[0].Name)
+	assert.Equal(t, "2024-01-02T00:00:00Z", *response[0].LastUsedAt)
+	assert.Nil(t, response[0].ExpiresAt)
+}
+
+func TestService_DeleteToken_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	mockRepo.On("GetOne", tokenID).Return(&AccessToken{ID: tokenID, UserID: "1"}, nil)
+	mockRepo.On("Delete", tokenID).Return(nil)
+
+	// Act
+	err := s.DeleteToken(tokenID, "1")
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_DeleteToken_NotFound(t *testing.T) {
+	tests := []struct {
+		name  string
+		token *AccessToken
+	}{
+		{name: "missing", token: nil},
+		{name: "other user", token: &AccessToken{ID: tokenID, UserID: "2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := &MockRepository{}
+			s := NewService(mockRepo)
+			if tt.token == nil {
+				mockRepo.On("GetOne", tokenID).Return(nil, nil)
+			} else {
+				mockRepo.On("GetOne", tokenID).Return(tt.token, nil)
+			}
+
+			// Act
+			err := s.DeleteToken(tokenID, "1")
+
+			// Assert
+			var appErr *apperror.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, apperror.CodeAccessTokenNotFound, appErr.Code)
+			mockRepo.AssertNotCalled(t, "Delete", mock.Anything)
+		})
+	}
+}
+
+func TestService_Authenticate_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	mockRepo.On("GetByHash", hashToken("vdp_secret")).Return(&AccessToken{ID: tokenID, UserID: "1", Scope: ScopeRead}, nil)
+	mockRepo.On("UpdateLastUsed", tokenID, mock.AnythingOfType("time.Time")).Return(nil)
+
+	// Act
+	userID, scopes, err := s.Authenticate("vdp_secret")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "1", userID)
+	assert.Equal(t, []string{middleware.ScopeDriveRead}, scopes)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_Authenticate_RecentlyUsed(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	lastUsedAt := time.Now().Add(-time.Second)
+	mockRepo.On("GetByHash", hashToken("vdp_secret")).Return(&AccessToken{ID: tokenID, UserID: "1", Scope: ScopeWrite, LastUsedAt: &lastUsedAt}, nil)
+
+	// Act
+	userID, scopes, err := s.Authenticate("vdp_secret")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "1", userID)
+	assert.Equal(t, []string{middleware.ScopeDriveWrite}, scopes)
+	mockRepo.AssertNotCalled(t, "UpdateLastUsed", mock.Anything, mock.Anything)
+}
+func TestService_Authenticate_Invalid(t *testing.T) {
+	expiredAt := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name  string
+		token *AccessToken
+	}{
+		{name: "unknown", token: nil},
+		{name: "expired", token: &AccessToken{ID: tokenID, UserID: "1", Scope: ScopeWrite, ExpiresAt: &expiredAt}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := &MockRepository{}
+			s := NewService(mockRepo)
+			if tt.token == nil {
+				mockRepo.On("GetByHash", hashToken("vdp_secret")).Return(nil, nil)
+			} else {
+				mockRepo.On("GetByHash", hashToken("vdp_secret")).Return(tt.token, nil)
+			}
+
+			// Act
+			_, _, err := s.Authenticate("vdp_secret")
+
+			// Assert
+			var appErr *apperror.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, apperror.CodeAccessTokenInvalid, appErr.Code)
+			mockRepo.AssertNotCalled(t, "UpdateLastUsed", mock.Anything, mock.Anything)
+		})
+	}
+}
+func TestService_Authenticate_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	mockRepo.On("GetByHash", hashToken("vdp_secret")).Return(nil, errors.New("database error"))
+
+	// Act
+	_, _, err := s.Authenticate("vdp_secret")
+
+	// Assert
+	assert.EqualError(t, err, "database error")
+}
@@ -0,0 +1,10 @@
+package account
+
+// UserErased is emitted when the data of a user was erased, so that other
+// services can erase what they hold about the user.
+type UserErased struct {
+	UserID    string `json:"user_id"`
+	ErasureID string `json:"erasure_id"`
+}
+
+func (UserErased) EventType() string { return "user.erased" }
@@ -0,0 +1,74 @@
+package account
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type UserURI struct {
+	UserID string `uri:"user_id" binding:"required,max=255"`
+}
+
+type TransferRequestBody struct {
+	ToUserID string `json:"to_user_id" binding:"required,max=255"`
+}
+
+type StatsResponse struct {
+	UserID         string  `json:"user_id"`
+	Folders        int64   `json:"folders"`
+	URLs           int64   `json:"urls"`
+	Mounts         int64   `json:"mounts"`
+	Trashed        int64   `json:"trashed"`
+	BrokenURLs     int64   `json:"broken_urls"`
+	Collaborators  int64   `json:"collaborators"`
+	SharedWithUser int64   `json:"shared_with_user"`
+	ShareLinks     int64   `json:"share_links"`
+	Webhooks       int64   `json:"webhooks"`
+	AccessTokens   int64   `json:"access_tokens"`
+	LastChangedAt  *string `json:"last_changed_at"`
+}
+
+func newStatsResponse(userID string, stats *Stats) *StatsResponse {
+	response := &StatsResponse{
+		UserID:         userID,
+		Folders:        stats.Folders,
+		URLs:           stats.URLs,
+		Mounts:         stats.Mounts,
+		Trashed:        stats.Trashed,
+		BrokenURLs:     stats.BrokenURLs,
+		Collaborators:  stats.Collaborators,
+		SharedWithUser: stats.SharedWithUser,
+		ShareLinks:     stats.ShareLinks,
+		Webhooks:       stats.Webhooks,
+		AccessTokens:   stats.AccessTokens,
+	}
+	if stats.LastChangedAt != nil {
+		lastChangedAt := stats.LastChangedAt.UTC().Format(time.RFC3339)
+		response.LastChangedAt = &lastChangedAt
+	}
+	return response
+}
+
+type TransferResponse struct {
+	FolderID string `json:"folder_id"`
+	Name     string `json:"name"`
+	Nodes    int64  `json:"nodes"`
+}
+
+type ErasureResponse struct {
+	ID          string          `json:"id"`
+	UserID      string          `json:"user_id"`
+	RequestedBy string          `json:"requested_by"`
+	DeletedRows json.RawMessage `json:"deleted_rows"`
+	CreatedAt   string          `json:"created_at"`
+}
+
+func newErasureResponse(erasure *Erasure) *ErasureResponse {
+	return &ErasureResponse{
+		ID:          erasure.ID,
+		UserID:      erasure.UserID,
+		RequestedBy: erasure.RequestedBy,
+		DeletedRows: json.RawMessage(erasure.DeletedRows),
+		CreatedAt:   erasure.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,296 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/url"
+	"github.com/vera/vera-drive-service/internal/user"
+	"github.com/vera/vera-drive-service/internal/webhook"
+)
+
+// exportFormatVersion is increased when the layout of the export archive
+// changes in a way readers have to know about.
+const exportFormatVersion = 1
+
+// ExportArchive is a zip archive of everything stored for a user, with one
+// JSON file per kind of data and a manifest.json describing them.
+type ExportArchive struct {
+	FileName string
+	Content  []byte
+}
+
+type exportManifest struct {
+	UserID        string         `json:"user_id"`
+	GeneratedAt   time.Time      `json:"generated_at"`
+	FormatVersion int            `json:"format_version"`
+	Files         map[string]int `json:"files"`
+}
+
+type exportNode struct {
+	ID             string     `json:"id"`
+	ParentID       *string    `json:"parent_id"`
+	Name           string     `json:"name"`
+	Type           string     `json:"type"`
+	URL            *string    `json:"url"`
+	TargetID       *string    `json:"target_id"`
+	NormalizedURL  *string    `json:"normalized_url"`
+	Title          *string    `json:"title"`
+	Description    *string    `json:"description"`
+	ImageURL       *string    `json:"image_url"`
+	FaviconURL     *string    `json:"favicon_url"`
+	LastStatusCode *int       `json:"last_status_code"`
+	LastCheckError *string    `json:"last_check_error"`
+	RedirectURL    *string    `json:"redirect_url"`
+	LastCheckedAt  *time.Time `json:"last_checked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at"`
+}
+
+type exportRevision struct {
+	ID        string          `json:"id"`
+	NodeID    string          `json:"node_id"`
+	Revision  int             `json:"revision"`
+	ActorID   string          `json:"actor_id"`
+	Snapshot  json.RawMessage `json:"snapshot"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type exportOperation struct {
+	ID        string          `json:"id"`
+	Changes   json.RawMessage `json:"changes"`
+	UndoneAt  *time.Time      `json:"undone_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type exportChange struct {
+	Seq       int64        `json:"seq"`
+	NodeID    string       `json:"node_id"`
+	Action    audit.Action `json:"action"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type exportPermission struct {
+	ID        string    `json:"id"`
+	FolderID  string    `json:"folder_id"`
+	UserID    string    `json:"user_id"`
+	Role      url.Role  `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// exportShareLink leaves out the token, which grants access to the folder to
+// anyone holding it.
+type exportShareLink struct {
+	ID          string     `json:"id"`
+	FolderID    string     `json:"folder_id"`
+	CreatedBy   string     `json:"created_by"`
+	HasPassword bool       `json:"has_password"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// exportWebhook leaves out the signing secret, which is a credential rather
+// than data about the user.
+type exportWebhook struct {
+	ID        string          `json:"id"`
+	URL       string          `json:"url"`
+	Events    json.RawMessage `json:"events"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type exportDelivery struct {
+	ID             string                 `json:"id"`
+	WebhookID      string                 `json:"webhook_id"`
+	Event          audit.Action           `json:"event"`
+	Payload        json.RawMessage        `json:"payload"`
+	Status         webhook.DeliveryStatus `json:"status"`
+	Attempts       int                    `json:"attempts"`
+	ResponseStatus *int                   `json:"response_status"`
+	Error          *string                `json:"error"`
+	DeliveredAt    *time.Time             `json:"delivered_at"`
+	CreatedAt      time.Time              `json:"created_at"`
+}
+
+// exportAccessToken leaves out the token hash for the same reason.
+type exportAccessToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scope      string     `json:"scope"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type exportPreferences struct {
+	SortOrder     user.SortOrder `json:"sort_order"`
+	ViewMode      user.ViewMode  `json:"view_mode"`
+	StartFolderID *string        `json:"start_folder_id"`
+	Theme         user.Theme     `json:"theme"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+type exportErasure struct {
+	ID          string          `json:"id"`
+	RequestedBy string          `json:"requested_by"`
+	DeletedRows json.RawMessage `json:"deleted_rows"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func exportPermissions(permissions []url.FolderPermission) []exportPermission {
+	records := make([]exportPermission, len(permissions))
+	for i, p := range permissions {
+		records[i] = exportPermission{ID: p.ID, FolderID: p.FolderID, UserID: p.UserID, Role: p.Role, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt}
+	}
+	return records
+}
+
+// exportFile is a JSON file of the archive holding an array of records.
+type exportFile struct {
+	name    string
+	records interface{}
+	count   int
+}
+
+// exportFiles returns the files of the archive in the order they are
+// written.
+func exportFiles(data *UserData) []exportFile {
+	nodes := make([]exportNode, len(data.Nodes))
+	for i, n := range data.Nodes {
+		nodes[i] = exportNode{
+			ID:             n.ID,
+			ParentID:       n.ParentID,
+			Name:           n.Name,
+			Type:           n.Type,
+			URL:            n.URL,
+			TargetID:       n.TargetID,
+			NormalizedURL:  n.NormalizedURL,
+			Title:          n.Title,
+			Description:    n.Description,
+			ImageURL:       n.ImageURL,
+			FaviconURL:     n.FaviconURL,
+			LastStatusCode: n.LastStatusCode,
+			LastCheckError: n.LastCheckError,
+			RedirectURL:    n.RedirectURL,
+			LastCheckedAt:  n.LastCheckedAt,
+			CreatedAt:      n.CreatedAt,
+			UpdatedAt:      n.UpdatedAt,
+			DeletedAt:      n.DeletedAt,
+		}
+	}
+	revisions := make([]exportRevision, len(data.Revisions))
+	for i, r := range data.Revisions {
+		revisions[i] = exportRevision{ID: r.ID, NodeID: r.NodeID, Revision: r.Revision, ActorID: r.ActorID, Snapshot: json.RawMessage(r.Snapshot), CreatedAt: r.CreatedAt}
+	}
+	auditLogs := make([]audit.Entry, len(data.AuditLogs))
+	for i, l := range data.AuditLogs {
+		auditLogs[i] = *audit.NewEntry(&l)
+	}
+	operations := make([]exportOperation, len(data.Operations))
+	for i, o := range data.Operations {
+		operations[i] = exportOperation{ID: o.ID, Changes: json.RawMessage(o.Changes), UndoneAt: o.UndoneAt, CreatedAt: o.CreatedAt}
+	}
+	changes := make([]exportChange, len(data.Changes))
+	for i, c := range data.Changes {
+		changes[i] = exportChange{Seq: c.Seq, NodeID: c.NodeID, Action: c.Action, CreatedAt: c.CreatedAt}
+	}
+	shareLinks := make([]exportShareLink, len(data.ShareLinks))
+	for i, l := range data.ShareLinks {
+		shareLinks[i] = exportShareLink{ID: l.ID, FolderID: l.FolderID, CreatedBy: l.UserID, HasPassword: l.PasswordHash != nil, ExpiresAt: l.ExpiresAt, CreatedAt: l.CreatedAt}
+	}
+	webhooks := make([]exportWebhook, len(data.Webhooks))
+	for i, w := range data.Webhooks {
+		webhooks[i] = exportWebhook{ID: w.ID, URL: w.URL, Events: json.RawMessage(w.Events), CreatedAt: w.CreatedAt}
+	}
+	deliveries := make([]exportDelivery, len(data.Deliveries))
+	for i, d := range data.Deliveries {
+		deliveries[i] = exportDelivery{
+			ID:             d.ID,
+			WebhookID:      d.WebhookID,
+			Event:          d.Event,
+			Payload:        json.RawMessage(d.Payload),
+			Status:         d.Status,
+			Attempts:       d.Attempts,
+			ResponseStatus: d.ResponseStatus,
+			Error:          d.Error,
+			DeliveredAt:    d.DeliveredAt,
+			CreatedAt:      d.CreatedAt,
+		}
+	}
+	accessTokens := make([]exportAccessToken, len(data.AccessTokens))
+	for i, t := range data.AccessTokens {
+		accessTokens[i] = exportAccessToken{ID: t.ID, Name: t.Name, Prefix: t.Prefix, Scope: string(t.Scope), ExpiresAt: t.ExpiresAt, LastUsedAt: t.LastUsedAt, CreatedAt: t.CreatedAt}
+	}
+	preferences := make([]exportPreferences, len(data.Preferences))
+	for i, p := range data.Preferences {
+		preferences[i] = exportPreferences{SortOrder: p.SortOrder, ViewMode: p.ViewMode, StartFolderID: p.StartFolderID, Theme: p.Theme, UpdatedAt: p.UpdatedAt}
+	}
+	erasures := make([]exportErasure, len(data.Erasures))
+	for i, e := range data.Erasures {
+		erasures[i] = exportErasure{ID: e.ID, RequestedBy: e.RequestedBy, DeletedRows: json.RawMessage(e.DeletedRows), CreatedAt: e.CreatedAt}
+	}
+
+	return []exportFile{
+		{"nodes.json", nodes, len(nodes)},
+		{"revisions.json", revisions, len(revisions)},
+		{"audit_logs.json", auditLogs, len(auditLogs)},
+		{"operations.json", operations, len(operations)},
+		{"changes.json", changes, len(changes)},
+		{"collaborators.json", exportPermissions(data.Collaborators), len(data.Collaborators)},
+		{"shared_with_me.json", exportPermissions(data.SharedWithUser), len(data.SharedWithUser)},
+		{"share_links.json", shareLinks, len(shareLinks)},
+		{"webhooks.json", webhooks, len(webhooks)},
+		{"webhook_deliveries.json", deliveries, len(deliveries)},
+		{"access_tokens.json", accessTokens, len(accessTokens)},
+		{"preferences.json", preferences, len(preferences)},
+		{"erasures.json", erasures, len(erasures)},
+	}
+}
+
+// newExportArchive writes the data of the user to a zip archive.
+func newExportArchive(userID string, data *UserData, generatedAt time.Time) (*ExportArchive, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	write := func(name string, content interface{}) error {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: generatedAt})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(content)
+	}
+
+	files := exportFiles(data)
+	manifest := exportManifest{
+		UserID:        userID,
+		GeneratedAt:   generatedAt,
+		FormatVersion: exportFormatVersion,
+		Files:         map[string]int{},
+	}
+	for _, file := range files {
+		manifest.Files[file.name] = file.count
+	}
+	if err := write("manifest.json", manifest); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err := write(file.name, file.records); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return &ExportArchive{
+		FileName: fmt.Sprintf("vera-drive-export-%s-%s.zip", userID, generatedAt.Format("20060102T150405Z")),
+		Content:  buf.Bytes(),
+	}, nil
+}
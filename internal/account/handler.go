@@ -0,0 +1,105 @@
+package account
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetStats(c *gin.Context) {
+	uri := &UserURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	response, err := h.service.GetStats(uri.UserID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) writeExport(c *gin.Context, userID string) {
+	archive, err := h.service.Export(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// User IDs are opaque, so the file name is quoted or encoded as needed.
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": archive.FileName}))
+	c.Data(http.StatusOK, "application/zip", archive.Content)
+}
+
+func (h *Handler) ExportUserData(c *gin.Context) {
+	uri := &UserURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	h.writeExport(c, uri.UserID)
+}
+
+func (h *Handler) ExportOwnData(c *gin.Context) {
+	h.writeExport(c, c.GetString("user_id"))
+}
+
+func (h *Handler) EraseUser(c *gin.Context) {
+	uri := &UserURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	response, err := h.service.EraseUser(uri.UserID, "service:"+c.GetString("service"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) EraseOwnData(c *gin.Context) {
+	response, err := h.service.EraseUser(c.GetString("user_id"), "user")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) TransferTree(c *gin.Context) {
+	uri := &UserURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+	body := &TransferRequestBody{}
+	if err := c.ShouldBindJSON(body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body | " + err.Error()})
+		return
+	}
+
+	response, err := h.service.TransferTree(uri.UserID, body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
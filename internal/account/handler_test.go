@@ -0,0 +1,279 @@
+package account
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) GetStats(userID string) (*StatsResponse, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*StatsResponse), args.Error(1)
+}
+func (m *MockService) Export(userID string) (*ExportArchive, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ExportArchive), args.Error(1)
+}
+func (m *MockService) EraseUser(userID string, requestedBy string) (*ErasureResponse, error) {
+	args := m.Called(userID, requestedBy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ErasureResponse), args.Error(1)
+}
+func (m *MockService) TransferTree(fromUserID string, transfers *TransferRequestBody) (*TransferResponse, error) {
+	args := m.Called(fromUserID, transfers)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TransferResponse), args.Error(1)
+}
+
+func TestHandler_NewHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+
+	// Act
+	h := NewHandler(mockService)
+
+	// Assert
+	assert.IsType(t, &Handler{}, h)
+	assert.Equal(t, mockService, h.service)
+}
+
+func TestHandler_GetStats_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "user_id", Value: "1"}}
+
+	expected := &StatsResponse{UserID: "1", Folders: 2, URLs: 5}
+	mockService.On("GetStats", "1").Return(expected, nil)
+
+	// Act
+	handler.GetStats(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	var response StatsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expected, response)
+}
+func TestHandler_GetStats_InvalidURI(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "user_id", Value: strings.Repeat("a", 256)}}
+
+	// Act
+	handler.GetStats(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "GetStats", mock.Anything)
+}
+
+func TestHandler_ExportUserData_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "user_id", Value: "1"}}
+
+	archive := &ExportArchive{FileName: "vera-drive-export-1-20240102T030405Z.zip", Content: []byte("PK")}
+	mockService.On("Export", "1").Return(archive, nil)
+
+	// Act
+	handler.ExportUserData(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=vera-drive-export-1-20240102T030405Z.zip", w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "PK", w.Body.String())
+}
+func TestHandler_ExportUserData_InvalidURI(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "user_id", Value: strings.Repeat("a", 256)}}
+
+	// Act
+	handler.ExportUserData(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "Export", mock.Anything)
+}
+
+func TestHandler_ExportOwnData_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Set("user_id", "1")
+
+	mockService.On("Export", "1").Return(&ExportArchive{FileName: "export.zip", Content: []byte("PK")}, nil)
+
+	// Act
+	handler.ExportOwnData(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_ExportOwnData_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Set("user_id", "1")
+
+	mockService.On("Export", "1").Return(nil, errors.New("database error"))
+
+	// Act
+	handler.ExportOwnData(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+}
+
+func TestHandler_EraseUser_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "user_id", Value: "1"}}
+	c.Set("service", "identity-service")
+
+	expected := &ErasureResponse{ID: erasureID, UserID: "1", RequestedBy: "service:identity-service", DeletedRows: json.RawMessage(`{"url_nodes":3}`)}
+	mockService.On("EraseUser", "1", "service:identity-service").Return(expected, nil)
+
+	// Act
+	handler.EraseUser(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	var response ErasureResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expected, response)
+}
+func TestHandler_EraseUser_InvalidURI(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "user_id", Value: strings.Repeat("a", 256)}}
+
+	// Act
+	handler.EraseUser(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "EraseUser", mock.Anything, mock.Anything)
+}
+
+func TestHandler_EraseOwnData_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Set("user_id", "1")
+
+	mockService.On("EraseUser", "1", "user").Return(&ErasureResponse{ID: erasureID, UserID: "1", RequestedBy: "user"}, nil)
+
+	// Act
+	handler.EraseOwnData(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_TransferTree_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "user_id", Value: "1"}}
+
+	body := TransferRequestBody{ToUserID: "2"}
+	requestJSON, _ := json.Marshal(body)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	expected := &TransferResponse{FolderID: sourceRootID, Name: "From user 1", Nodes: 3}
+	mockService.On("TransferTree", "1", &body).Return(expected, nil)
+
+	// Act
+	handler.TransferTree(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	var response TransferResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expected, response)
+}
+func TestHandler_TransferTree_InvalidBody(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "user_id", Value: "1"}}
+	c.Request.Body = io.NopCloser(bytes.NewBufferString(`{}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	// Act
+	handler.TransferTree(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "TransferTree", mock.Anything, mock.Anything)
+}
+func TestHandler_TransferTree_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "user_id", Value: "1"}}
+	c.Request.Body = io.NopCloser(bytes.NewBufferString(`{"to_user_id":"2"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	mockService.On("TransferTree", "1", &TransferRequestBody{ToUserID: "2"}).Return(nil, apperror.New(apperror.CodeTreeNotFound, "User has no tree"))
+
+	// Act
+	handler.TransferTree(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+}
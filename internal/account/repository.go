@@ -0,0 +1,383 @@
+package account
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/accesstoken"
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/outbox"
+	"github.com/vera/vera-drive-service/internal/share"
+	"github.com/vera/vera-drive-service/internal/url"
+	"github.com/vera/vera-drive-service/internal/user"
+	"github.com/vera/vera-drive-service/internal/webhook"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Erasure records that the data of a user was erased. It holds no personal
+// data besides the user id, so that it can be kept after the erasure.
+// DeletedRows is a JSON object of the number of rows deleted per table.
+type Erasure struct {
+	ID          string    `gorm:"type:uuid;primary_key"`
+	UserID      string    `gorm:"type:varchar(255);not null;index"`
+	RequestedBy string    `gorm:"type:varchar(100);not null"`
+	DeletedRows string    `gorm:"type:jsonb;not null"`
+	CreatedAt   time.Time `gorm:"type:timestamptz;not null"`
+}
+
+func (Erasure) TableName() string {
+	return "erasures"
+}
+
+func (e *Erasure) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	e.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// UserData is everything stored for a user, including the trash and the
+// history of their tree.
+type UserData struct {
+	Nodes          []url.URLNode
+	Revisions      []url.NodeRevision
+	AuditLogs      []audit.AuditLog
+	Operations     []url.Operation
+	Changes        []url.NodeChange
+	Collaborators  []url.FolderPermission
+	SharedWithUser []url.FolderPermission
+	ShareLinks     []share.ShareLink
+	Webhooks       []webhook.Webhook
+	Deliveries     []webhook.Delivery
+	AccessTokens   []accesstoken.AccessToken
+	Preferences    []user.Preferences
+	Erasures       []Erasure
+}
+
+// Stats counts what is stored for a user. The root folder of the tree is not
+// counted.
+type Stats struct {
+	Folders        int64
+	URLs           int64 `gorm:"column:urls"`
+	Mounts         int64
+	Trashed        int64
+	BrokenURLs     int64 `gorm:"column:broken_urls"`
+	Collaborators  int64
+	SharedWithUser int64
+	ShareLinks     int64
+	Webhooks       int64
+	AccessTokens   int64
+	LastChangedAt  *time.Time
+}
+
+type Repository interface {
+	Transaction(fn func(repo Repository) error) error
+	GetStats(userID string) (*Stats, error)
+	GetUserData(userID string) (*UserData, error)
+	GetForeignMounts(userID string) ([]url.URLNode, error)
+	DeleteUserData(userID string) (map[string]int64, error)
+	CreateErasure(erasure *Erasure) error
+	CreateOutboxEvent(event *outbox.OutboxEvent) error
+	GetRoot(userID string) (*url.URLNode, error)
+	CreateNode(node *url.URLNode) error
+	GetChildNames(parentID string) ([]string, error)
+	GetNodes(userID string) ([]url.URLNode, error)
+	AppendChanges(userID string, nodeIDs []string, action audit.Action) error
+	CreateAuditLogs(entries []audit.AuditLog) error
+	TransferNodes(fromUserID string, toUserID string) (int64, error)
+	MoveNode(id string, parentID string, name string) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+func (r *repository) GetStats(userID string) (*Stats, error) {
+	var stats Stats
+	err := r.db.Raw(`
+		SELECT
+			(SELECT COUNT(*) FROM url_nodes WHERE user_id = @user_id AND deleted_at IS NULL AND type = 'folder' AND parent_id IS NOT NULL) AS folders,
+			(SELECT COUNT(*) FROM url_nodes WHERE user_id = @user_id AND deleted_at IS NULL AND type = 'url') AS urls,
+			(SELECT COUNT(*) FROM url_nodes WHERE user_id = @user_id AND deleted_at IS NULL AND type = 'mount') AS mounts,
+			(SELECT COUNT(*) FROM url_nodes WHERE user_id = @user_id AND deleted_at IS NOT NULL) AS trashed,
+			(SELECT COUNT(*) FROM url_nodes WHERE user_id = @user_id AND deleted_at IS NULL AND type = 'url'
+				AND last_checked_at IS NOT NULL AND (last_status_code IS NULL OR last_status_code >= 400)) AS broken_urls,
+			(SELECT COUNT(*) FROM folder_permissions p JOIN url_nodes n ON n.id = p.folder_id WHERE n.user_id = @user_id) AS collaborators,
+			(SELECT COUNT(*) FROM folder_permissions WHERE user_id = @user_id) AS shared_with_user,
+			(SELECT COUNT(*) FROM share_links WHERE user_id = @user_id) AS share_links,
+			(SELECT COUNT(*) FROM webhooks WHERE user_id = @user_id) AS webhooks,
+			(SELECT COUNT(*) FROM access_tokens WHERE user_id = @user_id) AS access_tokens,
+			(SELECT MAX(updated_at) FROM url_nodes WHERE user_id = @user_id) AS last_changed_at`,
+		map[string]interface{}{"user_id": userID}).Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// ownNodes selects the ids of every node of the user, including the trash.
+func (r *repository) ownNodes(userID string) *gorm.DB {
+	return r.db.Model(&url.URLNode{}).Select("id").Where("user_id = ?", userID)
+}
+
+// GetForeignMounts returns the mounts other users made of folders of the
+// user.
+func (r *repository) GetForeignMounts(userID string) ([]url.URLNode, error) {
+	var nodes []url.URLNode
+	err := r.db.
+		Where("type = 'mount' AND user_id <> ? AND target_id IN (?)", userID, r.ownNodes(userID)).
+		Find(&nodes).Error
+	return nodes, err
+}
+
+// GetUserData reads everything stored for the user from one snapshot of the
+// database. Audit logs and revisions of changes the user made in trees of
+// other users are included.
+func (r *repository) GetUserData(userID string) (*UserData, error) {
+	data := &UserData{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		nodes := tx.Model(&url.URLNode{}).Select("id").Where("user_id = ?", userID)
+		webhooks := tx.Model(&webhook.Webhook{}).Select("id").Where("user_id = ?", userID)
+		queries := []func() error{
+			func() error {
+				return tx.Where("user_id = ?", userID).Order("created_at, id").Find(&data.Nodes).Error
+			},
+			func() error {
+				return tx.Where("node_id IN (?) OR actor_id = ?", nodes, userID).Order("created_at, id").Find(&data.Revisions).Error
+			},
+			func() error {
+				return tx.Where("owner_id = ? OR actor_id = ?", userID, userID).Order("created_at, id").Find(&data.AuditLogs).Error
+			},
+			func() error {
+				return tx.Where("user_id = ?", userID).Order("created_at, id").Find(&data.Operations).Error
+			},
+			func() error {
+				return tx.Where("user_id = ?", userID).Order("seq").Find(&data.Changes).Error
+			},
+			func() error {
+				return tx.Where("folder_id IN (?)", nodes).Order("created_at, id").Find(&data.Collaborators).Error
+			},
+			func() error {
+				return tx.Where("user_id = ?", userID).Order("created_at, id").Find(&data.SharedWithUser).Error
+			},
+			func() error {
+				return tx.Where("user_id = ? OR folder_id IN (?)", userID, nodes).Order("created_at, id").Find(&data.ShareLinks).Error
+			},
+			func() error {
+				return tx.Where("user_id = ?", userID).Order("created_at, id").Find(&data.Webhooks).Error
+			},
+			func() error {
+				return tx.Where("webhook_id IN (?)", webhooks).Order("created_at, id").Find(&data.Deliveries).Error
+			},
+			func() error {
+				return tx.Where("user_id = ?", userID).Order("created_at, id").Find(&data.AccessTokens).Error
+			},
+			func() error {
+				return tx.Where("user_id = ?", userID).Find(&data.Preferences).Error
+			},
+			func() error {
+				return tx.Where("user_id = ?", userID).Order("created_at, id").Find(&data.Erasures).Error
+			},
+		}
+		for _, query := range queries {
+			if err := query(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// DeleteUserData hard-deletes the tree of the user with its history, the
+// sharing of and with the user, webhooks, access tokens, preferences and the
+// domain events about their tree. Mounts other users made of folders of the user
+// are deleted as well, since they would point to nothing. It returns the
+// number of rows deleted per table.
+//
+// Audit logs and revisions of changes the user made in trees of other users
+// are kept, they are part of the history of those trees.
+func (r *repository) DeleteUserData(userID string) (map[string]int64, error) {
+	mounts := r.db.Model(&url.URLNode{}).Select("id").
+		Where("type = 'mount' AND user_id <> ? AND target_id IN (?)", userID, r.ownNodes(userID))
+	webhooks := r.db.Model(&webhook.Webhook{}).Select("id").Where("user_id = ?", userID)
+
+	steps := []struct {
+		table  string
+		delete func() *gorm.DB
+	}{
+		{"node_revisions", func() *gorm.DB {
+			return r.db.Where("node_id IN (?) OR node_id IN (?)", r.ownNodes(userID), mounts).Delete(&url.NodeRevision{})
+		}},
+		{"url_nodes", func() *gorm.DB {
+			return r.db.Where("id IN (?)", mounts).Delete(&url.URLNode{})
+		}},
+		{"folder_permissions", func() *gorm.DB {
+			return r.db.Where("user_id = ? OR folder_id IN (?)", userID, r.ownNodes(userID)).Delete(&url.FolderPermission{})
+		}},
+		{"share_links", func() *gorm.DB {
+			return r.db.Where("user_id = ? OR folder_id IN (?)", userID, r.ownNodes(userID)).Delete(&share.ShareLink{})
+		}},
+		{"audit_logs", func() *gorm.DB {
+			return r.db.Where("owner_id = ?", userID).Delete(&audit.AuditLog{})
+		}},
+		{"operations", func() *gorm.DB {
+			return r.db.Where("user_id = ?", userID).Delete(&url.Operation{})
+		}},
+		{"node_changes", func() *gorm.DB {
+			return r.db.Where("user_id = ?", userID).Delete(&url.NodeChange{})
+		}},
+		{"change_sequences", func() *gorm.DB {
+			return r.db.Where("user_id = ?", userID).Delete(&url.ChangeSequence{})
+		}},
+		{"webhook_deliveries", func() *gorm.DB {
+			return r.db.Where("webhook_id IN (?)", webhooks).Delete(&webhook.Delivery{})
+		}},
+		{"webhooks", func() *gorm.DB {
+			return r.db.Where("user_id = ?", userID).Delete(&webhook.Webhook{})
+		}},
+		{"access_tokens", func() *gorm.DB {
+			return r.db.Where("user_id = ?", userID).Delete(&accesstoken.AccessToken{})
+		}},
+		{"user_preferences", func() *gorm.DB {
+			return r.db.Where("user_id = ?", userID).Delete(&user.Preferences{})
+		}},
+		{"outbox_events", func() *gorm.DB {
+			return r.db.Where("payload->>'owner_id' = ?", userID).Delete(&outbox.OutboxEvent{})
+		}},
+		{"url_nodes", func() *gorm.DB {
+			return r.db.Where("user_id = ?", userID).Delete(&url.URLNode{})
+		}},
+	}
+	rows := map[string]int64{}
+	for _, step := range steps {
+		result := step.delete()
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		rows[step.table] += result.RowsAffected
+	}
+	return rows, nil
+}
+
+func (r *repository) CreateErasure(erasure *Erasure) error {
+	return r.db.Create(erasure).Error
+}
+
+func (r *repository) CreateOutboxEvent(event *outbox.OutboxEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *repository) GetRoot(userID string) (*url.URLNode, error) {
+	var root url.URLNode
+	err := r.db.Where("parent_id IS NULL AND deleted_at IS NULL AND user_id = ?", userID).First(&root).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+func (r *repository) CreateNode(node *url.URLNode) error {
+	return r.db.Create(node).Error
+}
+
+func (r *repository) GetChildNames(parentID string) ([]string, error) {
+	var names []string
+	err := r.db.Model(&url.URLNode{}).
+		Where("parent_id = ? AND deleted_at IS NULL", parentID).
+		Pluck("name", &names).Error
+	return names, err
+}
+
+// GetNodes returns the nodes of the user outside the trash, in creation
+// order.
+func (r *repository) GetNodes(userID string) ([]url.URLNode, error) {
+	var nodes []url.URLNode
+	err := r.db.
+		Where("user_id = ? AND deleted_at IS NULL", userID).
+		Order("created_at, id").
+		Find(&nodes).Error
+	return nodes, err
+}
+
+// AppendChanges adds an entry per node to the change feed of the user,
+// reserving the sequence numbers in one step.
+func (r *repository) AppendChanges(userID string, nodeIDs []string, action audit.Action) error {
+	if len(nodeIDs) == 0 {
+		return nil
+	}
+	var lastSeq int64
+	err := r.db.Raw(`
+		INSERT INTO change_sequences (user_id, last_seq) VALUES (?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET last_seq = change_sequences.last_seq + EXCLUDED.last_seq
+		RETURNING last_seq`, userID, len(nodeIDs)).Scan(&lastSeq).Error
+	if err != nil {
+		return err
+	}
+
+	firstSeq := lastSeq - int64(len(nodeIDs)) + 1
+	changes := make([]url.NodeChange, len(nodeIDs))
+	for i, nodeID := range nodeIDs {
+		changes[i] = url.NodeChange{UserID: userID, Seq: firstSeq + int64(i), NodeID: nodeID, Action: action}
+	}
+	return r.db.CreateInBatches(changes, 500).Error
+}
+
+// CreateAuditLogs appends the entries to the audit log.
+func (r *repository) CreateAuditLogs(entries []audit.AuditLog) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(entries, 500).Error
+}
+
+// TransferNodes hands every node of fromUserID, including the trash, to
+// toUserID together with its share links. Audit log entries are append-only
+// and keep the owner at the time of the change. Permissions
+// toUserID held on the nodes are dropped since they now own them, and the
+// undo history of fromUserID is dropped since it refers to nodes they no
+// longer own. It returns the number of nodes transferred.
+func (r *repository) TransferNodes(fromUserID string, toUserID string) (int64, error) {
+	err := r.db.Where("user_id = ? AND folder_id IN (?)", toUserID, r.ownNodes(fromUserID)).Delete(&url.FolderPermission{}).Error
+	if err != nil {
+		return 0, err
+	}
+	err = r.db.Model(&share.ShareLink{}).Where("folder_id IN (?)", r.ownNodes(fromUserID)).Update("user_id", toUserID).Error
+	if err != nil {
+		return 0, err
+	}
+	err = r.db.Where("user_id = ?", fromUserID).Delete(&url.Operation{}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	result := r.db.Model(&url.URLNode{}).Where("user_id = ?", fromUserID).UpdateColumn("user_id", toUserID)
+	return result.RowsAffected, result.Error
+}
+
+func (r *repository) MoveNode(id string, parentID string, name string) error {
+	return r.db.Model(&url.URLNode{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"parent_id":  parentID,
+		"name":       name,
+		"updated_at": time.Now().UTC(),
+	}).Error
+}
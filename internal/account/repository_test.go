@@ -0,0 +1,330 @@
+package account
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"github.com/vera/vera-drive-service/internal/accesstoken"
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/db"
+	"github.com/vera/vera-drive-service/internal/outbox"
+	"github.com/vera/vera-drive-service/internal/share"
+	"github.com/vera/vera-drive-service/internal/url"
+	"github.com/vera/vera-drive-service/internal/user"
+	"github.com/vera/vera-drive-service/internal/webhook"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var d *gorm.DB
+
+// migrated uses a schema built with the SQL migrations, which has the
+// triggers AutoMigrate does not create.
+var migrated *gorm.DB
+
+func TestMain(m *testing.M) {
+	// Setup
+	dbURL, closeDB, err := test.SetupPostgresql()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	d, err = db.NewDatabase(&config.Config{DatabaseURL: dbURL})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = d.AutoMigrate(&url.URLNode{}, &url.FolderPermission{}, &audit.AuditLog{}, &url.NodeRevision{}, &url.Operation{}, &url.NodeChange{}, &url.ChangeSequence{}, &share.ShareLink{}, &webhook.Webhook{}, &webhook.Delivery{}, &accesstoken.AccessToken{}, &user.Preferences{}, &outbox.OutboxEvent{}, &Erasure{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = d.Exec("CREATE SCHEMA migrated").Error
+	if err != nil {
+		log.Fatal(err)
+	}
+	migrated, err = db.NewDatabase(&config.Config{DatabaseURL: dbURL + "&search_path=migrated"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = test.ApplyMigrations(migrated, "../../migrations")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Run
+	code := m.Run()
+
+	// Teardown
+	closeDB()
+
+	os.Exit(code)
+}
+
+// createTree creates a root folder for the user holding a folder with a
+// link, and returns the nodes.
+func createTree(t *testing.T, userID string) (*url.URLNode, *url.URLNode, *url.URLNode) {
+	link := "https://example.com"
+	root := &url.URLNode{UserID: userID, Type: "folder"}
+	require.NoError(t, d.Create(root).Error)
+	folder := &url.URLNode{UserID: userID, ParentID: &root.ID, Name: "Work", Type: "folder"}
+	require.NoError(t, d.Create(folder).Error)
+	node := &url.URLNode{UserID: userID, ParentID: &folder.ID, Name: "Example", Type: "url", URL: &link}
+	require.NoError(t, d.Create(node).Error)
+	return root, folder, node
+}
+
+func count(t *testing.T, model interface{}, query string, args ...interface{}) int64 {
+	var n int64
+	require.NoError(t, d.Model(model).Where(query, args...).Count(&n).Error)
+	return n
+}
+
+func TestRepository_NewRepository_Success(t *testing.T) {
+	// Act
+	repo := NewRepository(d)
+
+	// Assert
+	assert.NotNil(t, repo)
+	assert.IsType(t, &repository{}, repo)
+}
+
+func TestRepository_GetStats_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	_, folder, node := createTree(t, "1")
+	require.NoError(t, d.Create(&url.FolderPermission{FolderID: folder.ID, UserID: "2", Role: url.RoleViewer}).Error)
+	require.NoError(t, d.Create(&accesstoken.AccessToken{UserID: "1", Name: "cli", TokenHash: "hash", Prefix: "vdp_", Scope: accesstoken.ScopeRead}).Error)
+	require.NoError(t, d.Model(node).Update("deleted_at", node.CreatedAt).Error)
+
+	// Act
+	stats, err := repo.GetStats("1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Folders)
+	assert.Equal(t, int64(0), stats.URLs)
+	assert.Equal(t, int64(1), stats.Trashed)
+	assert.Equal(t, int64(1), stats.Collaborators)
+	assert.Equal(t, int64(1), stats.AccessTokens)
+	assert.NotNil(t, stats.LastChangedAt)
+}
+
+func TestRepository_DeleteUserData_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	_, folder, node := createTree(t, "1")
+	otherRoot, _, otherNode := createTree(t, "2")
+	mount := &url.URLNode{UserID: "2", ParentID: &otherRoot.ID, Name: "Work", Type: "mount", TargetID: &folder.ID}
+	require.NoError(t, d.Create(mount).Error)
+	require.NoError(t, d.Create(&url.FolderPermission{FolderID: folder.ID, UserID: "2", Role: url.RoleViewer}).Error)
+	require.NoError(t, d.Create(&url.NodeRevision{NodeID: node.ID, Revision: 1, ActorID: "1", Snapshot: "{}"}).Error)
+	require.NoError(t, d.Create(&url.NodeRevision{NodeID: otherNode.ID, Revision: 1, ActorID: "2", Snapshot: "{}"}).Error)
+	require.NoError(t, d.Create(&share.ShareLink{Token: "token", FolderID: folder.ID, UserID: "1"}).Error)
+	require.NoError(t, d.Create(&audit.AuditLog{NodeID: node.ID, OwnerID: "1", ActorID: "1", Action: audit.ActionCreate}).Error)
+	hook := &webhook.Webhook{UserID: "1", URL: "https://example.com/hook", Secret: "secret", Events: "[]"}
+	require.NoError(t, d.Create(hook).Error)
+	require.NoError(t, d.Create(&webhook.Delivery{WebhookID: hook.ID, Event: audit.ActionCreate, Payload: "{}"}).Error)
+	require.NoError(t, d.Create(&accesstoken.AccessToken{UserID: "1", Name: "cli", TokenHash: "hash", Prefix: "vdp_", Scope: accesstoken.ScopeRead}).Error)
+	require.NoError(t, d.Create(user.DefaultPreferences("1")).Error)
+	require.NoError(t, d.Create(&outbox.OutboxEvent{Type: "node.created", Payload: `{"owner_id":"1"}`}).Error)
+	require.NoError(t, d.Create(&outbox.OutboxEvent{Type: "node.created", Payload: `{"owner_id":1}`}).Error)
+	require.NoError(t, d.Create(&outbox.OutboxEvent{Type: "node.created", Payload: `{"owner_id":"2"}`}).Error)
+
+	// Act
+	var rows map[string]int64
+	err = repo.Transaction(func(repo Repository) error {
+		rows, err = repo.DeleteUserData("1")
+		return err
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), rows["url_nodes"])
+	assert.Equal(t, int64(1), rows["node_revisions"])
+	assert.Equal(t, int64(2), rows["outbox_events"])
+	assert.Equal(t, int64(1), rows["user_preferences"])
+	assert.Equal(t, int64(1), count(t, &outbox.OutboxEvent{}, "1 = 1"))
+	assert.Zero(t, count(t, &url.URLNode{}, "user_id = ?", "1"))
+	assert.Zero(t, count(t, &url.URLNode{}, "id = ?", mount.ID))
+	assert.Equal(t, int64(3), count(t, &url.URLNode{}, "user_id = ?", "2"))
+	assert.Zero(t, count(t, &url.FolderPermission{}, "1 = 1"))
+	assert.Equal(t, int64(1), count(t, &url.NodeRevision{}, "1 = 1"))
+	assert.Zero(t, count(t, &share.ShareLink{}, "1 = 1"))
+	assert.Zero(t, count(t, &audit.AuditLog{}, "1 = 1"))
+	assert.Zero(t, count(t, &webhook.Webhook{}, "1 = 1"))
+	assert.Zero(t, count(t, &webhook.Delivery{}, "1 = 1"))
+	assert.Zero(t, count(t, &accesstoken.AccessToken{}, "1 = 1"))
+}
+
+func TestRepository_GetUserData_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	_, folder, node := createTree(t, "1")
+	otherRoot, _, otherNode := createTree(t, "2")
+	require.NoError(t, d.Model(node).Update("deleted_at", node.CreatedAt).Error)
+	require.NoError(t, d.Create(&url.FolderPermission{FolderID: folder.ID, UserID: "2", Role: url.RoleViewer}).Error)
+	require.NoError(t, d.Create(&url.FolderPermission{FolderID: otherRoot.ID, UserID: "1", Role: url.RoleEditor}).Error)
+	require.NoError(t, d.Create(&url.NodeRevision{NodeID: node.ID, Revision: 1, ActorID: "1", Snapshot: "{}"}).Error)
+	require.NoError(t, d.Create(&url.NodeRevision{NodeID: otherNode.ID, Revision: 1, ActorID: "1", Snapshot: "{}"}).Error)
+	require.NoError(t, d.Create(&url.NodeRevision{NodeID: otherNode.ID, Revision: 2, ActorID: "2", Snapshot: "{}"}).Error)
+	require.NoError(t, d.Create(&audit.AuditLog{NodeID: otherNode.ID, OwnerID: "2", ActorID: "1", Action: audit.ActionUpdate}).Error)
+	require.NoError(t, d.Create(&audit.AuditLog{NodeID: otherNode.ID, OwnerID: "2", ActorID: "2", Action: audit.ActionUpdate}).Error)
+	require.NoError(t, d.Create(user.DefaultPreferences("1")).Error)
+	require.NoError(t, d.Create(user.DefaultPreferences("2")).Error)
+
+	// Act
+	data, err := repo.GetUserData("1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, data.Nodes, 3)
+	assert.Len(t, data.Revisions, 2)
+	assert.Len(t, data.AuditLogs, 1)
+	require.Len(t, data.Collaborators, 1)
+	assert.Equal(t, "2", data.Collaborators[0].UserID)
+	require.Len(t, data.SharedWithUser, 1)
+	assert.Equal(t, otherRoot.ID, data.SharedWithUser[0].FolderID)
+	assert.Len(t, data.Preferences, 1)
+}
+
+func TestRepository_AppendChanges_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+	require.NoError(t, d.Create(&url.ChangeSequence{UserID: "1", LastSeq: 4}).Error)
+
+	// Act
+	err = repo.AppendChanges("1", []string{"123e4567-e89b-12d3-a456-426614174000", "123e4567-e89b-12d3-a456-426614174001"}, audit.ActionCreate)
+
+	// Assert
+	require.NoError(t, err)
+	var changes []url.NodeChange
+	require.NoError(t, d.Where("user_id = ?", "1").Order("seq").Find(&changes).Error)
+	require.Len(t, changes, 2)
+	assert.Equal(t, int64(5), changes[0].Seq)
+	assert.Equal(t, int64(6), changes[1].Seq)
+	var sequence url.ChangeSequence
+	require.NoError(t, d.Where("user_id = ?", "1").First(&sequence).Error)
+	assert.Equal(t, int64(6), sequence.LastSeq)
+}
+
+func TestRepository_GetNodes_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	root, folder, node := createTree(t, "1")
+	createTree(t, "2")
+	trashed := &url.URLNode{UserID: "1", ParentID: &root.ID, Name: "Trashed", Type: "folder"}
+	require.NoError(t, d.Create(trashed).Error)
+	require.NoError(t, d.Delete(trashed).Error)
+
+	// Act
+	nodes, err := repo.GetNodes("1")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, nodes, 3)
+	assert.Equal(t, root.ID, nodes[0].ID)
+	assert.Equal(t, folder.ID, nodes[1].ID)
+	assert.Equal(t, node.ID, nodes[2].ID)
+}
+
+func TestRepository_CreateAuditLogs_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+	_, _, node := createTree(t, "1")
+
+	// Act
+	err = repo.CreateAuditLogs([]audit.AuditLog{
+		{NodeID: node.ID, OwnerID: "1", ActorID: "1", Action: audit.ActionDelete},
+		{NodeID: node.ID, OwnerID: "2", ActorID: "1", Action: audit.ActionCreate},
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count(t, &audit.AuditLog{}, "owner_id = ? AND action = ?", "1", audit.ActionDelete))
+	assert.Equal(t, int64(1), count(t, &audit.AuditLog{}, "owner_id = ? AND action = ?", "2", audit.ActionCreate))
+}
+
+func TestRepository_TransferNodes_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	root, folder, node := createTree(t, "1")
+	require.NoError(t, d.Create(&url.FolderPermission{FolderID: folder.ID, UserID: "2", Role: url.RoleEditor}).Error)
+	require.NoError(t, d.Create(&url.FolderPermission{FolderID: folder.ID, UserID: "3", Role: url.RoleViewer}).Error)
+	require.NoError(t, d.Create(&share.ShareLink{Token: "token", FolderID: folder.ID, UserID: "1"}).Error)
+	require.NoError(t, d.Create(&audit.AuditLog{NodeID: node.ID, OwnerID: "1", ActorID: "1", Action: audit.ActionCreate}).Error)
+	require.NoError(t, d.Create(&url.Operation{UserID: "1", Changes: "[]"}).Error)
+
+	// Act
+	transferred, err := repo.TransferNodes("1", "2")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), transferred)
+	assert.Equal(t, int64(3), count(t, &url.URLNode{}, "user_id = ?", "2"))
+	assert.Zero(t, count(t, &url.FolderPermission{}, "user_id = ?", "2"))
+	assert.Equal(t, int64(1), count(t, &url.FolderPermission{}, "user_id = ?", "3"))
+	assert.Equal(t, int64(1), count(t, &share.ShareLink{}, "user_id = ?", "2"))
+	assert.Equal(t, int64(1), count(t, &audit.AuditLog{}, "owner_id = ? AND actor_id = ?", "1", "1"))
+	assert.Zero(t, count(t, &url.Operation{}, "user_id = ?", "1"))
+	rootNode, err := repo.GetRoot("2")
+	require.NoError(t, err)
+	assert.Equal(t, root.ID, rootNode.ID)
+}
+func TestRepository_TransferNodes_MigratedSchema(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(migrated)
+	require.NoError(t, err)
+	repo := NewRepository(migrated)
+
+	link := "https://example.com"
+	root := &url.URLNode{UserID: "1", Type: "folder"}
+	require.NoError(t, migrated.Create(root).Error)
+	node := &url.URLNode{UserID: "1", ParentID: &root.ID, Name: "Example", Type: "url", URL: &link}
+	require.NoError(t, migrated.Create(node).Error)
+	require.NoError(t, migrated.Create(&audit.AuditLog{NodeID: node.ID, OwnerID: "1", ActorID: "1", Action: audit.ActionCreate}).Error)
+
+	// Act
+	var transferred int64
+	err = repo.Transaction(func(repo Repository) error {
+		var err error
+		transferred, err = repo.TransferNodes("1", "2")
+		if err != nil {
+			return err
+		}
+		return repo.CreateAuditLogs([]audit.AuditLog{{NodeID: node.ID, OwnerID: "2", ActorID: "1", Action: audit.ActionCreate}})
+	})
+	updateErr := migrated.Model(&audit.AuditLog{}).Where("owner_id = ?", "1").Update("owner_id", "2").Error
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), transferred)
+	var owners []string
+	require.NoError(t, migrated.Model(&audit.AuditLog{}).Order("owner_id").Pluck("owner_id", &owners).Error)
+	assert.Equal(t, []string{"1", "2"}, owners)
+	assert.ErrorContains(t, updateErr, "append-only")
+}
@@ -0,0 +1,28 @@
+package account
+
+import (
+	"github.com/vera/vera-drive-service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, h *Handler, authMiddleware middleware.AuthMiddleware, serviceAuthMiddleware middleware.ServiceAuthMiddleware) {
+	read := middleware.RequireScope(middleware.ScopeDriveRead)
+	admin := middleware.RequireScope(middleware.ScopeDriveAdmin)
+
+	g := r.Group("/account")
+	g.Use(gin.HandlerFunc(authMiddleware))
+	{
+		g.GET("/export", read, h.ExportOwnData)
+		g.DELETE("", admin, h.EraseOwnData)
+	}
+
+	internal := r.Group("/internal/users/:user_id")
+	internal.Use(gin.HandlerFunc(serviceAuthMiddleware))
+	{
+		internal.GET("/stats", h.GetStats)
+		internal.GET("/export", h.ExportUserData)
+		internal.DELETE("", h.EraseUser)
+		internal.POST("/transfer", h.TransferTree)
+	}
+}
@@ -0,0 +1,205 @@
+package account
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/outbox"
+	"github.com/vera/vera-drive-service/internal/url"
+)
+
+// Service holds the operations on the data of a user as a whole. They are
+// called by other services through the internal API, and the export and
+// erasure also by users for their own data.
+type Service interface {
+	GetStats(userID string) (*StatsResponse, error)
+	Export(userID string) (*ExportArchive, error)
+	// EraseUser hard-deletes the data of the user. requestedBy names who
+	// asked for the erasure in its audit record.
+	EraseUser(userID string, requestedBy string) (*ErasureResponse, error)
+	TransferTree(fromUserID string, transfers *TransferRequestBody) (*TransferResponse, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) GetStats(userID string) (*StatsResponse, error) {
+	stats, err := s.repo.GetStats(userID)
+	if err != nil {
+		return nil, err
+	}
+	return newStatsResponse(userID, stats), nil
+}
+
+// Export returns an archive of everything stored for the user, including
+// the trash and the history of their tree.
+func (s *service) Export(userID string) (*ExportArchive, error) {
+	data, err := s.repo.GetUserData(userID)
+	if err != nil {
+		return nil, err
+	}
+	return newExportArchive(userID, data, time.Now().UTC())
+}
+
+// EraseUser removes everything stored for the user in one transaction and
+// records the erasure with the number of rows deleted per table. Erasing a
+// user without any data is not an error. The mounts other users made of the
+// folders of the user are removed from their change feeds, and a user.erased
+// domain event tells other services to erase the user too.
+func (s *service) EraseUser(userID string, requestedBy string) (*ErasureResponse, error) {
+	var erasure *Erasure
+	err := s.repo.Transaction(func(repo Repository) error {
+		mounts, err := repo.GetForeignMounts(userID)
+		if err != nil {
+			return err
+		}
+		mountIDs := map[string][]string{}
+		for _, mount := range mounts {
+			if mount.DeletedAt == nil {
+				mountIDs[mount.UserID] = append(mountIDs[mount.UserID], mount.ID)
+			}
+		}
+		owners := make([]string, 0, len(mountIDs))
+		for owner := range mountIDs {
+			owners = append(owners, owner)
+		}
+		slices.Sort(owners)
+		for _, owner := range owners {
+			if err := repo.AppendChanges(owner, mountIDs[owner], audit.ActionDelete); err != nil {
+				return err
+			}
+		}
+
+		rows, err := repo.DeleteUserData(userID)
+		if err != nil {
+			return err
+		}
+		rowsJSON, err := json.Marshal(rows)
+		if err != nil {
+			return err
+		}
+		erasure = &Erasure{UserID: userID, RequestedBy: requestedBy, DeletedRows: string(rowsJSON)}
+		if err := repo.CreateErasure(erasure); err != nil {
+			return err
+		}
+
+		event, err := outbox.NewOutboxEvent(UserErased{UserID: userID, ErasureID: erasure.ID})
+		if err != nil {
+			return err
+		}
+		return repo.CreateOutboxEvent(event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newErasureResponse(erasure), nil
+}
+
+// transferredName returns the name of the folder holding a transferred tree,
+// numbered when the name is taken in the root folder of the recipient. Like
+// other names it is cut to url.MaxNameLength characters.
+func transferredName(fromUserID string, taken []string) string {
+	base := "From user " + fromUserID
+	name := url.TruncateName(base, url.MaxNameLength)
+	for n := 2; slices.Contains(taken, name); n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		name = url.TruncateName(base, url.MaxNameLength-utf8.RuneCountInString(suffix)) + suffix
+	}
+	return name
+}
+
+// TransferTree hands the whole tree of fromUserID to another user. The root
+// folder of the tree becomes a folder in the root of the recipient, and the
+// change feeds and audit logs of both users report the nodes as deleted and
+// created. The audit entries are recorded as made by fromUserID.
+func (s *service) TransferTree(fromUserID string, transfers *TransferRequestBody) (*TransferResponse, error) {
+	toUserID := transfers.ToUserID
+	if fromUserID == toUserID {
+		return nil, apperror.New(apperror.CodeTransferInvalid, "Cannot transfer a tree to its owner | userID: "+fromUserID)
+	}
+
+	var response *TransferResponse
+	err := s.repo.Transaction(func(repo Repository) error {
+		source, err := repo.GetRoot(fromUserID)
+		if err != nil {
+			return err
+		}
+		if source == nil {
+			return apperror.New(apperror.CodeTreeNotFound, "User has no tree | userID: "+fromUserID)
+		}
+
+		target, err := repo.GetRoot(toUserID)
+		if err != nil {
+			return err
+		}
+		if target == nil {
+			target = &url.URLNode{UserID: toUserID, Name: "", Type: "folder"}
+			if err := repo.CreateNode(target); err != nil {
+				return err
+			}
+		}
+		taken, err := repo.GetChildNames(target.ID)
+		if err != nil {
+			return err
+		}
+		name := transferredName(fromUserID, taken)
+
+		nodes, err := repo.GetNodes(fromUserID)
+		if err != nil {
+			return err
+		}
+		nodeIDs := make([]string, 0, len(nodes))
+		entries := make([]audit.AuditLog, 0, 2*len(nodes))
+		for _, node := range nodes {
+			after := node
+			after.UserID = toUserID
+			if node.ID == source.ID {
+				after.ParentID = &target.ID
+				after.Name = name
+			}
+			deleted, err := url.NewAuditLog(audit.ActionDelete, &node, nil, fromUserID, "")
+			if err != nil {
+				return err
+			}
+			created, err := url.NewAuditLog(audit.ActionCreate, nil, &after, fromUserID, "")
+			if err != nil {
+				return err
+			}
+			nodeIDs = append(nodeIDs, node.ID)
+			entries = append(entries, *deleted, *created)
+		}
+		if err := repo.AppendChanges(fromUserID, nodeIDs, audit.ActionDelete); err != nil {
+			return err
+		}
+		count, err := repo.TransferNodes(fromUserID, toUserID)
+		if err != nil {
+			return err
+		}
+		if err := repo.MoveNode(source.ID, target.ID, name); err != nil {
+			return err
+		}
+		if err := repo.AppendChanges(toUserID, nodeIDs, audit.ActionCreate); err != nil {
+			return err
+		}
+		if err := repo.CreateAuditLogs(entries); err != nil {
+			return err
+		}
+
+		response = &TransferResponse{FolderID: source.ID, Name: name, Nodes: count}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
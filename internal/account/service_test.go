@@ -0,0 +1,410 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/outbox"
+	"github.com/vera/vera-drive-service/internal/share"
+	"github.com/vera/vera-drive-service/internal/url"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Transaction(fn func(repo Repository) error) error {
+	return fn(m)
+}
+func (m *MockRepository) GetStats(userID string) (*Stats, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Stats), args.Error(1)
+}
+func (m *MockRepository) GetForeignMounts(userID string) ([]url.URLNode, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]url.URLNode), args.Error(1)
+}
+func (m *MockRepository) GetUserData(userID string) (*UserData, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*UserData), args.Error(1)
+}
+func (m *MockRepository) DeleteUserData(userID string) (map[string]int64, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+func (m *MockRepository) CreateErasure(erasure *Erasure) error {
+	args := m.Called(erasure)
+	return args.Error(0)
+}
+func (m *MockRepository) CreateOutboxEvent(event *outbox.OutboxEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+func (m *MockRepository) GetRoot(userID string) (*url.URLNode, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*url.URLNode), args.Error(1)
+}
+func (m *MockRepository) CreateNode(node *url.URLNode) error {
+	args := m.Called(node)
+	return args.Error(0)
+}
+func (m *MockRepository) GetChildNames(parentID string) ([]string, error) {
+	args := m.Called(parentID)
+	return args.Get(0).([]string), args.Error(1)
+}
+func (m *MockRepository) GetNodes(userID string) ([]url.URLNode, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]url.URLNode), args.Error(1)
+}
+func (m *MockRepository) AppendChanges(userID string, nodeIDs []string, action audit.Action) error {
+	args := m.Called(userID, nodeIDs, action)
+	return args.Error(0)
+}
+func (m *MockRepository) CreateAuditLogs(entries []audit.AuditLog) error {
+	args := m.Called(entries)
+	return args.Error(0)
+}
+func (m *MockRepository) TransferNodes(fromUserID string, toUserID string) (int64, error) {
+	args := m.Called(fromUserID, toUserID)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockRepository) MoveNode(id string, parentID string, name string) error {
+	args := m.Called(id, parentID, name)
+	return args.Error(0)
+}
+
+// readArchive returns the content of each file of a zip archive.
+func readArchive(t *testing.T, content []byte) map[string][]byte {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range reader.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+	}
+	return files
+}
+
+const (
+	sourceRootID = "123e4567-e89b-12d3-a456-426614174000"
+	targetRootID = "123e4567-e89b-12d3-a456-426614174001"
+	childID      = "123e4567-e89b-12d3-a456-426614174002"
+	erasureID    = "123e4567-e89b-12d3-a456-426614174003"
+)
+
+func TestService_NewService_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+
+	// Act
+	s := NewService(mockRepo)
+
+	// Assert
+	assert.IsType(t, &service{}, s)
+	assert.Equal(t, mockRepo, s.(*service).repo)
+}
+
+func TestService_GetStats_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	lastChangedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockRepo.On("GetStats", "1").Return(&Stats{Folders: 2, URLs: 5, Trashed: 1, LastChangedAt: &lastChangedAt}, nil)
+
+	// Act
+	response, err := s.GetStats("1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "1", response.UserID)
+	assert.Equal(t, int64(2), response.Folders)
+	assert.Equal(t, int64(5), response.URLs)
+	assert.Equal(t, int64(1), response.Trashed)
+	assert.Equal(t, "2024-01-02T03:04:05Z", *response.LastChangedAt)
+}
+func TestService_GetStats_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	mockRepo.On("GetStats", "1").Return(nil, errors.New("database error"))
+
+	// Act
+	response, err := s.GetStats("1")
+
+	// Assert
+	assert.Nil(t, response)
+	assert.EqualError(t, err, "database error")
+}
+
+func TestService_Export_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	deletedAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	rootID := sourceRootID
+	mockRepo.On("GetUserData", "1").Return(&UserData{
+		Nodes: []url.URLNode{
+			{ID: sourceRootID, UserID: "1", Type: "folder"},
+			{ID: childID, UserID: "1", ParentID: &rootID, Name: "Old", Type: "folder", DeletedAt: &deletedAt},
+		},
+		Revisions: []url.NodeRevision{{ID: erasureID, NodeID: childID, Revision: 1, ActorID: "1", Snapshot: `{"name":"Old"}`}},
+	}, nil)
+
+	// Act
+	archive, err := s.Export("1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Regexp(t, `^vera-drive-export-1-\d{8}T\d{6}Z\.zip$`, archive.FileName)
+	files := readArchive(t, archive.Content)
+	require.Contains(t, files, "manifest.json")
+	var manifest exportManifest
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	assert.Equal(t, "1", manifest.UserID)
+	assert.Equal(t, 2, manifest.Files["nodes.json"])
+	assert.Equal(t, 0, manifest.Files["webhooks.json"])
+
+	var nodes []exportNode
+	require.NoError(t, json.Unmarshal(files["nodes.json"], &nodes))
+	require.Len(t, nodes, 2)
+	assert.Equal(t, deletedAt, *nodes[1].DeletedAt)
+	var revisions []exportRevision
+	require.NoError(t, json.Unmarshal(files["revisions.json"], &revisions))
+	require.Len(t, revisions, 1)
+	assert.JSONEq(t, `{"name":"Old"}`, string(revisions[0].Snapshot))
+	assert.JSONEq(t, `[]`, string(files["access_tokens.json"]))
+	assert.JSONEq(t, `[]`, string(files["preferences.json"]))
+}
+func TestService_Export_LeavesOutShareLinkToken(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	mockRepo.On("GetUserData", "1").Return(&UserData{
+		Nodes:      []url.URLNode{{ID: sourceRootID, UserID: "1", Type: "folder"}},
+		ShareLinks: []share.ShareLink{{ID: erasureID, Token: "secret-link-token", FolderID: sourceRootID, UserID: "1"}},
+	}, nil)
+
+	// Act
+	archive, err := s.Export("1")
+
+	// Assert
+	require.NoError(t, err)
+	files := readArchive(t, archive.Content)
+	var shareLinks []map[string]any
+	require.NoError(t, json.Unmarshal(files["share_links.json"], &shareLinks))
+	require.Len(t, shareLinks, 1)
+	assert.Equal(t, erasureID, shareLinks[0]["id"])
+	assert.NotContains(t, shareLinks[0], "token")
+	assert.NotContains(t, string(files["share_links.json"]), "secret-link-token")
+}
+func TestService_Export_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	mockRepo.On("GetUserData", "1").Return(nil, errors.New("database error"))
+
+	// Act
+	archive, err := s.Export("1")
+
+	// Assert
+	assert.Nil(t, archive)
+	assert.EqualError(t, err, "database error")
+}
+
+func TestService_EraseUser_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	deletedAt := time.Now()
+	mockRepo.On("GetForeignMounts", "1").Return([]url.URLNode{
+		{ID: "mount-1", UserID: "3", Type: "mount"},
+		{ID: "mount-2", UserID: "2", Type: "mount"},
+		{ID: "mount-3", UserID: "3", Type: "mount"},
+		{ID: "mount-4", UserID: "2", Type: "mount", DeletedAt: &deletedAt},
+	}, nil)
+	mockRepo.On("AppendChanges", "2", []string{"mount-2"}, audit.ActionDelete).Return(nil)
+	mockRepo.On("AppendChanges", "3", []string{"mount-1", "mount-3"}, audit.ActionDelete).Return(nil)
+	mockRepo.On("DeleteUserData", "1").Return(map[string]int64{"url_nodes": 5, "webhooks": 1}, nil)
+	mockRepo.On("CreateErasure", mock.AnythingOfType("*account.Erasure")).Run(func(args mock.Arguments) {
+		args.Get(0).(*Erasure).ID = erasureID
+	}).Return(nil)
+	var event *outbox.OutboxEvent
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Run(func(args mock.Arguments) {
+		event = args.Get(0).(*outbox.OutboxEvent)
+	}).Return(nil)
+
+	// Act
+	response, err := s.EraseUser("1", "user")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, erasureID, response.ID)
+	assert.Equal(t, "1", response.UserID)
+	assert.Equal(t, "user", response.RequestedBy)
+	assert.JSONEq(t, `{"url_nodes":5,"webhooks":1}`, string(response.DeletedRows))
+	assert.Equal(t, "user.erased", event.Type)
+	assert.JSONEq(t, `{"user_id":"1","erasure_id":"`+erasureID+`"}`, event.Payload)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_EraseUser_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	mockRepo.On("GetForeignMounts", "1").Return([]url.URLNode{}, nil)
+	mockRepo.On("DeleteUserData", "1").Return(nil, errors.New("database error"))
+
+	// Act
+	response, err := s.EraseUser("1", "user")
+
+	// Assert
+	assert.Nil(t, response)
+	assert.EqualError(t, err, "database error")
+	mockRepo.AssertNotCalled(t, "CreateErasure", mock.Anything)
+}
+
+func TestTransferredName(t *testing.T) {
+	tests := []struct {
+		name       string
+		fromUserID string
+		taken      []string
+		expected   string
+	}{
+		{name: "free", fromUserID: "1", taken: nil, expected: "From user 1"},
+		{name: "taken", fromUserID: "1", taken: []string{"From user 1", "From user 1 (2)"}, expected: "From user 1 (3)"},
+		{name: "long user id", fromUserID: "6f1c2e0a-8b7d-4c3e-9f21-0a1b2c3d4e5f", taken: nil, expected: "From user 6f1c2e0a-8"},
+		{name: "long user id taken", fromUserID: "6f1c2e0a-8b7d-4c3e-9f21-0a1b2c3d4e5f", taken: []string{"From user 6f1c2e0a-8"}, expected: "From user 6f1c2e (2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			result := transferredName(tt.fromUserID, tt.taken)
+
+			// Assert
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestService_TransferTree_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	nodeIDs := []string{sourceRootID, childID}
+	root := url.URLNode{ID: sourceRootID, UserID: "1", Type: "folder"}
+	mockRepo.On("GetRoot", "1").Return(&root, nil)
+	mockRepo.On("GetRoot", "2").Return(&url.URLNode{ID: targetRootID, UserID: "2", Type: "folder"}, nil)
+	mockRepo.On("GetChildNames", targetRootID).Return([]string{"Work", "From user 1"}, nil)
+	mockRepo.On("GetNodes", "1").Return([]url.URLNode{root, {ID: childID, UserID: "1", ParentID: &root.ID, Name: "Work", Type: "folder"}}, nil)
+	mockRepo.On("AppendChanges", "1", nodeIDs, audit.ActionDelete).Return(nil)
+	mockRepo.On("TransferNodes", "1", "2").Return(int64(3), nil)
+	mockRepo.On("MoveNode", sourceRootID, targetRootID, "From user 1 (2)").Return(nil)
+	mockRepo.On("AppendChanges", "2", nodeIDs, audit.ActionCreate).Return(nil)
+	var entries []audit.AuditLog
+	mockRepo.On("CreateAuditLogs", mock.Anything).Run(func(args mock.Arguments) {
+		entries = args.Get(0).([]audit.AuditLog)
+	}).Return(nil)
+
+	// Act
+	response, err := s.TransferTree("1", &TransferRequestBody{ToUserID: "2"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, &TransferResponse{FolderID: sourceRootID, Name: "From user 1 (2)", Nodes: 3}, response)
+	require.Len(t, entries, 4)
+	assert.Equal(t, audit.ActionDelete, entries[0].Action)
+	assert.Equal(t, "1", entries[0].OwnerID)
+	assert.Equal(t, "1", entries[0].ActorID)
+	assert.Contains(t, *entries[0].Before, `"parent_id":null`)
+	assert.Nil(t, entries[0].After)
+	assert.Equal(t, audit.ActionCreate, entries[1].Action)
+	assert.Equal(t, "2", entries[1].OwnerID)
+	assert.Nil(t, entries[1].Before)
+	assert.Contains(t, *entries[1].After, `"parent_id":"`+targetRootID+`"`)
+	assert.Contains(t, *entries[1].After, `"name":"From user 1 (2)"`)
+	assert.Equal(t, childID, entries[3].NodeID)
+	assert.Equal(t, "2", entries[3].OwnerID)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_TransferTree_CreatesTargetRoot(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	mockRepo.On("GetRoot", "1").Return(&url.URLNode{ID: sourceRootID, UserID: "1", Type: "folder"}, nil)
+	mockRepo.On("GetRoot", "2").Return(nil, nil)
+	mockRepo.On("CreateNode", mock.MatchedBy(func(node *url.URLNode) bool {
+		return node.UserID == "2" && node.Type == "folder" && node.ParentID == nil
+	})).Run(func(args mock.Arguments) {
+		args.Get(0).(*url.URLNode).ID = targetRootID
+	}).Return(nil)
+	mockRepo.On("GetChildNames", targetRootID).Return([]string{}, nil)
+	mockRepo.On("GetNodes", "1").Return([]url.URLNode{{ID: sourceRootID, UserID: "1", Type: "folder"}}, nil)
+	mockRepo.On("AppendChanges", mock.Anything, []string{sourceRootID}, mock.Anything).Return(nil)
+	mockRepo.On("TransferNodes", "1", "2").Return(int64(1), nil)
+	mockRepo.On("MoveNode", sourceRootID, targetRootID, "From user 1").Return(nil)
+	mockRepo.On("CreateAuditLogs", mock.Anything).Return(nil)
+
+	// Act
+	response, err := s.TransferTree("1", &TransferRequestBody{ToUserID: "2"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "From user 1", response.Name)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_TransferTree_SameUser(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+
+	// Act
+	response, err := s.TransferTree("1", &TransferRequestBody{ToUserID: "1"})
+
+	// Assert
+	assert.Nil(t, response)
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeTransferInvalid, appErr.Code)
+	mockRepo.AssertNotCalled(t, "GetRoot", mock.Anything)
+}
+func TestService_TransferTree_NoTree(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	s := NewService(mockRepo)
+	mockRepo.On("GetRoot", "1").Return(nil, nil)
+
+	// Act
+	response, err := s.TransferTree("1", &TransferRequestBody{ToUserID: "2"})
+
+	// Assert
+	assert.Nil(t, response)
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeTreeNotFound, appErr.Code)
+	mockRepo.AssertNotCalled(t, "TransferNodes", mock.Anything, mock.Anything)
+}
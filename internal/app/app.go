@@ -1,6 +1,8 @@
 package app
 
 import (
+	"context"
+
 	"github.com/vera/vera-drive-service/internal/config"
 
 	"github.com/gin-gonic/gin"
@@ -9,10 +11,13 @@ import (
 )
 
 type App struct {
-	Config *config.Config
-	Router *gin.Engine
-	DB     *gorm.DB
-	Logger *zap.Logger
+	Config  *config.Config
+	Router  *gin.Engine
+	DB      *gorm.DB
+	Logger  *zap.Logger
+	Workers []Worker
+
+	stopWorkers context.CancelFunc
 }
 
 func NewApp(
@@ -20,22 +25,33 @@ func NewApp(
 	router *gin.Engine,
 	db *gorm.DB,
 	logger *zap.Logger,
+	workers []Worker,
 ) *App {
 	return &App{
-		Config: config,
-		Router: router,
-		DB:     db,
-		Logger: logger,
+		Config:  config,
+		Router:  router,
+		DB:      db,
+		Logger:  logger,
+		Workers: workers,
 	}
 }
 
 func (a *App) Close() {
+	if a.stopWorkers != nil {
+		a.stopWorkers()
+	}
 	a.Logger.Sync()
 	d, _ := a.DB.DB()
 	d.Close()
 }
 
 func (a *App) Run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopWorkers = cancel
+	for _, worker := range a.Workers {
+		go worker.Run(ctx)
+	}
+
 	if err := a.Router.Run(a.Config.Domain + ":" + a.Config.Port); err != nil {
 		a.Logger.Fatal("failed to run server", zap.Error(err))
 	}
@@ -4,12 +4,19 @@
 package app
 
 import (
+	"github.com/vera/vera-drive-service/internal/accesstoken"
+	"github.com/vera/vera-drive-service/internal/account"
+	"github.com/vera/vera-drive-service/internal/audit"
 	"github.com/vera/vera-drive-service/internal/config"
 	"github.com/vera/vera-drive-service/internal/db"
 	"github.com/vera/vera-drive-service/internal/logger"
 	"github.com/vera/vera-drive-service/internal/middleware"
+	"github.com/vera/vera-drive-service/internal/outbox"
 	"github.com/vera/vera-drive-service/internal/router"
+	"github.com/vera/vera-drive-service/internal/share"
 	"github.com/vera/vera-drive-service/internal/url"
+	"github.com/vera/vera-drive-service/internal/user"
+	"github.com/vera/vera-drive-service/internal/webhook"
 
 	"github.com/google/wire"
 )
@@ -23,9 +30,47 @@ func InitApp() (*App, error) {
 		middleware.NewHTTPMiddleware,
 		middleware.NewCORSMiddleware,
 		middleware.NewAuthMiddleware,
+		middleware.NewAdminMiddleware,
+		middleware.NewServiceAuthMiddleware,
+		middleware.NewRateLimitStore,
+		middleware.NewRateLimiter,
 		url.NewRepository,
+		url.NewMetadataFetcher,
+		url.NewFaviconStore,
+		url.NewFaviconCache,
+		url.NewBroker,
 		url.NewService,
 		url.NewHandler,
+		url.NewLinkChecker,
+		url.NewRevisionPruner,
+		url.NewNormalizedURLBackfill,
+		url.NewAuthorizer,
+		share.NewRepository,
+		share.NewService,
+		share.NewHandler,
+		wire.Bind(new(share.NodeRepository), new(url.Repository)),
+		audit.NewRepository,
+		audit.NewService,
+		audit.NewHandler,
+		webhook.NewRepository,
+		webhook.NewService,
+		webhook.NewHandler,
+		webhook.NewDispatcher,
+		outbox.NewRepository,
+		outbox.NewPublisher,
+		outbox.NewRelay,
+		accesstoken.NewRepository,
+		accesstoken.NewService,
+		accesstoken.NewHandler,
+		wire.Bind(new(middleware.AccessTokenAuthenticator), new(accesstoken.Service)),
+		account.NewRepository,
+		account.NewService,
+		account.NewHandler,
+		user.NewRepository,
+		user.NewService,
+		user.NewHandler,
+		wire.Bind(new(user.NodeRepository), new(url.Repository)),
+		NewWorkers,
 		NewApp,
 	)
 	return &App{}, nil
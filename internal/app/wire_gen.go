@@ -7,12 +7,19 @@
 package app
 
 import (
+	"github.com/vera/vera-drive-service/internal/accesstoken"
+	"github.com/vera/vera-drive-service/internal/account"
+	"github.com/vera/vera-drive-service/internal/audit"
 	"github.com/vera/vera-drive-service/internal/config"
 	"github.com/vera/vera-drive-service/internal/db"
 	"github.com/vera/vera-drive-service/internal/logger"
 	"github.com/vera/vera-drive-service/internal/middleware"
+	"github.com/vera/vera-drive-service/internal/outbox"
 	"github.com/vera/vera-drive-service/internal/router"
+	"github.com/vera/vera-drive-service/internal/share"
 	"github.com/vera/vera-drive-service/internal/url"
+	"github.com/vera/vera-drive-service/internal/user"
+	"github.com/vera/vera-drive-service/internal/webhook"
 )
 
 // Injectors from wire.go:
@@ -25,15 +32,53 @@ func InitApp() (*App, error) {
 	configConfig := config.NewConfig(zapLogger)
 	httpMiddleware := middleware.NewHTTPMiddleware(zapLogger)
 	corsMiddleware := middleware.NewCORSMiddleware(configConfig)
-	authMiddleware := middleware.NewAuthMiddleware(configConfig)
 	gormDB, err := db.NewDatabase(configConfig)
 	if err != nil {
 		return nil, err
 	}
-	repository := url.NewRepository(gormDB)
-	service := url.NewService(repository)
-	handler := url.NewHandler(service)
-	engine := router.NewRouter(httpMiddleware, corsMiddleware, authMiddleware, handler)
-	app := NewApp(configConfig, engine, gormDB, zapLogger)
+	rateLimitStore := middleware.NewRateLimitStore(configConfig, gormDB, zapLogger)
+	rateLimiter := middleware.NewRateLimiter(configConfig, rateLimitStore, zapLogger)
+	repository := accesstoken.NewRepository(gormDB)
+	service := accesstoken.NewService(repository)
+	authMiddleware := middleware.NewAuthMiddleware(configConfig, service)
+	adminMiddleware := middleware.NewAdminMiddleware(configConfig)
+	serviceAuthMiddleware := middleware.NewServiceAuthMiddleware(configConfig)
+	urlRepository := url.NewRepository(gormDB)
+	metadataFetcher := url.NewMetadataFetcher(configConfig)
+	faviconStore := url.NewFaviconStore(gormDB)
+	faviconCache := url.NewFaviconCache(faviconStore, configConfig, zapLogger)
+	broker := url.NewBroker(configConfig, gormDB, zapLogger)
+	urlService := url.NewService(urlRepository, metadataFetcher, faviconCache, broker, configConfig)
+	handler := url.NewHandler(urlService)
+	shareRepository := share.NewRepository(gormDB)
+	authorizer := url.NewAuthorizer(urlRepository)
+	shareService := share.NewService(shareRepository, urlRepository, authorizer)
+	shareHandler := share.NewHandler(shareService)
+	auditRepository := audit.NewRepository(gormDB)
+	auditService := audit.NewService(auditRepository)
+	auditHandler := audit.NewHandler(auditService)
+	webhookRepository := webhook.NewRepository(gormDB)
+	webhookService := webhook.NewService(webhookRepository)
+	webhookHandler := webhook.NewHandler(webhookService)
+	accesstokenHandler := accesstoken.NewHandler(service)
+	accountRepository := account.NewRepository(gormDB)
+	accountService := account.NewService(accountRepository)
+	accountHandler := account.NewHandler(accountService)
+	userRepository := user.NewRepository(gormDB)
+	userService := user.NewService(userRepository, urlRepository, authorizer)
+	userHandler := user.NewHandler(userService)
+	engine, err := router.NewRouter(configConfig, httpMiddleware, corsMiddleware, rateLimiter, authMiddleware, adminMiddleware, serviceAuthMiddleware, handler, shareHandler, auditHandler, webhookHandler, accesstokenHandler, accountHandler, userHandler)
+	if err != nil {
+		return nil, err
+	}
+	linkChecker := url.NewLinkChecker(urlRepository, configConfig, zapLogger)
+	revisionPruner := url.NewRevisionPruner(urlRepository, configConfig, zapLogger)
+	normalizedURLBackfill := url.NewNormalizedURLBackfill(urlRepository, zapLogger)
+	dispatcher := webhook.NewDispatcher(webhookRepository, configConfig, zapLogger)
+	outboxRepository := outbox.NewRepository(gormDB)
+	publisher := outbox.NewPublisher(configConfig, zapLogger)
+	relay := outbox.NewRelay(outboxRepository, publisher, configConfig, zapLogger)
+	v := NewWorkers(linkChecker, revisionPruner, normalizedURLBackfill, dispatcher, relay, broker, rateLimitStore)
+	app := NewApp(configConfig, engine, gormDB, zapLogger, v)
 	return app, nil
 }
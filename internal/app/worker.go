@@ -0,0 +1,37 @@
+package app
+
+import (
+	"context"
+
+	"github.com/vera/vera-drive-service/internal/middleware"
+	"github.com/vera/vera-drive-service/internal/outbox"
+	"github.com/vera/vera-drive-service/internal/url"
+	"github.com/vera/vera-drive-service/internal/webhook"
+)
+
+// Worker is a background job that runs alongside the HTTP server until ctx is
+// cancelled.
+type Worker interface {
+	Run(ctx context.Context)
+}
+
+func NewWorkers(
+	linkChecker *url.LinkChecker,
+	revisionPruner *url.RevisionPruner,
+	normalizedURLBackfill *url.NormalizedURLBackfill,
+	webhookDispatcher *webhook.Dispatcher,
+	outboxRelay *outbox.Relay,
+	broker url.Broker,
+	rateLimitStore middleware.RateLimitStore,
+) []Worker {
+	workers := []Worker{linkChecker, revisionPruner, normalizedURLBackfill, webhookDispatcher, outboxRelay}
+	// Brokers relaying events between replicas listen in the background.
+	if worker, ok := broker.(Worker); ok {
+		workers = append(workers, worker)
+	}
+	// So do rate limit stores pruning shared buckets.
+	if worker, ok := rateLimitStore.(Worker); ok {
+		workers = append(workers, worker)
+	}
+	return workers
+}
@@ -7,9 +7,57 @@ const (
 	// middleware
 	CodeIdentityServiceUnavailable = "401_02_001"
 	CodeInvalidClaimsInUserToken   = "401_02_002"
+	CodeAdminRequired              = "403_02_016"
+	CodeInvalidUserToken           = "401_02_023"
+	CodeInsufficientScope          = "403_02_028"
+	CodeServiceAuthInvalid         = "401_02_029"
+	CodeRateLimited                = "429_02_033"
+	CodeOriginNotAllowed           = "403_02_034"
 
 	// url package
 	CodeURLNotFound          = "404_02_003"
 	CodeURLAccessDenied      = "403_02_004"
 	CodeURLNameAlreadyExists = "400_02_005"
+	CodeURLNotDuplicate      = "400_02_006"
+	CodeURLNameRequired      = "400_02_007"
+
+	// share package
+	CodeShareNotFound        = "404_02_008"
+	CodeSharePasswordInvalid = "401_02_009"
+	CodeShareFolderOnly      = "400_02_010"
+	CodeShareExpiryInvalid   = "400_02_011"
+
+	// collaborators
+	CodeCollaboratorNotFound      = "404_02_012"
+	CodeCollaboratorAlreadyExists = "400_02_013"
+	CodeCollaboratorInvalid       = "400_02_014"
+
+	// mounts
+	CodeMountInvalid = "400_02_015"
+
+	// revisions
+	CodeRevisionNotFound      = "404_02_017"
+	CodeRevisionParentInvalid = "400_02_018"
+
+	// undo
+	CodeNothingToUndo = "404_02_019"
+	CodeUndoConflict  = "409_02_020"
+
+	// change feed
+	CodeSyncTokenInvalid = "400_02_021"
+
+	// webhook package
+	CodeWebhookNotFound = "404_02_022"
+
+	// accesstoken package
+	CodeAccessTokenNotFound      = "404_02_024"
+	CodeAccessTokenInvalid       = "401_02_025"
+	CodeAccessTokenExpiryInvalid = "400_02_027"
+
+	// account package
+	CodeTransferInvalid = "400_02_030"
+	CodeTreeNotFound    = "404_02_031"
+
+	// user package
+	CodeStartFolderInvalid = "400_02_032"
 )
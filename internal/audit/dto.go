@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type QueryParams struct {
+	ActorID string     `form:"actor_id" binding:"omitempty,max=255"`
+	OwnerID string     `form:"owner_id" binding:"omitempty,max=255"`
+	NodeID  string     `form:"node_id" binding:"omitempty,uuid"`
+	Action  Action     `form:"action" binding:"omitempty,oneof=create update move delete restore"`
+	From    *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To      *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit   int        `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+type Entry struct {
+	ID        string          `json:"id"`
+	NodeID    string          `json:"node_id"`
+	OwnerID   string          `json:"owner_id"`
+	ActorID   string          `json:"actor_id"`
+	Action    Action          `json:"action"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	RequestID *string         `json:"request_id"`
+	CreatedAt string          `json:"created_at"`
+}
+
+func rawJSON(value *string) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	return json.RawMessage(*value)
+}
+
+func NewEntry(log *AuditLog) *Entry {
+	return &Entry{
+		ID:        log.ID,
+		NodeID:    log.NodeID,
+		OwnerID:   log.OwnerID,
+		ActorID:   log.ActorID,
+		Action:    log.Action,
+		Before:    rawJSON(log.Before),
+		After:     rawJSON(log.After),
+		RequestID: log.RequestID,
+		CreatedAt: log.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func NewEntries(logs []AuditLog) []Entry {
+	entries := make([]Entry, len(logs))
+	for i, log := range logs {
+		entries[i] = *NewEntry(&log)
+	}
+	return entries
+}
@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) Query(params *QueryParams) ([]Entry, error) {
+	args := m.Called(params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Entry), args.Error(1)
+}
+
+func TestHandler_NewHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+
+	// Act
+	h := NewHandler(mockService)
+
+	// Assert
+	assert.NotNil(t, h)
+	assert.Equal(t, mockService, h.service)
+}
+
+func TestHandler_Query_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Request = httptest.NewRequest("GET", "/?actor_id=2&action=delete&from=2024-01-01T00:00:00Z&limit=50", nil)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := []Entry{{ID: "log-id", NodeID: "node-id", OwnerID: "1", ActorID: "2", Action: ActionDelete}}
+
+	mockService.On("Query", mock.MatchedBy(func(params *QueryParams) bool {
+		return params.ActorID == "2" && params.Action == ActionDelete && params.From.Equal(from) && params.Limit == 50
+	})).Return(expected, nil)
+
+	// Act
+	handler.Query(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response []Entry
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, "log-id", response[0].ID)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_Query_InvalidQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "unknown action", query: "action=rename"},
+		{name: "invalid node id", query: "node_id=abc"},
+		{name: "limit too large", query: "limit=1000"},
+		{name: "invalid time", query: "from=yesterday"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := &MockService{}
+			handler := NewHandler(mockService)
+			c, w := test.SetupContext()
+
+			c.Request = httptest.NewRequest("GET", "/?"+tt.query, nil)
+
+			// Act
+			handler.Query(c)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			mockService.AssertNotCalled(t, "Query", mock.Anything)
+		})
+	}
+}
+func TestHandler_Query_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	mockService.On("Query", mock.Anything).Return(nil, assert.AnError)
+
+	// Act
+	handler.Query(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, assert.AnError, c.Errors[0].Err)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Action string
+
+const (
+	ActionCreate  Action = "create"
+	ActionUpdate  Action = "update"
+	ActionMove    Action = "move"
+	ActionDelete  Action = "delete"
+	ActionRestore Action = "restore"
+)
+
+// AuditLog records a single change to a node of the url tree. Rows are
+// written in the same transaction as the change and never updated.
+type AuditLog struct {
+	ID        string    `gorm:"type:uuid;primary_key"`
+	NodeID    string    `gorm:"type:uuid;not null;index"`
+	OwnerID   string    `gorm:"type:varchar(255);not null;index"`
+	ActorID   string    `gorm:"type:varchar(255);not null;index"`
+	Action    Action    `gorm:"type:varchar(10);not null;check:action IN ('create','update','move','delete','restore')"`
+	Before    *string   `gorm:"type:jsonb"`
+	After     *string   `gorm:"type:jsonb"`
+	RequestID *string   `gorm:"type:varchar(64)"`
+	CreatedAt time.Time `gorm:"type:timestamptz;not null;index"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+func (l *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == "" {
+		l.ID = uuid.New().String()
+	}
+	l.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// Filter narrows down an audit log query. Zero values are ignored.
+type Filter struct {
+	ActorID string
+	OwnerID string
+	NodeID  string
+	Action  Action
+	From    *time.Time
+	To      *time.Time
+	Limit   int
+}
+
+type Repository interface {
+	Query(filter *Filter) ([]AuditLog, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Query returns the matching entries, newest first.
+func (r *repository) Query(filter *Filter) ([]AuditLog, error) {
+	query := r.db.Model(&AuditLog{})
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.OwnerID != "" {
+		query = query.Where("owner_id = ?", filter.OwnerID)
+	}
+	if filter.NodeID != "" {
+		query = query.Where("node_id = ?", filter.NodeID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var logs []AuditLog
+	err := query.Order("created_at DESC").Limit(filter.Limit).Find(&logs).Error
+	return logs, err
+}
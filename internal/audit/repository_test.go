@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/db"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var d *gorm.DB
+
+func TestMain(m *testing.M) {
+	// Setup
+	dbURL, closeDB, err := test.SetupPostgresql()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	d, err = db.NewDatabase(&config.Config{DatabaseURL: dbURL})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = d.AutoMigrate(&AuditLog{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Run
+	code := m.Run()
+
+	// Teardown
+	closeDB()
+
+	os.Exit(code)
+}
+
+func TestRepository_NewRepository_Success(t *testing.T) {
+	// Act
+	repo := NewRepository(d)
+
+	// Assert
+	assert.NotNil(t, repo)
+	assert.IsType(t, &repository{}, repo)
+}
+
+func TestRepository_Query_Filters(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	nodeID := uuid.New().String()
+	after := `{"name": "a"}`
+	logs := []*AuditLog{
+		{NodeID: nodeID, OwnerID: "1", ActorID: "1", Action: ActionCreate, After: &after},
+		{NodeID: nodeID, OwnerID: "1", ActorID: "2", Action: ActionDelete, Before: &after},
+		{NodeID: uuid.New().String(), OwnerID: "3", ActorID: "3", Action: ActionCreate, After: &after},
+	}
+	for _, entry := range logs {
+		err = d.Create(entry).Error
+		require.NoError(t, err)
+	}
+	future := time.Now().Add(time.Hour)
+
+	// Act
+	byNode, byNodeErr := repo.Query(&Filter{NodeID: nodeID, Limit: 10})
+	byActor, byActorErr := repo.Query(&Filter{ActorID: "2", Limit: 10})
+	byAction, byActionErr := repo.Query(&Filter{Action: ActionCreate, Limit: 1})
+	fromFuture, fromFutureErr := repo.Query(&Filter{From: &future, Limit: 10})
+
+	// Assert
+	require.NoError(t, byNodeErr)
+	require.Len(t, byNode, 2)
+	assert.Equal(t, ActionDelete, byNode[0].Action)
+	assert.JSONEq(t, after, *byNode[0].Before)
+	require.NoError(t, byActorErr)
+	require.Len(t, byActor, 1)
+	assert.Equal(t, "2", byActor[0].ActorID)
+	require.NoError(t, byActionErr)
+	assert.Len(t, byAction, 1)
+	require.NoError(t, fromFutureErr)
+	assert.Empty(t, fromFuture)
+}
@@ -0,0 +1,15 @@
+package audit
+
+import (
+	"github.com/vera/vera-drive-service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, h *Handler, authMiddleware middleware.AuthMiddleware, adminMiddleware middleware.AdminMiddleware) {
+	g := r.Group("/admin/audit-logs")
+	g.Use(gin.HandlerFunc(authMiddleware), middleware.RequireScope(middleware.ScopeDriveAdmin), gin.HandlerFunc(adminMiddleware))
+	{
+		g.GET("", h.Query)
+	}
+}
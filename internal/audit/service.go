@@ -0,0 +1,36 @@
+package audit
+
+const defaultQueryLimit = 100
+
+type Service interface {
+	Query(params *QueryParams) ([]Entry, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) Query(params *QueryParams) ([]Entry, error) {
+	filter := &Filter{
+		ActorID: params.ActorID,
+		OwnerID: params.OwnerID,
+		NodeID:  params.NodeID,
+		Action:  params.Action,
+		From:    params.From,
+		To:      params.To,
+		Limit:   params.Limit,
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultQueryLimit
+	}
+
+	logs, err := s.repo.Query(filter)
+	if err != nil {
+		return nil, err
+	}
+	return NewEntries(logs), nil
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Query(filter *Filter) ([]AuditLog, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]AuditLog), args.Error(1)
+}
+
+func TestService_NewService_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+
+	// Act
+	s := NewService(mockRepo)
+
+	// Assert
+	assert.IsType(t, &service{}, s)
+	assert.Equal(t, mockRepo, s.(*service).repo)
+}
+
+func TestService_Query_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	from := time.Unix(0, 0)
+	params := &QueryParams{ActorID: "2", NodeID: "node-id", Action: ActionDelete, From: &from, Limit: 10}
+	before := `{"name":"a"}`
+	logs := []AuditLog{
+		{ID: "log-id", NodeID: "node-id", OwnerID: "1", ActorID: "2", Action: ActionDelete, Before: &before, CreatedAt: time.Unix(0, 0)},
+	}
+
+	mockRepo.On("Query", &Filter{ActorID: "2", NodeID: "node-id", Action: ActionDelete, From: &from, Limit: 10}).Return(logs, nil)
+
+	// Act
+	entries, err := service.Query(params)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "log-id", entries[0].ID)
+	assert.JSONEq(t, before, string(entries[0].Before))
+	assert.Nil(t, entries[0].After)
+	assert.Equal(t, "1970-01-01T00:00:00Z", entries[0].CreatedAt)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_Query_DefaultLimit(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+
+	mockRepo.On("Query", &Filter{Limit: defaultQueryLimit}).Return([]AuditLog{}, nil)
+
+	// Act
+	entries, err := service.Query(&QueryParams{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_Query_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+
+	mockRepo.On("Query", mock.Anything).Return([]AuditLog{}, assert.AnError)
+
+	// Act
+	entries, err := service.Query(&QueryParams{})
+
+	// Assert
+	assert.Equal(t, assert.AnError, err)
+	assert.Nil(t, entries)
+}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
@@ -13,6 +17,152 @@ type Config struct {
 	DatabaseURL        string
 	IdentityServiceURL string
 	SiteURL            string
+
+	AllowedOrigins     []string
+	CORSAllowedHeaders []string
+	CORSAllowedMethods []string
+	CORSMaxAge         time.Duration
+
+	JWKSURL                  string
+	JWKSRefreshInterval      time.Duration
+	JWTIssuer                string
+	JWTAudience              string
+	AuthRemoteVerifyFallback bool
+	DefaultUserScopes        string
+
+	IdentityServiceTimeout          time.Duration
+	IdentityBreakerFailureThreshold int
+	IdentityBreakerOpenTimeout      time.Duration
+	AuthCacheSize                   int
+
+	LinkCheckInterval     time.Duration
+	LinkCheckRecheckAfter time.Duration
+	LinkCheckTimeout      time.Duration
+	LinkCheckConcurrency  int
+	LinkCheckHostDelay    time.Duration
+	LinkCheckBatchSize    int
+
+	MetadataFetchEnabled bool
+	MetadataFetchTimeout time.Duration
+
+	FaviconCacheTTL     time.Duration
+	FaviconMaxSize      int64
+	FaviconFetchTimeout time.Duration
+
+	OutboundAllowedNetworks []netip.Prefix
+
+	RevisionPruneInterval time.Duration
+	RevisionRetention     time.Duration
+	RevisionKeepLatest    int
+
+	UndoWindow time.Duration
+
+	EventBroker string
+
+	WebhookDispatchInterval time.Duration
+	WebhookTimeout          time.Duration
+	WebhookMaxAttempts      int
+	WebhookRetryBaseDelay   time.Duration
+	WebhookBatchSize        int
+
+	OutboxRelayInterval    time.Duration
+	OutboxBatchSize        int
+	OutboxRetention        time.Duration
+	OutboxPublisherURL     string
+	OutboxPublisherTimeout time.Duration
+
+	RateLimitEnabled   bool
+	RateLimitStore     string
+	RateLimitWindow    time.Duration
+	RateLimitUserLimit int
+	RateLimitIPLimit   int
+
+	TrustedProxies []string
+
+	AdminUserIDs []string
+
+	ServiceSharedSecret  string
+	ServiceTokenSecret   string
+	ServiceTokenAudience string
+}
+
+func getEnvString(key string, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func getEnvDuration(logger *zap.Logger, key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Warn("Invalid duration in environment variable, using default", zap.String("key", key), zap.Error(err))
+		return fallback
+	}
+	return duration
+}
+
+func getEnvBool(logger *zap.Logger, key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Warn("Invalid boolean in environment variable, using default", zap.String("key", key), zap.Error(err))
+		return fallback
+	}
+	return enabled
+}
+
+func getEnvInt(logger *zap.Logger, key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Warn("Invalid integer in environment variable, using default", zap.String("key", key), zap.Error(err))
+		return fallback
+	}
+	return number
+}
+
+// getEnvList parses a comma separated list, skipping empty entries. The
+// fallback is returned when the list is empty.
+func getEnvList(key string, fallback []string) []string {
+	values := []string{}
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		return fallback
+	}
+	return values
+}
+
+// getEnvNetworkList parses a comma separated list of CIDR prefixes, skipping
+// the invalid ones.
+func getEnvNetworkList(logger *zap.Logger, key string) []netip.Prefix {
+	networks := []netip.Prefix{}
+	for _, value := range getEnvList(key, []string{}) {
+		network, err := netip.ParsePrefix(value)
+		if err != nil {
+			logger.Warn("Invalid network in environment variable, skipping it", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
 }
 
 func NewConfig(logger *zap.Logger) *Config {
@@ -38,5 +188,72 @@ func NewConfig(logger *zap.Logger) *Config {
 		DatabaseURL:        os.Getenv("DATABASE_URL"),
 		IdentityServiceURL: os.Getenv("IDENTITY_SERVICE_URL"),
 		SiteURL:            os.Getenv("SITE_URL"),
+
+		AllowedOrigins:     getEnvList("ALLOWED_ORIGIN", getEnvList("SITE_URL", []string{})),
+		CORSAllowedHeaders: getEnvList("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization", "X-CSRF-Token", "X-Share-Password"}),
+		CORSAllowedMethods: getEnvList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		CORSMaxAge:         getEnvDuration(logger, "CORS_MAX_AGE", 24*time.Hour),
+
+		JWKSURL:                  os.Getenv("JWKS_URL"),
+		JWKSRefreshInterval:      getEnvDuration(logger, "JWKS_REFRESH_INTERVAL", time.Hour),
+		JWTIssuer:                os.Getenv("JWT_ISSUER"),
+		JWTAudience:              os.Getenv("JWT_AUDIENCE"),
+		AuthRemoteVerifyFallback: getEnvBool(logger, "AUTH_REMOTE_VERIFY_FALLBACK", false),
+		DefaultUserScopes:        getEnvString("DEFAULT_USER_SCOPES", "drive:read drive:write drive:admin"),
+
+		IdentityServiceTimeout:          getEnvDuration(logger, "IDENTITY_SERVICE_TIMEOUT", 5*time.Second),
+		IdentityBreakerFailureThreshold: getEnvInt(logger, "IDENTITY_BREAKER_FAILURE_THRESHOLD", 5),
+		IdentityBreakerOpenTimeout:      getEnvDuration(logger, "IDENTITY_BREAKER_OPEN_TIMEOUT", 30*time.Second),
+		AuthCacheSize:                   getEnvInt(logger, "AUTH_CACHE_SIZE", 10000),
+
+		LinkCheckInterval:     getEnvDuration(logger, "LINK_CHECK_INTERVAL", 10*time.Minute),
+		LinkCheckRecheckAfter: getEnvDuration(logger, "LINK_CHECK_RECHECK_AFTER", 24*time.Hour),
+		LinkCheckTimeout:      getEnvDuration(logger, "LINK_CHECK_TIMEOUT", 10*time.Second),
+		LinkCheckConcurrency:  getEnvInt(logger, "LINK_CHECK_CONCURRENCY", 8),
+		LinkCheckHostDelay:    getEnvDuration(logger, "LINK_CHECK_HOST_DELAY", time.Second),
+		LinkCheckBatchSize:    getEnvInt(logger, "LINK_CHECK_BATCH_SIZE", 200),
+
+		MetadataFetchEnabled: getEnvBool(logger, "METADATA_FETCH_ENABLED", true),
+		MetadataFetchTimeout: getEnvDuration(logger, "METADATA_FETCH_TIMEOUT", 3*time.Second),
+
+		FaviconCacheTTL:     getEnvDuration(logger, "FAVICON_CACHE_TTL", 7*24*time.Hour),
+		FaviconMaxSize:      int64(getEnvInt(logger, "FAVICON_MAX_SIZE", 100*1024)),
+		FaviconFetchTimeout: getEnvDuration(logger, "FAVICON_FETCH_TIMEOUT", 5*time.Second),
+
+		OutboundAllowedNetworks: getEnvNetworkList(logger, "OUTBOUND_ALLOWED_NETWORKS"),
+
+		RevisionPruneInterval: getEnvDuration(logger, "REVISION_PRUNE_INTERVAL", time.Hour),
+		RevisionRetention:     getEnvDuration(logger, "REVISION_RETENTION", 90*24*time.Hour),
+		RevisionKeepLatest:    getEnvInt(logger, "REVISION_KEEP_LATEST", 10),
+
+		UndoWindow: getEnvDuration(logger, "UNDO_WINDOW", time.Hour),
+
+		EventBroker: os.Getenv("EVENT_BROKER"),
+
+		WebhookDispatchInterval: getEnvDuration(logger, "WEBHOOK_DISPATCH_INTERVAL", 5*time.Second),
+		WebhookTimeout:          getEnvDuration(logger, "WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:      getEnvInt(logger, "WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookRetryBaseDelay:   getEnvDuration(logger, "WEBHOOK_RETRY_BASE_DELAY", 30*time.Second),
+		WebhookBatchSize:        getEnvInt(logger, "WEBHOOK_BATCH_SIZE", 20),
+
+		OutboxRelayInterval:    getEnvDuration(logger, "OUTBOX_RELAY_INTERVAL", time.Second),
+		OutboxBatchSize:        getEnvInt(logger, "OUTBOX_BATCH_SIZE", 100),
+		OutboxRetention:        getEnvDuration(logger, "OUTBOX_RETENTION", 7*24*time.Hour),
+		OutboxPublisherURL:     os.Getenv("OUTBOX_PUBLISHER_URL"),
+		OutboxPublisherTimeout: getEnvDuration(logger, "OUTBOX_PUBLISHER_TIMEOUT", 5*time.Second),
+
+		RateLimitEnabled:   getEnvBool(logger, "RATE_LIMIT_ENABLED", true),
+		RateLimitStore:     os.Getenv("RATE_LIMIT_STORE"),
+		RateLimitWindow:    getEnvDuration(logger, "RATE_LIMIT_WINDOW", time.Minute),
+		RateLimitUserLimit: getEnvInt(logger, "RATE_LIMIT_USER_LIMIT", 600),
+		RateLimitIPLimit:   getEnvInt(logger, "RATE_LIMIT_IP_LIMIT", 1200),
+
+		TrustedProxies: getEnvList("TRUSTED_PROXIES", []string{}),
+
+		AdminUserIDs: getEnvList("ADMIN_USER_IDS", []string{}),
+
+		ServiceSharedSecret:  os.Getenv("SERVICE_SHARED_SECRET"),
+		ServiceTokenSecret:   os.Getenv("SERVICE_TOKEN_SECRET"),
+		ServiceTokenAudience: getEnvString("SERVICE_TOKEN_AUDIENCE", "vera-drive-service"),
 	}
 }
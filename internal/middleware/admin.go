@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminMiddleware gin.HandlerFunc
+
+// NewAdminMiddleware only lets users listed in ADMIN_USER_IDS through. It
+// must run after the auth middleware.
+func NewAdminMiddleware(config *config.Config) AdminMiddleware {
+	admins := map[string]bool{}
+	for _, id := range config.AdminUserIDs {
+		admins[id] = true
+	}
+
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		if !admins[userID] {
+			c.Error(apperror.New(apperror.CodeAdminRequired, "Admin access required | userID: "+userID))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
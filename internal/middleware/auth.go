@@ -1,10 +1,10 @@
 package middleware
 
 import (
-	"io"
-	"net/http"
-	"strconv"
+	"context"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/vera/vera-drive-service/internal/apperror"
 	"github.com/vera/vera-drive-service/internal/config"
@@ -17,60 +17,149 @@ type UserClaims struct {
 	jwt.RegisteredClaims
 	Email   string `json:"email"`
 	Picture string `json:"picture"`
+	// Scope is the space separated list of scopes granted to the token.
+	Scope string `json:"scope,omitempty"`
 }
 
 type AuthMiddleware gin.HandlerFunc
 
-func NewAuthMiddleware(config *config.Config) AuthMiddleware {
+// AccessTokenPrefix starts every personal access token, which tells them
+// apart from user tokens in the Authorization header.
+const AccessTokenPrefix = "vdp_"
+
+// AccessTokenAuthenticator resolves personal access tokens.
+type AccessTokenAuthenticator interface {
+	// Authenticate returns the owner of the token and the scopes granted to
+	// it.
+	Authenticate(token string) (string, []string, error)
+}
+
+// maxUserIDLength bounds the subject of user tokens, which is stored as the
+// opaque user ID in VARCHAR(255) columns.
+const maxUserIDLength = 255
+
+// jwtLeeway absorbs clock skew with the identity service when checking the
+// time based claims.
+const jwtLeeway = 30 * time.Second
+
+// NewAuthMiddleware verifies the user token of the request. With JWKS_URL set
+// tokens are verified locally with the keys published by the identity
+// service, and only sent to its /auth/verify endpoint when the keys cannot be
+// fetched and AUTH_REMOTE_VERIFY_FALLBACK is set. Without JWKS_URL every token
+// is verified remotely, behind a cache and a circuit breaker.
+//
+// Personal access tokens are accepted in place of user tokens. The scopes
+// granted to the token are set for RequireScope; user tokens without a scope
+// claim are granted DEFAULT_USER_SCOPES.
+//
+// The email and picture claims of user tokens are set for the profile of the
+// user, personal access tokens carry no profile.
+//
+// The middleware does not call c.Next, so that RateLimiter.AfterAuth can check
+// the limit of the user before the handlers that follow.
+func NewAuthMiddleware(config *config.Config, accessTokens AccessTokenAuthenticator) AuthMiddleware {
+	client := NewIdentityClient(config.IdentityServiceTimeout)
+	var jwks *JWKS
+	if config.JWKSURL != "" {
+		jwks = NewJWKS(config.JWKSURL, client, config.JWKSRefreshInterval)
+	}
+	parser := newTokenParser(config)
+	remote := &remoteVerifier{
+		url:     config.IdentityServiceURL,
+		client:  client,
+		breaker: NewCircuitBreaker(config.IdentityBreakerFailureThreshold, config.IdentityBreakerOpenTimeout, identityMetrics),
+		cache:   newVerifyCache(config.AuthCacheSize),
+		metrics: identityMetrics,
+	}
+
+	defaultScopes := ParseScopes(config.DefaultUserScopes)
+
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		token := strings.TrimPrefix(authHeader, "Bearer ")
 
-		req, err := http.NewRequest("POST", config.IdentityServiceURL+"/auth/verify", nil)
-		if err != nil {
-			c.Error(err)
-			c.Abort()
+		if strings.HasPrefix(token, AccessTokenPrefix) {
+			userID, scopes, err := accessTokens.Authenticate(token)
+			if err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+			c.Set("user_id", userID)
+			c.Set("scopes", scopes)
 			return
 		}
-		req.Header.Set("Authorization", authHeader)
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			c.Error(apperror.New(apperror.CodeIdentityServiceUnavailable, "failed to call identity service | "+err.Error()))
-			c.Abort()
-			return
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusNoContent {
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				c.Error(err)
+		var userClaims *UserClaims
+		if jwks != nil {
+			claims, err := verifyLocally(c.Request.Context(), parser, jwks, token)
+			switch {
+			case err == nil:
+				userClaims = claims
+			case errors.Is(err, errJWKSUnavailable) && config.AuthRemoteVerifyFallback:
+			case errors.Is(err, errJWKSUnavailable):
+				c.Error(apperror.New(apperror.CodeIdentityServiceUnavailable, "failed to fetch identity service keys | "+err.Error()))
+				c.Abort()
+				return
+			default:
+				c.Error(apperror.New(apperror.CodeInvalidUserToken, "invalid user token | "+err.Error()))
 				c.Abort()
 				return
 			}
+		}
 
-			c.Data(resp.StatusCode, "application/json", body)
-			c.Abort()
-			return
+		if userClaims == nil {
+			claims, ok := remote.verify(c, authHeader, token)
+			if !ok {
+				return
+			}
+			userClaims = claims
 		}
 
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-		userClaims := &UserClaims{}
-		_, _, err = new(jwt.Parser).ParseUnverified(token, userClaims)
-		if err != nil {
-			c.Error(apperror.New(apperror.CodeInvalidClaimsInUserToken, "invalid claims in user token | "+err.Error()))
+		userID := userClaims.Subject
+		if userID == "" || len(userID) > maxUserIDLength {
+			c.Error(apperror.New(apperror.CodeInvalidClaimsInUserToken, "invalid user ID in token | subject must be 1 to 255 characters"))
 			c.Abort()
 			return
 		}
 
-		userID, err := strconv.Atoi(userClaims.Subject)
-		if err != nil {
-			c.Error(apperror.New(apperror.CodeInvalidClaimsInUserToken, "invalid user ID in token | "+err.Error()))
-			c.Abort()
-			return
+		scopes := defaultScopes
+		if userClaims.Scope != "" {
+			scopes = ParseScopes(userClaims.Scope)
 		}
 
 		c.Set("user_id", userID)
-		c.Next()
+		c.Set("email", userClaims.Email)
+		c.Set("picture", userClaims.Picture)
+		c.Set("scopes", scopes)
+	}
+}
+
+func newTokenParser(config *config.Config) *jwt.Parser {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+	}
+	if config.JWTIssuer != "" {
+		options = append(options, jwt.WithIssuer(config.JWTIssuer))
+	}
+	if config.JWTAudience != "" {
+		options = append(options, jwt.WithAudience(config.JWTAudience))
+	}
+	return jwt.NewParser(options...)
+}
+
+// verifyLocally checks the signature of the token with the key named by its
+// kid header, and its expiry, issuer and audience.
+func verifyLocally(ctx context.Context, parser *jwt.Parser, jwks *JWKS, token string) (*UserClaims, error) {
+	userClaims := &UserClaims{}
+	_, err := parser.ParseWithClaims(token, userClaims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return jwks.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return userClaims, nil
 }
@@ -0,0 +1,380 @@
+package middleware
+
+import (
+	"crypto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAccessTokenAuthenticator struct {
+	mock.Mock
+}
+
+func (m *MockAccessTokenAuthenticator) Authenticate(token string) (string, []string, error) {
+	args := m.Called(token)
+	if args.Get(1) == nil {
+		return args.String(0), nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).([]string), args.Error(2)
+}
+
+func setupAuthContext(token string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Request.Header.Set("Authorization", "Bearer "+token)
+	return c, w
+}
+
+// setupRemoteVerify starts an identity service answering /auth/verify with
+// status.
+func setupRemoteVerify(status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/verify" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, key crypto.Signer, kid string, claims jwt.RegisteredClaims) string {
+	token := jwt.NewWithClaims(method, UserClaims{RegisteredClaims: claims, Email: "user@example.com"})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func validClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Subject:   "42",
+		Issuer:    "https://identity.example.com",
+		Audience:  jwt.ClaimStrings{"vera-drive"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+}
+
+func jwksConfig(jwksURL string) *config.Config {
+	return &config.Config{
+		JWKSURL:             jwksURL,
+		JWKSRefreshInterval: time.Hour,
+		JWTIssuer:           "https://identity.example.com",
+		JWTAudience:         "vera-drive",
+	}
+}
+
+func TestAuthMiddleware_Local_Success(t *testing.T) {
+	// Arrange
+	rsaKey, rsaJWK := newRSAKey(t, "rsa-key")
+	ecKey, ecJWK := newECKey(t, "ec-key")
+	stub := setupJWKS(rsaJWK, ecJWK)
+	defer stub.Close()
+	middleware := NewAuthMiddleware(jwksConfig(stub.URL), nil)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "RS256", token: signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-key", validClaims())},
+		{name: "ES256", token: signToken(t, jwt.SigningMethodES256, ecKey, "ec-key", validClaims())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := setupAuthContext(tt.token)
+
+			// Act
+			middleware(c)
+
+			// Assert
+			assert.Empty(t, c.Errors)
+			assert.False(t, c.IsAborted())
+			assert.Equal(t, "42", c.GetString("user_id"))
+			assert.Equal(t, "user@example.com", c.GetString("email"))
+		})
+	}
+	assert.Equal(t, 1, stub.requestCount())
+}
+func TestAuthMiddleware_Local_InvalidToken(t *testing.T) {
+	// Arrange
+	key, keyJWK := newECKey(t, "key")
+	otherKey, _ := newECKey(t, "other-key")
+	rsaKey, _ := newRSAKey(t, "key")
+	stub := setupJWKS(keyJWK)
+	defer stub.Close()
+	middleware := NewAuthMiddleware(jwksConfig(stub.URL), nil)
+
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	withoutExpiry := validClaims()
+	withoutExpiry.ExpiresAt = nil
+	wrongIssuer := validClaims()
+	wrongIssuer.Issuer = "https://evil.example.com"
+	wrongAudience := validClaims()
+	wrongAudience.Audience = jwt.ClaimStrings{"other-service"}
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "malformed", token: "not-a-token"},
+		{name: "expired", token: signToken(t, jwt.SigningMethodES256, key, "key", expired)},
+		{name: "without expiry", token: signToken(t, jwt.SigningMethodES256, key, "key", withoutExpiry)},
+		{name: "wrong issuer", token: signToken(t, jwt.SigningMethodES256, key, "key", wrongIssuer)},
+		{name: "wrong audience", token: signToken(t, jwt.SigningMethodES256, key, "key", wrongAudience)},
+		{name: "wrong signature", token: signToken(t, jwt.SigningMethodES256, otherKey, "key", validClaims())},
+		{name: "unknown kid", token: signToken(t, jwt.SigningMethodES256, otherKey, "other-key", validClaims())},
+		{name: "key type mismatch", token: signToken(t, jwt.SigningMethodRS256, rsaKey, "key", validClaims())},
+		{name: "symmetric algorithm", token: hmacToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := setupAuthContext(tt.token)
+
+			// Act
+			middleware(c)
+
+			// Assert
+			assert.True(t, c.IsAborted())
+			require.Len(t, c.Errors, 1)
+			appErr, ok := c.Errors[0].Err.(*apperror.AppError)
+			require.True(t, ok)
+			assert.Equal(t, apperror.CodeInvalidUserToken, appErr.Code)
+		})
+	}
+}
+func TestAuthMiddleware_Local_OpaqueSubject(t *testing.T) {
+	// Arrange
+	key, keyJWK := newECKey(t, "key")
+	stub := setupJWKS(keyJWK)
+	defer stub.Close()
+	middleware := NewAuthMiddleware(jwksConfig(stub.URL), nil)
+	claims := validClaims()
+	claims.Subject = "7f9c2ba4-e88f-4c1b-9a3d-5b6e8d0c1a2f"
+	c, _ := setupAuthContext(signToken(t, jwt.SigningMethodES256, key, "key", claims))
+
+	// Act
+	middleware(c)
+
+	// Assert
+	assert.Empty(t, c.Errors)
+	assert.Equal(t, "7f9c2ba4-e88f-4c1b-9a3d-5b6e8d0c1a2f", c.GetString("user_id"))
+}
+func TestAuthMiddleware_Local_InvalidSubject(t *testing.T) {
+	// Arrange
+	key, keyJWK := newECKey(t, "key")
+	stub := setupJWKS(keyJWK)
+	defer stub.Close()
+	middleware := NewAuthMiddleware(jwksConfig(stub.URL), nil)
+
+	tests := []struct {
+		name    string
+		subject string
+	}{
+		{name: "empty", subject: ""},
+		{name: "too long", subject: strings.Repeat("a", 256)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			claims.Subject = tt.subject
+			c, _ := setupAuthContext(signToken(t, jwt.SigningMethodES256, key, "key", claims))
+
+			// Act
+			middleware(c)
+
+			// Assert
+			assert.True(t, c.IsAborted())
+			require.Len(t, c.Errors, 1)
+			appErr, ok := c.Errors[0].Err.(*apperror.AppError)
+			require.True(t, ok)
+			assert.Equal(t, apperror.CodeInvalidClaimsInUserToken, appErr.Code)
+		})
+	}
+}
+func TestAuthMiddleware_Local_JWKSUnavailable(t *testing.T) {
+	// Arrange
+	key, _ := newECKey(t, "key")
+	stub := setupJWKS()
+	defer stub.Close()
+	stub.setStatus(http.StatusServiceUnavailable)
+	middleware := NewAuthMiddleware(jwksConfig(stub.URL), nil)
+	c, _ := setupAuthContext(signToken(t, jwt.SigningMethodES256, key, "key", validClaims()))
+
+	// Act
+	middleware(c)
+
+	// Assert
+	assert.True(t, c.IsAborted())
+	require.Len(t, c.Errors, 1)
+	appErr, ok := c.Errors[0].Err.(*apperror.AppError)
+	require.True(t, ok)
+	assert.Equal(t, apperror.CodeIdentityServiceUnavailable, appErr.Code)
+}
+func TestAuthMiddleware_Local_FallbackToRemote(t *testing.T) {
+	// Arrange
+	key, _ := newECKey(t, "key")
+	stub := setupJWKS()
+	defer stub.Close()
+	stub.setStatus(http.StatusServiceUnavailable)
+	identity := setupRemoteVerify(http.StatusNoContent)
+	defer identity.Close()
+	config := jwksConfig(stub.URL)
+	config.IdentityServiceURL = identity.URL
+	config.AuthRemoteVerifyFallback = true
+	middleware := NewAuthMiddleware(config, nil)
+	c, _ := setupAuthContext(signToken(t, jwt.SigningMethodES256, key, "key", validClaims()))
+
+	// Act
+	middleware(c)
+
+	// Assert
+	assert.Empty(t, c.Errors)
+	assert.False(t, c.IsAborted())
+	assert.Equal(t, "42", c.GetString("user_id"))
+}
+func TestAuthMiddleware_Local_NoFallbackForInvalidToken(t *testing.T) {
+	// Arrange
+	key, keyJWK := newECKey(t, "key")
+	stub := setupJWKS(keyJWK)
+	defer stub.Close()
+	identity := setupRemoteVerify(http.StatusNoContent)
+	defer identity.Close()
+	config := jwksConfig(stub.URL)
+	config.IdentityServiceURL = identity.URL
+	config.AuthRemoteVerifyFallback = true
+	middleware := NewAuthMiddleware(config, nil)
+	claims := validClaims()
+	claims.Issuer = "https://evil.example.com"
+	c, _ := setupAuthContext(signToken(t, jwt.SigningMethodES256, key, "key", claims))
+
+	// Act
+	middleware(c)
+
+	// Assert
+	assert.True(t, c.IsAborted())
+	require.Len(t, c.Errors, 1)
+	appErr, ok := c.Errors[0].Err.(*apperror.AppError)
+	require.True(t, ok)
+	assert.Equal(t, apperror.CodeInvalidUserToken, appErr.Code)
+}
+
+func TestAuthMiddleware_Remote_Success(t *testing.T) {
+	// Arrange
+	key, _ := newECKey(t, "key")
+	identity := setupRemoteVerify(http.StatusNoContent)
+	defer identity.Close()
+	middleware := NewAuthMiddleware(&config.Config{IdentityServiceURL: identity.URL}, nil)
+	c, _ := setupAuthContext(signToken(t, jwt.SigningMethodES256, key, "key", validClaims()))
+
+	// Act
+	middleware(c)
+
+	// Assert
+	assert.Empty(t, c.Errors)
+	assert.Equal(t, "42", c.GetString("user_id"))
+	assert.Equal(t, "user@example.com", c.GetString("email"))
+}
+func TestAuthMiddleware_Remote_Rejected(t *testing.T) {
+	// Arrange
+	key, _ := newECKey(t, "key")
+	identity := setupRemoteVerify(http.StatusUnauthorized)
+	defer identity.Close()
+	middleware := NewAuthMiddleware(&config.Config{IdentityServiceURL: identity.URL}, nil)
+	c, w := setupAuthContext(signToken(t, jwt.SigningMethodES256, key, "key", validClaims()))
+
+	// Act
+	middleware(c)
+
+	// Assert
+	assert.True(t, c.IsAborted())
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthMiddleware_AccessToken_Success(t *testing.T) {
+	// Arrange
+	accessTokens := new(MockAccessTokenAuthenticator)
+	accessTokens.On("Authenticate", "vdp_secret").Return("42", []string{ScopeDriveRead}, nil)
+	middleware := NewAuthMiddleware(&config.Config{IdentityServiceURL: "http://127.0.0.1:0"}, accessTokens)
+	c, _ := setupAuthContext("vdp_secret")
+
+	// Act
+	middleware(c)
+
+	// Assert
+	assert.Empty(t, c.Errors)
+	assert.False(t, c.IsAborted())
+	assert.Equal(t, "42", c.GetString("user_id"))
+	assert.Equal(t, []string{ScopeDriveRead}, c.GetStringSlice("scopes"))
+	assert.Empty(t, c.GetString("email"))
+	accessTokens.AssertExpectations(t)
+}
+func TestAuthMiddleware_AccessToken_Invalid(t *testing.T) {
+	// Arrange
+	accessTokens := new(MockAccessTokenAuthenticator)
+	accessTokens.On("Authenticate", "vdp_secret").Return("", nil, apperror.New(apperror.CodeAccessTokenInvalid, "Access token is unknown, revoked or expired"))
+	middleware := NewAuthMiddleware(&config.Config{}, accessTokens)
+	c, _ := setupAuthContext("vdp_secret")
+
+	// Act
+	middleware(c)
+
+	// Assert
+	assert.True(t, c.IsAborted())
+	require.Len(t, c.Errors, 1)
+	appErr, ok := c.Errors[0].Err.(*apperror.AppError)
+	require.True(t, ok)
+	assert.Equal(t, apperror.CodeAccessTokenInvalid, appErr.Code)
+}
+
+func TestAuthMiddleware_Scopes(t *testing.T) {
+	tests := []struct {
+		name     string
+		scope    string
+		expected []string
+	}{
+		{name: "scope claim", scope: "drive:read openid", expected: []string{ScopeDriveRead, "openid"}},
+		{name: "default scopes", scope: "", expected: []string{ScopeDriveRead, ScopeDriveWrite}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			key, keyJWK := newECKey(t, "key")
+			stub := setupJWKS(keyJWK)
+			defer stub.Close()
+			config := jwksConfig(stub.URL)
+			config.DefaultUserScopes = "drive:read drive:write"
+			middleware := NewAuthMiddleware(config, nil)
+			token := jwt.NewWithClaims(jwt.SigningMethodES256, UserClaims{RegisteredClaims: validClaims(), Scope: tt.scope})
+			token.Header["kid"] = "key"
+			signed, err := token.SignedString(key)
+			require.NoError(t, err)
+			c, _ := setupAuthContext(signed)
+
+			// Act
+			middleware(c)
+
+			// Assert
+			assert.Empty(t, c.Errors)
+			assert.Equal(t, tt.expected, c.GetStringSlice("scopes"))
+		})
+	}
+}
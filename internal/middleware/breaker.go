@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit open")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// CircuitBreaker stops calling a dependency after failureThreshold calls in
+// a row failed, so that requests fail fast instead of waiting for timeouts.
+// Once openTimeout has passed a single probe call is let through: the
+// circuit closes again when it succeeds and stays open when it fails.
+//
+// Calls, failures, rejected calls and state changes are counted in metrics.
+type CircuitBreaker struct {
+	failureThreshold int
+	openTimeout      time.Duration
+	metrics          *expvar.Map
+	state            expvar.String
+	now              func() time.Time
+
+	mu       sync.Mutex
+	current  BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration, metrics *expvar.Map) *CircuitBreaker {
+	b := &CircuitBreaker{
+		failureThreshold: max(failureThreshold, 1),
+		openTimeout:      openTimeout,
+		metrics:          metrics,
+		now:              time.Now,
+		current:          BreakerClosed,
+	}
+	b.state.Set(string(BreakerClosed))
+	metrics.Set("breaker_state", &b.state)
+	return b
+}
+
+// Do calls fn unless the circuit is open, in which case ErrCircuitOpen is
+// returned. An error returned by fn counts as a failure of the dependency,
+// unless ctx is done by then: a caller going away says nothing about the
+// dependency, so the call counts neither as a failure nor as a success.
+func (b *CircuitBreaker) Do(ctx context.Context, fn func() error) error {
+	if !b.allow() {
+		b.metrics.Add("breaker_rejected", 1)
+		return ErrCircuitOpen
+	}
+	b.metrics.Add("calls", 1)
+	err := fn()
+	if err != nil && ctx.Err() != nil {
+		b.metrics.Add("canceled", 1)
+		b.release()
+		return err
+	}
+	if err != nil {
+		b.metrics.Add("failures", 1)
+	}
+	b.record(err == nil)
+	return err
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current
+}
+
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.current {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+func (b *CircuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		b.failures = 0
+		b.probing = false
+		if b.current != BreakerClosed {
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.current == BreakerHalfOpen || b.failures >= b.failureThreshold {
+		b.probing = false
+		b.openedAt = b.now()
+		if b.current != BreakerOpen {
+			b.metrics.Add("breaker_opened", 1)
+			b.setState(BreakerOpen)
+		}
+	}
+}
+
+// release ends a call without recording its outcome, letting another probe
+// through when the circuit is half-open.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// setState must be called with mu held.
+func (b *CircuitBreaker) setState(state BreakerState) {
+	b.current = state
+	b.state.Set(string(state))
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDependency = errors.New("dependency failed")
+
+func newTestBreaker(failureThreshold int, openTimeout time.Duration) (*CircuitBreaker, *expvar.Map, *time.Time) {
+	metrics := new(expvar.Map)
+	breaker := NewCircuitBreaker(failureThreshold, openTimeout, metrics)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	return breaker, metrics, &now
+}
+
+func failing() error {
+	return errDependency
+}
+
+func succeeding() error {
+	return nil
+}
+
+func metricValue(metrics *expvar.Map, key string) string {
+	value := metrics.Get(key)
+	if value == nil {
+		return ""
+	}
+	return value.String()
+}
+
+func TestCircuitBreaker_Do_Success(t *testing.T) {
+	// Arrange
+	breaker, metrics, _ := newTestBreaker(2, time.Minute)
+
+	// Act
+	err := breaker.Do(context.Background(), succeeding)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, BreakerClosed, breaker.State())
+	assert.Equal(t, "1", metricValue(metrics, "calls"))
+	assert.Equal(t, `"closed"`, metricValue(metrics, "breaker_state"))
+}
+func TestCircuitBreaker_Do_OpensAfterThreshold(t *testing.T) {
+	// Arrange
+	breaker, metrics, _ := newTestBreaker(2, time.Minute)
+	called := false
+
+	// Act
+	firstErr := breaker.Do(context.Background(), failing)
+	secondErr := breaker.Do(context.Background(), failing)
+	rejectedErr := breaker.Do(context.Background(), func() error {
+		called = true
+		return nil
+	})
+
+	// Assert
+	assert.ErrorIs(t, firstErr, errDependency)
+	assert.ErrorIs(t, secondErr, errDependency)
+	assert.ErrorIs(t, rejectedErr, ErrCircuitOpen)
+	assert.False(t, called)
+	assert.Equal(t, BreakerOpen, breaker.State())
+	assert.Equal(t, "2", metricValue(metrics, "failures"))
+	assert.Equal(t, "1", metricValue(metrics, "breaker_opened"))
+	assert.Equal(t, "1", metricValue(metrics, "breaker_rejected"))
+	assert.Equal(t, `"open"`, metricValue(metrics, "breaker_state"))
+}
+func TestCircuitBreaker_Do_SuccessResetsFailures(t *testing.T) {
+	// Arrange
+	breaker, _, _ := newTestBreaker(2, time.Minute)
+
+	// Act
+	breaker.Do(context.Background(), failing)
+	breaker.Do(context.Background(), succeeding)
+	breaker.Do(context.Background(), failing)
+
+	// Assert
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+func TestCircuitBreaker_Do_HalfOpenProbe(t *testing.T) {
+	tests := []struct {
+		name     string
+		probe    func() error
+		expected BreakerState
+	}{
+		{name: "probe succeeds", probe: succeeding, expected: BreakerClosed},
+		{name: "probe fails", probe: failing, expected: BreakerOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			breaker, _, now := newTestBreaker(1, time.Minute)
+			breaker.Do(context.Background(), failing)
+			*now = now.Add(time.Minute)
+			var concurrentErr error
+
+			// Act
+			err := breaker.Do(context.Background(), func() error {
+				assert.Equal(t, BreakerHalfOpen, breaker.State())
+				concurrentErr = breaker.Do(context.Background(), succeeding)
+				return tt.probe()
+			})
+
+			// Assert
+			assert.Equal(t, tt.probe(), err)
+			assert.ErrorIs(t, concurrentErr, ErrCircuitOpen)
+			assert.Equal(t, tt.expected, breaker.State())
+		})
+	}
+}
+func TestCircuitBreaker_Do_Canceled(t *testing.T) {
+	// Arrange
+	breaker, metrics, _ := newTestBreaker(1, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	err := breaker.Do(ctx, failing)
+
+	// Assert
+	assert.Equal(t, errDependency, err)
+	assert.Equal(t, BreakerClosed, breaker.State())
+	assert.Equal(t, "", metricValue(metrics, "failures"))
+	assert.Equal(t, "1", metricValue(metrics, "canceled"))
+}
+func TestCircuitBreaker_Do_CanceledProbe(t *testing.T) {
+	// Arrange
+	breaker, _, now := newTestBreaker(1, time.Minute)
+	breaker.Do(context.Background(), failing)
+	*now = now.Add(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	breaker.Do(ctx, failing)
+
+	// Act
+	err := breaker.Do(context.Background(), succeeding)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+func TestCircuitBreaker_Do_ReopenedWaitsAgain(t *testing.T) {
+	// Arrange
+	breaker, _, now := newTestBreaker(1, time.Minute)
+	breaker.Do(context.Background(), failing)
+	*now = now.Add(time.Minute)
+	breaker.Do(context.Background(), failing)
+	*now = now.Add(30 * time.Second)
+
+	// Act
+	err := breaker.Do(context.Background(), succeeding)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
@@ -2,7 +2,11 @@ package middleware
 
 import (
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
+	"github.com/vera/vera-drive-service/internal/apperror"
 	"github.com/vera/vera-drive-service/internal/config"
 
 	"github.com/gin-gonic/gin"
@@ -10,23 +14,106 @@ import (
 
 type CORSMiddleware gin.HandlerFunc
 
+// corsExposedHeaders are the response headers readable by the allowed
+// origins, besides the CORS-safelisted ones.
+const corsExposedHeaders = "X-Request-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After"
+
+// originPattern is an allowed origin. A host starting with "*." matches the
+// subdomains of the rest of it, at any depth, but not the domain itself.
+type originPattern struct {
+	scheme   string
+	host     string
+	wildcard bool
+}
+
+func parseOriginPattern(pattern string) originPattern {
+	scheme, host, _ := strings.Cut(strings.ToLower(strings.TrimSuffix(pattern, "/")), "://")
+	if strings.HasPrefix(host, "*.") {
+		return originPattern{scheme: scheme, host: host[1:], wildcard: true}
+	}
+	return originPattern{scheme: scheme, host: host}
+}
+
+func (p originPattern) matches(scheme string, host string) bool {
+	if scheme != p.scheme {
+		return false
+	}
+	if p.wildcard {
+		return len(host) > len(p.host) && strings.HasSuffix(host, p.host)
+	}
+	return host == p.host
+}
+
+// splitOrigin returns the scheme and host of an Origin header, or false when
+// it is not a serialized origin, like "null".
+func splitOrigin(origin string) (string, string, bool) {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", "", false
+	}
+	return strings.ToLower(u.Scheme), strings.ToLower(u.Host), true
+}
+
+// NewCORSMiddleware allows the configured origins, given as exact origins
+// like "https://drive.example.com", wildcard subdomains like
+// "https://*.preview.example.com" or extension ids like
+// "chrome-extension://abcdefghijklmnop".
+//
+// Preflight requests from other origins are rejected with 403. Other requests
+// from them are served without CORS headers, so that browsers do not expose
+// the responses.
 func NewCORSMiddleware(config *config.Config) CORSMiddleware {
+	patterns := make([]originPattern, 0, len(config.AllowedOrigins))
+	for _, origin := range config.AllowedOrigins {
+		patterns = append(patterns, parseOriginPattern(origin))
+	}
+	allowed := func(origin string) bool {
+		scheme, host, ok := splitOrigin(origin)
+		if !ok {
+			return false
+		}
+		for _, pattern := range patterns {
+			if pattern.matches(scheme, host) {
+				return true
+			}
+		}
+		return false
+	}
+	allowedHeaders := strings.Join(config.CORSAllowedHeaders, ", ")
+	allowedMethods := strings.Join(config.CORSAllowedMethods, ", ")
+	maxAge := strconv.Itoa(int(config.CORSMaxAge.Seconds()))
+
 	return func(c *gin.Context) {
-		origin := c.Request.Header.Get("Origin")
+		// The response depends on the origin, so caches must not share it
+		// between origins.
+		c.Writer.Header().Add("Vary", "Origin")
 
-		if config.SiteURL == origin {
+		origin := c.Request.Header.Get("Origin")
+		isAllowed := origin != "" && allowed(origin)
+		if isAllowed {
 			c.Header("Access-Control-Allow-Origin", origin)
 			c.Header("Access-Control-Allow-Credentials", "true")
-			c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
+			c.Header("Access-Control-Expose-Headers", corsExposedHeaders)
 		}
 
-		if c.Request.Method == "OPTIONS" {
-			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			c.Header("Access-Control-Max-Age", "86400") // 24 hours
-			c.AbortWithStatus(http.StatusNoContent)
+		if c.Request.Method != http.MethodOptions {
+			c.Next()
 			return
 		}
 
-		c.Next()
+		preflight := origin != "" && c.Request.Header.Get("Access-Control-Request-Method") != ""
+		if preflight && !isAllowed {
+			c.Error(apperror.New(apperror.CodeOriginNotAllowed, "Origin not allowed | "+origin))
+			c.Abort()
+			return
+		}
+		if preflight {
+			c.Header("Access-Control-Allow-Methods", allowedMethods)
+			c.Header("Access-Control-Allow-Headers", allowedHeaders)
+			if config.CORSMaxAge > 0 {
+				c.Header("Access-Control-Max-Age", maxAge)
+			}
+		}
+		c.AbortWithStatus(http.StatusNoContent)
 	}
 }
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func setupCORSRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.HandlerFunc(NewHTTPMiddleware(zap.NewNop())), gin.HandlerFunc(NewCORSMiddleware(&config.Config{
+		AllowedOrigins:     []string{"https://drive.example.com", "https://*.preview.example.com", "chrome-extension://abcdefghijklmnop"},
+		CORSAllowedHeaders: []string{"Content-Type", "Authorization"},
+		CORSAllowedMethods: []string{"GET", "POST"},
+		CORSMaxAge:         10 * time.Minute,
+	})))
+	r.GET("/urls", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func serveCORS(r *gin.Engine, method string, origin string, requestMethod string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/urls", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if requestMethod != "" {
+		req.Header.Set("Access-Control-Request-Method", requestMethod)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSMiddleware_Origins(t *testing.T) {
+	tests := []struct {
+		name     string
+		origin   string
+		expected bool
+	}{
+		{name: "exact", origin: "https://drive.example.com", expected: true},
+		{name: "exact with other case", origin: "https://Drive.Example.com", expected: true},
+		{name: "exact with other scheme", origin: "http://drive.example.com", expected: false},
+		{name: "exact with other port", origin: "https://drive.example.com:8443", expected: false},
+		{name: "subdomain", origin: "https://pr-12.preview.example.com", expected: true},
+		{name: "nested subdomain", origin: "https://a.b.preview.example.com", expected: true},
+		{name: "wildcard domain itself", origin: "https://preview.example.com", expected: false},
+		{name: "wildcard suffix only", origin: "https://evilpreview.example.com", expected: false},
+		{name: "wildcard with path", origin: "https://evil.com/.preview.example.com", expected: false},
+		{name: "extension", origin: "chrome-extension://abcdefghijklmnop", expected: true},
+		{name: "other extension", origin: "chrome-extension://ponmlkjihgfedcba", expected: false},
+		{name: "null", origin: "null", expected: false},
+		{name: "other origin", origin: "https://evil.com", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			r := setupCORSRouter()
+
+			// Act
+			w := serveCORS(r, "GET", tt.origin, "")
+
+			// Assert
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "Origin", w.Header().Get("Vary"))
+			if tt.expected {
+				assert.Equal(t, tt.origin, w.Header().Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+				assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "Retry-After")
+			} else {
+				assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+				assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+			}
+		})
+	}
+}
+
+func TestCORSMiddleware_Preflight_Allowed(t *testing.T) {
+	// Arrange
+	r := setupCORSRouter()
+
+	// Act
+	w := serveCORS(r, "OPTIONS", "https://pr-12.preview.example.com", "POST")
+
+	// Assert
+	require.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://pr-12.preview.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+}
+func TestCORSMiddleware_Preflight_OriginNotAllowed(t *testing.T) {
+	// Arrange
+	r := setupCORSRouter()
+
+	// Act
+	w := serveCORS(r, "OPTIONS", "https://evil.com", "POST")
+
+	// Assert
+	require.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), apperror.CodeOriginNotAllowed)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Empty(t, w.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+}
+func TestCORSMiddleware_Preflight_NoOrigin(t *testing.T) {
+	// Arrange
+	r := setupCORSRouter()
+
+	// Act
+	w := serveCORS(r, "OPTIONS", "", "")
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Methods"))
+}
@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/vera/vera-drive-service/internal/apperror"
@@ -14,6 +16,52 @@ import (
 
 type HTTPMiddleware gin.HandlerFunc
 
+const redacted = "***REDACTED***"
+
+// redactBody returns a request body for the logs with the values of
+// "password" fields, like the one of share links, redacted at any depth. A
+// body that is not JSON is logged as is unless it mentions a password.
+func redactBody(body []byte) string {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		if bytes.Contains(bytes.ToLower(body), []byte("password")) {
+			return redacted
+		}
+		return string(body)
+	}
+	if !redactPasswords(value) {
+		return string(body)
+	}
+	redactedBody, err := json.Marshal(value)
+	if err != nil {
+		return redacted
+	}
+	return string(redactedBody)
+}
+
+// redactPasswords replaces the "password" fields of value and reports whether
+// there were any.
+func redactPasswords(value any) bool {
+	found := false
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			// Fields are bound case-insensitively, like encoding/json does.
+			if strings.EqualFold(key, "password") {
+				v[key] = redacted
+				found = true
+				continue
+			}
+			found = redactPasswords(field) || found
+		}
+	case []any:
+		for _, item := range v {
+			found = redactPasswords(item) || found
+		}
+	}
+	return found
+}
+
 func logRequest(c *gin.Context, logger *zap.Logger, requestID string) {
 	method := c.Request.Method
 	path := c.Request.URL.Path
@@ -22,12 +70,12 @@ func logRequest(c *gin.Context, logger *zap.Logger, requestID string) {
 	cookies := c.Request.Cookies()
 	cookieStrs := make([]string, len(cookies))
 	for i, c := range c.Request.Cookies() {
-		cookieStrs[i] = c.Name + "=***REDACTED***"
+		cookieStrs[i] = c.Name + "=" + redacted
 	}
 
 	authHeader := c.GetHeader("Authorization")
 	if authHeader != "" {
-		authHeader = "***REDACTED***"
+		authHeader = redacted
 	}
 
 	var bodyBytes []byte
@@ -40,7 +88,7 @@ func logRequest(c *gin.Context, logger *zap.Logger, requestID string) {
 		zap.String("method", method),
 		zap.String("path", path),
 		zap.String("query", query),
-		zap.String("body", string(bodyBytes)),
+		zap.String("body", redactBody(bodyBytes)),
 		zap.Strings("cookies", cookieStrs),
 		zap.String("auth_header", authHeader),
 	}
@@ -51,6 +99,8 @@ func NewHTTPMiddleware(logger *zap.Logger) HTTPMiddleware {
 	return func(c *gin.Context) {
 		start := time.Now()
 		requestID := uuid.New().String()
+		c.Set("request_id", requestID)
+		c.Header("X-Request-ID", requestID)
 		logRequest(c, logger, requestID)
 
 		c.Next()
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestHTTPMiddleware_RedactsPassword(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.InfoLevel)
+	r := gin.New()
+	r.Use(gin.HandlerFunc(NewHTTPMiddleware(zap.New(core))))
+	var received string
+	r.POST("/urls/:id/share", func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		received = string(body)
+		c.Status(http.StatusCreated)
+	})
+	body := `{"role":"viewer","password":"secret-password"}`
+
+	// Act
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/urls/node-id/share", strings.NewReader(body)))
+
+	// Assert
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, body, received)
+	started := logs.FilterMessage("request started").All()
+	require.Len(t, started, 1)
+	logged := started[0].ContextMap()["body"].(string)
+	assert.NotContains(t, logged, "secret-password")
+	assert.Contains(t, logged, `"role":"viewer"`)
+}
+
+func TestHTTPMiddleware_redactBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{name: "empty", body: "", expected: ""},
+		{name: "no password", body: `{"name": "a"}`, expected: `{"name": "a"}`},
+		{name: "password", body: `{"password":"secret"}`, expected: `{"password":"***REDACTED***"}`},
+		{name: "other case", body: `{"Password":"secret"}`, expected: `{"Password":"***REDACTED***"}`},
+		{name: "nested", body: `{"links":[{"password":"secret"}]}`, expected: `{"links":[{"password":"***REDACTED***"}]}`},
+		{name: "invalid json with password", body: `{"password":"secret"`, expected: "***REDACTED***"},
+		{name: "invalid json", body: `not json`, expected: `not json`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			result := redactBody([]byte(tt.body))
+
+			// Assert
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
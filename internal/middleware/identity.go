@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"errors"
+	"expvar"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// identityMetrics is published by expvar as "identity_service".
+var identityMetrics = expvar.NewMap("identity_service")
+
+// NewIdentityClient returns the client shared by all calls to the identity
+// service, so that connections are reused across requests.
+func NewIdentityClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 32
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// remoteVerifier verifies tokens with the /auth/verify endpoint of the
+// identity service. Accepted tokens are cached until they expire, so that
+// the identity service is called once per token rather than once per
+// request.
+type remoteVerifier struct {
+	url     string
+	client  *http.Client
+	breaker *CircuitBreaker
+	cache   *verifyCache
+	metrics *expvar.Map
+}
+
+// verify returns the claims of a token accepted by the identity service.
+// Otherwise the error or the response of the identity service is written to
+// c and false is returned.
+func (v *remoteVerifier) verify(c *gin.Context, authHeader string, token string) (*UserClaims, bool) {
+	key := sha256.Sum256([]byte(token))
+	if claims := v.cache.get(key); claims != nil {
+		v.metrics.Add("cache_hits", 1)
+		return claims, true
+	}
+	v.metrics.Add("cache_misses", 1)
+
+	var status int
+	var body []byte
+	err := v.breaker.Do(c.Request.Context(), func() error {
+		var err error
+		status, body, err = v.call(c, authHeader)
+		if err == nil && status >= http.StatusInternalServerError {
+			err = errors.New("unexpected status | status: " + strconv.Itoa(status))
+		}
+		return err
+	})
+	if errors.Is(err, ErrCircuitOpen) {
+		c.Error(apperror.New(apperror.CodeIdentityServiceUnavailable, "identity service unavailable | "+err.Error()))
+		c.Abort()
+		return nil, false
+	}
+	if err != nil && status == 0 {
+		c.Error(apperror.New(apperror.CodeIdentityServiceUnavailable, "failed to call identity service | "+err.Error()))
+		c.Abort()
+		return nil, false
+	}
+	if status != http.StatusNoContent {
+		c.Data(status, "application/json", body)
+		c.Abort()
+		return nil, false
+	}
+
+	userClaims := &UserClaims{}
+	_, _, err = new(jwt.Parser).ParseUnverified(token, userClaims)
+	if err != nil {
+		c.Error(apperror.New(apperror.CodeInvalidClaimsInUserToken, "invalid claims in user token | "+err.Error()))
+		c.Abort()
+		return nil, false
+	}
+	if userClaims.ExpiresAt != nil {
+		v.cache.set(key, userClaims, userClaims.ExpiresAt.Time)
+	}
+	return userClaims, true
+}
+
+func (v *remoteVerifier) call(c *gin.Context, authHeader string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), "POST", v.url+"/auth/verify", nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+type verifyCacheEntry struct {
+	claims    *UserClaims
+	expiresAt time.Time
+}
+
+// verifyCache holds the claims of accepted tokens by token hash. When it is
+// full expired entries are dropped, and new tokens are not cached while it
+// stays full.
+type verifyCache struct {
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]verifyCacheEntry
+}
+
+func newVerifyCache(size int) *verifyCache {
+	return &verifyCache{size: size, now: time.Now, entries: map[[sha256.Size]byte]verifyCacheEntry{}}
+}
+
+func (c *verifyCache) get(key [sha256.Size]byte) *UserClaims {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil
+	}
+	return entry.claims
+}
+
+func (c *verifyCache) set(key [sha256.Size]byte, claims *UserClaims, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if !now.Before(expiresAt) {
+		return
+	}
+	if len(c.entries) >= c.size {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= c.size {
+			return
+		}
+	}
+	c.entries[key] = verifyCacheEntry{claims: claims, expiresAt: expiresAt}
+}
@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupCountingIdentity starts an identity service answering /auth/verify
+// with status and counting the calls.
+func setupCountingIdentity(status int) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status != http.StatusNoContent {
+			w.Write([]byte(`{"code":"mock_error_code"}`))
+		}
+	}))
+	return server, &calls
+}
+
+func newTestRemoteVerifier(url string) *remoteVerifier {
+	metrics := new(expvar.Map)
+	return &remoteVerifier{
+		url:     url,
+		client:  NewIdentityClient(time.Second),
+		breaker: NewCircuitBreaker(2, time.Minute, metrics),
+		cache:   newVerifyCache(10),
+		metrics: metrics,
+	}
+}
+
+func TestRemoteVerifier_Verify_Cached(t *testing.T) {
+	// Arrange
+	key, _ := newECKey(t, "key")
+	identity, calls := setupCountingIdentity(http.StatusNoContent)
+	defer identity.Close()
+	verifier := newTestRemoteVerifier(identity.URL)
+	token := signToken(t, jwt.SigningMethodES256, key, "key", validClaims())
+
+	// Act
+	for range 3 {
+		c, _ := setupAuthContext(token)
+		claims, ok := verifier.verify(c, "Bearer "+token, token)
+		require.True(t, ok)
+		assert.Equal(t, "42", claims.Subject)
+	}
+
+	// Assert
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, "2", metricValue(verifier.metrics, "cache_hits"))
+	assert.Equal(t, "1", metricValue(verifier.metrics, "cache_misses"))
+}
+func TestRemoteVerifier_Verify_WithoutExpiryNotCached(t *testing.T) {
+	// Arrange
+	key, _ := newECKey(t, "key")
+	identity, calls := setupCountingIdentity(http.StatusNoContent)
+	defer identity.Close()
+	verifier := newTestRemoteVerifier(identity.URL)
+	claims := validClaims()
+	claims.ExpiresAt = nil
+	token := signToken(t, jwt.SigningMethodES256, key, "key", claims)
+
+	// Act
+	for range 2 {
+		c, _ := setupAuthContext(token)
+		_, ok := verifier.verify(c, "Bearer "+token, token)
+		require.True(t, ok)
+	}
+
+	// Assert
+	assert.Equal(t, int32(2), calls.Load())
+}
+func TestRemoteVerifier_Verify_RejectedNotCached(t *testing.T) {
+	// Arrange
+	key, _ := newECKey(t, "key")
+	identity, calls := setupCountingIdentity(http.StatusUnauthorized)
+	defer identity.Close()
+	verifier := newTestRemoteVerifier(identity.URL)
+	token := signToken(t, jwt.SigningMethodES256, key, "key", validClaims())
+
+	// Act
+	for range 3 {
+		c, w := setupAuthContext(token)
+		_, ok := verifier.verify(c, "Bearer "+token, token)
+		require.False(t, ok)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "mock_error_code")
+	}
+
+	// Assert
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Equal(t, BreakerClosed, verifier.breaker.State())
+}
+func TestRemoteVerifier_Verify_CircuitOpen(t *testing.T) {
+	// Arrange
+	key, _ := newECKey(t, "key")
+	identity, calls := setupCountingIdentity(http.StatusBadGateway)
+	defer identity.Close()
+	verifier := newTestRemoteVerifier(identity.URL)
+	token := signToken(t, jwt.SigningMethodES256, key, "key", validClaims())
+	for range 2 {
+		c, w := setupAuthContext(token)
+		verifier.verify(c, "Bearer "+token, token)
+		require.Equal(t, http.StatusBadGateway, w.Code)
+	}
+	c, _ := setupAuthContext(token)
+
+	// Act
+	_, ok := verifier.verify(c, "Bearer "+token, token)
+
+	// Assert
+	require.False(t, ok)
+	require.Len(t, c.Errors, 1)
+	appErr, isAppErr := c.Errors[0].Err.(*apperror.AppError)
+	require.True(t, isAppErr)
+	assert.Equal(t, apperror.CodeIdentityServiceUnavailable, appErr.Code)
+	assert.Equal(t, int32(2), calls.Load())
+}
+func TestRemoteVerifier_Verify_Unreachable(t *testing.T) {
+	// Arrange
+	key, _ := newECKey(t, "key")
+	identity, _ := setupCountingIdentity(http.StatusNoContent)
+	identity.Close()
+	verifier := newTestRemoteVerifier(identity.URL)
+	token := signToken(t, jwt.SigningMethodES256, key, "key", validClaims())
+	c, _ := setupAuthContext(token)
+
+	// Act
+	_, ok := verifier.verify(c, "Bearer "+token, token)
+
+	// Assert
+	require.False(t, ok)
+	require.Len(t, c.Errors, 1)
+	appErr, isAppErr := c.Errors[0].Err.(*apperror.AppError)
+	require.True(t, isAppErr)
+	assert.Equal(t, apperror.CodeIdentityServiceUnavailable, appErr.Code)
+	assert.Equal(t, "1", metricValue(verifier.metrics, "failures"))
+}
+func TestRemoteVerifier_Verify_Canceled(t *testing.T) {
+	// Arrange
+	key, _ := newECKey(t, "key")
+	identity, _ := setupCountingIdentity(http.StatusNoContent)
+	defer identity.Close()
+	verifier := newTestRemoteVerifier(identity.URL)
+	token := signToken(t, jwt.SigningMethodES256, key, "key", validClaims())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	for range 2 {
+		c, _ := setupAuthContext(token)
+		c.Request = c.Request.WithContext(ctx)
+		_, ok := verifier.verify(c, "Bearer "+token, token)
+		require.False(t, ok)
+	}
+
+	// Assert
+	assert.Equal(t, BreakerClosed, verifier.breaker.State())
+	assert.Equal(t, "", metricValue(verifier.metrics, "failures"))
+	assert.Equal(t, "2", metricValue(verifier.metrics, "canceled"))
+}
+
+func TestVerifyCache_Get_Expired(t *testing.T) {
+	// Arrange
+	cache := newVerifyCache(10)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	key := sha256.Sum256([]byte("token"))
+	cache.set(key, &UserClaims{}, now.Add(time.Minute))
+	now = now.Add(time.Minute)
+
+	// Act
+	claims := cache.get(key)
+
+	// Assert
+	assert.Nil(t, claims)
+	assert.Empty(t, cache.entries)
+}
+func TestVerifyCache_Set_Full(t *testing.T) {
+	// Arrange
+	cache := newVerifyCache(2)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	expiring := sha256.Sum256([]byte("expiring"))
+	valid := sha256.Sum256([]byte("valid"))
+	cache.set(expiring, &UserClaims{}, now.Add(time.Second))
+	cache.set(valid, &UserClaims{}, now.Add(time.Hour))
+	now = now.Add(time.Minute)
+	first := sha256.Sum256([]byte("first"))
+	second := sha256.Sum256([]byte("second"))
+
+	// Act
+	cache.set(first, &UserClaims{}, now.Add(time.Hour))
+	cache.set(second, &UserClaims{}, now.Add(time.Hour))
+
+	// Assert
+	assert.NotNil(t, cache.get(valid))
+	assert.NotNil(t, cache.get(first))
+	assert.Nil(t, cache.get(second))
+	assert.Nil(t, cache.get(expiring))
+}
@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// jwksMinRefreshInterval limits how often a token signed with an unknown key
+// can make us fetch the key set again.
+const jwksMinRefreshInterval = 30 * time.Second
+
+// jwksFetchTimeout bounds a fetch of the key set. The fetch does not use the
+// deadline of the request that started it, since other requests wait for it.
+const jwksFetchTimeout = 10 * time.Second
+
+var (
+	errJWKSUnavailable = errors.New("key set unavailable")
+	errKeyNotFound     = errors.New("signing key not found")
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// JWKS caches the public keys published by the identity service. Keys are
+// fetched again once the refresh interval has passed, and when a token names
+// a key we do not know yet, so that rotated keys are picked up. When the key
+// set cannot be fetched the keys fetched last are kept.
+//
+// Only one fetch runs at a time, and callers needing the key set wait for it
+// without holding the lock, so that a slow identity service does not hold up
+// requests with keys that are still valid.
+type JWKS struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]any
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	refreshing  *jwksRefresh
+}
+
+// jwksRefresh is a fetch of the key set in progress. done is closed once the
+// fetch finished and err is set.
+type jwksRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+func NewJWKS(url string, client *http.Client, refreshInterval time.Duration) *JWKS {
+	return &JWKS{url: url, client: client, refreshInterval: refreshInterval}
+}
+
+// Key returns the public key with the given id. An empty kid selects the only
+// key of the set.
+func (k *JWKS) Key(ctx context.Context, kid string) (any, error) {
+	k.mu.Lock()
+	now := time.Now()
+	expired := k.keys == nil || (k.refreshInterval > 0 && now.Sub(k.fetchedAt) >= k.refreshInterval)
+	key := k.lookup(kid)
+	if key != nil && !expired {
+		k.mu.Unlock()
+		return key, nil
+	}
+
+	// Fetch the set again when it expired or the key is unknown, but not more
+	// than once per jwksMinRefreshInterval. A fetch already running is
+	// waited for instead.
+	refresh := k.refreshing
+	if refresh == nil && (k.attemptedAt.IsZero() || now.Sub(k.attemptedAt) >= jwksMinRefreshInterval) {
+		refresh = k.refresh(ctx, now)
+	}
+	k.mu.Unlock()
+
+	var err error
+	if refresh != nil {
+		select {
+		case <-refresh.done:
+			err = refresh.err
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key = k.lookup(kid)
+	if key != nil {
+		return key, nil
+	}
+	if k.keys == nil {
+		return nil, errors.Join(errJWKSUnavailable, err)
+	}
+	return nil, errKeyNotFound
+}
+
+func (k *JWKS) lookup(kid string) any {
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key
+		}
+	}
+	return k.keys[kid]
+}
+
+// refresh starts fetching the key set in the background. It must be called
+// with mu held.
+func (k *JWKS) refresh(ctx context.Context, now time.Time) *jwksRefresh {
+	refresh := &jwksRefresh{done: make(chan struct{})}
+	k.refreshing = refresh
+	k.attemptedAt = now
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jwksFetchTimeout)
+		defer cancel()
+		keys, err := k.fetch(ctx)
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		if err == nil {
+			k.keys = keys
+			k.fetchedAt = now
+		}
+		k.refreshing = nil
+		refresh.err = err
+		close(refresh.done)
+	}()
+	return refresh
+}
+
+func (k *JWKS) fetch(ctx context.Context) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected status | status: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := map[string]any{}
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			// Skip keys we cannot use rather than the whole set.
+			continue
+		}
+		keys[key.Kid] = publicKey
+	}
+	return keys, nil
+}
+
+func (j jwk) publicKey() (any, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("unsupported curve | crv: " + j.Crv)
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, errors.New("unsupported key type | kty: " + j.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
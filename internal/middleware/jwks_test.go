@@ -0,0 +1,306 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jwksStub serves a key set that tests can rotate, and counts the requests
+// it gets.
+type jwksStub struct {
+	*httptest.Server
+	mu       sync.Mutex
+	keys     []jwk
+	status   int
+	requests int
+}
+
+func setupJWKS(keys ...jwk) *jwksStub {
+	stub := &jwksStub{keys: keys, status: http.StatusOK}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		stub.requests++
+		if stub.status != http.StatusOK {
+			w.WriteHeader(stub.status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"keys": stub.keys})
+	}))
+	return stub
+}
+
+func (s *jwksStub) setKeys(keys ...jwk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func (s *jwksStub) setStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+func (s *jwksStub) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func newRSAKey(t *testing.T, kid string) (*rsa.PrivateKey, jwk) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key, jwk{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func newECKey(t *testing.T, kid string) (*ecdsa.PrivateKey, jwk) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key, jwk{
+		Kty: "EC",
+		Kid: kid,
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func TestJWKS_Key_Success(t *testing.T) {
+	// Arrange
+	rsaKey, rsaJWK := newRSAKey(t, "rsa-key")
+	ecKey, ecJWK := newECKey(t, "ec-key")
+	stub := setupJWKS(rsaJWK, ecJWK)
+	defer stub.Close()
+	jwks := NewJWKS(stub.URL, stub.Client(), time.Hour)
+
+	// Act
+	rsaPublic, rsaErr := jwks.Key(context.Background(), "rsa-key")
+	ecPublic, ecErr := jwks.Key(context.Background(), "ec-key")
+
+	// Assert
+	require.NoError(t, rsaErr)
+	require.NoError(t, ecErr)
+	assert.True(t, rsaKey.PublicKey.Equal(rsaPublic))
+	assert.True(t, ecKey.PublicKey.Equal(ecPublic))
+	assert.Equal(t, 1, stub.requestCount())
+}
+func TestJWKS_Key_SingleKeyWithoutKid(t *testing.T) {
+	// Arrange
+	key, keyJWK := newECKey(t, "")
+	stub := setupJWKS(keyJWK)
+	defer stub.Close()
+	jwks := NewJWKS(stub.URL, stub.Client(), time.Hour)
+
+	// Act
+	public, err := jwks.Key(context.Background(), "")
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(public))
+}
+func TestJWKS_Key_SkipsUnusableKeys(t *testing.T) {
+	// Arrange
+	_, encJWK := newECKey(t, "enc-key")
+	encJWK.Use = "enc"
+	stub := setupJWKS(encJWK, jwk{Kty: "oct", Kid: "oct-key"})
+	defer stub.Close()
+	jwks := NewJWKS(stub.URL, stub.Client(), time.Hour)
+
+	// Act
+	_, encErr := jwks.Key(context.Background(), "enc-key")
+	_, octErr := jwks.Key(context.Background(), "oct-key")
+
+	// Assert
+	assert.ErrorIs(t, encErr, errKeyNotFound)
+	assert.ErrorIs(t, octErr, errKeyNotFound)
+}
+func TestJWKS_Key_Rotated(t *testing.T) {
+	// Arrange
+	_, oldJWK := newECKey(t, "old-key")
+	newKey, newJWK := newECKey(t, "new-key")
+	stub := setupJWKS(oldJWK)
+	defer stub.Close()
+	jwks := NewJWKS(stub.URL, stub.Client(), time.Hour)
+	_, err := jwks.Key(context.Background(), "old-key")
+	require.NoError(t, err)
+	stub.setKeys(oldJWK, newJWK)
+	jwks.attemptedAt = time.Now().Add(-jwksMinRefreshInterval)
+
+	// Act
+	public, err := jwks.Key(context.Background(), "new-key")
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, newKey.PublicKey.Equal(public))
+	assert.Equal(t, 2, stub.requestCount())
+}
+func TestJWKS_Key_UnknownKidRefreshLimited(t *testing.T) {
+	// Arrange
+	_, keyJWK := newECKey(t, "key")
+	stub := setupJWKS(keyJWK)
+	defer stub.Close()
+	jwks := NewJWKS(stub.URL, stub.Client(), time.Hour)
+	_, err := jwks.Key(context.Background(), "key")
+	require.NoError(t, err)
+
+	// Act
+	_, firstErr := jwks.Key(context.Background(), "unknown-key")
+	_, secondErr := jwks.Key(context.Background(), "unknown-key")
+
+	// Assert
+	assert.ErrorIs(t, firstErr, errKeyNotFound)
+	assert.ErrorIs(t, secondErr, errKeyNotFound)
+	assert.Equal(t, 1, stub.requestCount())
+}
+func TestJWKS_Key_Expired(t *testing.T) {
+	// Arrange
+	_, keyJWK := newECKey(t, "key")
+	stub := setupJWKS(keyJWK)
+	defer stub.Close()
+	jwks := NewJWKS(stub.URL, stub.Client(), time.Minute)
+	_, err := jwks.Key(context.Background(), "key")
+	require.NoError(t, err)
+	jwks.fetchedAt = time.Now().Add(-time.Hour)
+	jwks.attemptedAt = jwks.fetchedAt
+	stub.setKeys()
+
+	// Act
+	_, err = jwks.Key(context.Background(), "key")
+
+	// Assert
+	assert.ErrorIs(t, err, errKeyNotFound)
+	assert.Equal(t, 2, stub.requestCount())
+}
+func TestJWKS_Key_KeepsKeysWhenUnavailable(t *testing.T) {
+	// Arrange
+	key, keyJWK := newECKey(t, "key")
+	stub := setupJWKS(keyJWK)
+	defer stub.Close()
+	jwks := NewJWKS(stub.URL, stub.Client(), time.Minute)
+	_, err := jwks.Key(context.Background(), "key")
+	require.NoError(t, err)
+	jwks.fetchedAt = time.Now().Add(-time.Hour)
+	jwks.attemptedAt = jwks.fetchedAt
+	stub.setStatus(http.StatusServiceUnavailable)
+
+	// Act
+	public, err := jwks.Key(context.Background(), "key")
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(public))
+}
+func TestJWKS_Key_Unavailable(t *testing.T) {
+	// Arrange
+	stub := setupJWKS()
+	defer stub.Close()
+	stub.setStatus(http.StatusServiceUnavailable)
+	jwks := NewJWKS(stub.URL, stub.Client(), time.Hour)
+
+	// Act
+	_, err := jwks.Key(context.Background(), "key")
+
+	// Assert
+	assert.ErrorIs(t, err, errJWKSUnavailable)
+}
+func TestJWKS_Key_CanceledCaller(t *testing.T) {
+	// Arrange
+	key, keyJWK := newECKey(t, "key")
+	stub := setupJWKS(keyJWK)
+	defer stub.Close()
+	jwks := NewJWKS(stub.URL, stub.Client(), time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stub.mu.Lock() // hold the fetch until the canceled caller returned
+
+	// Act
+	_, canceledErr := jwks.Key(ctx, "key")
+	stub.mu.Unlock()
+	public, err := jwks.Key(context.Background(), "key")
+
+	// Assert
+	assert.ErrorIs(t, canceledErr, errJWKSUnavailable)
+	assert.ErrorIs(t, canceledErr, context.Canceled)
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(public))
+	assert.Equal(t, 1, stub.requestCount())
+}
+func TestJWKS_Key_DoesNotWaitForFetchOfOtherKey(t *testing.T) {
+	// Arrange
+	key, keyJWK := newECKey(t, "key")
+	stub := setupJWKS(keyJWK)
+	defer stub.Close()
+	jwks := NewJWKS(stub.URL, stub.Client(), time.Hour)
+	_, err := jwks.Key(context.Background(), "key")
+	require.NoError(t, err)
+	jwks.attemptedAt = time.Now().Add(-time.Hour)
+	stub.mu.Lock() // hold the fetch of the unknown key
+	unknownDone := make(chan error)
+	go func() {
+		_, err := jwks.Key(context.Background(), "unknown-key")
+		unknownDone <- err
+	}()
+	require.Eventually(t, func() bool {
+		jwks.mu.Lock()
+		defer jwks.mu.Unlock()
+		return jwks.refreshing != nil
+	}, time.Second, time.Millisecond)
+
+	// Act
+	public, err := jwks.Key(context.Background(), "key")
+	stub.mu.Unlock()
+	unknownErr := <-unknownDone
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(public))
+	assert.ErrorIs(t, unknownErr, errKeyNotFound)
+	assert.Equal(t, 2, stub.requestCount())
+}
+func TestJWKS_Key_ConcurrentCallersShareFetch(t *testing.T) {
+	// Arrange
+	_, keyJWK := newECKey(t, "key")
+	stub := setupJWKS(keyJWK)
+	defer stub.Close()
+	jwks := NewJWKS(stub.URL, stub.Client(), time.Hour)
+
+	// Act
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = jwks.Key(context.Background(), "key")
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, stub.requestCount())
+}
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"expvar"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// rateLimitMetrics is published by expvar as "rate_limit".
+var rateLimitMetrics = expvar.NewMap("rate_limit")
+
+// routeCosts weighs expensive routes by method and route pattern. Other
+// routes cost 1 token, routes costing 0 are not limited.
+var routeCosts = map[string]float64{
+	"GET /healthz":                           0,
+	"GET /docs":                              0,
+	"GET /docs/swagger.yaml":                 0,
+	"POST /urls/duplicates/merge":            5,
+	"POST /urls/lookup":                      5,
+	"GET /shared/:token":                     5,
+	"GET /account/export":                    20,
+	"DELETE /account":                        20,
+	"GET /internal/users/:user_id/export":    20,
+	"POST /internal/users/:user_id/transfer": 10,
+}
+
+func routeCost(c *gin.Context) float64 {
+	if cost, ok := routeCosts[c.Request.Method+" "+c.FullPath()]; ok {
+		return cost
+	}
+	return 1
+}
+
+// TokenBucket holds up to Capacity tokens and is refilled evenly, from empty
+// to full in Window.
+type TokenBucket struct {
+	Capacity float64
+	Window   time.Duration
+}
+
+func (b TokenBucket) enabled() bool {
+	return b.Capacity > 0 && b.Window > 0
+}
+
+// rate is the number of tokens refilled per second.
+func (b TokenBucket) rate() float64 {
+	return b.Capacity / b.Window.Seconds()
+}
+
+// refill returns the tokens of a bucket that held tokens elapsed ago.
+func (b TokenBucket) refill(tokens float64, elapsed time.Duration) float64 {
+	return math.Min(b.Capacity, tokens+max(elapsed.Seconds(), 0)*b.rate())
+}
+
+// RateLimitStore keeps the token buckets of the rate limiter.
+type RateLimitStore interface {
+	// Take refills the bucket of key for the time passed since it was last
+	// used, then takes cost tokens from it if it holds that many. It returns
+	// the tokens left and whether they were taken. Unknown keys start full.
+	Take(ctx context.Context, key string, bucket TokenBucket, cost float64, now time.Time) (float64, bool, error)
+}
+
+// NewRateLimitStore returns the store selected by the configuration. The
+// memory store limits each replica on its own, the postgres store shares the
+// buckets between all replicas.
+func NewRateLimitStore(config *config.Config, db *gorm.DB, logger *zap.Logger) RateLimitStore {
+	if config.RateLimitStore == "postgres" {
+		return NewPostgresRateLimitStore(db, config.RateLimitWindow, logger)
+	}
+	return NewMemoryRateLimitStore(config.RateLimitWindow)
+}
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryRateLimitStore keeps the buckets in this process. Buckets unused for
+// a window are full again and are dropped, at most once per window.
+type MemoryRateLimitStore struct {
+	window time.Duration
+
+	mu       sync.Mutex
+	buckets  map[string]memoryBucket
+	prunedAt time.Time
+}
+
+func NewMemoryRateLimitStore(window time.Duration) *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{window: window, buckets: map[string]memoryBucket{}}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, bucket TokenBucket, cost float64, now time.Time) (float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+
+	tokens := bucket.Capacity
+	if current, ok := s.buckets[key]; ok {
+		tokens = bucket.refill(current.tokens, now.Sub(current.updatedAt))
+	}
+	taken := tokens >= cost
+	if taken {
+		tokens -= cost
+	}
+	s.buckets[key] = memoryBucket{tokens: tokens, updatedAt: now}
+	return tokens, taken, nil
+}
+
+// prune must be called with mu held.
+func (s *MemoryRateLimitStore) prune(now time.Time) {
+	if now.Sub(s.prunedAt) < s.window {
+		return
+	}
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updatedAt) >= s.window {
+			delete(s.buckets, key)
+		}
+	}
+	s.prunedAt = now
+}
+
+// RateLimiter limits requests with token buckets, one per client IP and one
+// per user. Each request takes the cost of its route from the buckets and is
+// rejected with 429 once a bucket runs dry. The state of the bucket checked
+// last is reported in the RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset and RateLimit-Policy headers, rejected requests get a
+// Retry-After header as well.
+//
+// Requests are let through when the store fails, so that the API stays up
+// while it is unavailable.
+type RateLimiter struct {
+	store  RateLimitStore
+	ip     TokenBucket
+	user   TokenBucket
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewRateLimiter(config *config.Config, store RateLimitStore, logger *zap.Logger) *RateLimiter {
+	limiter := &RateLimiter{store: store, logger: logger, now: time.Now}
+	if config.RateLimitEnabled {
+		limiter.ip = TokenBucket{Capacity: float64(config.RateLimitIPLimit), Window: config.RateLimitWindow}
+		limiter.user = TokenBucket{Capacity: float64(config.RateLimitUserLimit), Window: config.RateLimitWindow}
+	}
+	return limiter
+}
+
+// ByIP limits requests by client IP. It runs before authentication, so that
+// requests with invalid tokens are limited as well.
+func (l *RateLimiter) ByIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l.limit(c, "ip:"+c.ClientIP(), l.ip)
+	}
+}
+
+// AfterAuth limits the requests authenticated by auth by user id. auth must
+// not call c.Next, so that the limit is checked before the handlers that
+// follow it.
+func (l *RateLimiter) AfterAuth(auth AuthMiddleware) AuthMiddleware {
+	return func(c *gin.Context) {
+		auth(c)
+		if c.IsAborted() {
+			return
+		}
+		l.limit(c, "user:"+c.GetString("user_id"), l.user)
+	}
+}
+
+// limit takes the cost of the route from the bucket of key, or aborts the
+// request when the bucket holds too few tokens.
+func (l *RateLimiter) limit(c *gin.Context, key string, bucket TokenBucket) {
+	cost := min(routeCost(c), bucket.Capacity)
+	if !bucket.enabled() || cost == 0 {
+		return
+	}
+
+	tokens, taken, err := l.store.Take(c.Request.Context(), key, bucket, cost, l.now())
+	if err != nil {
+		rateLimitMetrics.Add("store_errors", 1)
+		l.logger.Error("failed to check rate limit", zap.Error(err))
+		return
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(int(bucket.Capacity)))
+	c.Header("RateLimit-Remaining", strconv.Itoa(int(math.Floor(tokens))))
+	c.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil((bucket.Capacity-tokens)/bucket.rate()))))
+	c.Header("RateLimit-Policy", strconv.Itoa(int(bucket.Capacity))+";w="+strconv.Itoa(int(bucket.Window.Seconds())))
+	if taken {
+		rateLimitMetrics.Add("allowed", 1)
+		return
+	}
+
+	rateLimitMetrics.Add("limited", 1)
+	retryAfter := strconv.Itoa(int(math.Ceil((cost - tokens) / bucket.rate())))
+	c.Header("Retry-After", retryAfter)
+	c.Error(apperror.New(apperror.CodeRateLimited, "Rate limit exceeded | retry after "+retryAfter+"s"))
+	c.Abort()
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// RateLimitBucket is a token bucket of the postgres store, holding Tokens at
+// UpdatedAt.
+type RateLimitBucket struct {
+	Key       string    `gorm:"type:varchar(300);primary_key"`
+	Tokens    float64   `gorm:"type:double precision;not null"`
+	UpdatedAt time.Time `gorm:"type:timestamptz;not null;index"`
+}
+
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
+
+// takeTokens refills and takes the tokens in one statement. The update is
+// skipped, and no row returned, when the bucket holds too few tokens.
+const takeTokens = `
+INSERT INTO rate_limit_buckets AS b (key, tokens, updated_at)
+VALUES (@key, @capacity::float8 - @cost::float8, @now::timestamptz)
+ON CONFLICT (key) DO UPDATE
+SET tokens = LEAST(@capacity::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM @now::timestamptz - b.updated_at)::float8, 0) * @rate::float8) - @cost::float8,
+	updated_at = GREATEST(b.updated_at, @now::timestamptz)
+WHERE LEAST(@capacity::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM @now::timestamptz - b.updated_at)::float8, 0) * @rate::float8) >= @cost::float8
+RETURNING tokens`
+
+// PostgresRateLimitStore keeps the buckets in the database, shared by all
+// replicas. Run deletes the buckets unused for a window, which are full
+// again.
+type PostgresRateLimitStore struct {
+	db     *gorm.DB
+	window time.Duration
+	logger *zap.Logger
+}
+
+func NewPostgresRateLimitStore(db *gorm.DB, window time.Duration, logger *zap.Logger) *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{db: db, window: window, logger: logger}
+}
+
+func (s *PostgresRateLimitStore) Take(ctx context.Context, key string, bucket TokenBucket, cost float64, now time.Time) (float64, bool, error) {
+	var tokens []float64
+	err := s.db.WithContext(ctx).Raw(takeTokens, map[string]interface{}{
+		"key":      key,
+		"capacity": bucket.Capacity,
+		"rate":     bucket.rate(),
+		"cost":     cost,
+		"now":      now,
+	}).Scan(&tokens).Error
+	if err != nil {
+		return 0, false, err
+	}
+	if len(tokens) == 1 {
+		return tokens[0], true, nil
+	}
+
+	var current RateLimitBucket
+	if err := s.db.WithContext(ctx).Where("key = ?", key).First(&current).Error; err != nil {
+		return 0, false, err
+	}
+	return bucket.refill(current.Tokens, now.Sub(current.UpdatedAt)), false, nil
+}
+
+func (s *PostgresRateLimitStore) Run(ctx context.Context) {
+	if s.window <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.PruneOnce(); err != nil {
+			s.logger.Error("failed to prune rate limit buckets", zap.Error(err))
+		}
+	}
+}
+
+// PruneOnce deletes the buckets unused for a window.
+func (s *PostgresRateLimitStore) PruneOnce() error {
+	return s.db.Where("updated_at < ?", time.Now().UTC().Add(-s.window)).Delete(&RateLimitBucket{}).Error
+}
@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type MockRateLimitStore struct {
+	mock.Mock
+}
+
+func (m *MockRateLimitStore) Take(ctx context.Context, key string, bucket TokenBucket, cost float64, now time.Time) (float64, bool, error) {
+	args := m.Called(key, bucket, cost)
+	return args.Get(0).(float64), args.Bool(1), args.Error(2)
+}
+
+func rateLimitConfig(ipLimit int, userLimit int) *config.Config {
+	return &config.Config{
+		RateLimitEnabled:   true,
+		RateLimitWindow:    time.Minute,
+		RateLimitIPLimit:   ipLimit,
+		RateLimitUserLimit: userLimit,
+	}
+}
+
+// newTestLimiter returns a limiter on a memory store whose clock is moved
+// with the returned pointer.
+func newTestLimiter(config *config.Config) (*RateLimiter, *time.Time) {
+	limiter := NewRateLimiter(config, NewMemoryRateLimitStore(config.RateLimitWindow), zap.NewNop())
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+// setupRateLimitRouter serves the routes used by the tests behind the IP
+// limit, with the user limit after an auth middleware taking the user id from
+// the X-User-ID header.
+func setupRateLimitRouter(limiter *RateLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.HandlerFunc(NewHTTPMiddleware(zap.NewNop())), limiter.ByIP())
+	auth := limiter.AfterAuth(func(c *gin.Context) {
+		userID := c.GetHeader("X-User-ID")
+		if userID == "" {
+			c.Error(apperror.New(apperror.CodeInvalidUserToken, "invalid user token"))
+			c.Abort()
+			return
+		}
+		c.Set("user_id", userID)
+	})
+	ok := func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}
+	r.GET("/healthz", ok)
+	r.GET("/shared/:token", ok)
+	r.GET("/urls/:id", gin.HandlerFunc(auth), ok)
+	return r
+}
+
+func serve(r *gin.Engine, path string, userID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	if userID != "" {
+		req.Header.Set("X-User-ID", userID)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMemoryRateLimitStore_Take_Success(t *testing.T) {
+	// Arrange
+	store := NewMemoryRateLimitStore(time.Minute)
+	bucket := TokenBucket{Capacity: 3, Window: time.Minute}
+	now := time.Now()
+
+	// Act
+	first, firstTaken, _ := store.Take(context.Background(), "key", bucket, 2, now)
+	denied, deniedTaken, _ := store.Take(context.Background(), "key", bucket, 2, now)
+	refilled, refilledTaken, err := store.Take(context.Background(), "key", bucket, 2, now.Add(20*time.Second))
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, firstTaken)
+	assert.Equal(t, 1.0, first)
+	assert.False(t, deniedTaken)
+	assert.Equal(t, 1.0, denied)
+	assert.True(t, refilledTaken)
+	assert.InDelta(t, 0.0, refilled, 1e-9)
+}
+func TestMemoryRateLimitStore_Take_PrunesIdleBuckets(t *testing.T) {
+	// Arrange
+	store := NewMemoryRateLimitStore(time.Minute)
+	bucket := TokenBucket{Capacity: 3, Window: time.Minute}
+	now := time.Now()
+	store.Take(context.Background(), "idle", bucket, 1, now)
+	store.Take(context.Background(), "busy", bucket, 1, now.Add(30*time.Second))
+
+	// Act
+	store.Take(context.Background(), "busy", bucket, 1, now.Add(time.Minute))
+
+	// Assert
+	assert.NotContains(t, store.buckets, "idle")
+	assert.Contains(t, store.buckets, "busy")
+}
+
+func TestRateLimiter_ByIP_Headers(t *testing.T) {
+	// Arrange
+	limiter, _ := newTestLimiter(rateLimitConfig(10, 5))
+	r := setupRateLimitRouter(limiter)
+
+	// Act
+	w := serve(r, "/shared/token", "")
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "10", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "5", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "10;w=60", w.Header().Get("RateLimit-Policy"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+}
+func TestRateLimiter_ByIP_Limited(t *testing.T) {
+	// Arrange
+	limiter, now := newTestLimiter(rateLimitConfig(6, 5))
+	r := setupRateLimitRouter(limiter)
+	serve(r, "/shared/token", "")
+
+	// Act
+	limited := serve(r, "/shared/token", "")
+	*now = now.Add(24 * time.Second)
+	retried := serve(r, "/shared/token", "")
+	*now = now.Add(16 * time.Second)
+	refilled := serve(r, "/shared/token", "")
+
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Contains(t, limited.Body.String(), apperror.CodeRateLimited)
+	assert.Equal(t, "1", limited.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "40", limited.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusTooManyRequests, retried.Code)
+	assert.Equal(t, http.StatusOK, refilled.Code)
+}
+func TestRateLimiter_ByIP_FreeRoute(t *testing.T) {
+	// Arrange
+	limiter, _ := newTestLimiter(rateLimitConfig(1, 5))
+	r := setupRateLimitRouter(limiter)
+
+	// Act
+	first := serve(r, "/healthz", "")
+	second := serve(r, "/healthz", "")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Empty(t, second.Header().Get("RateLimit-Limit"))
+}
+func TestRateLimiter_ByIP_CostAboveCapacity(t *testing.T) {
+	// Arrange
+	limiter, _ := newTestLimiter(rateLimitConfig(2, 5))
+	r := setupRateLimitRouter(limiter)
+
+	// Act
+	w := serve(r, "/shared/token", "")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+}
+
+func TestRateLimiter_AfterAuth_KeyedByUser(t *testing.T) {
+	// Arrange
+	limiter, _ := newTestLimiter(rateLimitConfig(100, 2))
+	r := setupRateLimitRouter(limiter)
+
+	// Act
+	first := serve(r, "/urls/1", "alice")
+	second := serve(r, "/urls/1", "alice")
+	limited := serve(r, "/urls/1", "alice")
+	other := serve(r, "/urls/1", "bob")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Equal(t, "30", limited.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, other.Code)
+}
+func TestRateLimiter_AfterAuth_Unauthenticated(t *testing.T) {
+	// Arrange
+	limiter, _ := newTestLimiter(rateLimitConfig(100, 1))
+	r := setupRateLimitRouter(limiter)
+
+	// Act
+	first := serve(r, "/urls/1", "")
+	second := serve(r, "/urls/1", "")
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, first.Code)
+	assert.Equal(t, http.StatusUnauthorized, second.Code)
+	assert.Equal(t, "100", second.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	// Arrange
+	config := rateLimitConfig(1, 1)
+	config.RateLimitEnabled = false
+	store := &MockRateLimitStore{}
+	limiter := NewRateLimiter(config, store, zap.NewNop())
+	r := setupRateLimitRouter(limiter)
+
+	// Act
+	first := serve(r, "/urls/1", "alice")
+	second := serve(r, "/urls/1", "alice")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Empty(t, second.Header().Get("RateLimit-Limit"))
+	store.AssertNotCalled(t, "Take", mock.Anything, mock.Anything, mock.Anything)
+}
+func TestRateLimiter_StoreError(t *testing.T) {
+	// Arrange
+	store := &MockRateLimitStore{}
+	limiter := NewRateLimiter(rateLimitConfig(10, 10), store, zap.NewNop())
+	r := setupRateLimitRouter(limiter)
+
+	store.On("Take", "ip:192.0.2.1", mock.Anything, 1.0).Return(0.0, false, errors.New("database error"))
+	store.On("Take", "user:alice", mock.Anything, 1.0).Return(0.0, false, errors.New("database error"))
+
+	// Act
+	w := serve(r, "/urls/1", "alice")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	store.AssertExpectations(t)
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ScopeDriveRead  = "drive:read"
+	ScopeDriveWrite = "drive:write"
+	ScopeDriveAdmin = "drive:admin"
+)
+
+// impliedScopes lists the scopes granted along with a scope: write access
+// includes read access, and admin access includes both.
+var impliedScopes = map[string][]string{
+	ScopeDriveRead:  {ScopeDriveRead},
+	ScopeDriveWrite: {ScopeDriveRead, ScopeDriveWrite},
+	ScopeDriveAdmin: {ScopeDriveRead, ScopeDriveWrite, ScopeDriveAdmin},
+}
+
+// ParseScopes splits a space separated scope claim.
+func ParseScopes(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// HasScope reports whether the granted scopes include scope.
+func HasScope(granted []string, scope string) bool {
+	for _, g := range granted {
+		if slices.Contains(impliedScopes[g], scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireScope rejects requests whose token was not granted scope. It must
+// run after the auth middleware, which sets the granted scopes.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := c.GetStringSlice("scopes")
+		if !HasScope(granted, scope) {
+			c.Error(apperror.New(apperror.CodeInsufficientScope, "Insufficient scope | required: "+scope+", granted: "+strings.Join(granted, " ")))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
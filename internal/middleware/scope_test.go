@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		granted  []string
+		scope    string
+		expected bool
+	}{
+		{name: "granted", granted: []string{ScopeDriveRead}, scope: ScopeDriveRead, expected: true},
+		{name: "write implies read", granted: []string{ScopeDriveWrite}, scope: ScopeDriveRead, expected: true},
+		{name: "admin implies write", granted: []string{ScopeDriveAdmin}, scope: ScopeDriveWrite, expected: true},
+		{name: "read does not imply write", granted: []string{ScopeDriveRead}, scope: ScopeDriveWrite, expected: false},
+		{name: "write does not imply admin", granted: []string{"openid", ScopeDriveWrite}, scope: ScopeDriveAdmin, expected: false},
+		{name: "nothing granted", granted: nil, scope: ScopeDriveRead, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			result := HasScope(tt.granted, tt.scope)
+
+			// Assert
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestRequireScope_Granted(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	c.Set("scopes", []string{ScopeDriveWrite})
+
+	// Act
+	RequireScope(ScopeDriveWrite)(c)
+
+	// Assert
+	assert.False(t, c.IsAborted())
+	assert.Empty(t, c.Errors)
+}
+func TestRequireScope_Missing(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	c.Set("scopes", []string{ScopeDriveRead})
+
+	// Act
+	RequireScope(ScopeDriveWrite)(c)
+
+	// Assert
+	assert.True(t, c.IsAborted())
+	require.Len(t, c.Errors, 1)
+	appErr, ok := c.Errors[0].Err.(*apperror.AppError)
+	require.True(t, ok)
+	assert.Equal(t, apperror.CodeInsufficientScope, appErr.Code)
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type ServiceAuthMiddleware gin.HandlerFunc
+
+// serviceTokenMaxLifetime bounds how far in the future a service token may
+// expire, so that a leaked token is only usable for a short time.
+const serviceTokenMaxLifetime = 5 * time.Minute
+
+// sharedSecretService is set as the calling service of requests
+// authenticated with the shared secret, which does not name the caller.
+const sharedSecretService = "shared-secret"
+
+var errServiceTokenLifetime = errors.New("token expires too far in the future")
+
+// NewServiceAuthMiddleware authenticates other services calling the internal
+// API. A service either sends SERVICE_SHARED_SECRET as its bearer token, or
+// a short lived HS256 token signed with SERVICE_TOKEN_SECRET whose subject
+// names the service. Each method is disabled while its secret is empty, so
+// the internal API rejects every request until one is configured.
+//
+// The name of the calling service is set as "service".
+func NewServiceAuthMiddleware(config *config.Config) ServiceAuthMiddleware {
+	sharedSecret := []byte(config.ServiceSharedSecret)
+	tokenSecret := []byte(config.ServiceTokenSecret)
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"HS256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(config.ServiceTokenAudience),
+		jwt.WithLeeway(jwtLeeway),
+	)
+
+	return func(c *gin.Context) {
+		token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || token == "" {
+			c.Error(apperror.New(apperror.CodeServiceAuthInvalid, "Service credentials required"))
+			c.Abort()
+			return
+		}
+
+		if len(sharedSecret) > 0 && subtle.ConstantTimeCompare([]byte(token), sharedSecret) == 1 {
+			c.Set("service", sharedSecretService)
+			c.Next()
+			return
+		}
+
+		if len(tokenSecret) == 0 {
+			c.Error(apperror.New(apperror.CodeServiceAuthInvalid, "Invalid service credentials"))
+			c.Abort()
+			return
+		}
+		service, err := verifyServiceToken(parser, tokenSecret, token)
+		if err != nil {
+			c.Error(apperror.New(apperror.CodeServiceAuthInvalid, "Invalid service token | "+err.Error()))
+			c.Abort()
+			return
+		}
+		c.Set("service", service)
+		c.Next()
+	}
+}
+
+// verifyServiceToken returns the service named by the subject of a valid
+// service token.
+func verifyServiceToken(parser *jwt.Parser, secret []byte, token string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := parser.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return secret, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if claims.Subject == "" {
+		return "", jwt.ErrTokenInvalidSubject
+	}
+	if time.Until(claims.ExpiresAt.Time) > serviceTokenMaxLifetime {
+		return "", errServiceTokenLifetime
+	}
+	return claims.Subject, nil
+}
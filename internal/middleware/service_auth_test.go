@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	serviceSharedSecret = "shared-secret-value"
+	serviceTokenSecret  = "service-token-secret"
+)
+
+func newServiceAuthConfig() *config.Config {
+	return &config.Config{
+		ServiceSharedSecret:  serviceSharedSecret,
+		ServiceTokenSecret:   serviceTokenSecret,
+		ServiceTokenAudience: "vera-drive-service",
+	}
+}
+
+func signServiceToken(t *testing.T, secret string, claims jwt.RegisteredClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return token
+}
+
+func TestServiceAuthMiddleware_SharedSecret(t *testing.T) {
+	// Arrange
+	middleware := NewServiceAuthMiddleware(newServiceAuthConfig())
+	c, _ := setupAuthContext(serviceSharedSecret)
+
+	// Act
+	middleware(c)
+
+	// Assert
+	assert.Empty(t, c.Errors)
+	assert.False(t, c.IsAborted())
+	assert.Equal(t, "shared-secret", c.GetString("service"))
+}
+func TestServiceAuthMiddleware_ServiceToken(t *testing.T) {
+	// Arrange
+	middleware := NewServiceAuthMiddleware(newServiceAuthConfig())
+	token := signServiceToken(t, serviceTokenSecret, jwt.RegisteredClaims{
+		Subject:   "identity-service",
+		Audience:  jwt.ClaimStrings{"vera-drive-service"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	c, _ := setupAuthContext(token)
+
+	// Act
+	middleware(c)
+
+	// Assert
+	assert.Empty(t, c.Errors)
+	assert.False(t, c.IsAborted())
+	assert.Equal(t, "identity-service", c.GetString("service"))
+}
+func TestServiceAuthMiddleware_Rejected(t *testing.T) {
+	valid := jwt.RegisteredClaims{
+		Subject:   "identity-service",
+		Audience:  jwt.ClaimStrings{"vera-drive-service"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+	withChange := func(change func(claims *jwt.RegisteredClaims)) jwt.RegisteredClaims {
+		claims := valid
+		change(&claims)
+		return claims
+	}
+
+	tests := []struct {
+		name   string
+		config *config.Config
+		token  string
+	}{
+		{name: "missing", config: newServiceAuthConfig(), token: ""},
+		{name: "wrong shared secret", config: newServiceAuthConfig(), token: "not-the-secret"},
+		{name: "user token", config: newServiceAuthConfig(), token: "vdp_secret"},
+		{
+			name:   "wrong signing secret",
+			config: newServiceAuthConfig(),
+			token:  signServiceToken(t, "other-secret", valid),
+		},
+		{
+			name:   "expired",
+			config: newServiceAuthConfig(),
+			token: signServiceToken(t, serviceTokenSecret, withChange(func(claims *jwt.RegisteredClaims) {
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+			})),
+		},
+		{
+			name:   "without expiry",
+			config: newServiceAuthConfig(),
+			token: signServiceToken(t, serviceTokenSecret, withChange(func(claims *jwt.RegisteredClaims) {
+				claims.ExpiresAt = nil
+			})),
+		},
+		{
+			name:   "expiry too far",
+			config: newServiceAuthConfig(),
+			token: signServiceToken(t, serviceTokenSecret, withChange(func(claims *jwt.RegisteredClaims) {
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+			})),
+		},
+		{
+			name:   "wrong audience",
+			config: newServiceAuthConfig(),
+			token: signServiceToken(t, serviceTokenSecret, withChange(func(claims *jwt.RegisteredClaims) {
+				claims.Audience = jwt.ClaimStrings{"other-service"}
+			})),
+		},
+		{
+			name:   "without subject",
+			config: newServiceAuthConfig(),
+			token: signServiceToken(t, serviceTokenSecret, withChange(func(claims *jwt.RegisteredClaims) {
+				claims.Subject = ""
+			})),
+		},
+		{
+			name:   "shared secret disabled",
+			config: &config.Config{ServiceTokenSecret: serviceTokenSecret, ServiceTokenAudience: "vera-drive-service"},
+			token:  serviceSharedSecret,
+		},
+		{
+			name:   "service tokens disabled",
+			config: &config.Config{ServiceSharedSecret: serviceSharedSecret, ServiceTokenAudience: "vera-drive-service"},
+			token:  signServiceToken(t, serviceTokenSecret, valid),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			middleware := NewServiceAuthMiddleware(tt.config)
+			c, _ := setupAuthContext(tt.token)
+
+			// Act
+			middleware(c)
+
+			// Assert
+			assert.True(t, c.IsAborted())
+			require.Len(t, c.Errors, 1)
+			appErr := apperror.FromError(c.Errors.Last().Err)
+			assert.Equal(t, apperror.CodeServiceAuthInvalid, appErr.Code)
+			assert.Empty(t, c.GetString("service"))
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Package outbound builds the HTTP clients used to reach URLs supplied by
+// users, like saved pages, their icons and webhook receivers.
+package outbound
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrAddressNotAllowed is returned when a connection to an internal address
+// is refused.
+var ErrAddressNotAllowed = errors.New("address not allowed")
+
+// blockedNetworks are the special-purpose ranges not covered by the netip
+// predicates used in allowed.
+var blockedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"), // shared address space, used by some cloud metadata services
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, including broadcast
+}
+
+// NewClient returns a client that refuses to connect to loopback, private,
+// link-local (including the 169.254.169.254 metadata address) and other
+// non-public addresses, unless they are in allowedNetworks.
+//
+// The address is checked when dialing, after DNS resolution, so that neither
+// a host name resolving to an internal address nor a redirect to one can
+// reach internal services. Proxies from the environment are not used, since
+// the proxy would make the connection on our behalf.
+func NewClient(timeout time.Duration, allowedNetworks []netip.Prefix) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !allowed(addrPort.Addr(), allowedNetworks) {
+				return fmt.Errorf("%w | address: %s", ErrAddressNotAllowed, address)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func allowed(addr netip.Addr, allowedNetworks []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, network := range allowedNetworks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package outbound
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     string
+		expected bool
+	}{
+		{name: "public ipv4", addr: "93.184.216.34", expected: true},
+		{name: "public ipv6", addr: "2606:2800:220:1:248:1893:25c8:1946", expected: true},
+		{name: "loopback", addr: "127.0.0.1", expected: false},
+		{name: "other loopback", addr: "127.1.2.3", expected: false},
+		{name: "ipv6 loopback", addr: "::1", expected: false},
+		{name: "private 10/8", addr: "10.1.2.3", expected: false},
+		{name: "private 172.16/12", addr: "172.20.0.1", expected: false},
+		{name: "private 192.168/16", addr: "192.168.1.1", expected: false},
+		{name: "ipv6 unique local", addr: "fd00::1", expected: false},
+		{name: "metadata", addr: "169.254.169.254", expected: false},
+		{name: "ipv6 metadata", addr: "fd00:ec2::254", expected: false},
+		{name: "link-local", addr: "169.254.1.1", expected: false},
+		{name: "ipv6 link-local", addr: "fe80::1", expected: false},
+		{name: "unspecified", addr: "0.0.0.0", expected: false},
+		{name: "ipv6 unspecified", addr: "::", expected: false},
+		{name: "shared address space", addr: "100.100.100.200", expected: false},
+		{name: "broadcast", addr: "255.255.255.255", expected: false},
+		{name: "multicast", addr: "224.0.0.1", expected: false},
+		{name: "ipv4-mapped loopback", addr: "::ffff:127.0.0.1", expected: false},
+		{name: "ipv4-mapped private", addr: "::ffff:10.0.0.1", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			result := allowed(netip.MustParseAddr(tt.addr), nil)
+
+			// Assert
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+func TestAllowed_AllowedNetworks(t *testing.T) {
+	// Arrange
+	allowedNetworks := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}
+
+	// Act
+	inside := allowed(netip.MustParseAddr("10.0.0.5"), allowedNetworks)
+	outside := allowed(netip.MustParseAddr("10.0.1.5"), allowedNetworks)
+
+	// Assert
+	assert.True(t, inside)
+	assert.False(t, outside)
+}
+
+func TestNewClient_RefusesLoopback(t *testing.T) {
+	// Arrange
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+	client := NewClient(time.Second, nil)
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "ip", url: server.URL},
+		{name: "host name", url: strings.Replace(server.URL, "127.0.0.1", "localhost", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := client.Get(tt.url)
+
+			// Assert
+			require.ErrorIs(t, err, ErrAddressNotAllowed)
+			assert.False(t, requested)
+		})
+	}
+}
+func TestNewClient_RefusesRedirectToInternalAddress(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer server.Close()
+	client := NewClient(time.Second, []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")})
+
+	// Act
+	_, err := client.Get(server.URL)
+
+	// Assert
+	require.ErrorIs(t, err, ErrAddressNotAllowed)
+}
+func TestNewClient_AllowedNetwork(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	client := NewClient(time.Second, []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")})
+
+	// Act
+	resp, err := client.Get(server.URL)
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
@@ -0,0 +1,47 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is a typed domain event. Type names the event for consumers, for
+// example "node.created".
+type Event interface {
+	EventType() string
+}
+
+// Message is an event as handed to a Publisher. ID is the same on every
+// attempt to publish the event, so that consumers can drop duplicates.
+type Message struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt string          `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// NewOutboxEvent serializes the event for the outbox. The row must be created
+// in the transaction making the change the event describes.
+func NewOutboxEvent(event Event) (*OutboxEvent, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxEvent{
+		EventID:   uuid.New().String(),
+		Type:      event.EventType(),
+		Payload:   string(data),
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+func newMessage(event *OutboxEvent) Message {
+	return Message{
+		ID:         event.EventID,
+		Type:       event.Type,
+		OccurredAt: event.CreatedAt.UTC().Format(time.RFC3339Nano),
+		Data:       json.RawMessage(event.Payload),
+	}
+}
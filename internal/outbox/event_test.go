@@ -0,0 +1,47 @@
+package outbox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	NodeID string `json:"node_id"`
+}
+
+func (testEvent) EventType() string { return "node.tested" }
+
+func TestNewOutboxEvent_Success(t *testing.T) {
+	// Act
+	event, err := NewOutboxEvent(testEvent{NodeID: "node-id"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`, event.EventID)
+	assert.Equal(t, "node.tested", event.Type)
+	assert.JSONEq(t, `{"node_id":"node-id"}`, event.Payload)
+	assert.Nil(t, event.PublishedAt)
+}
+
+func TestNewMessage_Success(t *testing.T) {
+	// Arrange
+	event := &OutboxEvent{
+		ID:        1,
+		EventID:   "event-id",
+		Type:      "node.tested",
+		Payload:   `{"node_id":"node-id"}`,
+		CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	// Act
+	message := newMessage(event)
+
+	// Assert
+	assert.Equal(t, "event-id", message.ID)
+	assert.Equal(t, "node.tested", message.Type)
+	assert.Equal(t, "2024-01-01T00:00:00Z", message.OccurredAt)
+	assert.JSONEq(t, `{"node_id":"node-id"}`, string(message.Data))
+}
@@ -0,0 +1,99 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// Publisher delivers messages to downstream consumers. Publish returns once
+// the message was accepted, an error makes the relay retry it later.
+type Publisher interface {
+	Publish(ctx context.Context, message Message) error
+}
+
+// NewPublisher returns the publisher selected by the configuration. Without
+// a broker URL, events are only handed to in-process subscribers.
+func NewPublisher(config *config.Config, logger *zap.Logger) Publisher {
+	if config.OutboxPublisherURL != "" {
+		return NewHTTPPublisher(config.OutboxPublisherURL, config.OutboxPublisherTimeout)
+	}
+	logger.Info("no outbox publisher URL configured, publishing events in memory")
+	return NewMemoryPublisher()
+}
+
+// MemoryPublisher hands messages to the handlers subscribed in this process.
+// It stands in for a broker in development and tests.
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	handlers []func(Message)
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Subscribe(handler func(Message)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers = append(p.handlers, handler)
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, message Message) error {
+	p.mu.Lock()
+	handlers := p.handlers
+	p.mu.Unlock()
+	for _, handler := range handlers {
+		handler(message)
+	}
+	return nil
+}
+
+// HTTPPublisher posts each message to <baseURL>/<type>, the subject layout
+// of NATS style HTTP gateways. The Msg-Id header carries the message ID for
+// the broker's duplicate detection.
+type HTTPPublisher struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHTTPPublisher(baseURL string, timeout time.Duration) *HTTPPublisher {
+	return &HTTPPublisher{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *HTTPPublisher) Publish(ctx context.Context, message Message) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/"+message.Type, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Msg-Id", message.ID)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("unexpected status | status: " + strconv.Itoa(resp.StatusCode))
+	}
+	return nil
+}
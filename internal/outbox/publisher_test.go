@@ -0,0 +1,83 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewPublisher(t *testing.T) {
+	// Act
+	memory := NewPublisher(&config.Config{}, zap.NewNop())
+	remote := NewPublisher(&config.Config{OutboxPublisherURL: "http://localhost:4222/publish", OutboxPublisherTimeout: time.Second}, zap.NewNop())
+
+	// Assert
+	assert.IsType(t, &MemoryPublisher{}, memory)
+	assert.IsType(t, &HTTPPublisher{}, remote)
+}
+
+func TestMemoryPublisher_Publish_Success(t *testing.T) {
+	// Arrange
+	publisher := NewMemoryPublisher()
+	var received []Message
+	publisher.Subscribe(func(message Message) {
+		received = append(received, message)
+	})
+	message := Message{ID: "event-id", Type: "node.created", Data: json.RawMessage(`{}`)}
+
+	// Act
+	err := publisher.Publish(context.Background(), message)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []Message{message}, received)
+}
+
+func TestHTTPPublisher_Publish_Success(t *testing.T) {
+	// Arrange
+	var path, msgID string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		msgID = r.Header.Get("Msg-Id")
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	publisher := NewHTTPPublisher(server.URL+"/publish/", time.Second)
+	message := Message{ID: "event-id", Type: "node.created", OccurredAt: "2024-01-01T00:00:00Z", Data: json.RawMessage(`{"node_id":"node-id"}`)}
+
+	// Act
+	err := publisher.Publish(context.Background(), message)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "/publish/node.created", path)
+	assert.Equal(t, "event-id", msgID)
+	assert.JSONEq(t, `{"id":"event-id","type":"node.created","occurred_at":"2024-01-01T00:00:00Z","data":{"node_id":"node-id"}}`, string(body))
+}
+func TestHTTPPublisher_Publish_Rejected(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	publisher := NewHTTPPublisher(server.URL, time.Second)
+
+	// Act
+	err := publisher.Publish(context.Background(), Message{ID: "event-id", Type: "node.created", Data: json.RawMessage(`{}`)})
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+}
@@ -0,0 +1,126 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// Relay publishes the outbox in ID order. A message that fails to publish
+// blocks the ones after it until it goes through. IDs are taken when events
+// are inserted, so an event whose transaction commits after the events
+// following it may be published after them; consumers order events by
+// occurred_at where it matters. Messages are published at least once.
+type Relay struct {
+	repo      Repository
+	publisher Publisher
+	logger    *zap.Logger
+	interval  time.Duration
+	timeout   time.Duration
+	batchSize int
+	retention time.Duration
+}
+
+func NewRelay(repo Repository, publisher Publisher, config *config.Config, logger *zap.Logger) *Relay {
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		logger:    logger,
+		interval:  config.OutboxRelayInterval,
+		timeout:   config.OutboxPublisherTimeout,
+		batchSize: max(config.OutboxBatchSize, 1),
+		retention: config.OutboxRetention,
+	}
+}
+
+func (r *Relay) Run(ctx context.Context) {
+	if r.interval <= 0 {
+		r.logger.Info("outbox relay disabled")
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		if err := r.RelayOnce(ctx); err != nil {
+			r.logger.Error("failed to relay outbox", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce publishes one batch of the outbox and deletes the events
+// published longer than the retention period ago. The batch is claimed under
+// the relay lock and published after the transaction, so that no connection
+// or lock is held while waiting for the broker.
+func (r *Relay) RelayOnce(ctx context.Context) error {
+	now := time.Now().UTC()
+	// The lease covers publishing the whole batch one after another.
+	leaseUntil := now.Add(time.Duration(r.batchSize) * (r.timeout + time.Second))
+	var events []OutboxEvent
+	err := r.repo.Transaction(func(repo Repository) error {
+		locked, err := repo.TryLock()
+		if err != nil || !locked {
+			return err
+		}
+		events, err = repo.ClaimUnpublished(now, leaseUntil, r.batchSize)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range events {
+		published := r.publish(ctx, &events[i])
+		if err := r.repo.Update(&events[i]); err != nil {
+			return err
+		}
+		if !published {
+			ids := make([]int64, 0, len(events)-i-1)
+			for _, event := range events[i+1:] {
+				ids = append(ids, event.ID)
+			}
+			if err := r.repo.ReleaseClaims(ids); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	if r.retention <= 0 {
+		return nil
+	}
+	deleted, err := r.repo.DeletePublished(time.Now().UTC().Add(-r.retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		r.logger.Info("deleted published outbox events", zap.Int64("count", deleted))
+	}
+	return nil
+}
+
+// publish sends the event and records the attempt, releasing its claim. It
+// reports whether the event was published.
+func (r *Relay) publish(ctx context.Context, event *OutboxEvent) bool {
+	event.Attempts++
+	event.ClaimedUntil = nil
+	if err := r.publisher.Publish(ctx, newMessage(event)); err != nil {
+		message := err.Error()
+		event.LastError = &message
+		r.logger.Warn("failed to publish event", zap.Int64("id", event.ID), zap.String("type", event.Type), zap.Error(err))
+		return false
+	}
+	now := time.Now().UTC()
+	event.PublishedAt = &now
+	event.LastError = nil
+	return true
+}
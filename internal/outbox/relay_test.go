@@ -0,0 +1,204 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type MockRepository struct {
+	mock.Mock
+	inTransaction bool
+}
+
+func (m *MockRepository) Transaction(fn func(repo Repository) error) error {
+	m.Called(fn)
+	m.inTransaction = true
+	defer func() { m.inTransaction = false }()
+	return fn(m)
+}
+func (m *MockRepository) TryLock() (bool, error) {
+	args := m.Called()
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockRepository) GetUnpublished(limit int) ([]OutboxEvent, error) {
+	args := m.Called(limit)
+	return args.Get(0).([]OutboxEvent), args.Error(1)
+}
+func (m *MockRepository) ClaimUnpublished(now time.Time, leaseUntil time.Time, limit int) ([]OutboxEvent, error) {
+	args := m.Called(now, leaseUntil, limit)
+	return args.Get(0).([]OutboxEvent), args.Error(1)
+}
+func (m *MockRepository) ReleaseClaims(ids []int64) error {
+	args := m.Called(ids)
+	return args.Error(0)
+}
+func (m *MockRepository) Update(event *OutboxEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+func (m *MockRepository) DeletePublished(publishedBefore time.Time) (int64, error) {
+	args := m.Called(publishedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type failingPublisher struct {
+	failType  string
+	published []Message
+}
+
+func (p *failingPublisher) Publish(ctx context.Context, message Message) error {
+	if message.Type == p.failType {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, message)
+	return nil
+}
+
+func newTestRelay(repo Repository, publisher Publisher) *Relay {
+	return NewRelay(repo, publisher, &config.Config{
+		OutboxRelayInterval:    time.Second,
+		OutboxBatchSize:        10,
+		OutboxRetention:        time.Hour,
+		OutboxPublisherTimeout: 5 * time.Second,
+	}, zap.NewNop())
+}
+
+func TestRelay_RelayOnce_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	publisher := NewMemoryPublisher()
+	var received []string
+	publisher.Subscribe(func(message Message) {
+		received = append(received, message.ID)
+	})
+	relay := newTestRelay(mockRepo, publisher)
+
+	events := []OutboxEvent{
+		{ID: 1, EventID: "event-1", Type: "node.created", Payload: `{}`},
+		{ID: 2, EventID: "event-2", Type: "node.moved", Payload: `{}`},
+	}
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("TryLock").Return(true, nil)
+	mockRepo.On("ClaimUnpublished", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return(events, nil)
+	mockRepo.On("Update", mock.MatchedBy(func(event *OutboxEvent) bool {
+		return event.PublishedAt != nil && event.Attempts == 1 && event.LastError == nil && event.ClaimedUntil == nil
+	})).Return(nil).Twice()
+	mockRepo.On("DeletePublished", mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+
+	// Act
+	err := relay.RelayOnce(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"event-1", "event-2"}, received)
+	mockRepo.AssertExpectations(t)
+}
+func TestRelay_RelayOnce_StopsAtFailure(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	publisher := &failingPublisher{failType: "node.moved"}
+	relay := newTestRelay(mockRepo, publisher)
+
+	events := []OutboxEvent{
+		{ID: 1, EventID: "event-1", Type: "node.created", Payload: `{}`},
+		{ID: 2, EventID: "event-2", Type: "node.moved", Payload: `{}`},
+		{ID: 3, EventID: "event-3", Type: "node.deleted", Payload: `{}`},
+	}
+	var updated []OutboxEvent
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("TryLock").Return(true, nil)
+	mockRepo.On("ClaimUnpublished", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return(events, nil)
+	mockRepo.On("Update", mock.AnythingOfType("*outbox.OutboxEvent")).Run(func(args mock.Arguments) {
+		updated = append(updated, *args.Get(0).(*OutboxEvent))
+	}).Return(nil)
+	mockRepo.On("ReleaseClaims", []int64{3}).Return(nil)
+	mockRepo.On("DeletePublished", mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+
+	// Act
+	err := relay.RelayOnce(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, publisher.published, 1)
+	assert.Equal(t, "event-1", publisher.published[0].ID)
+	require.Len(t, updated, 2)
+	assert.NotNil(t, updated[0].PublishedAt)
+	assert.Nil(t, updated[1].PublishedAt)
+	assert.Equal(t, 1, updated[1].Attempts)
+	assert.Equal(t, "broker unavailable", *updated[1].LastError)
+	assert.Nil(t, updated[1].ClaimedUntil)
+	mockRepo.AssertExpectations(t)
+}
+func TestRelay_RelayOnce_PublishesOutsideTransaction(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	publisher := NewMemoryPublisher()
+	var inTransaction []bool
+	publisher.Subscribe(func(message Message) {
+		inTransaction = append(inTransaction, mockRepo.inTransaction)
+	})
+	relay := newTestRelay(mockRepo, publisher)
+
+	leaseUntil := time.Now().Add(time.Minute)
+	events := []OutboxEvent{{ID: 1, EventID: "event-1", Type: "node.created", Payload: `{}`, ClaimedUntil: &leaseUntil}}
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("TryLock").Return(true, nil)
+	mockRepo.On("ClaimUnpublished", mock.AnythingOfType("time.Time"), mock.MatchedBy(func(leaseUntil time.Time) bool {
+		// Ten events, each taking up to the publisher timeout.
+		return time.Until(leaseUntil) > 50*time.Second && time.Until(leaseUntil) <= 60*time.Second
+	}), 10).Return(events, nil)
+	mockRepo.On("Update", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("DeletePublished", mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+
+	// Act
+	err := relay.RelayOnce(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false}, inTransaction)
+	mockRepo.AssertExpectations(t)
+}
+func TestRelay_RelayOnce_LockedByOtherRelay(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	relay := newTestRelay(mockRepo, NewMemoryPublisher())
+
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("TryLock").Return(false, nil)
+	mockRepo.On("DeletePublished", mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+
+	// Act
+	err := relay.RelayOnce(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "ClaimUnpublished", mock.Anything, mock.Anything, mock.Anything)
+}
+func TestRelay_RelayOnce_DeletesPublished(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	relay := newTestRelay(mockRepo, NewMemoryPublisher())
+
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("TryLock").Return(true, nil)
+	mockRepo.On("ClaimUnpublished", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return([]OutboxEvent{}, nil)
+	mockRepo.On("DeletePublished", mock.MatchedBy(func(publishedBefore time.Time) bool {
+		return time.Since(publishedBefore) > 59*time.Minute && time.Since(publishedBefore) < 61*time.Minute
+	})).Return(int64(3), nil)
+
+	// Act
+	err := relay.RelayOnce(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,124 @@
+package outbox
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// relayLockID is the key of the advisory lock held by the relay while it
+// claims a batch of the outbox, so that only one replica claims at a time.
+const relayLockID = 7_402_113
+
+// OutboxEvent is a domain event waiting to be published. Rows are written in
+// the same transaction as the change they describe and published in ID
+// order by the Relay. ClaimedUntil is set while a relay is publishing the
+// event.
+type OutboxEvent struct {
+	ID           int64      `gorm:"primary_key;autoIncrement"`
+	EventID      string     `gorm:"type:uuid;not null;uniqueIndex"`
+	Type         string     `gorm:"type:varchar(50);not null"`
+	Payload      string     `gorm:"type:jsonb;not null"`
+	Attempts     int        `gorm:"type:int;not null"`
+	LastError    *string    `gorm:"type:text"`
+	PublishedAt  *time.Time `gorm:"type:timestamptz;index"`
+	ClaimedUntil *time.Time `gorm:"type:timestamptz"`
+	CreatedAt    time.Time  `gorm:"type:timestamptz;not null"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+func (e *OutboxEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.EventID == "" {
+		e.EventID = uuid.New().String()
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+type Repository interface {
+	Transaction(fn func(repo Repository) error) error
+	TryLock() (bool, error)
+	GetUnpublished(limit int) ([]OutboxEvent, error)
+	ClaimUnpublished(now time.Time, leaseUntil time.Time, limit int) ([]OutboxEvent, error)
+	ReleaseClaims(ids []int64) error
+	Update(event *OutboxEvent) error
+	DeletePublished(publishedBefore time.Time) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+// TryLock takes the relay lock until the end of the transaction. It returns
+// false when another relay holds it.
+func (r *repository) TryLock() (bool, error) {
+	var locked bool
+	err := r.db.Raw("SELECT pg_try_advisory_xact_lock(?)", relayLockID).Scan(&locked).Error
+	return locked, err
+}
+
+func (r *repository) GetUnpublished(limit int) ([]OutboxEvent, error) {
+	var events []OutboxEvent
+	err := r.db.Where("published_at IS NULL").Order("id").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// ClaimUnpublished returns the first unpublished events in ID order and
+// claims them until leaseUntil, so that the events are published outside of
+// the transaction while other relays wait for them. Nothing is returned while
+// a claim is held; the claims of a relay that died run out at leaseUntil. It
+// must be called with the relay lock held.
+func (r *repository) ClaimUnpublished(now time.Time, leaseUntil time.Time, limit int) ([]OutboxEvent, error) {
+	var claimed int64
+	err := r.db.Model(&OutboxEvent{}).Where("published_at IS NULL AND claimed_until > ?", now).Count(&claimed).Error
+	if err != nil || claimed > 0 {
+		return nil, err
+	}
+
+	events, err := r.GetUnpublished(limit)
+	if err != nil || len(events) == 0 {
+		return events, err
+	}
+	ids := make([]int64, len(events))
+	for i := range events {
+		ids[i] = events[i].ID
+		events[i].ClaimedUntil = &leaseUntil
+	}
+	if err := r.db.Model(&OutboxEvent{}).Where("id IN ?", ids).Update("claimed_until", leaseUntil).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ReleaseClaims lets the events be claimed again right away.
+func (r *repository) ReleaseClaims(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&OutboxEvent{}).Where("id IN ?", ids).Update("claimed_until", nil).Error
+}
+
+func (r *repository) Update(event *OutboxEvent) error {
+	return r.db.Save(event).Error
+}
+
+func (r *repository) DeletePublished(publishedBefore time.Time) (int64, error) {
+	result := r.db.Where("published_at < ?", publishedBefore).Delete(&OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
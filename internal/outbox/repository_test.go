@@ -0,0 +1,221 @@
+package outbox
+
+import (
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/db"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var d *gorm.DB
+
+func TestMain(m *testing.M) {
+	// Setup
+	dbURL, closeDB, err := test.SetupPostgresql()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	d, err = db.NewDatabase(&config.Config{DatabaseURL: dbURL})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = d.AutoMigrate(&OutboxEvent{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Run
+	code := m.Run()
+
+	// Teardown
+	closeDB()
+
+	os.Exit(code)
+}
+
+func TestRepository_NewRepository_Success(t *testing.T) {
+	// Act
+	repo := NewRepository(d)
+
+	// Assert
+	assert.NotNil(t, repo)
+	assert.IsType(t, &repository{}, repo)
+}
+
+func TestRepository_GetUnpublished_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	publishedAt := time.Now().UTC()
+	events := []*OutboxEvent{
+		{Type: "node.created", Payload: `{}`},
+		{Type: "node.moved", Payload: `{}`, PublishedAt: &publishedAt},
+		{Type: "node.deleted", Payload: `{}`},
+	}
+	for _, event := range events {
+		err = d.Create(event).Error
+		require.NoError(t, err)
+	}
+
+	// Act
+	unpublished, err := repo.GetUnpublished(10)
+	limited, limitedErr := repo.GetUnpublished(1)
+
+	// Assert
+	require.NoError(t, err)
+	require.NoError(t, limitedErr)
+	require.Len(t, unpublished, 2)
+	assert.Equal(t, "node.created", unpublished[0].Type)
+	assert.Equal(t, "node.deleted", unpublished[1].Type)
+	require.Len(t, limited, 1)
+	assert.Equal(t, events[0].ID, limited[0].ID)
+}
+
+func TestRepository_ClaimUnpublished_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	events := []*OutboxEvent{
+		{Type: "node.created", Payload: `{}`},
+		{Type: "node.moved", Payload: `{}`},
+		{Type: "node.deleted", Payload: `{}`},
+	}
+	for _, event := range events {
+		err = d.Create(event).Error
+		require.NoError(t, err)
+	}
+	now := time.Now().UTC()
+	leaseUntil := now.Add(time.Minute)
+
+	// Act
+	claimed, err := repo.ClaimUnpublished(now, leaseUntil, 2)
+	whileClaimed, whileClaimedErr := repo.ClaimUnpublished(now, leaseUntil, 2)
+	afterLease, afterLeaseErr := repo.ClaimUnpublished(leaseUntil.Add(time.Second), leaseUntil.Add(time.Minute), 2)
+
+	// Assert
+	require.NoError(t, err)
+	require.NoError(t, whileClaimedErr)
+	require.NoError(t, afterLeaseErr)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, events[0].ID, claimed[0].ID)
+	assert.Equal(t, events[1].ID, claimed[1].ID)
+	assert.Empty(t, whileClaimed)
+	require.Len(t, afterLease, 2)
+	assert.Equal(t, events[0].ID, afterLease[0].ID)
+
+	var stored OutboxEvent
+	err = d.First(&stored, events[0].ID).Error
+	require.NoError(t, err)
+	require.NotNil(t, stored.ClaimedUntil)
+	assert.WithinDuration(t, leaseUntil.Add(time.Minute), *stored.ClaimedUntil, time.Millisecond)
+}
+func TestRepository_ClaimUnpublished_Released(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	event := &OutboxEvent{Type: "node.created", Payload: `{}`}
+	err = d.Create(event).Error
+	require.NoError(t, err)
+	now := time.Now().UTC()
+	_, err = repo.ClaimUnpublished(now, now.Add(time.Minute), 10)
+	require.NoError(t, err)
+
+	// Act
+	err = repo.ReleaseClaims([]int64{event.ID})
+	claimed, claimErr := repo.ClaimUnpublished(now, now.Add(time.Minute), 10)
+
+	// Assert
+	require.NoError(t, err)
+	require.NoError(t, claimErr)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, event.ID, claimed[0].ID)
+}
+
+func TestRepository_TryLock_HeldByOtherTransaction(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	// Act
+	var first, second bool
+	var secondErr error
+	err = repo.Transaction(func(repo Repository) error {
+		var err error
+		first, err = repo.TryLock()
+		if err != nil {
+			return err
+		}
+		secondErr = NewRepository(d).Transaction(func(other Repository) error {
+			second, err = other.TryLock()
+			return err
+		})
+		return nil
+	})
+	var afterwards bool
+	afterwardsErr := repo.Transaction(func(repo Repository) error {
+		var err error
+		afterwards, err = repo.TryLock()
+		return err
+	})
+
+	// Assert
+	require.NoError(t, err)
+	require.NoError(t, secondErr)
+	require.NoError(t, afterwardsErr)
+	assert.True(t, first)
+	assert.False(t, second)
+	assert.True(t, afterwards)
+}
+
+func TestRepository_UpdateAndDeletePublished_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	old := &OutboxEvent{Type: "node.created", Payload: `{}`}
+	recent := &OutboxEvent{Type: "node.moved", Payload: `{}`}
+	pending := &OutboxEvent{Type: "node.deleted", Payload: `{}`}
+	for _, event := range []*OutboxEvent{old, recent, pending} {
+		err = d.Create(event).Error
+		require.NoError(t, err)
+	}
+	oldPublishedAt := time.Now().UTC().Add(-2 * time.Hour)
+	old.PublishedAt = &oldPublishedAt
+	recentPublishedAt := time.Now().UTC()
+	recent.PublishedAt = &recentPublishedAt
+
+	// Act
+	oldErr := repo.Update(old)
+	recentErr := repo.Update(recent)
+	deleted, deleteErr := repo.DeletePublished(time.Now().UTC().Add(-time.Hour))
+
+	// Assert
+	require.NoError(t, oldErr)
+	require.NoError(t, recentErr)
+	require.NoError(t, deleteErr)
+	assert.Equal(t, int64(1), deleted)
+	var remaining []OutboxEvent
+	err = d.Order("id").Find(&remaining).Error
+	require.NoError(t, err)
+	require.Len(t, remaining, 2)
+	assert.Equal(t, recent.ID, remaining[0].ID)
+	assert.Equal(t, pending.ID, remaining[1].ID)
+}
@@ -1,34 +1,68 @@
 package router
 
 import (
+	"expvar"
 	"net/http"
 
+	"github.com/vera/vera-drive-service/internal/accesstoken"
+	"github.com/vera/vera-drive-service/internal/account"
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/config"
 	"github.com/vera/vera-drive-service/internal/middleware"
+	"github.com/vera/vera-drive-service/internal/share"
 	"github.com/vera/vera-drive-service/internal/url"
+	"github.com/vera/vera-drive-service/internal/user"
+	"github.com/vera/vera-drive-service/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 )
 
 func NewRouter(
+	config *config.Config,
 	httpMiddleware middleware.HTTPMiddleware,
 	corsMiddleware middleware.CORSMiddleware,
+	rateLimiter *middleware.RateLimiter,
 	authMiddleware middleware.AuthMiddleware,
+	adminMiddleware middleware.AdminMiddleware,
+	serviceAuthMiddleware middleware.ServiceAuthMiddleware,
 	urlHandler *url.Handler,
-) *gin.Engine {
+	shareHandler *share.Handler,
+	auditHandler *audit.Handler,
+	webhookHandler *webhook.Handler,
+	accessTokenHandler *accesstoken.Handler,
+	accountHandler *account.Handler,
+	userHandler *user.Handler,
+) (*gin.Engine, error) {
 	r := gin.New()
+	// The client IP, used by the rate limiter and the logs, is only taken from
+	// X-Forwarded-For when the request comes through one of TRUSTED_PROXIES.
+	// Otherwise any client could pick its own bucket.
+	if err := r.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, err
+	}
 	r.Use(
 		gin.Recovery(),
 		gin.HandlerFunc(httpMiddleware),
 		gin.HandlerFunc(corsMiddleware),
+		rateLimiter.ByIP(),
 	)
+	// Authenticated requests are limited per user as well.
+	authMiddleware = rateLimiter.AfterAuth(authMiddleware)
 
 	r.GET("/healthz", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "OK"})
 	})
 	r.StaticFile("/docs/swagger.yaml", "./api/swagger.yaml")
 	r.StaticFile("/docs", "./api/swagger.html")
+	r.GET("/admin/metrics", gin.HandlerFunc(authMiddleware), middleware.RequireScope(middleware.ScopeDriveAdmin), gin.HandlerFunc(adminMiddleware), gin.WrapH(expvar.Handler()))
 
 	url.RegisterRoutes(r, urlHandler, authMiddleware)
+	share.RegisterRoutes(r, shareHandler, authMiddleware)
+	audit.RegisterRoutes(r, auditHandler, authMiddleware, adminMiddleware)
+	webhook.RegisterRoutes(r, webhookHandler, authMiddleware)
+	accesstoken.RegisterRoutes(r, accessTokenHandler, authMiddleware)
+	account.RegisterRoutes(r, accountHandler, authMiddleware, serviceAuthMiddleware)
+	user.RegisterRoutes(r, userHandler, authMiddleware)
 
-	return r
+	return r, nil
 }
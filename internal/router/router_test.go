@@ -0,0 +1,87 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// setupRouter returns the router with an IP limit of one request per minute
+// and an auth middleware rejecting every request.
+func setupRouter(t *testing.T, trustedProxies []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	config := &config.Config{
+		RateLimitEnabled:   true,
+		RateLimitWindow:    time.Minute,
+		RateLimitIPLimit:   1,
+		RateLimitUserLimit: 1,
+		TrustedProxies:     trustedProxies,
+	}
+	rejectAll := func(c *gin.Context) {
+		c.Error(apperror.New(apperror.CodeInvalidUserToken, "invalid user token"))
+		c.Abort()
+	}
+	r, err := NewRouter(
+		config,
+		middleware.NewHTTPMiddleware(zap.NewNop()),
+		middleware.NewCORSMiddleware(config),
+		middleware.NewRateLimiter(config, middleware.NewMemoryRateLimitStore(config.RateLimitWindow), zap.NewNop()),
+		rejectAll,
+		rejectAll,
+		rejectAll,
+		nil, nil, nil, nil, nil, nil, nil,
+	)
+	require.NoError(t, err)
+	return r
+}
+
+func serveFrom(r *gin.Engine, remoteAddr string, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/urls/root", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestNewRouter_SpoofedForwardedFor(t *testing.T) {
+	// Arrange
+	r := setupRouter(t, nil)
+	serveFrom(r, "203.0.113.7:1234", "198.51.100.1")
+
+	// Act
+	w := serveFrom(r, "203.0.113.7:1234", "198.51.100.2")
+
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+func TestNewRouter_TrustedProxy(t *testing.T) {
+	// Arrange
+	r := setupRouter(t, []string{"10.0.0.0/8"})
+	serveFrom(r, "10.0.0.5:1234", "198.51.100.1")
+
+	// Act
+	w := serveFrom(r, "10.0.0.5:1234", "198.51.100.2")
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+func TestNewRouter_InvalidTrustedProxy(t *testing.T) {
+	// Act
+	r, err := NewRouter(&config.Config{TrustedProxies: []string{"not-an-ip"}},
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, r)
+}
@@ -0,0 +1,80 @@
+package share
+
+import (
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/url"
+)
+
+type RequestURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type ShareLinkURI struct {
+	ID      string `uri:"id" binding:"required,uuid"`
+	ShareID string `uri:"share_id" binding:"required,uuid"`
+}
+
+type TokenURI struct {
+	Token string `uri:"token" binding:"required"`
+}
+
+type CreateRequestBody struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	Password  string     `json:"password" binding:"omitempty,min=8,max=72"`
+}
+
+type ShareLinkResponse struct {
+	ID          string  `json:"id"`
+	FolderID    string  `json:"folder_id"`
+	Token       string  `json:"token"`
+	ExpiresAt   *string `json:"expires_at"`
+	HasPassword bool    `json:"has_password"`
+	CreatedAt   string  `json:"created_at"`
+}
+
+// SharedNode is a node of a publicly shared folder. Only fields that are safe
+// to show to anonymous visitors are included.
+type SharedNode struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Type        string       `json:"type"`
+	URL         *string      `json:"url"`
+	Title       *string      `json:"title"`
+	Description *string      `json:"description"`
+	ImageURL    *string      `json:"image_url"`
+	CreatedAt   string       `json:"created_at"`
+	UpdatedAt   string       `json:"updated_at"`
+	Children    []SharedNode `json:"children"`
+}
+
+func newShareLinkResponse(link *ShareLink) *ShareLinkResponse {
+	var expiresAt *string
+	if link.ExpiresAt != nil {
+		formatted := link.ExpiresAt.UTC().Format(time.RFC3339)
+		expiresAt = &formatted
+	}
+	return &ShareLinkResponse{
+		ID:          link.ID,
+		FolderID:    link.FolderID,
+		Token:       link.Token,
+		ExpiresAt:   expiresAt,
+		HasPassword: link.PasswordHash != nil,
+		CreatedAt:   link.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func newSharedNode(node *url.URLNode) *SharedNode {
+	return &SharedNode{
+		ID:          node.ID,
+		Name:        node.Name,
+		Type:        node.Type,
+		URL:         node.URL,
+		Title:       node.Title,
+		Description: node.Description,
+		ImageURL:    node.ImageURL,
+		CreatedAt:   node.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:   node.UpdatedAt.UTC().Format(time.RFC3339),
+		Children:    []SharedNode{},
+	}
+}
@@ -0,0 +1,91 @@
+package share
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) CreateShareLink(c *gin.Context) {
+	uri := &RequestURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	var body CreateRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := h.service.CreateShareLink(uri.ID, &body, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *Handler) GetShareLinks(c *gin.Context) {
+	uri := &RequestURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := h.service.GetShareLinks(uri.ID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) RevokeShareLink(c *gin.Context) {
+	uri := &ShareLinkURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if err := h.service.RevokeShareLink(uri.ID, uri.ShareID, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetSharedFolder serves a shared folder to anonymous visitors. The password
+// of a protected link is sent in the X-Share-Password header so that it does
+// not end up in URLs or access logs.
+func (h *Handler) GetSharedFolder(c *gin.Context) {
+	uri := &TokenURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	response, err := h.service.GetSharedFolder(uri.Token, c.GetHeader("X-Share-Password"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,245 @@
+package share
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) CreateShareLink(folderID string, creates *CreateRequestBody, userID string) (*ShareLinkResponse, error) {
+	args := m.Called(folderID, creates, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ShareLinkResponse), args.Error(1)
+}
+func (m *MockService) GetShareLinks(folderID string, userID string) ([]ShareLinkResponse, error) {
+	args := m.Called(folderID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]ShareLinkResponse), args.Error(1)
+}
+func (m *MockService) RevokeShareLink(folderID string, shareID string, userID string) error {
+	args := m.Called(folderID, shareID, userID)
+	return args.Error(0)
+}
+func (m *MockService) GetSharedFolder(token string, password string) (*SharedNode, error) {
+	args := m.Called(token, password)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*SharedNode), args.Error(1)
+}
+
+const (
+	folderID = "123e4567-e89b-12d3-a456-426614174000"
+	shareID  = "123e4567-e89b-12d3-a456-426614174001"
+)
+
+func TestHandler_NewHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+
+	// Act
+	h := NewHandler(mockService)
+
+	// Assert
+	assert.IsType(t, &Handler{}, h)
+	assert.Equal(t, mockService, h.service)
+}
+
+func TestHandler_CreateShareLink_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	requestJSON, _ := json.Marshal(CreateRequestBody{Password: "secret-password"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: folderID}}
+	c.Set("user_id", "1")
+
+	expectedResponse := &ShareLinkResponse{ID: shareID, FolderID: folderID, Token: "token", HasPassword: true}
+	mockService.On("CreateShareLink", folderID, &CreateRequestBody{Password: "secret-password"}, "1").Return(expectedResponse, nil)
+
+	// Act
+	handler.CreateShareLink(c)
+
+	// Assert
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var response ShareLinkResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expectedResponse, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_CreateShareLink_InvalidBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "short password", body: `{"password": "short"}`},
+		{name: "invalid expiry", body: `{"expires_at": "tomorrow"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := &MockService{}
+			handler := NewHandler(mockService)
+			c, w := test.SetupContext()
+
+			c.Request.Body = io.NopCloser(bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Params = gin.Params{{Key: "id", Value: folderID}}
+			c.Set("user_id", "1")
+
+			// Act
+			handler.CreateShareLink(c)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			mockService.AssertNotCalled(t, "CreateShareLink", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandler_GetShareLinks_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Params = gin.Params{{Key: "id", Value: folderID}}
+	c.Set("user_id", "1")
+
+	expectedResponse := []ShareLinkResponse{{ID: shareID, FolderID: folderID, Token: "token"}}
+	mockService.On("GetShareLinks", folderID, "1").Return(expectedResponse, nil)
+
+	// Act
+	handler.GetShareLinks(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response []ShareLinkResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, expectedResponse, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_GetShareLinks_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, _ := test.SetupContext()
+
+	c.Params = gin.Params{{Key: "id", Value: folderID}}
+	c.Set("user_id", "1")
+
+	mockService.On("GetShareLinks", folderID, "1").Return(nil, assert.AnError)
+
+	// Act
+	handler.GetShareLinks(c)
+
+	// Assert
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, assert.AnError, c.Errors[0].Err)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_RevokeShareLink_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Params = gin.Params{{Key: "id", Value: folderID}, {Key: "share_id", Value: shareID}}
+	c.Set("user_id", "1")
+
+	mockService.On("RevokeShareLink", folderID, shareID, "1").Return(nil)
+
+	// Act
+	handler.RevokeShareLink(c)
+	c.Writer.WriteHeaderNow()
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_RevokeShareLink_InvalidURI(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Params = gin.Params{{Key: "id", Value: folderID}, {Key: "share_id", Value: "invalid-uuid"}}
+	c.Set("user_id", "1")
+
+	// Act
+	handler.RevokeShareLink(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "RevokeShareLink", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandler_GetSharedFolder_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Params = gin.Params{{Key: "token", Value: "token"}}
+	c.Request.Header.Set("X-Share-Password", "secret-password")
+
+	expectedResponse := &SharedNode{ID: folderID, Name: "shared", Type: "folder", Children: []SharedNode{}}
+	mockService.On("GetSharedFolder", "token", "secret-password").Return(expectedResponse, nil)
+
+	// Act
+	handler.GetSharedFolder(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	var response SharedNode
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expectedResponse, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_GetSharedFolder_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, _ := test.SetupContext()
+
+	c.Params = gin.Params{{Key: "token", Value: "token"}}
+
+	mockService.On("GetSharedFolder", "token", "").Return(nil, assert.AnError)
+
+	// Act
+	handler.GetSharedFolder(c)
+
+	// Assert
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, assert.AnError, c.Errors[0].Err)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,84 @@
+package share
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ShareLink struct {
+	ID           string     `gorm:"type:uuid;primary_key"`
+	Token        string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	FolderID     string     `gorm:"type:uuid;not null;index"`
+	UserID       string     `gorm:"type:varchar(255);not null"`
+	PasswordHash *string    `gorm:"type:text"`
+	ExpiresAt    *time.Time `gorm:"type:timestamptz"`
+	CreatedAt    time.Time  `gorm:"type:timestamptz;not null"`
+}
+
+func (ShareLink) TableName() string {
+	return "share_links"
+}
+
+func (l *ShareLink) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == "" {
+		l.ID = uuid.New().String()
+	}
+	l.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+type Repository interface {
+	Create(link *ShareLink) error
+	GetOne(id string) (*ShareLink, error)
+	GetByToken(token string) (*ShareLink, error)
+	GetByFolder(folderID string) ([]ShareLink, error)
+	Delete(id string) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(link *ShareLink) error {
+	return r.db.Create(link).Error
+}
+
+func (r *repository) GetOne(id string) (*ShareLink, error) {
+	var link ShareLink
+	err := r.db.Where("id = ?", id).First(&link).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *repository) GetByToken(token string) (*ShareLink, error) {
+	var link ShareLink
+	err := r.db.Where("token = ?", token).First(&link).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *repository) GetByFolder(folderID string) ([]ShareLink, error) {
+	var links []ShareLink
+	err := r.db.Where("folder_id = ?", folderID).Order("created_at").Find(&links).Error
+	return links, err
+}
+
+func (r *repository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&ShareLink{}).Error
+}
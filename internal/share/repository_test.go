@@ -0,0 +1,129 @@
+package share
+
+import (
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/db"
+	"github.com/vera/vera-drive-service/internal/url"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var d *gorm.DB
+
+func TestMain(m *testing.M) {
+	// Setup
+	dbURL, closeDB, err := test.SetupPostgresql()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	d, err = db.NewDatabase(&config.Config{DatabaseURL: dbURL})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = d.AutoMigrate(&url.URLNode{}, &ShareLink{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Run
+	code := m.Run()
+
+	// Teardown
+	closeDB()
+
+	os.Exit(code)
+}
+
+func TestRepository_NewRepository_Success(t *testing.T) {
+	// Act
+	repo := NewRepository(d)
+
+	// Assert
+	assert.NotNil(t, repo)
+	assert.IsType(t, &repository{}, repo)
+}
+
+func TestRepository_CreateAndGet_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	folderID := uuid.New().String()
+	expiresAt := time.Now().Add(time.Hour).UTC()
+	link := &ShareLink{Token: "token", FolderID: folderID, UserID: "1", ExpiresAt: &expiresAt}
+
+	// Act
+	err = repo.Create(link)
+	require.NoError(t, err)
+	byID, byIDErr := repo.GetOne(link.ID)
+	byToken, byTokenErr := repo.GetByToken("token")
+	missing, missingErr := repo.GetByToken("unknown")
+
+	// Assert
+	require.NoError(t, byIDErr)
+	require.NoError(t, byTokenErr)
+	require.NoError(t, missingErr)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`, link.ID)
+	assert.Equal(t, folderID, byID.FolderID)
+	assert.Equal(t, link.ID, byToken.ID)
+	assert.WithinDuration(t, expiresAt, *byToken.ExpiresAt, time.Second)
+	assert.Nil(t, missing)
+}
+
+func TestRepository_GetByFolder_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	folderID := uuid.New().String()
+	links := []*ShareLink{
+		{Token: "token-1", FolderID: folderID, UserID: "1"},
+		{Token: "token-2", FolderID: folderID, UserID: "1"},
+		{Token: "token-3", FolderID: uuid.New().String(), UserID: "1"},
+	}
+	for _, link := range links {
+		err = repo.Create(link)
+		require.NoError(t, err)
+	}
+
+	// Act
+	result, err := repo.GetByFolder(folderID)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.ElementsMatch(t, []string{"token-1", "token-2"}, []string{result[0].Token, result[1].Token})
+}
+
+func TestRepository_Delete_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	link := &ShareLink{Token: "token", FolderID: uuid.New().String(), UserID: "1"}
+	err = repo.Create(link)
+	require.NoError(t, err)
+
+	// Act
+	err = repo.Delete(link.ID)
+
+	// Assert
+	require.NoError(t, err)
+	deleted, err := repo.GetOne(link.ID)
+	require.NoError(t, err)
+	assert.Nil(t, deleted)
+}
@@ -0,0 +1,22 @@
+package share
+
+import (
+	"github.com/vera/vera-drive-service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, h *Handler, authMiddleware middleware.AuthMiddleware) {
+	read := middleware.RequireScope(middleware.ScopeDriveRead)
+	admin := middleware.RequireScope(middleware.ScopeDriveAdmin)
+
+	g := r.Group("/urls/:id/shares")
+	g.Use(gin.HandlerFunc(authMiddleware))
+	{
+		g.POST("", admin, h.CreateShareLink)
+		g.GET("", read, h.GetShareLinks)
+		g.DELETE("/:share_id", admin, h.RevokeShareLink)
+	}
+
+	r.GET("/shared/:token", h.GetSharedFolder)
+}
@@ -0,0 +1,185 @@
+package share
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sort"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/url"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// NodeRepository is the part of url.Repository needed to resolve shared
+// folders.
+type NodeRepository interface {
+	GetOne(id string) (*url.URLNode, error)
+	GetSubtree(id string) ([]url.URLNode, error)
+}
+
+type Service interface {
+	CreateShareLink(folderID string, creates *CreateRequestBody, userID string) (*ShareLinkResponse, error)
+	GetShareLinks(folderID string, userID string) ([]ShareLinkResponse, error)
+	RevokeShareLink(folderID string, shareID string, userID string) error
+	GetSharedFolder(token string, password string) (*SharedNode, error)
+}
+
+type service struct {
+	repo       Repository
+	nodes      NodeRepository
+	authorizer url.Authorizer
+}
+
+func NewService(repo Repository, nodes NodeRepository, authorizer url.Authorizer) Service {
+	return &service{repo: repo, nodes: nodes, authorizer: authorizer}
+}
+
+// validateFolderOwnership checks that the node is a folder the user owns or
+// was granted the owner role on.
+func (s *service) validateFolderOwnership(folderID string, userID string) error {
+	if err := s.authorizer.Authorize(folderID, userID, url.RoleOwner); err != nil {
+		return err
+	}
+	node, err := s.nodes.GetOne(folderID)
+	if err != nil {
+		return err
+	}
+	if node.Type != "folder" {
+		return apperror.New(apperror.CodeShareFolderOnly, "Only folders can be shared | id: "+folderID)
+	}
+	return nil
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (s *service) CreateShareLink(folderID string, creates *CreateRequestBody, userID string) (*ShareLinkResponse, error) {
+	if err := s.validateFolderOwnership(folderID, userID); err != nil {
+		return nil, err
+	}
+	if creates.ExpiresAt != nil && !creates.ExpiresAt.After(time.Now()) {
+		return nil, apperror.New(apperror.CodeShareExpiryInvalid, "Expiry must be in the future | expires_at: "+creates.ExpiresAt.Format(time.RFC3339))
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	link := &ShareLink{
+		Token:     token,
+		FolderID:  folderID,
+		UserID:    userID,
+		ExpiresAt: creates.ExpiresAt,
+	}
+	if creates.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(creates.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		passwordHash := string(hash)
+		link.PasswordHash = &passwordHash
+	}
+
+	if err := s.repo.Create(link); err != nil {
+		return nil, err
+	}
+	return newShareLinkResponse(link), nil
+}
+
+func (s *service) GetShareLinks(folderID string, userID string) ([]ShareLinkResponse, error) {
+	if err := s.validateFolderOwnership(folderID, userID); err != nil {
+		return nil, err
+	}
+
+	links, err := s.repo.GetByFolder(folderID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]ShareLinkResponse, len(links))
+	for i, link := range links {
+		responses[i] = *newShareLinkResponse(&link)
+	}
+	return responses, nil
+}
+
+func (s *service) RevokeShareLink(folderID string, shareID string, userID string) error {
+	if err := s.validateFolderOwnership(folderID, userID); err != nil {
+		return err
+	}
+
+	link, err := s.repo.GetOne(shareID)
+	if err != nil {
+		return err
+	}
+	if link == nil || link.FolderID != folderID {
+		return apperror.New(apperror.CodeShareNotFound, "Share link not found | id: "+shareID)
+	}
+	return s.repo.Delete(shareID)
+}
+
+// GetSharedFolder returns the folder tree behind a share token. Expired
+// links and links to folders in the trash, directly or below a trashed
+// folder, are reported as not found.
+func (s *service) GetSharedFolder(token string, password string) (*SharedNode, error) {
+	link, err := s.repo.GetByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil || (link.ExpiresAt != nil && !link.ExpiresAt.After(time.Now())) {
+		return nil, apperror.New(apperror.CodeShareNotFound, "Share link not found or expired")
+	}
+	if link.PasswordHash != nil {
+		if err := bcrypt.CompareHashAndPassword([]byte(*link.PasswordHash), []byte(password)); err != nil {
+			return nil, apperror.New(apperror.CodeSharePasswordInvalid, "Share link password is missing or wrong | id: "+link.ID)
+		}
+	}
+
+	nodes, err := s.nodes.GetSubtree(link.FolderID)
+	if err != nil {
+		return nil, err
+	}
+	root := buildTree(link.FolderID, nodes)
+	if root == nil {
+		return nil, apperror.New(apperror.CodeShareNotFound, "Shared folder no longer exists | id: "+link.ID)
+	}
+	return root, nil
+}
+
+// buildTree assembles the nested tree below rootID. Children are sorted by
+// name so that the output is stable.
+func buildTree(rootID string, nodes []url.URLNode) *SharedNode {
+	childrenByParent := map[string][]url.URLNode{}
+	var root *url.URLNode
+	for i, node := range nodes {
+		if node.ID == rootID {
+			root = &nodes[i]
+			continue
+		}
+		if node.ParentID != nil {
+			childrenByParent[*node.ParentID] = append(childrenByParent[*node.ParentID], node)
+		}
+	}
+	if root == nil {
+		return nil
+	}
+
+	var build func(node *url.URLNode) SharedNode
+	build = func(node *url.URLNode) SharedNode {
+		shared := *newSharedNode(node)
+		children := childrenByParent[node.ID]
+		sort.Slice(children, func(i, j int) bool { return children[i].Name < children[j].Name })
+		for _, child := range children {
+			shared.Children = append(shared.Children, build(&child))
+		}
+		return shared
+	}
+	tree := build(root)
+	return &tree
+}
@@ -0,0 +1,373 @@
+package share
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/url"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Create(link *ShareLink) error {
+	args := m.Called(link)
+	return args.Error(0)
+}
+func (m *MockRepository) GetOne(id string) (*ShareLink, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ShareLink), args.Error(1)
+}
+func (m *MockRepository) GetByToken(token string) (*ShareLink, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ShareLink), args.Error(1)
+}
+func (m *MockRepository) GetByFolder(folderID string) ([]ShareLink, error) {
+	args := m.Called(folderID)
+	return args.Get(0).([]ShareLink), args.Error(1)
+}
+func (m *MockRepository) Delete(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+type MockNodeRepository struct {
+	mock.Mock
+}
+
+func (m *MockNodeRepository) GetOne(id string) (*url.URLNode, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*url.URLNode), args.Error(1)
+}
+func (m *MockNodeRepository) GetSubtree(id string) ([]url.URLNode, error) {
+	args := m.Called(id)
+	return args.Get(0).([]url.URLNode), args.Error(1)
+}
+
+type MockAuthorizer struct {
+	mock.Mock
+}
+
+func (m *MockAuthorizer) Authorize(nodeID string, userID string, required url.Role) error {
+	args := m.Called(nodeID, userID, required)
+	return args.Error(0)
+}
+
+func TestService_NewService_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+
+	// Act
+	s := NewService(mockRepo, mockNodes, mockAuthorizer)
+
+	// Assert
+	assert.IsType(t, &service{}, s)
+	assert.Equal(t, mockRepo, s.(*service).repo)
+	assert.Equal(t, mockNodes, s.(*service).nodes)
+	assert.Equal(t, mockAuthorizer, s.(*service).authorizer)
+}
+
+func TestService_validateFolderOwnership_AccessDenied(t *testing.T) {
+	// Arrange
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+	service := &service{repo: &MockRepository{}, nodes: mockNodes, authorizer: mockAuthorizer}
+	denied := apperror.New(apperror.CodeURLAccessDenied, "Access denied")
+
+	mockAuthorizer.On("Authorize", "folder-id", "1", url.RoleOwner).Return(denied)
+
+	// Act
+	err := service.validateFolderOwnership("folder-id", "1")
+
+	// Assert
+	assert.Equal(t, denied, err)
+	mockNodes.AssertNotCalled(t, "GetOne", mock.Anything)
+	mockAuthorizer.AssertExpectations(t)
+}
+func TestService_validateFolderOwnership_NotFolder(t *testing.T) {
+	// Arrange
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+	service := &service{repo: &MockRepository{}, nodes: mockNodes, authorizer: mockAuthorizer}
+
+	mockAuthorizer.On("Authorize", "folder-id", "1", url.RoleOwner).Return(nil)
+	mockNodes.On("GetOne", "folder-id").Return(&url.URLNode{ID: "folder-id", UserID: "1", Type: "url"}, nil)
+
+	// Act
+	err := service.validateFolderOwnership("folder-id", "1")
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeShareFolderOnly, err.(*apperror.AppError).Code)
+	mockNodes.AssertExpectations(t)
+}
+
+func TestService_CreateShareLink_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+	service := &service{repo: mockRepo, nodes: mockNodes, authorizer: mockAuthorizer}
+	expiresAt := time.Now().Add(time.Hour)
+	creates := &CreateRequestBody{ExpiresAt: &expiresAt, Password: "secret-password"}
+
+	var saved *ShareLink
+	mockAuthorizer.On("Authorize", "folder-id", "1", url.RoleOwner).Return(nil)
+	mockNodes.On("GetOne", "folder-id").Return(&url.URLNode{ID: "folder-id", UserID: "1", Type: "folder"}, nil)
+	mockRepo.On("Create", mock.AnythingOfType("*share.ShareLink")).Run(func(args mock.Arguments) {
+		saved = args.Get(0).(*ShareLink)
+	}).Return(nil)
+
+	// Act
+	response, err := service.CreateShareLink("folder-id", creates, "1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "folder-id", response.FolderID)
+	assert.Len(t, response.Token, 43)
+	assert.True(t, response.HasPassword)
+	assert.Equal(t, expiresAt.UTC().Format(time.RFC3339), *response.ExpiresAt)
+	require.NotNil(t, saved.PasswordHash)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(*saved.PasswordHash), []byte("secret-password")))
+	assert.Equal(t, "1", saved.UserID)
+	mockRepo.AssertExpectations(t)
+	mockNodes.AssertExpectations(t)
+}
+func TestService_CreateShareLink_WithoutPassword(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+	service := &service{repo: mockRepo, nodes: mockNodes, authorizer: mockAuthorizer}
+
+	mockAuthorizer.On("Authorize", "folder-id", "1", url.RoleOwner).Return(nil)
+	mockNodes.On("GetOne", "folder-id").Return(&url.URLNode{ID: "folder-id", UserID: "1", Type: "folder"}, nil)
+	mockRepo.On("Create", mock.MatchedBy(func(link *ShareLink) bool {
+		return link.PasswordHash == nil && link.ExpiresAt == nil
+	})).Return(nil)
+
+	// Act
+	response, err := service.CreateShareLink("folder-id", &CreateRequestBody{}, "1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.False(t, response.HasPassword)
+	assert.Nil(t, response.ExpiresAt)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_CreateShareLink_ExpiryInPast(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+	service := &service{repo: mockRepo, nodes: mockNodes, authorizer: mockAuthorizer}
+	expiresAt := time.Now().Add(-time.Hour)
+
+	mockAuthorizer.On("Authorize", "folder-id", "1", url.RoleOwner).Return(nil)
+	mockNodes.On("GetOne", "folder-id").Return(&url.URLNode{ID: "folder-id", UserID: "1", Type: "folder"}, nil)
+
+	// Act
+	response, err := service.CreateShareLink("folder-id", &CreateRequestBody{ExpiresAt: &expiresAt}, "1")
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeShareExpiryInvalid, err.(*apperror.AppError).Code)
+	assert.Nil(t, response)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestService_GetShareLinks_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+	service := &service{repo: mockRepo, nodes: mockNodes, authorizer: mockAuthorizer}
+	links := []ShareLink{
+		{ID: "share-1", Token: "token-1", FolderID: "folder-id", CreatedAt: time.Unix(0, 0)},
+		{ID: "share-2", Token: "token-2", FolderID: "folder-id", PasswordHash: test.StringPtr("hash"), CreatedAt: time.Unix(0, 0)},
+	}
+
+	mockAuthorizer.On("Authorize", "folder-id", "1", url.RoleOwner).Return(nil)
+	mockNodes.On("GetOne", "folder-id").Return(&url.URLNode{ID: "folder-id", UserID: "1", Type: "folder"}, nil)
+	mockRepo.On("GetByFolder", "folder-id").Return(links, nil)
+
+	// Act
+	response, err := service.GetShareLinks("folder-id", "1")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, response, 2)
+	assert.Equal(t, "token-1", response[0].Token)
+	assert.False(t, response[0].HasPassword)
+	assert.True(t, response[1].HasPassword)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_RevokeShareLink_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+	service := &service{repo: mockRepo, nodes: mockNodes, authorizer: mockAuthorizer}
+
+	mockAuthorizer.On("Authorize", "folder-id", "1", url.RoleOwner).Return(nil)
+	mockNodes.On("GetOne", "folder-id").Return(&url.URLNode{ID: "folder-id", UserID: "1", Type: "folder"}, nil)
+	mockRepo.On("GetOne", "share-id").Return(&ShareLink{ID: "share-id", FolderID: "folder-id"}, nil)
+	mockRepo.On("Delete", "share-id").Return(nil)
+
+	// Act
+	err := service.RevokeShareLink("folder-id", "share-id", "1")
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_RevokeShareLink_OtherFolder(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+	service := &service{repo: mockRepo, nodes: mockNodes, authorizer: mockAuthorizer}
+
+	mockAuthorizer.On("Authorize", "folder-id", "1", url.RoleOwner).Return(nil)
+	mockNodes.On("GetOne", "folder-id").Return(&url.URLNode{ID: "folder-id", UserID: "1", Type: "folder"}, nil)
+	mockRepo.On("GetOne", "share-id").Return(&ShareLink{ID: "share-id", FolderID: "other-folder-id"}, nil)
+
+	// Act
+	err := service.RevokeShareLink("folder-id", "share-id", "1")
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeShareNotFound, err.(*apperror.AppError).Code)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestService_GetSharedFolder_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockNodes := &MockNodeRepository{}
+	service := &service{repo: mockRepo, nodes: mockNodes}
+	nodes := []url.URLNode{
+		{ID: "child-b", ParentID: test.StringPtr("folder-id"), Name: "b", Type: "url", URL: test.StringPtr("https://example.com")},
+		{ID: "folder-id", ParentID: test.StringPtr("outside-id"), Name: "shared", Type: "folder"},
+		{ID: "child-a", ParentID: test.StringPtr("folder-id"), Name: "a", Type: "folder"},
+		{ID: "grandchild", ParentID: test.StringPtr("child-a"), Name: "c", Type: "url", URL: test.StringPtr("https://example.org")},
+	}
+
+	mockRepo.On("GetByToken", "token").Return(&ShareLink{ID: "share-id", FolderID: "folder-id"}, nil)
+	mockNodes.On("GetSubtree", "folder-id").Return(nodes, nil)
+
+	// Act
+	tree, err := service.GetSharedFolder("token", "")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "folder-id", tree.ID)
+	require.Len(t, tree.Children, 2)
+	assert.Equal(t, "child-a", tree.Children[0].ID)
+	assert.Equal(t, "child-b", tree.Children[1].ID)
+	require.Len(t, tree.Children[0].Children, 1)
+	assert.Equal(t, "grandchild", tree.Children[0].Children[0].ID)
+	assert.Empty(t, tree.Children[1].Children)
+	mockRepo.AssertExpectations(t)
+	mockNodes.AssertExpectations(t)
+}
+func TestService_GetSharedFolder_Password(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		password     string
+		expectedCode string
+	}{
+		{name: "correct password", password: "secret-password"},
+		{name: "wrong password", password: "wrong-password", expectedCode: apperror.CodeSharePasswordInvalid},
+		{name: "missing password", password: "", expectedCode: apperror.CodeSharePasswordInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := &MockRepository{}
+			mockNodes := &MockNodeRepository{}
+			service := &service{repo: mockRepo, nodes: mockNodes}
+			link := &ShareLink{ID: "share-id", FolderID: "folder-id", PasswordHash: test.StringPtr(string(hash))}
+
+			mockRepo.On("GetByToken", "token").Return(link, nil)
+			mockNodes.On("GetSubtree", "folder-id").Return([]url.URLNode{{ID: "folder-id", Type: "folder"}}, nil).Maybe()
+
+			// Act
+			tree, err := service.GetSharedFolder("token", tt.password)
+
+			// Assert
+			if tt.expectedCode == "" {
+				require.NoError(t, err)
+				assert.Equal(t, "folder-id", tree.ID)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.expectedCode, err.(*apperror.AppError).Code)
+			mockNodes.AssertNotCalled(t, "GetSubtree", mock.Anything)
+		})
+	}
+}
+func TestService_GetSharedFolder_NotFound(t *testing.T) {
+	expiredAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		link    *ShareLink
+		subtree []url.URLNode
+	}{
+		{name: "unknown token", link: nil},
+		{name: "expired", link: &ShareLink{ID: "share-id", FolderID: "folder-id", ExpiresAt: &expiredAt}},
+		{name: "folder deleted", link: &ShareLink{ID: "share-id", FolderID: "folder-id"}, subtree: []url.URLNode{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := &MockRepository{}
+			mockNodes := &MockNodeRepository{}
+			service := &service{repo: mockRepo, nodes: mockNodes}
+
+			if tt.link == nil {
+				mockRepo.On("GetByToken", "token").Return(nil, nil)
+			} else {
+				mockRepo.On("GetByToken", "token").Return(tt.link, nil)
+			}
+			if tt.subtree != nil {
+				mockNodes.On("GetSubtree", "folder-id").Return(tt.subtree, nil)
+			}
+
+			// Act
+			tree, err := service.GetSharedFolder("token", "")
+
+			// Assert
+			require.Error(t, err)
+			assert.Equal(t, apperror.CodeShareNotFound, err.(*apperror.AppError).Code)
+			assert.Nil(t, tree)
+			mockNodes.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package url
+
+import (
+	"encoding/json"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+)
+
+// NodeSnapshot is the state of a node recorded before and after a change in
+// the audit log, and after a change as a revision.
+type NodeSnapshot struct {
+	ParentID    *string `json:"parent_id"`
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	URL         *string `json:"url"`
+	TargetID    *string `json:"target_id"`
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	ImageURL    *string `json:"image_url"`
+	FaviconURL  *string `json:"favicon_url"`
+}
+
+func newNodeSnapshot(node *URLNode) *NodeSnapshot {
+	if node == nil {
+		return nil
+	}
+	return &NodeSnapshot{
+		ParentID:    node.ParentID,
+		Name:        node.Name,
+		Type:        node.Type,
+		URL:         node.URL,
+		TargetID:    node.TargetID,
+		Title:       node.Title,
+		Description: node.Description,
+		ImageURL:    node.ImageURL,
+		FaviconURL:  node.FaviconURL,
+	}
+}
+
+func snapshotOf(node *URLNode) (*string, error) {
+	if node == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(newNodeSnapshot(node))
+	if err != nil {
+		return nil, err
+	}
+	snapshot := string(data)
+	return &snapshot, nil
+}
+
+// NewAuditLog returns the audit log entry for a change from before to after.
+// before is nil for creations, after is nil for deletions.
+func NewAuditLog(action audit.Action, before *URLNode, after *URLNode, actorID string, requestID string) (*audit.AuditLog, error) {
+	node := after
+	if node == nil {
+		node = before
+	}
+	beforeSnapshot, err := snapshotOf(before)
+	if err != nil {
+		return nil, err
+	}
+	afterSnapshot, err := snapshotOf(after)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &audit.AuditLog{
+		NodeID:  node.ID,
+		OwnerID: node.UserID,
+		ActorID: actorID,
+		Action:  action,
+		Before:  beforeSnapshot,
+		After:   afterSnapshot,
+	}
+	if requestID != "" {
+		entry.RequestID = &requestID
+	}
+	return entry, nil
+}
+
+// recordAudit appends an audit log entry for a change from before to after.
+// It must be called with the repository of the transaction making the change.
+func recordAudit(repo Repository, action audit.Action, before *URLNode, after *URLNode, actorID string, requestID string) error {
+	entry, err := NewAuditLog(action, before, after, actorID, requestID)
+	if err != nil {
+		return err
+	}
+	return repo.CreateAuditLog(entry)
+}
@@ -0,0 +1,59 @@
+package url
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+const backfillBatchSize = 500
+
+// NormalizedURLBackfill fills in the normalized URL of nodes saved before it
+// was stored, so that lookups and duplicate detection cover them without
+// looking for such nodes on every request. It runs once at startup, nodes
+// whose URL cannot be normalized are left as they are.
+type NormalizedURLBackfill struct {
+	repo   Repository
+	logger *zap.Logger
+}
+
+func NewNormalizedURLBackfill(repo Repository, logger *zap.Logger) *NormalizedURLBackfill {
+	return &NormalizedURLBackfill{repo: repo, logger: logger}
+}
+
+func (b *NormalizedURLBackfill) Run(ctx context.Context) {
+	if err := b.BackfillOnce(ctx); err != nil {
+		b.logger.Error("failed to backfill normalized URLs", zap.Error(err))
+	}
+}
+
+// BackfillOnce walks the nodes without a normalized URL in batches, in id
+// order, so that nodes left as they are are read only once.
+func (b *NormalizedURLBackfill) BackfillOnce(ctx context.Context) error {
+	afterID := "00000000-0000-0000-0000-000000000000"
+	var filled int64
+	for ctx.Err() == nil {
+		nodes, err := b.repo.GetUnnormalized(afterID, backfillBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(nodes) == 0 {
+			break
+		}
+		for _, node := range nodes {
+			normalizedURL := normalizedURLOf(&node)
+			if normalizedURL == nil {
+				continue
+			}
+			if err := b.repo.SetNormalizedURL(node.ID, *normalizedURL); err != nil {
+				return err
+			}
+			filled++
+		}
+		afterID = nodes[len(nodes)-1].ID
+	}
+	if filled > 0 {
+		b.logger.Info("backfilled normalized URLs", zap.Int64("count", filled))
+	}
+	return nil
+}
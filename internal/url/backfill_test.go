@@ -0,0 +1,46 @@
+package url
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNormalizedURLBackfill_BackfillOnce_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	backfill := NewNormalizedURLBackfill(mockRepo, zap.NewNop())
+	nodes := []URLNode{
+		{ID: "00000000-0000-0000-0000-000000000001", Type: "url", URL: test.StringPtr("HTTPS://Example.com/?utm_source=mail")},
+		{ID: "00000000-0000-0000-0000-000000000002", Type: "url", URL: test.StringPtr("not a url")},
+	}
+	mockRepo.On("GetUnnormalized", "00000000-0000-0000-0000-000000000000", backfillBatchSize).Return(nodes, nil)
+	mockRepo.On("GetUnnormalized", "00000000-0000-0000-0000-000000000002", backfillBatchSize).Return([]URLNode{}, nil)
+	mockRepo.On("SetNormalizedURL", "00000000-0000-0000-0000-000000000001", "https://example.com").Return(nil)
+
+	// Act
+	err := backfill.BackfillOnce(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNumberOfCalls(t, "SetNormalizedURL", 1)
+}
+func TestNormalizedURLBackfill_BackfillOnce_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	backfill := NewNormalizedURLBackfill(mockRepo, zap.NewNop())
+	mockRepo.On("GetUnnormalized", "00000000-0000-0000-0000-000000000000", backfillBatchSize).Return([]URLNode{}, errors.New("database error"))
+
+	// Act
+	err := backfill.BackfillOnce(context.Background())
+
+	// Assert
+	assert.EqualError(t, err, "database error")
+}
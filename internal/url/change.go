@@ -0,0 +1,84 @@
+package url
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+)
+
+// change is a change an operation made to one node. before is nil for
+// creations, after is nil for deletions.
+type change struct {
+	action audit.Action
+	before *URLNode
+	after  *URLNode
+}
+
+// OperationChange is a change as stored in an operation, with the states the
+// node is moved between by undo and redo.
+type OperationChange struct {
+	NodeID string        `json:"node_id"`
+	Action audit.Action  `json:"action"`
+	Before *NodeSnapshot `json:"before"`
+	After  *NodeSnapshot `json:"after"`
+}
+
+// recordChange records a change from before to after in the audit log, the
+// change feed, the webhook outbox and the domain event outbox and, unless the
+// node was deleted, as a new revision of the node. It must be called with the
+// repository of the transaction making the change.
+func recordChange(repo Repository, action audit.Action, before *URLNode, after *URLNode, actorID string, requestID string) error {
+	if err := recordAudit(repo, action, before, after, actorID, requestID); err != nil {
+		return err
+	}
+	node := after
+	if node == nil {
+		node = before
+	}
+	if err := recordFeedChange(repo, node, action); err != nil {
+		return err
+	}
+	if err := recordWebhookDeliveries(repo, action, before, after, actorID); err != nil {
+		return err
+	}
+	if err := recordDomainEvent(repo, action, before, after, actorID); err != nil {
+		return err
+	}
+	if after == nil {
+		return nil
+	}
+	return recordRevision(repo, after, actorID)
+}
+
+// recordOperation records the changes of one user operation and stores them
+// as an operation the user can undo. Starting a new operation discards the
+// operations the user could have redone. It must be called with the
+// repository of the transaction making the changes.
+func (s *service) recordOperation(repo Repository, changes []change, actorID string, requestID string) error {
+	operationChanges := make([]OperationChange, len(changes))
+	for i, c := range changes {
+		if err := recordChange(repo, c.action, c.before, c.after, actorID, requestID); err != nil {
+			return err
+		}
+		node := c.after
+		if node == nil {
+			node = c.before
+		}
+		operationChanges[i] = OperationChange{
+			NodeID: node.ID,
+			Action: c.action,
+			Before: newNodeSnapshot(c.before),
+			After:  newNodeSnapshot(c.after),
+		}
+	}
+
+	data, err := json.Marshal(operationChanges)
+	if err != nil {
+		return err
+	}
+	if err := repo.DeleteStaleOperations(actorID, time.Now().UTC().Add(-s.undoWindow)); err != nil {
+		return err
+	}
+	return repo.CreateOperation(&Operation{UserID: actorID, Changes: string(data)})
+}
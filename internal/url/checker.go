@@ -0,0 +1,172 @@
+package url
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"sync"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/outbound"
+
+	"go.uber.org/zap"
+)
+
+const crawlerUserAgent = "VeraDrive/1.0"
+
+// LinkChecker periodically checks saved URLs and records whether they are
+// still reachable. Requests to the same host are made one at a time with a
+// delay in between, while different hosts are checked concurrently. Links to
+// internal addresses are reported as errors without being requested, since
+// their status is shown to the user.
+type LinkChecker struct {
+	repo         Repository
+	client       *http.Client
+	logger       *zap.Logger
+	interval     time.Duration
+	recheckAfter time.Duration
+	concurrency  int
+	hostDelay    time.Duration
+	batchSize    int
+}
+
+func NewLinkChecker(repo Repository, config *config.Config, logger *zap.Logger) *LinkChecker {
+	return &LinkChecker{
+		repo:         repo,
+		client:       outbound.NewClient(config.LinkCheckTimeout, config.OutboundAllowedNetworks),
+		logger:       logger,
+		interval:     config.LinkCheckInterval,
+		recheckAfter: config.LinkCheckRecheckAfter,
+		concurrency:  max(config.LinkCheckConcurrency, 1),
+		hostDelay:    config.LinkCheckHostDelay,
+		batchSize:    config.LinkCheckBatchSize,
+	}
+}
+
+func (c *LinkChecker) Run(ctx context.Context) {
+	if c.interval <= 0 {
+		c.logger.Info("link checker disabled")
+		return
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		if err := c.CheckOnce(ctx); err != nil {
+			c.logger.Error("failed to check links", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckOnce checks one batch of URLs that have never been checked or were
+// last checked longer ago than the recheck period.
+func (c *LinkChecker) CheckOnce(ctx context.Context) error {
+	nodes, err := c.repo.GetDueForCheck(time.Now().UTC().Add(-c.recheckAfter), c.batchSize)
+	if err != nil {
+		return err
+	}
+
+	nodesByHost := map[string][]URLNode{}
+	for _, node := range nodes {
+		host := ""
+		if u, err := neturl.Parse(*node.URL); err == nil {
+			host = u.Host
+		}
+		nodesByHost[host] = append(nodesByHost[host], node)
+	}
+
+	semaphore := make(chan struct{}, c.concurrency)
+	var wg sync.WaitGroup
+	for _, hostNodes := range nodesByHost {
+		wg.Add(1)
+		go func(hostNodes []URLNode) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			for i, node := range hostNodes {
+				if i > 0 && !sleep(ctx, c.hostDelay) {
+					return
+				}
+				status := c.Check(ctx, *node.URL)
+				if err := c.repo.UpdateLinkStatus(node.ID, status); err != nil {
+					c.logger.Error("failed to save link status", zap.String("id", node.ID), zap.Error(err))
+				}
+			}
+		}(hostNodes)
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// Check requests rawURL with HEAD and falls back to GET for servers that do
+// not answer HEAD properly. Redirects are followed and the final address is
+// reported when it differs from rawURL.
+func (c *LinkChecker) Check(ctx context.Context, rawURL string) *LinkStatus {
+	status := &LinkStatus{CheckedAt: time.Now().UTC()}
+
+	u, err := neturl.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		message := "unsupported url | url: " + rawURL
+		status.Error = &message
+		return status
+	}
+
+	resp, err := c.request(ctx, http.MethodHead, rawURL)
+	if err != nil || resp.StatusCode >= 400 {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		resp, err = c.request(ctx, http.MethodGet, rawURL)
+	}
+	if errors.Is(err, outbound.ErrAddressNotAllowed) {
+		// The error names the resolved address, which is not for the user.
+		message := outbound.ErrAddressNotAllowed.Error()
+		status.Error = &message
+		return status
+	}
+	if err != nil {
+		message := err.Error()
+		status.Error = &message
+		return status
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	statusCode := resp.StatusCode
+	status.StatusCode = &statusCode
+	if finalURL := resp.Request.URL.String(); finalURL != rawURL {
+		status.RedirectURL = &finalURL
+	}
+	return status
+}
+
+func (c *LinkChecker) request(ctx context.Context, method string, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", crawlerUserAgent)
+	return c.client.Do(req)
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,213 @@
+package url
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func setupLinkServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+	return httptest.NewServer(mux)
+}
+
+func newTestLinkChecker(repo Repository) *LinkChecker {
+	return NewLinkChecker(repo, &config.Config{
+		LinkCheckInterval:     time.Minute,
+		LinkCheckRecheckAfter: time.Hour,
+		LinkCheckTimeout:      100 * time.Millisecond,
+		LinkCheckConcurrency:  2,
+		LinkCheckHostDelay:    time.Millisecond,
+		LinkCheckBatchSize:    10,
+		// The test server listens on the loopback address.
+		OutboundAllowedNetworks: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")},
+	}, zap.NewNop())
+}
+
+func TestLinkChecker_Check(t *testing.T) {
+	server := setupLinkServer()
+	defer server.Close()
+
+	tests := []struct {
+		name               string
+		rawURL             string
+		expectedStatusCode *int
+		expectedRedirect   *string
+		expectError        bool
+	}{
+		{
+			name:               "reachable",
+			rawURL:             server.URL + "/ok",
+			expectedStatusCode: intPtr(http.StatusOK),
+		},
+		{
+			name:               "not found",
+			rawURL:             server.URL + "/missing",
+			expectedStatusCode: intPtr(http.StatusNotFound),
+		},
+		{
+			name:               "redirect",
+			rawURL:             server.URL + "/moved",
+			expectedStatusCode: intPtr(http.StatusOK),
+			expectedRedirect:   test.StringPtr(server.URL + "/ok"),
+		},
+		{
+			name:               "head not allowed",
+			rawURL:             server.URL + "/get-only",
+			expectedStatusCode: intPtr(http.StatusOK),
+		},
+		{
+			name:        "timeout",
+			rawURL:      server.URL + "/slow",
+			expectError: true,
+		},
+		{
+			name:        "unsupported scheme",
+			rawURL:      "ftp://example.com/file",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			checker := newTestLinkChecker(&MockRepository{})
+
+			// Act
+			status := checker.Check(context.Background(), tt.rawURL)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatusCode, status.StatusCode)
+			assert.Equal(t, tt.expectedRedirect, status.RedirectURL)
+			assert.Equal(t, tt.expectError, status.Error != nil)
+			assert.WithinDuration(t, time.Now().UTC(), status.CheckedAt, time.Second)
+		})
+	}
+}
+func TestLinkChecker_Check_InternalAddress(t *testing.T) {
+	// Arrange
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+	checker := NewLinkChecker(&MockRepository{}, &config.Config{LinkCheckTimeout: time.Second}, zap.NewNop())
+
+	// Act
+	status := checker.Check(context.Background(), server.URL)
+
+	// Assert
+	assert.Nil(t, status.StatusCode)
+	require.NotNil(t, status.Error)
+	assert.Equal(t, "address not allowed", *status.Error)
+	assert.False(t, requested)
+}
+
+func TestLinkChecker_CheckOnce_Success(t *testing.T) {
+	// Arrange
+	server := setupLinkServer()
+	defer server.Close()
+
+	mockRepo := &MockRepository{}
+	checker := newTestLinkChecker(mockRepo)
+	nodes := []URLNode{
+		{ID: "ok-id", Type: "url", URL: test.StringPtr(server.URL + "/ok")},
+		{ID: "missing-id", Type: "url", URL: test.StringPtr(server.URL + "/missing")},
+	}
+
+	var mu sync.Mutex
+	saved := map[string]*LinkStatus{}
+	mockRepo.On("GetDueForCheck", mock.AnythingOfType("time.Time"), 10).Return(nodes, nil)
+	mockRepo.On("UpdateLinkStatus", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		mu.Lock()
+		defer mu.Unlock()
+		saved[args.String(0)] = args.Get(1).(*LinkStatus)
+	}).Return(nil)
+
+	// Act
+	err := checker.CheckOnce(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, saved, 2)
+	assert.Equal(t, http.StatusOK, *saved["ok-id"].StatusCode)
+	assert.Equal(t, http.StatusNotFound, *saved["missing-id"].StatusCode)
+	mockRepo.AssertExpectations(t)
+}
+func TestLinkChecker_CheckOnce_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	checker := newTestLinkChecker(mockRepo)
+
+	mockRepo.On("GetDueForCheck", mock.AnythingOfType("time.Time"), 10).Return([]URLNode{}, assert.AnError)
+
+	// Act
+	err := checker.CheckOnce(context.Background())
+
+	// Assert
+	assert.Equal(t, assert.AnError, err)
+	mockRepo.AssertExpectations(t)
+}
+func TestLinkChecker_CheckOnce_Cancelled(t *testing.T) {
+	// Arrange
+	server := setupLinkServer()
+	defer server.Close()
+
+	mockRepo := &MockRepository{}
+	checker := newTestLinkChecker(mockRepo)
+	checker.hostDelay = time.Hour
+	nodes := []URLNode{
+		{ID: "first-id", Type: "url", URL: test.StringPtr(server.URL + "/ok")},
+		{ID: "second-id", Type: "url", URL: test.StringPtr(server.URL + "/ok")},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mockRepo.On("GetDueForCheck", mock.AnythingOfType("time.Time"), 10).Return(nodes, nil)
+	mockRepo.On("UpdateLinkStatus", "first-id", mock.Anything).Run(func(args mock.Arguments) {
+		cancel()
+	}).Return(nil)
+
+	// Act
+	err := checker.CheckOnce(ctx)
+
+	// Assert
+	assert.ErrorIs(t, err, context.Canceled)
+	mockRepo.AssertNotCalled(t, "UpdateLinkStatus", "second-id", mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,96 @@
+package url
+
+import (
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/outbox"
+)
+
+// NodeEvent holds the fields shared by all domain events about a node.
+type NodeEvent struct {
+	NodeID  string `json:"node_id"`
+	OwnerID string `json:"owner_id"`
+	ActorID string `json:"actor_id"`
+}
+
+type NodeCreated struct {
+	NodeEvent
+	Node NodeSnapshot `json:"node"`
+}
+
+func (NodeCreated) EventType() string { return "node.created" }
+
+// NodeUpdated is emitted when a node changed without moving, e.g. it was
+// renamed or its URL was replaced.
+type NodeUpdated struct {
+	NodeEvent
+	Before NodeSnapshot `json:"before"`
+	After  NodeSnapshot `json:"after"`
+}
+
+func (NodeUpdated) EventType() string { return "node.updated" }
+
+// NodeMoved is emitted when a node got a new parent. Other fields may have
+// changed in the same operation.
+type NodeMoved struct {
+	NodeEvent
+	FromParentID *string      `json:"from_parent_id"`
+	ToParentID   *string      `json:"to_parent_id"`
+	Before       NodeSnapshot `json:"before"`
+	After        NodeSnapshot `json:"after"`
+}
+
+func (NodeMoved) EventType() string { return "node.moved" }
+
+type NodeDeleted struct {
+	NodeEvent
+	Node NodeSnapshot `json:"node"`
+}
+
+func (NodeDeleted) EventType() string { return "node.deleted" }
+
+// NodeRestored is emitted when undo or redo brings back a deleted node.
+type NodeRestored struct {
+	NodeEvent
+	Node NodeSnapshot `json:"node"`
+}
+
+func (NodeRestored) EventType() string { return "node.restored" }
+
+// newDomainEvent returns the domain event of a change from before to after.
+func newDomainEvent(action audit.Action, before *URLNode, after *URLNode, actorID string) outbox.Event {
+	node := after
+	if node == nil {
+		node = before
+	}
+	base := NodeEvent{NodeID: node.ID, OwnerID: node.UserID, ActorID: actorID}
+
+	switch action {
+	case audit.ActionCreate:
+		return NodeCreated{NodeEvent: base, Node: *newNodeSnapshot(after)}
+	case audit.ActionMove:
+		return NodeMoved{
+			NodeEvent:    base,
+			FromParentID: before.ParentID,
+			ToParentID:   after.ParentID,
+			Before:       *newNodeSnapshot(before),
+			After:        *newNodeSnapshot(after),
+		}
+	case audit.ActionDelete:
+		return NodeDeleted{NodeEvent: base, Node: *newNodeSnapshot(before)}
+	case audit.ActionRestore:
+		return NodeRestored{NodeEvent: base, Node: *newNodeSnapshot(after)}
+	default:
+		return NodeUpdated{NodeEvent: base, Before: *newNodeSnapshot(before), After: *newNodeSnapshot(after)}
+	}
+}
+
+// recordDomainEvent writes the domain event of a change to the outbox. It
+// must be called with the repository of the transaction making the change,
+// so that only committed changes are published.
+func recordDomainEvent(repo Repository, action audit.Action, before *URLNode, after *URLNode, actorID string) error {
+	event, err := outbox.NewOutboxEvent(newDomainEvent(action, before, after, actorID))
+	if err != nil {
+		return err
+	}
+	return repo.CreateOutboxEvent(event)
+}
@@ -0,0 +1,100 @@
+package url
+
+import (
+	"testing"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/outbox"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDomainEvent(t *testing.T) {
+	before := &URLNode{ID: "node-id", UserID: "1", ParentID: test.StringPtr("old-parent-id"), Name: "old", Type: "folder"}
+	after := &URLNode{ID: "node-id", UserID: "1", ParentID: test.StringPtr("new-parent-id"), Name: "new", Type: "folder"}
+	base := NodeEvent{NodeID: "node-id", OwnerID: "1", ActorID: "2"}
+
+	tests := []struct {
+		name     string
+		action   audit.Action
+		before   *URLNode
+		after    *URLNode
+		expected outbox.Event
+		typ      string
+	}{
+		{
+			name:     "created",
+			action:   audit.ActionCreate,
+			after:    after,
+			expected: NodeCreated{NodeEvent: base, Node: *newNodeSnapshot(after)},
+			typ:      "node.created",
+		},
+		{
+			name:     "updated",
+			action:   audit.ActionUpdate,
+			before:   before,
+			after:    after,
+			expected: NodeUpdated{NodeEvent: base, Before: *newNodeSnapshot(before), After: *newNodeSnapshot(after)},
+			typ:      "node.updated",
+		},
+		{
+			name:   "moved",
+			action: audit.ActionMove,
+			before: before,
+			after:  after,
+			expected: NodeMoved{
+				NodeEvent:    base,
+				FromParentID: before.ParentID,
+				ToParentID:   after.ParentID,
+				Before:       *newNodeSnapshot(before),
+				After:        *newNodeSnapshot(after),
+			},
+			typ: "node.moved",
+		},
+		{
+			name:     "deleted",
+			action:   audit.ActionDelete,
+			before:   before,
+			expected: NodeDeleted{NodeEvent: base, Node: *newNodeSnapshot(before)},
+			typ:      "node.deleted",
+		},
+		{
+			name:     "restored",
+			action:   audit.ActionRestore,
+			after:    after,
+			expected: NodeRestored{NodeEvent: base, Node: *newNodeSnapshot(after)},
+			typ:      "node.restored",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			event := newDomainEvent(tt.action, tt.before, tt.after, "2")
+
+			// Assert
+			assert.Equal(t, tt.expected, event)
+			assert.Equal(t, tt.typ, event.EventType())
+		})
+	}
+}
+
+func TestRecordDomainEvent_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	node := &URLNode{ID: "node-id", UserID: "1", ParentID: test.StringPtr("parent-id"), Name: "bookmark", Type: "url", URL: test.StringPtr("https://example.com")}
+
+	mockRepo.On("CreateOutboxEvent", mock.MatchedBy(func(event *outbox.OutboxEvent) bool {
+		return event.Type == "node.created"
+	})).Return(nil)
+
+	// Act
+	err := recordDomainEvent(mockRepo, audit.ActionCreate, nil, node, "1")
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
@@ -1,6 +1,11 @@
 package url
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+)
 
 type RequestURI struct {
 	ID string `uri:"id" binding:"required,uuid"`
@@ -8,18 +13,65 @@ type RequestURI struct {
 
 type RequestBody struct {
 	ParentID string  `json:"parent_id" binding:"required,uuid"`
-	Name     string  `json:"name" binding:"required,max=20"`
-	Type     string  `json:"type" binding:"required,oneof=folder url"`
+	Name     string  `json:"name" binding:"max=20"`
+	Type     string  `json:"type" binding:"required,oneof=folder url mount"`
 	URL      *string `json:"url"`
 }
 
+type MountRequestBody struct {
+	ParentID string `json:"parent_id" binding:"required,uuid"`
+	Name     string `json:"name" binding:"max=20"`
+}
+
+type MergeDuplicatesRequestBody struct {
+	TargetID  string   `json:"target_id" binding:"required,uuid"`
+	SourceIDs []string `json:"source_ids" binding:"required,min=1,dive,uuid"`
+}
+
+type LookupQuery struct {
+	URL string `form:"url" binding:"required"`
+}
+
+type LookupRequestBody struct {
+	URLs []string `json:"urls" binding:"required,min=1,max=100"`
+}
+
+type CollaboratorURI struct {
+	ID     string `uri:"id" binding:"required,uuid"`
+	UserID string `uri:"user_id" binding:"required,max=255"`
+}
+
+type RevisionURI struct {
+	ID       string `uri:"id" binding:"required,uuid"`
+	Revision int    `uri:"revision" binding:"required,min=1"`
+}
+
+type ChangesQuery struct {
+	Since string `form:"since"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+type AddCollaboratorRequestBody struct {
+	UserID string `json:"user_id" binding:"required,max=255"`
+	Role   Role   `json:"role" binding:"required,oneof=viewer editor owner"`
+}
+
+type UpdateCollaboratorRequestBody struct {
+	Role Role `json:"role" binding:"required,oneof=viewer editor owner"`
+}
+
 type BaseURL struct {
-	ID        string  `json:"id"`
-	Name      string  `json:"name"`
-	Type      string  `json:"type"`
-	URL       *string `json:"url"`
-	CreatedAt string  `json:"created_at"`
-	UpdatedAt string  `json:"updated_at"`
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	URL         *string `json:"url"`
+	TargetID    *string `json:"target_id"`
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	ImageURL    *string `json:"image_url"`
+	FaviconURL  *string `json:"favicon_url"`
+	CreatedAt   string  `json:"created_at"`
+	UpdatedAt   string  `json:"updated_at"`
 }
 
 type URLResponse struct {
@@ -28,14 +80,109 @@ type URLResponse struct {
 	Children []BaseURL `json:"children"`
 }
 
+type DuplicateWarning struct {
+	Message    string    `json:"message"`
+	Duplicates []BaseURL `json:"duplicates"`
+}
+
+type CreateURLResponse struct {
+	BaseURL
+	Warning *DuplicateWarning `json:"warning,omitempty"`
+}
+
+type URLWithParent struct {
+	BaseURL
+	Parent []BaseURL `json:"parent"`
+}
+
+type LookupResult struct {
+	URL     string          `json:"url"`
+	Matches []URLWithParent `json:"matches"`
+}
+
+type BrokenURL struct {
+	URLWithParent
+	LastStatusCode *int    `json:"last_status_code"`
+	LastCheckError *string `json:"last_check_error"`
+	RedirectURL    *string `json:"redirect_url"`
+	LastCheckedAt  string  `json:"last_checked_at"`
+}
+
+type Collaborator struct {
+	UserID    string `json:"user_id"`
+	Role      Role   `json:"role"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type SharedFolder struct {
+	BaseURL
+	OwnerID  string `json:"owner_id"`
+	Role     Role   `json:"role"`
+	SharedAt string `json:"shared_at"`
+}
+
+type Revision struct {
+	Revision  int             `json:"revision"`
+	ActorID   string          `json:"actor_id"`
+	Node      json.RawMessage `json:"node"`
+	CreatedAt string          `json:"created_at"`
+}
+
+type ChangeSummary struct {
+	NodeID string       `json:"node_id"`
+	Action audit.Action `json:"action"`
+}
+
+type OperationResponse struct {
+	ID        string          `json:"id"`
+	Changes   []ChangeSummary `json:"changes"`
+	CreatedAt string          `json:"created_at"`
+	UndoneAt  *string         `json:"undone_at"`
+}
+
+type SyncNode struct {
+	BaseURL
+	ParentID *string `json:"parent_id"`
+}
+
+type SyncChange struct {
+	NodeID string       `json:"node_id"`
+	Action audit.Action `json:"action"`
+	Node   *SyncNode    `json:"node"`
+}
+
+type ChangesResponse struct {
+	Changes   []SyncChange `json:"changes"`
+	NextToken string       `json:"next_token"`
+	HasMore   bool         `json:"has_more"`
+}
+
+// ChangeEvent is the data of a change event on the event stream. The id of
+// the event is the sync token to continue the change feed from.
+type ChangeEvent struct {
+	NodeID string       `json:"node_id"`
+	Action audit.Action `json:"action"`
+}
+
+type DuplicateGroup struct {
+	NormalizedURL string          `json:"normalized_url"`
+	URLs          []URLWithParent `json:"urls"`
+}
+
 func newBaseURL(node *URLNode) *BaseURL {
 	return &BaseURL{
-		ID:        node.ID,
-		Name:      node.Name,
-		Type:      node.Type,
-		URL:       node.URL,
-		CreatedAt: node.CreatedAt.UTC().Format(time.RFC3339),
-		UpdatedAt: node.UpdatedAt.UTC().Format(time.RFC3339),
+		ID:          node.ID,
+		Name:        node.Name,
+		Type:        node.Type,
+		URL:         node.URL,
+		TargetID:    node.TargetID,
+		Title:       node.Title,
+		Description: node.Description,
+		ImageURL:    node.ImageURL,
+		FaviconURL:  node.FaviconURL,
+		CreatedAt:   node.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:   node.UpdatedAt.UTC().Format(time.RFC3339),
 	}
 }
 func newURLResponse(node *URLNode, parents []URLNode, children []URLNode) *URLResponse {
@@ -54,3 +201,93 @@ func newURLResponse(node *URLNode, parents []URLNode, children []URLNode) *URLRe
 		Children: newChildren,
 	}
 }
+
+func newCreateURLResponse(node *URLNode, duplicates []URLNode) *CreateURLResponse {
+	response := &CreateURLResponse{BaseURL: *newBaseURL(node)}
+	if len(duplicates) == 0 {
+		return response
+	}
+
+	newDuplicates := make([]BaseURL, len(duplicates))
+	for i, duplicate := range duplicates {
+		newDuplicates[i] = *newBaseURL(&duplicate)
+	}
+	response.Warning = &DuplicateWarning{
+		Message:    "URL already exists elsewhere in your tree",
+		Duplicates: newDuplicates,
+	}
+	return response
+}
+
+func newURLWithParent(node *URLNode, parents []URLNode) *URLWithParent {
+	newParents := make([]BaseURL, len(parents))
+	for i, parent := range parents {
+		newParents[i] = *newBaseURL(&parent)
+	}
+
+	return &URLWithParent{
+		BaseURL: *newBaseURL(node),
+		Parent:  newParents,
+	}
+}
+
+func newBrokenURL(node *URLNode, parents []URLNode) *BrokenURL {
+	return &BrokenURL{
+		URLWithParent:  *newURLWithParent(node, parents),
+		LastStatusCode: node.LastStatusCode,
+		LastCheckError: node.LastCheckError,
+		RedirectURL:    node.RedirectURL,
+		LastCheckedAt:  node.LastCheckedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func newCollaborator(permission *FolderPermission) *Collaborator {
+	return &Collaborator{
+		UserID:    permission.UserID,
+		Role:      permission.Role,
+		CreatedAt: permission.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt: permission.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func newSharedFolder(folder *URLNode, permission *FolderPermission) *SharedFolder {
+	return &SharedFolder{
+		BaseURL:  *newBaseURL(folder),
+		OwnerID:  folder.UserID,
+		Role:     permission.Role,
+		SharedAt: permission.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func newRevision(revision *NodeRevision) *Revision {
+	return &Revision{
+		Revision:  revision.Revision,
+		ActorID:   revision.ActorID,
+		Node:      json.RawMessage(revision.Snapshot),
+		CreatedAt: revision.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func newOperationResponse(operation *Operation, changes []OperationChange) *OperationResponse {
+	summaries := make([]ChangeSummary, len(changes))
+	for i, c := range changes {
+		summaries[i] = ChangeSummary{NodeID: c.NodeID, Action: c.Action}
+	}
+	response := &OperationResponse{
+		ID:        operation.ID,
+		Changes:   summaries,
+		CreatedAt: operation.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if operation.UndoneAt != nil {
+		undoneAt := operation.UndoneAt.UTC().Format(time.RFC3339)
+		response.UndoneAt = &undoneAt
+	}
+	return response
+}
+
+func newSyncNode(node *URLNode) *SyncNode {
+	return &SyncNode{
+		BaseURL:  *newBaseURL(node),
+		ParentID: node.ParentID,
+	}
+}
@@ -0,0 +1,125 @@
+package url
+
+import (
+	"sync"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// eventBufferSize is the number of events buffered per subscriber. Events
+// for a subscriber that falls further behind are dropped, the client catches
+// up through the change feed.
+const eventBufferSize = 64
+
+// Event is a committed change of a node, published to the live sessions of
+// the owner of its tree. Seq is the position of the change in the owner's
+// change feed.
+type Event struct {
+	UserID string       `json:"user_id"`
+	Seq    int64        `json:"seq"`
+	NodeID string       `json:"node_id"`
+	Action audit.Action `json:"action"`
+}
+
+// Broker fans out events to the subscribers of a tree. Publish is only called
+// after the transaction making the changes has committed.
+type Broker interface {
+	Publish(events []Event)
+	// Subscribe returns a channel receiving the events of the tree of userID
+	// and a function that cancels the subscription and closes the channel.
+	Subscribe(userID string) (<-chan Event, func())
+}
+
+// NewBroker returns the broker selected by the configuration. The memory
+// broker only reaches subscribers connected to the same replica, the
+// postgres broker relays events through LISTEN/NOTIFY to all replicas.
+func NewBroker(config *config.Config, db *gorm.DB, logger *zap.Logger) Broker {
+	if config.EventBroker == "postgres" {
+		return NewPostgresBroker(db, config.DatabaseURL, logger)
+	}
+	return NewMemoryBroker()
+}
+
+// MemoryBroker delivers events to subscribers in this process.
+type MemoryBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan Event]struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: map[string]map[chan Event]struct{}{}}
+}
+
+func (b *MemoryBroker) Publish(events []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, event := range events {
+		for ch := range b.subscribers[event.UserID] {
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
+
+func (b *MemoryBroker) Subscribe(userID string) (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = map[chan Event]struct{}{}
+	}
+	b.subscribers[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers[userID], ch)
+			if len(b.subscribers[userID]) == 0 {
+				delete(b.subscribers, userID)
+			}
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// eventRepository records the change feed entries written through it, so
+// that they can be published once the transaction has committed.
+type eventRepository struct {
+	Repository
+	events *[]Event
+}
+
+func (r *eventRepository) CreateNodeChange(change *NodeChange) error {
+	if err := r.Repository.CreateNodeChange(change); err != nil {
+		return err
+	}
+	*r.events = append(*r.events, Event{UserID: change.UserID, Seq: change.Seq, NodeID: change.NodeID, Action: change.Action})
+	return nil
+}
+
+// transaction runs fn in a transaction and publishes the changes it recorded
+// after the commit, so that subscribers never see a change that was rolled
+// back.
+func (s *service) transaction(fn func(repo Repository) error) error {
+	var events []Event
+	err := s.repo.Transaction(func(repo Repository) error {
+		events = nil
+		return fn(&eventRepository{Repository: repo, events: &events})
+	})
+	if err != nil {
+		return err
+	}
+	if s.broker != nil && len(events) > 0 {
+		s.broker.Publish(events)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package url
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const eventChannel = "url_events"
+
+// PostgresBroker publishes events with NOTIFY and relays the notifications it
+// LISTENs to to the subscribers of this replica, so that events reach
+// sessions connected to any replica. Run must be running for events to be
+// delivered.
+type PostgresBroker struct {
+	db          *gorm.DB
+	databaseURL string
+	logger      *zap.Logger
+	local       *MemoryBroker
+}
+
+func NewPostgresBroker(db *gorm.DB, databaseURL string, logger *zap.Logger) *PostgresBroker {
+	return &PostgresBroker{db: db, databaseURL: databaseURL, logger: logger, local: NewMemoryBroker()}
+}
+
+func (b *PostgresBroker) Publish(events []Event) {
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			b.logger.Error("failed to encode event", zap.Error(err))
+			continue
+		}
+		if err := b.db.Exec("SELECT pg_notify(?, ?)", eventChannel, string(payload)).Error; err != nil {
+			b.logger.Error("failed to publish event", zap.String("node_id", event.NodeID), zap.Error(err))
+		}
+	}
+}
+
+func (b *PostgresBroker) Subscribe(userID string) (<-chan Event, func()) {
+	return b.local.Subscribe(userID)
+}
+
+// Run listens for notifications until ctx is cancelled, reconnecting with a
+// growing delay when the connection is lost.
+func (b *PostgresBroker) Run(ctx context.Context) {
+	delay := time.Second
+	for {
+		started := time.Now()
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			delay = time.Second
+		}
+		b.logger.Error("event listener disconnected", zap.Error(err), zap.Duration("retry_in", delay))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, time.Minute)
+	}
+}
+
+func (b *PostgresBroker) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, b.databaseURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{eventChannel}.Sanitize()); err != nil {
+		return err
+	}
+	b.logger.Info("listening for events", zap.String("channel", eventChannel))
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			b.logger.Warn("ignoring malformed event", zap.String("payload", notification.Payload), zap.Error(err))
+			continue
+		}
+		b.local.Publish([]Event{event})
+	}
+}
@@ -0,0 +1,115 @@
+package url
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBroker_Publish_Success(t *testing.T) {
+	// Arrange
+	broker := NewMemoryBroker()
+	events, unsubscribe := broker.Subscribe("1")
+	defer unsubscribe()
+	otherEvents, unsubscribeOther := broker.Subscribe("2")
+	defer unsubscribeOther()
+
+	event := Event{UserID: "1", Seq: 3, NodeID: "node-id", Action: audit.ActionCreate}
+
+	// Act
+	broker.Publish([]Event{event})
+
+	// Assert
+	require.Len(t, events, 1)
+	assert.Equal(t, event, <-events)
+	assert.Empty(t, otherEvents)
+}
+func TestMemoryBroker_Publish_DropsWhenBufferFull(t *testing.T) {
+	// Arrange
+	broker := NewMemoryBroker()
+	events, unsubscribe := broker.Subscribe("1")
+	defer unsubscribe()
+
+	published := make([]Event, eventBufferSize+1)
+	for i := range published {
+		published[i] = Event{UserID: "1", Seq: int64(i + 1), NodeID: "node-id", Action: audit.ActionUpdate}
+	}
+
+	// Act
+	broker.Publish(published)
+
+	// Assert
+	require.Len(t, events, eventBufferSize)
+	assert.Equal(t, int64(1), (<-events).Seq)
+}
+
+func TestMemoryBroker_Subscribe_Unsubscribe(t *testing.T) {
+	// Arrange
+	broker := NewMemoryBroker()
+	events, unsubscribe := broker.Subscribe("1")
+
+	// Act
+	unsubscribe()
+	unsubscribe()
+	broker.Publish([]Event{{UserID: "1", Seq: 1, NodeID: "node-id", Action: audit.ActionCreate}})
+
+	// Assert
+	_, ok := <-events
+	assert.False(t, ok)
+	assert.Empty(t, broker.subscribers)
+}
+
+func TestService_Transaction_PublishesAfterCommit(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	broker := NewMemoryBroker()
+	service := &service{repo: mockRepo, broker: broker}
+	events, unsubscribe := broker.Subscribe("1")
+	defer unsubscribe()
+
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("NextChangeSeq", "1").Return(int64(5), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+
+	// Act
+	err := service.transaction(func(repo Repository) error {
+		err := recordFeedChange(repo, &URLNode{ID: "node-id", UserID: "1"}, audit.ActionMove)
+		assert.Empty(t, events)
+		return err
+	})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, Event{UserID: "1", Seq: 5, NodeID: "node-id", Action: audit.ActionMove}, <-events)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_Transaction_RollbackNotPublished(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	broker := NewMemoryBroker()
+	service := &service{repo: mockRepo, broker: broker}
+	events, unsubscribe := broker.Subscribe("1")
+	defer unsubscribe()
+
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("NextChangeSeq", "1").Return(int64(5), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+
+	// Act
+	err := service.transaction(func(repo Repository) error {
+		if err := recordFeedChange(repo, &URLNode{ID: "node-id", UserID: "1"}, audit.ActionMove); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+
+	// Assert
+	require.Error(t, err)
+	assert.Empty(t, events)
+}
@@ -0,0 +1,202 @@
+package url
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/outbound"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Favicon is the cached icon of a host. Data is empty when the host has no
+// usable icon, so that it is not requested again until the entry expires.
+type Favicon struct {
+	Host        string `gorm:"primaryKey"`
+	ContentType string `gorm:"not null"`
+	Data        []byte
+	FetchedAt   time.Time `gorm:"not null"`
+}
+
+// FaviconStore keeps fetched favicons. Icons are stored in the database by
+// default; another implementation can put them in a blob store instead.
+type FaviconStore interface {
+	Get(host string) (*Favicon, error)
+	Put(favicon *Favicon) error
+}
+
+type dbFaviconStore struct {
+	db *gorm.DB
+}
+
+func NewFaviconStore(db *gorm.DB) FaviconStore {
+	return &dbFaviconStore{db: db}
+}
+
+func (s *dbFaviconStore) Get(host string) (*Favicon, error) {
+	var favicon Favicon
+	if err := s.db.Where("host = ?", host).First(&favicon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &favicon, nil
+}
+
+func (s *dbFaviconStore) Put(favicon *Favicon) error {
+	return s.db.Save(favicon).Error
+}
+
+// FaviconCache serves favicons of saved URLs from the store and fetches them
+// from the site when missing or older than the TTL, so that clients never
+// request icons from third-party hosts themselves. Icons are not fetched from
+// internal addresses, since their content would be served back to the user.
+type FaviconCache struct {
+	store   FaviconStore
+	client  *http.Client
+	logger  *zap.Logger
+	ttl     time.Duration
+	maxSize int64
+}
+
+func NewFaviconCache(store FaviconStore, config *config.Config, logger *zap.Logger) *FaviconCache {
+	return &FaviconCache{
+		store:   store,
+		client:  outbound.NewClient(config.FaviconFetchTimeout, config.OutboundAllowedNetworks),
+		logger:  logger,
+		ttl:     config.FaviconCacheTTL,
+		maxSize: config.FaviconMaxSize,
+	}
+}
+
+// Get returns the favicon for the host of rawURL. The icon declared by the
+// page is tried before /favicon.ico, and a letter icon is generated when
+// neither can be used.
+func (c *FaviconCache) Get(ctx context.Context, rawURL string, iconURL *string) (*Favicon, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return letterIcon(rawURL), nil
+	}
+	host := strings.ToLower(u.Host)
+
+	cached, err := c.store.Get(host)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && time.Since(cached.FetchedAt) < c.ttl {
+		if len(cached.Data) == 0 {
+			return letterIcon(u.Hostname()), nil
+		}
+		return cached, nil
+	}
+
+	candidates := []string{u.Scheme + "://" + u.Host + "/favicon.ico"}
+	if iconURL != nil {
+		candidates = append([]string{*iconURL}, candidates...)
+	}
+
+	favicon := &Favicon{Host: host, FetchedAt: time.Now().UTC()}
+	for _, candidate := range candidates {
+		data, contentType, err := c.fetch(ctx, candidate)
+		if err != nil {
+			c.logger.Debug("failed to fetch favicon", zap.String("url", candidate), zap.Error(err))
+			continue
+		}
+		favicon.Data = data
+		favicon.ContentType = contentType
+		break
+	}
+	if err := c.store.Put(favicon); err != nil {
+		c.logger.Error("failed to store favicon", zap.String("host", host), zap.Error(err))
+	}
+
+	if len(favicon.Data) == 0 {
+		return letterIcon(u.Hostname()), nil
+	}
+	return favicon, nil
+}
+
+func (c *FaviconCache) fetch(ctx context.Context, rawURL string) ([]byte, string, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, "", errors.New("unsupported url | url: " + rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", crawlerUserAgent)
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", errors.New("unexpected status | status: " + strconv.Itoa(resp.StatusCode))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, c.maxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > c.maxSize {
+		return nil, "", errors.New("favicon too large | limit: " + strconv.FormatInt(c.maxSize, 10))
+	}
+	if len(data) == 0 {
+		return nil, "", errors.New("empty favicon")
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", errors.New("unsupported content type | type: " + contentType)
+	}
+	return data, contentType, nil
+}
+
+var letterIconColors = []string{
+	"#e57373", "#f06292", "#ba68c8", "#7986cb", "#4fc3f7",
+	"#4db6ac", "#81c784", "#ffb74d", "#a1887f", "#90a4ae",
+}
+
+// letterIcon generates an SVG showing the first letter of label on a
+// background color derived from label.
+func letterIcon(label string) *Favicon {
+	label = strings.TrimPrefix(strings.ToLower(label), "www.")
+	letter := "?"
+	for _, r := range label {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			letter = string(unicode.ToUpper(r))
+			break
+		}
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(label))
+	color := letterIconColors[hash.Sum32()%uint32(len(letterIconColors))]
+
+	svg := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="64" height="64" viewBox="0 0 64 64">`+
+		`<rect width="64" height="64" rx="12" fill="%s"/>`+
+		`<text x="32" y="44" font-family="sans-serif" font-size="36" font-weight="bold" fill="#fff" text-anchor="middle">%s</text>`+
+		`</svg>`, color, html.EscapeString(letter))
+	return &Favicon{Host: label, ContentType: "image/svg+xml", Data: []byte(svg), FetchedAt: time.Now().UTC()}
+}
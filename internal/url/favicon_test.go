@@ -0,0 +1,286 @@
+package url
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var pngIcon = []byte("\x89PNG\r\n\x1a\nmock-icon")
+
+type MockFaviconStore struct {
+	mock.Mock
+}
+
+func (m *MockFaviconStore) Get(host string) (*Favicon, error) {
+	args := m.Called(host)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Favicon), args.Error(1)
+}
+
+func (m *MockFaviconStore) Put(favicon *Favicon) error {
+	args := m.Called(favicon)
+	return args.Error(0)
+}
+
+func setupIconServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/x-icon")
+		w.Write(pngIcon)
+	})
+	mux.HandleFunc("/declared.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(pngIcon)
+	})
+	mux.HandleFunc("/large.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(bytes.Repeat([]byte{0}, 1024))
+	})
+	mux.HandleFunc("/page.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func newTestFaviconCache(store FaviconStore) *FaviconCache {
+	return NewFaviconCache(store, &config.Config{
+		FaviconCacheTTL:     time.Hour,
+		FaviconMaxSize:      512,
+		FaviconFetchTimeout: time.Second,
+		// The test servers listen on the loopback address.
+		OutboundAllowedNetworks: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")},
+	}, zap.NewNop())
+}
+
+func TestFaviconCache_Get_Fetch(t *testing.T) {
+	server := setupIconServer()
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name                string
+		iconURL             *string
+		expectedContentType string
+	}{
+		{
+			name:                "declared icon",
+			iconURL:             test.StringPtr(server.URL + "/declared.png"),
+			expectedContentType: "image/png",
+		},
+		{
+			name:                "default favicon",
+			iconURL:             nil,
+			expectedContentType: "image/x-icon",
+		},
+		{
+			name:                "declared icon too large",
+			iconURL:             test.StringPtr(server.URL + "/large.png"),
+			expectedContentType: "image/x-icon",
+		},
+		{
+			name:                "declared icon not an image",
+			iconURL:             test.StringPtr(server.URL + "/page.html"),
+			expectedContentType: "image/x-icon",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockStore := &MockFaviconStore{}
+			cache := newTestFaviconCache(mockStore)
+
+			mockStore.On("Get", host).Return(nil, nil)
+			mockStore.On("Put", mock.MatchedBy(func(favicon *Favicon) bool {
+				return favicon.Host == host && bytes.Equal(favicon.Data, pngIcon)
+			})).Return(nil)
+
+			// Act
+			favicon, err := cache.Get(context.Background(), server.URL+"/page", tt.iconURL)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedContentType, favicon.ContentType)
+			assert.Equal(t, pngIcon, favicon.Data)
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+func TestFaviconCache_Get_NoIcon(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	mockStore := &MockFaviconStore{}
+	cache := newTestFaviconCache(mockStore)
+
+	mockStore.On("Get", host).Return(nil, nil)
+	mockStore.On("Put", mock.MatchedBy(func(favicon *Favicon) bool {
+		return favicon.Host == host && len(favicon.Data) == 0
+	})).Return(nil)
+
+	// Act
+	favicon, err := cache.Get(context.Background(), server.URL+"/page", nil)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", favicon.ContentType)
+	assert.Contains(t, string(favicon.Data), ">1</text>")
+	mockStore.AssertExpectations(t)
+}
+func TestFaviconCache_Get_InternalAddress(t *testing.T) {
+	// Arrange
+	server := setupIconServer()
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	mockStore := &MockFaviconStore{}
+	cache := NewFaviconCache(mockStore, &config.Config{
+		FaviconCacheTTL:     time.Hour,
+		FaviconMaxSize:      512,
+		FaviconFetchTimeout: time.Second,
+	}, zap.NewNop())
+
+	mockStore.On("Get", host).Return(nil, nil)
+	mockStore.On("Put", mock.MatchedBy(func(favicon *Favicon) bool {
+		return favicon.Host == host && len(favicon.Data) == 0
+	})).Return(nil)
+
+	// Act
+	favicon, err := cache.Get(context.Background(), server.URL+"/page", test.StringPtr("http://169.254.169.254/latest/meta-data/"))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", favicon.ContentType)
+	mockStore.AssertExpectations(t)
+}
+func TestFaviconCache_Get_Cached(t *testing.T) {
+	tests := []struct {
+		name                string
+		cached              *Favicon
+		expectedContentType string
+	}{
+		{
+			name:                "icon",
+			cached:              &Favicon{Host: "example.com", ContentType: "image/png", Data: pngIcon, FetchedAt: time.Now()},
+			expectedContentType: "image/png",
+		},
+		{
+			name:                "no icon",
+			cached:              &Favicon{Host: "example.com", FetchedAt: time.Now()},
+			expectedContentType: "image/svg+xml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockStore := &MockFaviconStore{}
+			cache := newTestFaviconCache(mockStore)
+
+			mockStore.On("Get", "example.com").Return(tt.cached, nil)
+
+			// Act
+			favicon, err := cache.Get(context.Background(), "https://Example.com/page", nil)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedContentType, favicon.ContentType)
+			mockStore.AssertNotCalled(t, "Put", mock.Anything)
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+func TestFaviconCache_Get_Expired(t *testing.T) {
+	// Arrange
+	server := setupIconServer()
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	mockStore := &MockFaviconStore{}
+	cache := newTestFaviconCache(mockStore)
+	expired := &Favicon{Host: host, FetchedAt: time.Now().Add(-2 * time.Hour)}
+
+	mockStore.On("Get", host).Return(expired, nil)
+	mockStore.On("Put", mock.AnythingOfType("*url.Favicon")).Return(nil)
+
+	// Act
+	favicon, err := cache.Get(context.Background(), server.URL+"/page", nil)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, pngIcon, favicon.Data)
+	mockStore.AssertExpectations(t)
+}
+func TestFaviconCache_Get_StoreError(t *testing.T) {
+	// Arrange
+	mockStore := &MockFaviconStore{}
+	cache := newTestFaviconCache(mockStore)
+
+	mockStore.On("Get", "example.com").Return(nil, assert.AnError)
+
+	// Act
+	favicon, err := cache.Get(context.Background(), "https://example.com/page", nil)
+
+	// Assert
+	assert.Equal(t, assert.AnError, err)
+	assert.Nil(t, favicon)
+	mockStore.AssertExpectations(t)
+}
+func TestFaviconCache_Get_InvalidURL(t *testing.T) {
+	// Arrange
+	mockStore := &MockFaviconStore{}
+	cache := newTestFaviconCache(mockStore)
+
+	// Act
+	favicon, err := cache.Get(context.Background(), "about:blank", nil)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", favicon.ContentType)
+	mockStore.AssertNotCalled(t, "Get", mock.Anything)
+}
+
+func TestFavicon_letterIcon(t *testing.T) {
+	tests := []struct {
+		name           string
+		label          string
+		expectedLetter string
+	}{
+		{name: "host", label: "example.com", expectedLetter: "E"},
+		{name: "www prefix", label: "www.github.com", expectedLetter: "G"},
+		{name: "leading symbol", label: "_private", expectedLetter: "P"},
+		{name: "non-latin", label: "ñandú.com", expectedLetter: "Ñ"},
+		{name: "no letter", label: "", expectedLetter: "?"},
+		{name: "markup", label: "<", expectedLetter: "?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			favicon := letterIcon(tt.label)
+
+			// Assert
+			assert.Equal(t, "image/svg+xml", favicon.ContentType)
+			assert.Contains(t, string(favicon.Data), ">"+tt.expectedLetter+"</text>")
+			assert.Equal(t, letterIcon(tt.label).Data, favicon.Data)
+		})
+	}
+}
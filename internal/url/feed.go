@@ -0,0 +1,43 @@
+package url
+
+import (
+	"encoding/base64"
+	"strconv"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/audit"
+)
+
+// actionPurge is the change feed action of a node deleted for good, which
+// has no state left to sync.
+const actionPurge audit.Action = "purge"
+
+const defaultChangesLimit = 500
+
+// recordFeedChange appends a change of the node to the change feed of the
+// owner of its tree. It must be called with the repository of the
+// transaction making the change.
+func recordFeedChange(repo Repository, node *URLNode, action audit.Action) error {
+	seq, err := repo.NextChangeSeq(node.UserID)
+	if err != nil {
+		return err
+	}
+	return repo.CreateNodeChange(&NodeChange{UserID: node.UserID, Seq: seq, NodeID: node.ID, Action: action})
+}
+
+// Sync tokens are opaque to clients, they encode the last change sequence
+// number the client has seen.
+func encodeSyncToken(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(seq, 10)))
+}
+
+func decodeSyncToken(token string) (int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		seq, err := strconv.ParseInt(string(data), 10, 64)
+		if err == nil && seq >= 0 {
+			return seq, nil
+		}
+	}
+	return 0, apperror.New(apperror.CodeSyncTokenInvalid, "Invalid sync token | since: "+token)
+}
@@ -0,0 +1,40 @@
+package url
+
+import (
+	"testing"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncToken_RoundTrip(t *testing.T) {
+	// Act
+	seq, err := decodeSyncToken(encodeSyncToken(42))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), seq)
+}
+func TestSyncToken_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "not base64", token: "!!!"},
+		{name: "not a number", token: "YWJj"},
+		{name: "negative", token: encodeSyncToken(-1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := decodeSyncToken(tt.token)
+
+			// Assert
+			require.Error(t, err)
+			assert.Equal(t, apperror.CodeSyncTokenInvalid, err.(*apperror.AppError).Code)
+		})
+	}
+}
@@ -1,12 +1,17 @@
 package url
 
 import (
-	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 )
 
+// eventHeartbeatInterval is how often a comment is sent on an idle event
+// stream, so that proxies don't close the connection.
+const eventHeartbeatInterval = 30 * time.Second
+
 type Handler struct {
 	service Service
 }
@@ -22,18 +27,19 @@ func (h *Handler) CreateURL(c *gin.Context) {
 		return
 	}
 
-	userID := c.GetInt("user_id")
-	if err := h.service.CreateURL(&body, userID); err != nil {
+	userID := c.GetString("user_id")
+	requestID := c.GetString("request_id")
+	response, err := h.service.CreateURL(c.Request.Context(), &body, userID, requestID)
+	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	c.Status(http.StatusNoContent)
+	c.JSON(http.StatusCreated, response)
 }
 
 func (h *Handler) GetRootID(c *gin.Context) {
-	userID := c.GetInt("user_id")
-	fmt.Println("GetRootID", userID)
+	userID := c.GetString("user_id")
 
 	rootID, err := h.service.GetRootID(userID)
 	if err != nil {
@@ -51,7 +57,7 @@ func (h *Handler) GetURL(c *gin.Context) {
 		return
 	}
 
-	userID := c.GetInt("user_id")
+	userID := c.GetString("user_id")
 	response, err := h.service.GetURL(uri.ID, userID)
 	if err != nil {
 		c.Error(err)
@@ -74,8 +80,9 @@ func (h *Handler) ReplaceURL(c *gin.Context) {
 		return
 	}
 
-	userID := c.GetInt("user_id")
-	if err := h.service.ReplaceURL(uri.ID, &body, userID); err != nil {
+	userID := c.GetString("user_id")
+	requestID := c.GetString("request_id")
+	if err := h.service.ReplaceURL(c.Request.Context(), uri.ID, &body, userID, requestID); err != nil {
 		c.Error(err)
 		return
 	}
@@ -90,11 +97,346 @@ func (h *Handler) DeleteURL(c *gin.Context) {
 		return
 	}
 
-	userID := c.GetInt("user_id")
-	if err := h.service.DeleteURL(uri.ID, userID); err != nil {
+	userID := c.GetString("user_id")
+	requestID := c.GetString("request_id")
+	if err := h.service.DeleteURL(uri.ID, userID, requestID); err != nil {
 		c.Error(err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+func (h *Handler) GetDuplicates(c *gin.Context) {
+	userID := c.GetString("user_id")
+	response, err := h.service.GetDuplicates(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) MergeDuplicates(c *gin.Context) {
+	var body MergeDuplicatesRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	requestID := c.GetString("request_id")
+	if err := h.service.MergeDuplicates(&body, userID, requestID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) LookupURL(c *gin.Context) {
+	query := &LookupQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request query | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := h.service.LookupURL(query.URL, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) LookupURLs(c *gin.Context) {
+	var body LookupRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := h.service.LookupURLs(body.URLs, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) GetBrokenURLs(c *gin.Context) {
+	userID := c.GetString("user_id")
+	response, err := h.service.GetBrokenURLs(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) GetFavicon(c *gin.Context) {
+	uri := &RequestURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	favicon, err := h.service.GetFavicon(c.Request.Context(), uri.ID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Icons come from third-party sites, so scripts inside SVGs must not run.
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, favicon.ContentType, favicon.Data)
+}
+
+func (h *Handler) GetCollaborators(c *gin.Context) {
+	uri := &RequestURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := h.service.GetCollaborators(uri.ID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) AddCollaborator(c *gin.Context) {
+	uri := &RequestURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	var body AddCollaboratorRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := h.service.AddCollaborator(uri.ID, &body, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *Handler) UpdateCollaborator(c *gin.Context) {
+	uri := &CollaboratorURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	var body UpdateCollaboratorRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if err := h.service.UpdateCollaborator(uri.ID, uri.UserID, &body, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) RemoveCollaborator(c *gin.Context) {
+	uri := &CollaboratorURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if err := h.service.RemoveCollaborator(uri.ID, uri.UserID, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) GetSharedWithMe(c *gin.Context) {
+	userID := c.GetString("user_id")
+	response, err := h.service.GetSharedWithMe(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) MountFolder(c *gin.Context) {
+	uri := &RequestURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	var body MountRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	requestID := c.GetString("request_id")
+	response, err := h.service.MountFolder(uri.ID, &body, userID, requestID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *Handler) GetHistory(c *gin.Context) {
+	uri := &RequestURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := h.service.GetHistory(uri.ID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) GetRevisions(c *gin.Context) {
+	uri := &RequestURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := h.service.GetRevisions(uri.ID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) RevertURL(c *gin.Context) {
+	uri := &RevisionURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	requestID := c.GetString("request_id")
+	if err := h.service.RevertURL(uri.ID, uri.Revision, userID, requestID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) Undo(c *gin.Context) {
+	userID := c.GetString("user_id")
+	requestID := c.GetString("request_id")
+	response, err := h.service.Undo(userID, requestID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) Redo(c *gin.Context) {
+	userID := c.GetString("user_id")
+	requestID := c.GetString("request_id")
+	response, err := h.service.Redo(userID, requestID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) GetChanges(c *gin.Context) {
+	query := &ChangesQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request query | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := h.service.GetChanges(query.Since, query.Limit, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// StreamEvents streams the changes committed to the caller's tree as
+// server-sent events until the client disconnects.
+func (h *Handler) StreamEvents(c *gin.Context) {
+	userID := c.GetString("user_id")
+	events, unsubscribe := h.service.SubscribeEvents(userID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			c.Render(-1, sse.Event{
+				Id:    encodeSyncToken(event.Seq),
+				Event: "change",
+				Data:  ChangeEvent{NodeID: event.NodeID, Action: event.Action},
+			})
+		case <-heartbeat.C:
+			c.Writer.WriteString(": heartbeat\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
@@ -2,12 +2,16 @@ package url
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/vera/vera-drive-service/internal/audit"
 	"github.com/vera/vera-drive-service/test"
 
 	"github.com/gin-gonic/gin"
@@ -20,29 +24,156 @@ type MockService struct {
 	mock.Mock
 }
 
-func (m *MockService) CreateURL(creates *RequestBody, userID int) error {
-	args := m.Called(creates, userID)
-	return args.Error(0)
+func (m *MockService) CreateURL(ctx context.Context, creates *RequestBody, userID string, requestID string) (*CreateURLResponse, error) {
+	args := m.Called(creates, userID, requestID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*CreateURLResponse), args.Error(1)
 }
-func (m *MockService) GetRootID(userID int) (string, error) {
+func (m *MockService) GetRootID(userID string) (string, error) {
 	args := m.Called(userID)
 	return args.String(0), args.Error(1)
 }
-func (m *MockService) GetURL(id string, userID int) (*URLResponse, error) {
+func (m *MockService) GetURL(id string, userID string) (*URLResponse, error) {
 	args := m.Called(id, userID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*URLResponse), args.Error(1)
 }
-func (m *MockService) ReplaceURL(id string, updates *RequestBody, userID int) error {
-	args := m.Called(id, updates, userID)
+func (m *MockService) ReplaceURL(ctx context.Context, id string, updates *RequestBody, userID string, requestID string) error {
+	args := m.Called(id, updates, userID, requestID)
+	return args.Error(0)
+}
+func (m *MockService) DeleteURL(id string, userID string, requestID string) error {
+	args := m.Called(id, userID, requestID)
+	return args.Error(0)
+}
+func (m *MockService) GetDuplicates(userID string) ([]DuplicateGroup, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]DuplicateGroup), args.Error(1)
+}
+func (m *MockService) MergeDuplicates(merges *MergeDuplicatesRequestBody, userID string, requestID string) error {
+	args := m.Called(merges, userID, requestID)
+	return args.Error(0)
+}
+func (m *MockService) LookupURL(rawURL string, userID string) (*LookupResult, error) {
+	args := m.Called(rawURL, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*LookupResult), args.Error(1)
+}
+func (m *MockService) LookupURLs(rawURLs []string, userID string) ([]LookupResult, error) {
+	args := m.Called(rawURLs, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]LookupResult), args.Error(1)
+}
+
+func (m *MockService) GetBrokenURLs(userID string) ([]BrokenURL, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]BrokenURL), args.Error(1)
+}
+
+func (m *MockService) GetFavicon(ctx context.Context, id string, userID string) (*Favicon, error) {
+	args := m.Called(id, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Favicon), args.Error(1)
+}
+
+func (m *MockService) GetCollaborators(folderID string, userID string) ([]Collaborator, error) {
+	args := m.Called(folderID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Collaborator), args.Error(1)
+}
+
+func (m *MockService) AddCollaborator(folderID string, adds *AddCollaboratorRequestBody, userID string) (*Collaborator, error) {
+	args := m.Called(folderID, adds, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Collaborator), args.Error(1)
+}
+
+func (m *MockService) UpdateCollaborator(folderID string, collaboratorID string, updates *UpdateCollaboratorRequestBody, userID string) error {
+	args := m.Called(folderID, collaboratorID, updates, userID)
+	return args.Error(0)
+}
+
+func (m *MockService) RemoveCollaborator(folderID string, collaboratorID string, userID string) error {
+	args := m.Called(folderID, collaboratorID, userID)
 	return args.Error(0)
 }
-func (m *MockService) DeleteURL(id string, userID int) error {
+func (m *MockService) GetSharedWithMe(userID string) ([]SharedFolder, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]SharedFolder), args.Error(1)
+}
+func (m *MockService) GetRevisions(id string, userID string) ([]Revision, error) {
 	args := m.Called(id, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Revision), args.Error(1)
+}
+func (m *MockService) RevertURL(id string, revision int, userID string, requestID string) error {
+	args := m.Called(id, revision, userID, requestID)
 	return args.Error(0)
 }
+func (m *MockService) Undo(userID string, requestID string) (*OperationResponse, error) {
+	args := m.Called(userID, requestID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*OperationResponse), args.Error(1)
+}
+func (m *MockService) Redo(userID string, requestID string) (*OperationResponse, error) {
+	args := m.Called(userID, requestID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*OperationResponse), args.Error(1)
+}
+func (m *MockService) GetChanges(since string, limit int, userID string) (*ChangesResponse, error) {
+	args := m.Called(since, limit, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ChangesResponse), args.Error(1)
+}
+func (m *MockService) SubscribeEvents(userID string) (<-chan Event, func()) {
+	args := m.Called(userID)
+	return args.Get(0).(<-chan Event), args.Get(1).(func())
+}
+func (m *MockService) GetHistory(id string, userID string) ([]audit.Entry, error) {
+	args := m.Called(id, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]audit.Entry), args.Error(1)
+}
+func (m *MockService) MountFolder(folderID string, mounts *MountRequestBody, userID string, requestID string) (*BaseURL, error) {
+	args := m.Called(folderID, mounts, userID, requestID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*BaseURL), args.Error(1)
+}
 
 func TestHandler_NewHandler_Success(t *testing.T) {
 	// Arrange
@@ -62,7 +193,7 @@ func TestHandler_CreateURL_Success(t *testing.T) {
 	handler := NewHandler(mockService)
 	c, w := test.SetupContext()
 
-	userID := 1
+	userID := "1"
 	requestBody := RequestBody{
 		ParentID: "123e4567-e89b-12d3-a456-426614174001",
 		Name:     "folder",
@@ -73,15 +204,29 @@ func TestHandler_CreateURL_Success(t *testing.T) {
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
 	c.Request.Header.Set("Content-Type", "application/json")
 	c.Set("user_id", userID)
+	c.Set("request_id", "request-id")
 
-	mockService.On("CreateURL", &requestBody, userID).Return(nil)
+	expectedResponse := &CreateURLResponse{
+		BaseURL: BaseURL{
+			ID:        "123e4567-e89b-12d3-a456-426614174002",
+			Name:      "folder",
+			Type:      "folder",
+			CreatedAt: time.Unix(0, 0).UTC().Format(time.RFC3339),
+			UpdatedAt: time.Unix(0, 0).UTC().Format(time.RFC3339),
+		},
+	}
+	mockService.On("CreateURL", &requestBody, userID, "request-id").Return(expectedResponse, nil)
 
 	// Act
 	handler.CreateURL(c)
-	c.Writer.WriteHeaderNow()
 
 	// Assert
-	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var response CreateURLResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expectedResponse, response)
 	mockService.AssertExpectations(t)
 }
 func TestHandler_CreateURL_InvalidRequestBody(t *testing.T) {
@@ -100,11 +245,6 @@ func TestHandler_CreateURL_InvalidRequestBody(t *testing.T) {
 			payload:       `{"parent_id": "not-a-uuid", "name": "folder", "type": "folder"}`,
 			errorContains: "uuid",
 		},
-		{
-			name:          "missing name",
-			payload:       `{"parent_id": "123e4567-e89b-12d3-a456-426614174000", "type": "folder"}`,
-			errorContains: "Name",
-		},
 		{
 			name:          "name too long",
 			payload:       `{"parent_id": "123e4567-e89b-12d3-a456-426614174000", "name": "this name is definitely too long", "type": "folder"}`,
@@ -131,7 +271,7 @@ func TestHandler_CreateURL_InvalidRequestBody(t *testing.T) {
 
 			c.Request.Body = io.NopCloser(bytes.NewBufferString(tt.payload))
 			c.Request.Header.Set("Content-Type", "application/json")
-			c.Set("user_id", 1)
+			c.Set("user_id", "1")
 
 			// Act
 			handler.CreateURL(c)
@@ -162,9 +302,10 @@ func TestHandler_CreateURL_ServiceError(t *testing.T) {
 
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
 	c.Request.Header.Set("Content-Type", "application/json")
-	c.Set("user_id", 1)
+	c.Set("user_id", "1")
+	c.Set("request_id", "request-id")
 
-	mockService.On("CreateURL", &requestBody, 1).Return(assert.AnError)
+	mockService.On("CreateURL", &requestBody, "1", "request-id").Return(nil, assert.AnError)
 
 	// Act
 	handler.CreateURL(c)
@@ -182,7 +323,7 @@ func TestHandler_GetRootID_Success(t *testing.T) {
 	handler := NewHandler(mockService)
 	c, w := test.SetupContext()
 
-	userID := 1
+	userID := "1"
 	expectedRootID := "123e4567-e89b-12d3-a456-426614174001"
 
 	c.Set("user_id", userID)
@@ -207,7 +348,7 @@ func TestHandler_GetRootID_ServiceError(t *testing.T) {
 	handler := NewHandler(mockService)
 	c, w := test.SetupContext()
 
-	userID := 1
+	userID := "1"
 
 	c.Set("user_id", userID)
 
@@ -229,7 +370,7 @@ func TestHandler_GetURL_Success(t *testing.T) {
 	handler := NewHandler(mockService)
 	c, w := test.SetupContext()
 
-	userID := 1
+	userID := "1"
 	urlID := "123e4567-e89b-12d3-a456-426614174001"
 	expectedResponse := &URLResponse{
 		BaseURL: BaseURL{
@@ -294,7 +435,7 @@ func TestHandler_GetURL_InvalidRequestURI(t *testing.T) {
 	c, w := test.SetupContext()
 
 	c.Params = gin.Params{{Key: "id", Value: "invalid-uuid"}}
-	c.Set("user_id", 1)
+	c.Set("user_id", "1")
 
 	// Act
 	handler.GetURL(c)
@@ -317,9 +458,9 @@ func TestHandler_GetURL_ServiceError(t *testing.T) {
 	urlID := "123e4567-e89b-12d3-a456-426614174001"
 
 	c.Params = gin.Params{{Key: "id", Value: urlID}}
-	c.Set("user_id", 1)
+	c.Set("user_id", "1")
 
-	mockService.On("GetURL", urlID, 1).Return(nil, assert.AnError)
+	mockService.On("GetURL", urlID, "1").Return(nil, assert.AnError)
 
 	// Act
 	handler.GetURL(c)
@@ -348,9 +489,10 @@ func TestHandler_ReplaceURL_Success(t *testing.T) {
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
 	c.Request.Header.Set("Content-Type", "application/json")
 	c.Params = gin.Params{{Key: "id", Value: urlID}}
-	c.Set("user_id", 1)
+	c.Set("user_id", "1")
+	c.Set("request_id", "request-id")
 
-	mockService.On("ReplaceURL", urlID, &requestBody, 1).Return(nil)
+	mockService.On("ReplaceURL", urlID, &requestBody, "1", "request-id").Return(nil)
 
 	// Act
 	handler.ReplaceURL(c)
@@ -368,7 +510,7 @@ func TestHandler_ReplaceURL_InvalidRequestURI(t *testing.T) {
 
 	c.Request.Header.Set("Content-Type", "application/json")
 	c.Params = gin.Params{{Key: "id", Value: "invalid-uuid"}}
-	c.Set("user_id", 1)
+	c.Set("user_id", "1")
 
 	// Act
 	handler.ReplaceURL(c)
@@ -398,11 +540,6 @@ func TestHandler_ReplaceURL_InvalidRequestBody(t *testing.T) {
 			payload:       `{"parent_id": "not-a-uuid", "name": "folder", "type": "folder"}`,
 			errorContains: "uuid",
 		},
-		{
-			name:          "missing name",
-			payload:       `{"parent_id": "123e4567-e89b-12d3-a456-426614174000", "type": "folder"}`,
-			errorContains: "Name",
-		},
 		{
 			name:          "name too long",
 			payload:       `{"parent_id": "123e4567-e89b-12d3-a456-426614174000", "name": "this name is definitely too long", "type": "folder"}`,
@@ -431,7 +568,7 @@ func TestHandler_ReplaceURL_InvalidRequestBody(t *testing.T) {
 			c.Request.Body = io.NopCloser(bytes.NewBufferString(tt.payload))
 			c.Request.Header.Set("Content-Type", "application/json")
 			c.Params = gin.Params{{Key: "id", Value: urlID}}
-			c.Set("user_id", 1)
+			c.Set("user_id", "1")
 
 			// Act
 			handler.ReplaceURL(c)
@@ -464,9 +601,10 @@ func TestHandler_ReplaceURL_ServiceError(t *testing.T) {
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
 	c.Request.Header.Set("Content-Type", "application/json")
 	c.Params = gin.Params{{Key: "id", Value: urlID}}
-	c.Set("user_id", 1)
+	c.Set("user_id", "1")
+	c.Set("request_id", "request-id")
 
-	mockService.On("ReplaceURL", urlID, &requestBody, 1).Return(assert.AnError)
+	mockService.On("ReplaceURL", urlID, &requestBody, "1", "request-id").Return(assert.AnError)
 
 	// Act
 	handler.ReplaceURL(c)
@@ -487,9 +625,10 @@ func TestHandler_DeleteURL_Success(t *testing.T) {
 	urlID := "123e4567-e89b-12d3-a456-426614174001"
 
 	c.Params = gin.Params{{Key: "id", Value: urlID}}
-	c.Set("user_id", 1)
+	c.Set("user_id", "1")
+	c.Set("request_id", "request-id")
 
-	mockService.On("DeleteURL", urlID, 1).Return(nil)
+	mockService.On("DeleteURL", urlID, "1", "request-id").Return(nil)
 
 	// Act
 	handler.DeleteURL(c)
@@ -506,7 +645,7 @@ func TestHandler_DeleteURL_InvalidRequestURI(t *testing.T) {
 	c, w := test.SetupContext()
 
 	c.Params = gin.Params{{Key: "id", Value: "invalid-uuid"}}
-	c.Set("user_id", 1)
+	c.Set("user_id", "1")
 
 	// Act
 	handler.DeleteURL(c)
@@ -529,9 +668,10 @@ func TestHandler_DeleteURL_ServiceError(t *testing.T) {
 	urlID := "123e4567-e89b-12d3-a456-426614174001"
 
 	c.Params = gin.Params{{Key: "id", Value: urlID}}
-	c.Set("user_id", 1)
+	c.Set("user_id", "1")
+	c.Set("request_id", "request-id")
 
-	mockService.On("DeleteURL", urlID, 1).Return(assert.AnError)
+	mockService.On("DeleteURL", urlID, "1", "request-id").Return(assert.AnError)
 
 	// Act
 	handler.DeleteURL(c)
@@ -542,3 +682,1025 @@ func TestHandler_DeleteURL_ServiceError(t *testing.T) {
 	assert.Equal(t, assert.AnError, c.Errors[0].Err)
 	mockService.AssertExpectations(t)
 }
+
+func TestHandler_GetDuplicates_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	expectedGroups := []DuplicateGroup{
+		{
+			NormalizedURL: "https://example.com",
+			URLs: []URLWithParent{
+				{BaseURL: BaseURL{ID: "mock-id-1", Name: "a", Type: "url"}, Parent: []BaseURL{}},
+				{BaseURL: BaseURL{ID: "mock-id-2", Name: "b", Type: "url"}, Parent: []BaseURL{}},
+			},
+		},
+	}
+	c.Set("user_id", "1")
+
+	mockService.On("GetDuplicates", "1").Return(expectedGroups, nil)
+
+	// Act
+	handler.GetDuplicates(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response []DuplicateGroup
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, expectedGroups, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_GetDuplicates_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Set("user_id", "1")
+
+	mockService.On("GetDuplicates", "1").Return(nil, assert.AnError)
+
+	// Act
+	handler.GetDuplicates(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, assert.AnError, c.Errors[0].Err)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_MergeDuplicates_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	requestBody := MergeDuplicatesRequestBody{
+		TargetID:  "123e4567-e89b-12d3-a456-426614174001",
+		SourceIDs: []string{"123e4567-e89b-12d3-a456-426614174002"},
+	}
+	requestJSON, _ := json.Marshal(requestBody)
+
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", "1")
+	c.Set("request_id", "request-id")
+
+	mockService.On("MergeDuplicates", &requestBody, "1", "request-id").Return(nil)
+
+	// Act
+	handler.MergeDuplicates(c)
+	c.Writer.WriteHeaderNow()
+
+	// Assert
+	require.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_MergeDuplicates_InvalidRequestBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       string
+		errorContains string
+	}{
+		{
+			name:          "missing target_id",
+			payload:       `{"source_ids": ["123e4567-e89b-12d3-a456-426614174002"]}`,
+			errorContains: "TargetID",
+		},
+		{
+			name:          "empty source_ids",
+			payload:       `{"target_id": "123e4567-e89b-12d3-a456-426614174001", "source_ids": []}`,
+			errorContains: "min",
+		},
+		{
+			name:          "invalid source_id format",
+			payload:       `{"target_id": "123e4567-e89b-12d3-a456-426614174001", "source_ids": ["not-a-uuid"]}`,
+			errorContains: "uuid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := &MockService{}
+			handler := NewHandler(mockService)
+			c, w := test.SetupContext()
+
+			c.Request.Body = io.NopCloser(bytes.NewBufferString(tt.payload))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", "1")
+
+			// Act
+			handler.MergeDuplicates(c)
+
+			// Assert
+			require.Equal(t, http.StatusBadRequest, w.Code)
+
+			var res map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &res)
+			require.NoError(t, err)
+			assert.Contains(t, res["error"], "invalid request body")
+			assert.Contains(t, res["error"], tt.errorContains)
+		})
+	}
+}
+func TestHandler_MergeDuplicates_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	requestBody := MergeDuplicatesRequestBody{
+		TargetID:  "123e4567-e89b-12d3-a456-426614174001",
+		SourceIDs: []string{"123e4567-e89b-12d3-a456-426614174002"},
+	}
+	requestJSON, _ := json.Marshal(requestBody)
+
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", "1")
+	c.Set("request_id", "request-id")
+
+	mockService.On("MergeDuplicates", &requestBody, "1", "request-id").Return(assert.AnError)
+
+	// Act
+	handler.MergeDuplicates(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, assert.AnError, c.Errors[0].Err)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_LookupURL_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	expectedResult := &LookupResult{
+		URL: "https://example.com",
+		Matches: []URLWithParent{
+			{BaseURL: BaseURL{ID: "mock-id", Name: "a", Type: "url"}, Parent: []BaseURL{}},
+		},
+	}
+	c.Request = httptest.NewRequest("GET", "/?url=https%3A%2F%2Fexample.com", nil)
+	c.Set("user_id", "1")
+
+	mockService.On("LookupURL", "https://example.com", "1").Return(expectedResult, nil)
+
+	// Act
+	handler.LookupURL(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response LookupResult
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expectedResult, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_LookupURL_InvalidRequestQuery(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Set("user_id", "1")
+
+	// Act
+	handler.LookupURL(c)
+
+	// Assert
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Contains(t, response["error"], "invalid request query")
+	assert.Contains(t, response["error"], "URL")
+}
+func TestHandler_LookupURL_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Request = httptest.NewRequest("GET", "/?url=https%3A%2F%2Fexample.com", nil)
+	c.Set("user_id", "1")
+
+	mockService.On("LookupURL", "https://example.com", "1").Return(nil, assert.AnError)
+
+	// Act
+	handler.LookupURL(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, assert.AnError, c.Errors[0].Err)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_LookupURLs_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	urls := []string{"https://example.com", "https://other.com"}
+	expectedResults := []LookupResult{
+		{URL: "https://example.com", Matches: []URLWithParent{{BaseURL: BaseURL{ID: "mock-id"}, Parent: []BaseURL{}}}},
+		{URL: "https://other.com", Matches: []URLWithParent{}},
+	}
+	requestJSON, _ := json.Marshal(LookupRequestBody{URLs: urls})
+
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", "1")
+
+	mockService.On("LookupURLs", urls, "1").Return(expectedResults, nil)
+
+	// Act
+	handler.LookupURLs(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response []LookupResult
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, expectedResults, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_LookupURLs_InvalidRequestBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       string
+		errorContains string
+	}{
+		{
+			name:          "missing urls",
+			payload:       `{}`,
+			errorContains: "URLs",
+		},
+		{
+			name:          "empty urls",
+			payload:       `{"urls": []}`,
+			errorContains: "min",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := &MockService{}
+			handler := NewHandler(mockService)
+			c, w := test.SetupContext()
+
+			c.Request.Body = io.NopCloser(bytes.NewBufferString(tt.payload))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", "1")
+
+			// Act
+			handler.LookupURLs(c)
+
+			// Assert
+			require.Equal(t, http.StatusBadRequest, w.Code)
+
+			var res map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &res)
+			require.NoError(t, err)
+			assert.Contains(t, res["error"], "invalid request body")
+			assert.Contains(t, res["error"], tt.errorContains)
+		})
+	}
+}
+func TestHandler_LookupURLs_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	urls := []string{"https://example.com"}
+	requestJSON, _ := json.Marshal(LookupRequestBody{URLs: urls})
+
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", "1")
+
+	mockService.On("LookupURLs", urls, "1").Return(nil, assert.AnError)
+
+	// Act
+	handler.LookupURLs(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, assert.AnError, c.Errors[0].Err)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetBrokenURLs_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	statusCode := 404
+	expectedBrokenURLs := []BrokenURL{
+		{
+			URLWithParent:  URLWithParent{BaseURL: BaseURL{ID: "mock-id", Name: "a", Type: "url"}, Parent: []BaseURL{}},
+			LastStatusCode: &statusCode,
+			LastCheckedAt:  time.Unix(0, 0).UTC().Format(time.RFC3339),
+		},
+	}
+	c.Set("user_id", "1")
+
+	mockService.On("GetBrokenURLs", "1").Return(expectedBrokenURLs, nil)
+
+	// Act
+	handler.GetBrokenURLs(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response []BrokenURL
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, expectedBrokenURLs, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_GetBrokenURLs_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Set("user_id", "1")
+
+	mockService.On("GetBrokenURLs", "1").Return(nil, assert.AnError)
+
+	// Act
+	handler.GetBrokenURLs(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, assert.AnError, c.Errors[0].Err)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetFavicon_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	urlID := "123e4567-e89b-12d3-a456-426614174000"
+	favicon := &Favicon{Host: "example.com", ContentType: "image/png", Data: []byte("mock-icon")}
+	c.Params = gin.Params{{Key: "id", Value: urlID}}
+	c.Set("user_id", "1")
+
+	mockService.On("GetFavicon", urlID, "1").Return(favicon, nil)
+
+	// Act
+	handler.GetFavicon(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.NotEmpty(t, w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "mock-icon", w.Body.String())
+	mockService.AssertExpectations(t)
+}
+func TestHandler_GetFavicon_InvalidURI(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Params = gin.Params{{Key: "id", Value: "invalid-uuid"}}
+	c.Set("user_id", "1")
+
+	// Act
+	handler.GetFavicon(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "GetFavicon", mock.Anything, mock.Anything)
+}
+func TestHandler_GetFavicon_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	urlID := "123e4567-e89b-12d3-a456-426614174000"
+	c.Params = gin.Params{{Key: "id", Value: urlID}}
+	c.Set("user_id", "1")
+
+	mockService.On("GetFavicon", urlID, "1").Return(nil, assert.AnError)
+
+	// Act
+	handler.GetFavicon(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, assert.AnError, c.Errors[0].Err)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetCollaborators_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	folderID := "123e4567-e89b-12d3-a456-426614174000"
+	expected := []Collaborator{{UserID: "2", Role: RoleEditor}}
+	c.Params = gin.Params{{Key: "id", Value: folderID}}
+	c.Set("user_id", "1")
+
+	mockService.On("GetCollaborators", folderID, "1").Return(expected, nil)
+
+	// Act
+	handler.GetCollaborators(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response []Collaborator
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, expected, response)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_AddCollaborator_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	folderID := "123e4567-e89b-12d3-a456-426614174000"
+	requestBody := AddCollaboratorRequestBody{UserID: "2", Role: RoleViewer}
+	requestJSON, _ := json.Marshal(requestBody)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: folderID}}
+	c.Set("user_id", "1")
+
+	expected := &Collaborator{UserID: "2", Role: RoleViewer}
+	mockService.On("AddCollaborator", folderID, &requestBody, "1").Return(expected, nil)
+
+	// Act
+	handler.AddCollaborator(c)
+
+	// Assert
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var response Collaborator
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expected, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_AddCollaborator_InvalidBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "missing user", body: `{"role": "viewer"}`},
+		{name: "numeric user", body: `{"user_id": 2, "role": "viewer"}`},
+		{name: "unknown role", body: `{"user_id": "2", "role": "admin"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := &MockService{}
+			handler := NewHandler(mockService)
+			c, w := test.SetupContext()
+
+			c.Request.Body = io.NopCloser(bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Params = gin.Params{{Key: "id", Value: "123e4567-e89b-12d3-a456-426614174000"}}
+			c.Set("user_id", "1")
+
+			// Act
+			handler.AddCollaborator(c)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			mockService.AssertNotCalled(t, "AddCollaborator", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandler_UpdateCollaborator_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	folderID := "123e4567-e89b-12d3-a456-426614174000"
+	requestBody := UpdateCollaboratorRequestBody{Role: RoleOwner}
+	requestJSON, _ := json.Marshal(requestBody)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: folderID}, {Key: "user_id", Value: "2"}}
+	c.Set("user_id", "1")
+
+	mockService.On("UpdateCollaborator", folderID, "2", &requestBody, "1").Return(nil)
+
+	// Act
+	handler.UpdateCollaborator(c)
+	c.Writer.WriteHeaderNow()
+
+	// Assert
+	require.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_UpdateCollaborator_InvalidURI(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Request.Body = io.NopCloser(bytes.NewBufferString(`{"role": "viewer"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: "123e4567-e89b-12d3-a456-426614174000"}, {Key: "user_id", Value: strings.Repeat("a", 256)}}
+	c.Set("user_id", "1")
+
+	// Act
+	handler.UpdateCollaborator(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "UpdateCollaborator", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandler_RemoveCollaborator_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	folderID := "123e4567-e89b-12d3-a456-426614174000"
+	c.Params = gin.Params{{Key: "id", Value: folderID}, {Key: "user_id", Value: "2"}}
+	c.Set("user_id", "1")
+
+	mockService.On("RemoveCollaborator", folderID, "2", "1").Return(nil)
+
+	// Act
+	handler.RemoveCollaborator(c)
+	c.Writer.WriteHeaderNow()
+
+	// Assert
+	require.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_RemoveCollaborator_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, _ := test.SetupContext()
+
+	folderID := "123e4567-e89b-12d3-a456-426614174000"
+	c.Params = gin.Params{{Key: "id", Value: folderID}, {Key: "user_id", Value: "2"}}
+	c.Set("user_id", "1")
+
+	mockService.On("RemoveCollaborator", folderID, "2", "1").Return(assert.AnError)
+
+	// Act
+	handler.RemoveCollaborator(c)
+
+	// Assert
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, assert.AnError, c.Errors[0].Err)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetSharedWithMe_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	expected := []SharedFolder{
+		{BaseURL: BaseURL{ID: "folder-id", Name: "team", Type: "folder"}, OwnerID: "1", Role: RoleEditor},
+	}
+	c.Set("user_id", "2")
+
+	mockService.On("GetSharedWithMe", "2").Return(expected, nil)
+
+	// Act
+	handler.GetSharedWithMe(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response []SharedFolder
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, expected, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_GetSharedWithMe_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Set("user_id", "2")
+
+	mockService.On("GetSharedWithMe", "2").Return(nil, assert.AnError)
+
+	// Act
+	handler.GetSharedWithMe(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, assert.AnError, c.Errors[0].Err)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_MountFolder_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	folderID := "123e4567-e89b-12d3-a456-426614174000"
+	requestBody := MountRequestBody{ParentID: "123e4567-e89b-12d3-a456-426614174001"}
+	requestJSON, _ := json.Marshal(requestBody)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: folderID}}
+	c.Set("user_id", "2")
+	c.Set("request_id", "request-id")
+
+	expected := &BaseURL{ID: "mount-id", Name: "team", Type: "mount", TargetID: &folderID}
+	mockService.On("MountFolder", folderID, &requestBody, "2", "request-id").Return(expected, nil)
+
+	// Act
+	handler.MountFolder(c)
+
+	// Assert
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var response BaseURL
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expected, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_MountFolder_InvalidBody(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Request.Body = io.NopCloser(bytes.NewBufferString(`{"parent_id": "not-a-uuid"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: "123e4567-e89b-12d3-a456-426614174000"}}
+	c.Set("user_id", "2")
+
+	// Act
+	handler.MountFolder(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "MountFolder", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandler_GetHistory_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	urlID := "123e4567-e89b-12d3-a456-426614174000"
+	expected := []audit.Entry{
+		{ID: "log-id", NodeID: urlID, OwnerID: "1", ActorID: "1", Action: audit.ActionCreate, After: []byte(`{"name":"a"}`)},
+	}
+	c.Params = gin.Params{{Key: "id", Value: urlID}}
+	c.Set("user_id", "1")
+
+	mockService.On("GetHistory", urlID, "1").Return(expected, nil)
+
+	// Act
+	handler.GetHistory(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response []audit.Entry
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	require.Len(t, response, 1)
+	assert.Equal(t, audit.ActionCreate, response[0].Action)
+	assert.JSONEq(t, `{"name":"a"}`, string(response[0].After))
+	mockService.AssertExpectations(t)
+}
+func TestHandler_GetHistory_InvalidURI(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Params = gin.Params{{Key: "id", Value: "invalid-uuid"}}
+	c.Set("user_id", "1")
+
+	// Act
+	handler.GetHistory(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "GetHistory", mock.Anything, mock.Anything)
+}
+
+func TestHandler_GetRevisions_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	urlID := "123e4567-e89b-12d3-a456-426614174000"
+	expected := []Revision{
+		{Revision: 1, ActorID: "1", Node: []byte(`{"name":"a"}`), CreatedAt: "2024-01-01T00:00:00Z"},
+	}
+	c.Params = gin.Params{{Key: "id", Value: urlID}}
+	c.Set("user_id", "1")
+
+	mockService.On("GetRevisions", urlID, "1").Return(expected, nil)
+
+	// Act
+	handler.GetRevisions(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response []Revision
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	require.Len(t, response, 1)
+	assert.Equal(t, 1, response[0].Revision)
+	assert.JSONEq(t, `{"name":"a"}`, string(response[0].Node))
+	mockService.AssertExpectations(t)
+}
+func TestHandler_GetRevisions_InvalidURI(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Params = gin.Params{{Key: "id", Value: "invalid-uuid"}}
+	c.Set("user_id", "1")
+
+	// Act
+	handler.GetRevisions(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "GetRevisions", mock.Anything, mock.Anything)
+}
+
+func TestHandler_RevertURL_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	urlID := "123e4567-e89b-12d3-a456-426614174000"
+	c.Params = gin.Params{{Key: "id", Value: urlID}, {Key: "revision", Value: "3"}}
+	c.Set("user_id", "1")
+	c.Set("request_id", "request-id")
+
+	mockService.On("RevertURL", urlID, 3, "1", "request-id").Return(nil)
+
+	// Act
+	handler.RevertURL(c)
+	c.Writer.WriteHeaderNow()
+
+	// Assert
+	require.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_RevertURL_InvalidURI(t *testing.T) {
+	tests := []struct {
+		name   string
+		params gin.Params
+	}{
+		{
+			name:   "invalid id",
+			params: gin.Params{{Key: "id", Value: "invalid-uuid"}, {Key: "revision", Value: "1"}},
+		},
+		{
+			name:   "invalid revision",
+			params: gin.Params{{Key: "id", Value: "123e4567-e89b-12d3-a456-426614174000"}, {Key: "revision", Value: "0"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := &MockService{}
+			handler := NewHandler(mockService)
+			c, w := test.SetupContext()
+
+			c.Params = tt.params
+			c.Set("user_id", "1")
+
+			// Act
+			handler.RevertURL(c)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			mockService.AssertNotCalled(t, "RevertURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandler_Undo_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	undoneAt := "2024-01-01T00:01:00Z"
+	expected := &OperationResponse{
+		ID:        "operation-id",
+		Changes:   []ChangeSummary{{NodeID: "node-id", Action: audit.ActionDelete}},
+		CreatedAt: "2024-01-01T00:00:00Z",
+		UndoneAt:  &undoneAt,
+	}
+	c.Set("user_id", "1")
+	c.Set("request_id", "request-id")
+
+	mockService.On("Undo", "1", "request-id").Return(expected, nil)
+
+	// Act
+	handler.Undo(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response OperationResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expected, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_Undo_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Set("user_id", "1")
+	c.Set("request_id", "request-id")
+
+	mockService.On("Undo", "1", "request-id").Return(nil, assert.AnError)
+
+	// Act
+	handler.Undo(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, assert.AnError, c.Errors[0].Err)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_Redo_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	expected := &OperationResponse{
+		ID:        "operation-id",
+		Changes:   []ChangeSummary{{NodeID: "node-id", Action: audit.ActionDelete}},
+		CreatedAt: "2024-01-01T00:00:00Z",
+	}
+	c.Set("user_id", "1")
+	c.Set("request_id", "request-id")
+
+	mockService.On("Redo", "1", "request-id").Return(expected, nil)
+
+	// Act
+	handler.Redo(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response OperationResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expected, response)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetChanges_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	expected := &ChangesResponse{
+		Changes:   []SyncChange{{NodeID: "node-id", Action: audit.ActionDelete}},
+		NextToken: "Mw",
+	}
+	c.Request = httptest.NewRequest("GET", "/?since=Mg&limit=10", nil)
+	c.Set("user_id", "1")
+
+	mockService.On("GetChanges", "Mg", 10, "1").Return(expected, nil)
+
+	// Act
+	handler.GetChanges(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response ChangesResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expected, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_GetChanges_InvalidQuery(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Request = httptest.NewRequest("GET", "/?limit=5000", nil)
+	c.Set("user_id", "1")
+
+	// Act
+	handler.GetChanges(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "GetChanges", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandler_StreamEvents_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Set("user_id", "1")
+
+	events := make(chan Event, 1)
+	events <- Event{UserID: "1", Seq: 3, NodeID: "node-id", Action: audit.ActionDelete}
+	close(events)
+	unsubscribed := false
+	mockService.On("SubscribeEvents", "1").Return((<-chan Event)(events), func() { unsubscribed = true })
+
+	// Act
+	handler.StreamEvents(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "id:"+encodeSyncToken(3)+"\nevent:change\ndata:{\"node_id\":\"node-id\",\"action\":\"delete\"}\n\n", w.Body.String())
+	assert.True(t, unsubscribed)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_StreamEvents_ClientDisconnected(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Request = httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	c.Set("user_id", "1")
+
+	unsubscribed := false
+	mockService.On("SubscribeEvents", "1").Return((<-chan Event)(make(chan Event)), func() { unsubscribed = true })
+
+	// Act
+	handler.StreamEvents(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.True(t, unsubscribed)
+}
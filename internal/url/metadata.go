@@ -0,0 +1,166 @@
+package url
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/outbound"
+
+	"golang.org/x/net/html"
+)
+
+const maxMetadataBodySize = 1 << 20
+
+type Metadata struct {
+	Title       *string
+	Description *string
+	ImageURL    *string
+	FaviconURL  *string
+}
+
+// MetadataFetcher fetches descriptive metadata of a web page so that saved
+// URLs can be shown with their title and icon.
+type MetadataFetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*Metadata, error)
+}
+
+type httpMetadataFetcher struct {
+	client *http.Client
+}
+
+// NewMetadataFetcher returns nil when metadata fetching is disabled. Pages at
+// internal addresses are not fetched, since their title would be shown to the
+// user.
+func NewMetadataFetcher(config *config.Config) MetadataFetcher {
+	if !config.MetadataFetchEnabled {
+		return nil
+	}
+	return &httpMetadataFetcher{
+		client: outbound.NewClient(config.MetadataFetchTimeout, config.OutboundAllowedNetworks),
+	}
+}
+
+func (f *httpMetadataFetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", crawlerUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, errors.New("unexpected status | status: " + strconv.Itoa(resp.StatusCode))
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, errors.New("unsupported content type | type: " + mediaType)
+	}
+
+	return parseMetadata(io.LimitReader(resp.Body, maxMetadataBodySize), resp.Request.URL), nil
+}
+
+// parseMetadata reads the document head and extracts the title, OpenGraph
+// description and image, and the favicon. Relative links are resolved
+// against base.
+func parseMetadata(r io.Reader, base *neturl.URL) *Metadata {
+	metadata := &Metadata{}
+	var title, description, ogTitle, ogDescription, ogImage, icon string
+
+	tokenizer := html.NewTokenizer(r)
+	inTitle := false
+parse:
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+
+		token := tokenizer.Token()
+		if tokenType == html.EndTagToken {
+			if token.Data == "title" {
+				inTitle = false
+			}
+			if token.Data == "head" {
+				break parse
+			}
+			continue
+		}
+		if tokenType == html.TextToken && inTitle {
+			title += token.Data
+			continue
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		attrs := map[string]string{}
+		for _, attr := range token.Attr {
+			attrs[strings.ToLower(attr.Key)] = attr.Val
+		}
+		switch token.Data {
+		case "body":
+			break parse
+		case "title":
+			inTitle = tokenType == html.StartTagToken
+		case "meta":
+			switch strings.ToLower(attrs["property"] + attrs["name"]) {
+			case "og:title":
+				ogTitle = attrs["content"]
+			case "og:description":
+				ogDescription = attrs["content"]
+			case "og:image":
+				ogImage = attrs["content"]
+			case "description":
+				description = attrs["content"]
+			}
+		case "link":
+			for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+				if rel == "icon" && (icon == "" || attrs["sizes"] == "") {
+					icon = attrs["href"]
+				}
+			}
+		}
+	}
+
+	metadata.Title = firstNonEmpty(title, ogTitle)
+	metadata.Description = firstNonEmpty(ogDescription, description)
+	metadata.ImageURL = resolveReference(base, ogImage)
+	metadata.FaviconURL = resolveReference(base, icon)
+	return metadata
+}
+
+func firstNonEmpty(values ...string) *string {
+	for _, value := range values {
+		value = strings.Join(strings.Fields(value), " ")
+		if value != "" {
+			return &value
+		}
+	}
+	return nil
+}
+
+func resolveReference(base *neturl.URL, ref string) *string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil
+	}
+	u, err := neturl.Parse(ref)
+	if err != nil {
+		return nil
+	}
+	resolved := base.ResolveReference(u).String()
+	return &resolved
+}
@@ -0,0 +1,169 @@
+package url
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	neturl "net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/outbound"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPageServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html>
+<html>
+<head>
+	<title>
+		Example   Page
+	</title>
+	<meta name="description" content="Plain description">
+	<meta property="og:description" content="OpenGraph description">
+	<meta property="og:image" content="/images/og.png">
+	<link rel="shortcut icon" href="/favicon.png">
+</head>
+<body><title>Not the title</title></body>
+</html>`))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte{0x89, 0x50, 0x4e, 0x47})
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	return httptest.NewServer(mux)
+}
+
+func newTestMetadataFetcher() MetadataFetcher {
+	return NewMetadataFetcher(&config.Config{
+		MetadataFetchEnabled: true,
+		MetadataFetchTimeout: time.Second,
+		// The test server listens on the loopback address.
+		OutboundAllowedNetworks: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")},
+	})
+}
+
+func TestMetadataFetcher_NewMetadataFetcher(t *testing.T) {
+	// Act
+	enabled := NewMetadataFetcher(&config.Config{MetadataFetchEnabled: true, MetadataFetchTimeout: time.Second})
+	disabled := NewMetadataFetcher(&config.Config{MetadataFetchEnabled: false})
+
+	// Assert
+	assert.IsType(t, &httpMetadataFetcher{}, enabled)
+	assert.Nil(t, disabled)
+}
+
+func TestMetadataFetcher_Fetch_Success(t *testing.T) {
+	// Arrange
+	server := setupPageServer()
+	defer server.Close()
+	fetcher := newTestMetadataFetcher()
+
+	// Act
+	metadata, err := fetcher.Fetch(context.Background(), server.URL+"/page")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "Example Page", *metadata.Title)
+	assert.Equal(t, "OpenGraph description", *metadata.Description)
+	assert.Equal(t, server.URL+"/images/og.png", *metadata.ImageURL)
+	assert.Equal(t, server.URL+"/favicon.png", *metadata.FaviconURL)
+}
+func TestMetadataFetcher_Fetch_Error(t *testing.T) {
+	server := setupPageServer()
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		rawURL string
+	}{
+		{name: "not html", rawURL: server.URL + "/image"},
+		{name: "error status", rawURL: server.URL + "/missing"},
+		{name: "unreachable", rawURL: "http://127.0.0.1:1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			fetcher := newTestMetadataFetcher()
+
+			// Act
+			metadata, err := fetcher.Fetch(context.Background(), tt.rawURL)
+
+			// Assert
+			assert.Error(t, err)
+			assert.Nil(t, metadata)
+		})
+	}
+}
+func TestMetadataFetcher_Fetch_InternalAddress(t *testing.T) {
+	// Arrange
+	server := setupPageServer()
+	defer server.Close()
+	fetcher := NewMetadataFetcher(&config.Config{MetadataFetchEnabled: true, MetadataFetchTimeout: time.Second})
+
+	// Act
+	metadata, err := fetcher.Fetch(context.Background(), server.URL+"/page")
+
+	// Assert
+	require.ErrorIs(t, err, outbound.ErrAddressNotAllowed)
+	assert.Nil(t, metadata)
+}
+
+func TestMetadataFetcher_parseMetadata(t *testing.T) {
+	base, _ := neturl.Parse("https://example.com/dir/page")
+
+	tests := []struct {
+		name     string
+		document string
+		expected *Metadata
+	}{
+		{
+			name:     "empty document",
+			document: "",
+			expected: &Metadata{},
+		},
+		{
+			name:     "opengraph title fallback",
+			document: `<head><meta property="og:title" content="OG Title"></head>`,
+			expected: &Metadata{Title: test.StringPtr("OG Title")},
+		},
+		{
+			name:     "plain description fallback",
+			document: `<head><meta name="description" content="Plain"></head>`,
+			expected: &Metadata{Description: test.StringPtr("Plain")},
+		},
+		{
+			name:     "relative icon",
+			document: `<head><link rel="icon" href="icon.svg"></head>`,
+			expected: &Metadata{FaviconURL: test.StringPtr("https://example.com/dir/icon.svg")},
+		},
+		{
+			name:     "absolute image",
+			document: `<head><meta property="og:image" content="https://cdn.example.com/a.png"></head>`,
+			expected: &Metadata{ImageURL: test.StringPtr("https://cdn.example.com/a.png")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			metadata := parseMetadata(strings.NewReader(tt.document), base)
+
+			// Assert
+			assert.Equal(t, tt.expected, metadata)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package url
+
+import (
+	"errors"
+	neturl "net/url"
+	"strings"
+)
+
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// NormalizeURL returns the canonical form of raw used to detect the same link
+// saved under different spellings. Scheme and host are lowercased, default
+// ports and trailing slashes are removed, tracking parameters (utm_* and
+// friends) are stripped and the remaining query parameters are sorted.
+func NormalizeURL(raw string) (string, error) {
+	u, err := neturl.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", errors.New("url must be absolute | url: " + raw)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+		host = host + ":" + port
+	}
+	u.Host = host
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+
+	query := u.Query()
+	for key := range query {
+		lowerKey := strings.ToLower(key)
+		if strings.HasPrefix(lowerKey, "utm_") || trackingParams[lowerKey] {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+func normalizedURLOf(node *URLNode) *string {
+	if node.Type != "url" || node.URL == nil {
+		return nil
+	}
+	normalized, err := NormalizeURL(*node.URL)
+	if err != nil {
+		return nil
+	}
+	return &normalized
+}
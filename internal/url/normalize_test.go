@@ -0,0 +1,156 @@
+package url
+
+import (
+	"testing"
+
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeURL_Success(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+	}{
+		{
+			name:     "already normalized",
+			raw:      "https://example.com/path",
+			expected: "https://example.com/path",
+		},
+		{
+			name:     "uppercase scheme and host",
+			raw:      "HTTPS://Example.COM/Path",
+			expected: "https://example.com/Path",
+		},
+		{
+			name:     "default http port",
+			raw:      "http://example.com:80/path",
+			expected: "http://example.com/path",
+		},
+		{
+			name:     "default https port",
+			raw:      "https://example.com:443/path",
+			expected: "https://example.com/path",
+		},
+		{
+			name:     "non-default port",
+			raw:      "https://example.com:8443/path",
+			expected: "https://example.com:8443/path",
+		},
+		{
+			name:     "trailing slash",
+			raw:      "https://example.com/path/",
+			expected: "https://example.com/path",
+		},
+		{
+			name:     "root path",
+			raw:      "https://example.com/",
+			expected: "https://example.com",
+		},
+		{
+			name:     "tracking parameters",
+			raw:      "https://example.com/path?utm_source=a&UTM_Medium=b&fbclid=c&gclid=d&id=1",
+			expected: "https://example.com/path?id=1",
+		},
+		{
+			name:     "only tracking parameters",
+			raw:      "https://example.com/path?utm_source=a",
+			expected: "https://example.com/path",
+		},
+		{
+			name:     "sorted query parameters",
+			raw:      "https://example.com/path?b=2&a=1",
+			expected: "https://example.com/path?a=1&b=2",
+		},
+		{
+			name:     "fragment is kept",
+			raw:      "https://example.com/#/page",
+			expected: "https://example.com#/page",
+		},
+		{
+			name:     "ipv6 host",
+			raw:      "http://[::1]:80/path",
+			expected: "http://[::1]/path",
+		},
+		{
+			name:     "surrounding whitespace",
+			raw:      "  https://example.com/path  ",
+			expected: "https://example.com/path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			normalized, err := NormalizeURL(tt.raw)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+}
+func TestNormalizeURL_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{name: "empty", raw: ""},
+		{name: "relative", raw: "/path"},
+		{name: "missing scheme", raw: "example.com/path"},
+		{name: "malformed", raw: "http://%zz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			normalized, err := NormalizeURL(tt.raw)
+
+			// Assert
+			assert.Error(t, err)
+			assert.Empty(t, normalized)
+		})
+	}
+}
+
+func TestNormalizeURL_normalizedURLOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     *URLNode
+		expected *string
+	}{
+		{
+			name:     "url node",
+			node:     &URLNode{Type: "url", URL: test.StringPtr("HTTPS://example.com/")},
+			expected: test.StringPtr("https://example.com"),
+		},
+		{
+			name:     "folder node",
+			node:     &URLNode{Type: "folder"},
+			expected: nil,
+		},
+		{
+			name:     "url node without url",
+			node:     &URLNode{Type: "url"},
+			expected: nil,
+		},
+		{
+			name:     "url node with invalid url",
+			node:     &URLNode{Type: "url", URL: test.StringPtr("not a url")},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			normalized := normalizedURLOf(tt.node)
+
+			// Assert
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+}
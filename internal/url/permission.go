@@ -0,0 +1,117 @@
+package url
+
+import (
+	"github.com/vera/vera-drive-service/internal/apperror"
+)
+
+// Role is the access level a collaborator has on a folder and everything
+// below it. The owner of a tree implicitly has RoleOwner on all its nodes.
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleOwner  Role = "owner"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// Includes reports whether r grants at least the access of required.
+func (r Role) Includes(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+// Authorizer checks whether a user may access a node with a given role. It
+// is used by other packages that act on nodes of the url tree.
+type Authorizer interface {
+	Authorize(nodeID string, userID string, required Role) error
+}
+
+type authorizer struct {
+	repo Repository
+}
+
+func NewAuthorizer(repo Repository) Authorizer {
+	return &authorizer{repo: repo}
+}
+
+func (a *authorizer) Authorize(nodeID string, userID string, required Role) error {
+	return authorize(a.repo, nodeID, userID, required)
+}
+
+func authorize(repo Repository, nodeID string, userID string, required Role) error {
+	node, err := repo.GetOne(nodeID)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return apperror.New(apperror.CodeURLNotFound, "URL not found | id: "+nodeID)
+	}
+	if node.UserID == userID {
+		return nil
+	}
+
+	role, err := grantedRole(repo, nodeID, userID)
+	if err != nil {
+		return err
+	}
+	if !role.Includes(required) {
+		return apperror.New(
+			apperror.CodeURLAccessDenied, "Access denied | userID: "+userID+", nodeID: "+nodeID+", role: "+string(required))
+	}
+	return nil
+}
+
+// grantedRole returns the strongest role granted to userID on the node or
+// any of its ancestors, or an empty role when nothing was granted.
+func grantedRole(repo Repository, nodeID string, userID string) (Role, error) {
+	ancestors, err := repo.GetParentUpToRoot(nodeID)
+	if err != nil {
+		return "", err
+	}
+	nodeIDs := []string{nodeID}
+	for _, ancestor := range ancestors {
+		nodeIDs = append(nodeIDs, ancestor.ID)
+	}
+
+	permissions, err := repo.GetPermissions(nodeIDs, userID)
+	if err != nil {
+		return "", err
+	}
+	var role Role
+	for _, permission := range permissions {
+		if roleRanks[permission.Role] > roleRanks[role] {
+			role = permission.Role
+		}
+	}
+	return role, nil
+}
+
+// sharedParents trims the ancestors of a node in another user's tree so that
+// they start at the topmost folder shared with userID. Collaborators never
+// see where a shared folder lives in the owner's tree.
+func sharedParents(repo Repository, nodeID string, parents []URLNode, userID string) ([]URLNode, error) {
+	nodeIDs := []string{nodeID}
+	for _, parent := range parents {
+		nodeIDs = append(nodeIDs, parent.ID)
+	}
+	permissions, err := repo.GetPermissions(nodeIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	granted := map[string]bool{}
+	for _, permission := range permissions {
+		granted[permission.FolderID] = true
+	}
+
+	for i, parent := range parents {
+		if granted[parent.ID] {
+			return parents[i:], nil
+		}
+	}
+	return []URLNode{}, nil
+}
@@ -3,22 +3,151 @@ package url
 import (
 	"time"
 
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/outbox"
+	"github.com/vera/vera-drive-service/internal/webhook"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type URLNode struct {
+	ID             string     `gorm:"type:uuid;primary_key"`
+	UserID         string     `gorm:"type:varchar(255);index;index:idx_url_nodes_user_id_normalized_url,priority:1,where:deleted_at IS NULL"`
+	ParentID       *string    `gorm:"type:uuid;index"`
+	Parent         *URLNode   `gorm:"foreignKey:ParentID"`
+	Children       []URLNode  `gorm:"foreignKey:ParentID"`
+	Name           string     `gorm:"type:varchar(255);not null"`
+	Type           string     `gorm:"type:varchar(10);not null;check:type IN ('folder','url','mount')"`
+	URL            *string    `gorm:"type:text"`
+	TargetID       *string    `gorm:"type:uuid;index"`
+	NormalizedURL  *string    `gorm:"type:text;index:idx_url_nodes_user_id_normalized_url,priority:2"`
+	Title          *string    `gorm:"type:text"`
+	Description    *string    `gorm:"type:text"`
+	ImageURL       *string    `gorm:"type:text"`
+	FaviconURL     *string    `gorm:"type:text"`
+	LastStatusCode *int       `gorm:"type:int"`
+	LastCheckError *string    `gorm:"type:text"`
+	RedirectURL    *string    `gorm:"type:text"`
+	LastCheckedAt  *time.Time `gorm:"type:timestamptz;index"`
+	CreatedAt      time.Time  `gorm:"type:timestamptz;not null"`
+	UpdatedAt      time.Time  `gorm:"type:timestamptz;not null"`
+	DeletedAt      *time.Time `gorm:"type:timestamptz;index"`
+}
+
+// FolderPermission grants a user other than the owner a role on a folder
+// and, by inheritance, on everything below it.
+type FolderPermission struct {
+	ID        string    `gorm:"type:uuid;primary_key"`
+	FolderID  string    `gorm:"type:uuid;not null;uniqueIndex:idx_folder_permissions_folder_id_user_id"`
+	UserID    string    `gorm:"type:varchar(255);not null;index;uniqueIndex:idx_folder_permissions_folder_id_user_id"`
+	Role      Role      `gorm:"type:varchar(10);not null;check:role IN ('viewer','editor','owner')"`
+	CreatedAt time.Time `gorm:"type:timestamptz;not null"`
+	UpdatedAt time.Time `gorm:"type:timestamptz;not null"`
+}
+
+func (FolderPermission) TableName() string {
+	return "folder_permissions"
+}
+
+func (p *FolderPermission) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+	p.CreatedAt = time.Now().UTC()
+	p.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func (p *FolderPermission) BeforeUpdate(tx *gorm.DB) error {
+	p.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// NodeRevision is the state of a node after a change. Revisions are numbered
+// per node starting at 1.
+type NodeRevision struct {
+	ID        string    `gorm:"type:uuid;primary_key"`
+	NodeID    string    `gorm:"type:uuid;not null;uniqueIndex:idx_node_revisions_node_id_revision"`
+	Revision  int       `gorm:"type:int;not null;uniqueIndex:idx_node_revisions_node_id_revision"`
+	ActorID   string    `gorm:"type:varchar(255);not null"`
+	Snapshot  string    `gorm:"type:jsonb;not null"`
+	CreatedAt time.Time `gorm:"type:timestamptz;not null;index"`
+}
+
+func (NodeRevision) TableName() string {
+	return "node_revisions"
+}
+
+func (r *NodeRevision) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	r.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// Operation is a change made by a user in one request, which can be undone
+// and redone as a whole. Changes holds a JSON array of OperationChange.
+type Operation struct {
 	ID        string     `gorm:"type:uuid;primary_key"`
-	UserID    int        `gorm:"type:int;index"`
-	ParentID  *string    `gorm:"type:uuid;index"`
-	Parent    *URLNode   `gorm:"foreignKey:ParentID"`
-	Children  []URLNode  `gorm:"foreignKey:ParentID"`
-	Name      string     `gorm:"type:varchar(255);not null"`
-	Type      string     `gorm:"type:varchar(10);not null;check:type IN ('folder','url')"`
-	URL       *string    `gorm:"type:text"`
-	CreatedAt time.Time  `gorm:"type:timestamptz;not null"`
-	UpdatedAt time.Time  `gorm:"type:timestamptz;not null"`
-	DeletedAt *time.Time `gorm:"type:timestamptz;index"`
+	UserID    string     `gorm:"type:varchar(255);not null;index"`
+	Changes   string     `gorm:"type:jsonb;not null"`
+	UndoneAt  *time.Time `gorm:"type:timestamptz"`
+	CreatedAt time.Time  `gorm:"type:timestamptz;not null;index"`
+}
+
+func (Operation) TableName() string {
+	return "operations"
+}
+
+func (o *Operation) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == "" {
+		o.ID = uuid.New().String()
+	}
+	o.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// NodeChange is an entry in the change feed of a tree. Seq increases by one
+// with every change in the tree of the user, in commit order.
+type NodeChange struct {
+	UserID    string       `gorm:"type:varchar(255);primary_key;autoIncrement:false"`
+	Seq       int64        `gorm:"type:bigint;primary_key;autoIncrement:false"`
+	NodeID    string       `gorm:"type:uuid;not null;index"`
+	Action    audit.Action `gorm:"type:varchar(10);not null;check:action IN ('create','update','move','delete','restore','purge')"`
+	CreatedAt time.Time    `gorm:"type:timestamptz;not null"`
+}
+
+func (NodeChange) TableName() string {
+	return "node_changes"
+}
+
+func (c *NodeChange) BeforeCreate(tx *gorm.DB) error {
+	c.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// ChangeSequence holds the last change feed sequence number of a user. The
+// row is locked while a change is recorded, so that sequence numbers are
+// committed in order.
+type ChangeSequence struct {
+	UserID  string `gorm:"type:varchar(255);primary_key;autoIncrement:false"`
+	LastSeq int64  `gorm:"type:bigint;not null"`
+}
+
+func (ChangeSequence) TableName() string {
+	return "change_sequences"
+}
+
+// LinkStatus is the outcome of checking whether a URL is still reachable.
+type LinkStatus struct {
+	StatusCode  *int
+	Error       *string
+	RedirectURL *string
+	CheckedAt   time.Time
 }
 
 func (URLNode) TableName() string {
@@ -40,13 +169,51 @@ func (u *URLNode) BeforeUpdate(tx *gorm.DB) error {
 }
 
 type Repository interface {
+	Transaction(fn func(repo Repository) error) error
 	Create(node *URLNode) error
-	GetRoot(userID int) (*URLNode, error)
+	GetRoot(userID string) (*URLNode, error)
 	GetOne(id string) (*URLNode, error)
+	GetDeleted(id string) (*URLNode, error)
+	GetByIDs(ids []string) ([]URLNode, error)
 	GetParentUpToRoot(id string) ([]URLNode, error)
 	GetChildren(id string) ([]URLNode, error)
+	GetSubtree(id string) ([]URLNode, error)
+	GetByNormalizedURL(userID string, normalizedURL string) ([]URLNode, error)
+	GetByNormalizedURLs(userID string, normalizedURLs []string) ([]URLNode, error)
+	GetDuplicateNormalizedURLs(userID string) ([]string, error)
+	GetUnnormalized(afterID string, limit int) ([]URLNode, error)
+	SetNormalizedURL(id string, normalizedURL string) error
+	GetDueForCheck(checkedBefore time.Time, limit int) ([]URLNode, error)
+	GetBroken(userID string) ([]URLNode, error)
+	UpdateLinkStatus(id string, status *LinkStatus) error
 	Update(node *URLNode) error
 	SoftDelete(id string) error
+	Restore(id string) error
+	GetPermissions(nodeIDs []string, userID string) ([]FolderPermission, error)
+	GetPermission(folderID string, userID string) (*FolderPermission, error)
+	GetCollaborators(folderID string) ([]FolderPermission, error)
+	GetPermissionsByUser(userID string) ([]FolderPermission, error)
+	CreatePermission(permission *FolderPermission) error
+	UpdatePermission(permission *FolderPermission) error
+	DeletePermission(folderID string, userID string) error
+	CreateAuditLog(entry *audit.AuditLog) error
+	GetAuditLogs(nodeID string) ([]audit.AuditLog, error)
+	CreateRevision(revision *NodeRevision) error
+	GetRevisions(nodeID string) ([]NodeRevision, error)
+	GetRevision(nodeID string, revision int) (*NodeRevision, error)
+	PruneRevisions(createdBefore time.Time, keepLatest int) (int64, error)
+	CreateOperation(operation *Operation) error
+	GetLastOperation(userID string, createdAfter time.Time) (*Operation, error)
+	GetLastUndoneOperation(userID string, undoneAfter time.Time) (*Operation, error)
+	UpdateOperation(operation *Operation) error
+	DeleteStaleOperations(userID string, createdBefore time.Time) error
+	NextChangeSeq(userID string) (int64, error)
+	GetLastChangeSeq(userID string) (int64, error)
+	CreateNodeChange(nodeChange *NodeChange) error
+	GetNodeChanges(userID string, afterSeq int64, limit int) ([]NodeChange, error)
+	GetWebhooks(userID string) ([]webhook.Webhook, error)
+	CreateWebhookDelivery(delivery *webhook.Delivery) error
+	CreateOutboxEvent(event *outbox.OutboxEvent) error
 }
 
 type repository struct {
@@ -57,11 +224,17 @@ func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
 
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
 func (r *repository) Create(node *URLNode) error {
 	return r.db.Create(node).Error
 }
 
-func (r *repository) GetRoot(userID int) (*URLNode, error) {
+func (r *repository) GetRoot(userID string) (*URLNode, error) {
 	var root URLNode
 	err := r.db.Where("parent_id IS NULL AND deleted_at IS NULL AND user_id = ?", userID).First(&root).Error
 	if err == gorm.ErrRecordNotFound {
@@ -85,6 +258,29 @@ func (r *repository) GetOne(id string) (*URLNode, error) {
 	return &node, nil
 }
 
+// GetDeleted returns the node with the given id only if it is soft-deleted.
+func (r *repository) GetDeleted(id string) (*URLNode, error) {
+	var node URLNode
+	err := r.db.Where("id = ? AND deleted_at IS NOT NULL", id).First(&node).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// GetByIDs returns the nodes with the given ids, including soft-deleted ones.
+func (r *repository) GetByIDs(ids []string) ([]URLNode, error) {
+	var nodes []URLNode
+	err := r.db.Where("id IN ?", ids).Find(&nodes).Error
+	return nodes, err
+}
+
+// GetParentUpToRoot returns the ancestors of the node, root first. Deleting a
+// folder only marks the folder itself, so a node below a deleted folder is
+// reported as not found.
 func (r *repository) GetParentUpToRoot(id string) ([]URLNode, error) {
 	var parents []URLNode
 	current, err := r.GetOne(id)
@@ -97,6 +293,9 @@ func (r *repository) GetParentUpToRoot(id string) ([]URLNode, error) {
 		if err != nil {
 			return nil, err
 		}
+		if parent == nil {
+			return nil, apperror.New(apperror.CodeURLNotFound, "URL is in the trash | id: "+id)
+		}
 
 		parents = append([]URLNode{*parent}, parents...)
 		current = parent
@@ -111,6 +310,144 @@ func (r *repository) GetChildren(id string) ([]URLNode, error) {
 	return children, err
 }
 
+// GetSubtree returns the node with the given id and all of its descendants.
+// Soft-deleted nodes and everything below them are left out, so nothing is
+// returned when the node or one of its ancestors is in the trash.
+func (r *repository) GetSubtree(id string) ([]URLNode, error) {
+	var nodes []URLNode
+	err := r.db.Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, deleted_at FROM url_nodes WHERE id = ?
+			UNION ALL
+			SELECT n.id, n.parent_id, n.deleted_at FROM url_nodes n JOIN ancestors a ON n.id = a.parent_id
+		),
+		subtree AS (
+			SELECT * FROM url_nodes
+			WHERE id = ? AND NOT EXISTS (SELECT 1 FROM ancestors WHERE deleted_at IS NOT NULL)
+			UNION ALL
+			SELECT n.* FROM url_nodes n JOIN subtree s ON n.parent_id = s.id WHERE n.deleted_at IS NULL
+		)
+		SELECT * FROM subtree`, id, id).Scan(&nodes).Error
+	return nodes, err
+}
+
+// GetByNormalizedURL returns the nodes of the user saving the normalized URL,
+// leaving out the trash.
+func (r *repository) GetByNormalizedURL(userID string, normalizedURL string) ([]URLNode, error) {
+	var nodes []URLNode
+	err := r.db.
+		Where("user_id = ? AND normalized_url = ? AND deleted_at IS NULL", userID, normalizedURL).
+		Where("id NOT IN (?)", trashedSubtrees(userID)).
+		Order("created_at").
+		Find(&nodes).Error
+	return nodes, err
+}
+
+// trashedSubtrees selects the ids of the deleted nodes of a user and of
+// everything below them, since deleting a folder only marks the folder
+// itself. An empty userID selects those of all users.
+func trashedSubtrees(userID string) clause.Expr {
+	if userID == "" {
+		return gorm.Expr(`
+			WITH RECURSIVE trashed AS (
+				SELECT id FROM url_nodes WHERE deleted_at IS NOT NULL
+				UNION ALL
+				SELECT n.id FROM url_nodes n JOIN trashed t ON n.parent_id = t.id
+			)
+			SELECT id FROM trashed`)
+	}
+	return gorm.Expr(`
+		WITH RECURSIVE trashed AS (
+			SELECT id FROM url_nodes WHERE user_id = ? AND deleted_at IS NOT NULL
+			UNION ALL
+			SELECT n.id FROM url_nodes n JOIN trashed t ON n.parent_id = t.id
+		)
+		SELECT id FROM trashed`, userID)
+}
+
+// GetByNormalizedURLs returns the nodes of the user saving one of the
+// normalized URLs, leaving out the trash.
+func (r *repository) GetByNormalizedURLs(userID string, normalizedURLs []string) ([]URLNode, error) {
+	var nodes []URLNode
+	err := r.db.
+		Where("user_id = ? AND normalized_url IN ? AND deleted_at IS NULL", userID, normalizedURLs).
+		Where("id NOT IN (?)", trashedSubtrees(userID)).
+		Order("created_at").
+		Find(&nodes).Error
+	return nodes, err
+}
+
+// GetDuplicateNormalizedURLs returns the normalized URLs the user saved more
+// than once outside the trash.
+func (r *repository) GetDuplicateNormalizedURLs(userID string) ([]string, error) {
+	var normalizedURLs []string
+	err := r.db.Model(&URLNode{}).
+		Where("user_id = ? AND normalized_url IS NOT NULL AND deleted_at IS NULL", userID).
+		Where("id NOT IN (?)", trashedSubtrees(userID)).
+		Group("normalized_url").
+		Having("COUNT(*) > 1").
+		Order("normalized_url").
+		Pluck("normalized_url", &normalizedURLs).Error
+	return normalizedURLs, err
+}
+
+// GetUnnormalized returns up to limit URL nodes without a normalized URL,
+// including the trash, with ids after afterID in id order.
+func (r *repository) GetUnnormalized(afterID string, limit int) ([]URLNode, error) {
+	var nodes []URLNode
+	err := r.db.
+		Where("type = 'url' AND url IS NOT NULL AND normalized_url IS NULL AND id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&nodes).Error
+	return nodes, err
+}
+
+// SetNormalizedURL stores the normalized URL of a node without touching
+// updated_at, since the node itself does not change.
+func (r *repository) SetNormalizedURL(id string, normalizedURL string) error {
+	return r.db.Model(&URLNode{}).Where("id = ?", id).UpdateColumn("normalized_url", normalizedURL).Error
+}
+
+// GetDueForCheck returns up to limit URL nodes outside the trash that were
+// never checked or last checked before checkedBefore, least recently checked
+// first.
+func (r *repository) GetDueForCheck(checkedBefore time.Time, limit int) ([]URLNode, error) {
+	var nodes []URLNode
+	err := r.db.
+		Where("type = 'url' AND url IS NOT NULL AND deleted_at IS NULL").
+		Where("id NOT IN (?)", trashedSubtrees("")).
+		Where("last_checked_at IS NULL OR last_checked_at < ?", checkedBefore).
+		Order("last_checked_at NULLS FIRST").
+		Limit(limit).
+		Find(&nodes).Error
+	return nodes, err
+}
+
+// GetBroken returns the URL nodes of the user outside the trash whose last
+// check failed, most recently checked first.
+func (r *repository) GetBroken(userID string) ([]URLNode, error) {
+	var nodes []URLNode
+	err := r.db.
+		Where("user_id = ? AND type = 'url' AND deleted_at IS NULL AND last_checked_at IS NOT NULL", userID).
+		Where("id NOT IN (?)", trashedSubtrees(userID)).
+		Where("last_status_code IS NULL OR last_status_code >= 400").
+		Order("last_checked_at DESC").
+		Find(&nodes).Error
+	return nodes, err
+}
+
+// UpdateLinkStatus stores the result of a link check without touching
+// updated_at, since checking a link is not a change made by the user.
+func (r *repository) UpdateLinkStatus(id string, status *LinkStatus) error {
+	return r.db.Model(&URLNode{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"last_status_code": status.StatusCode,
+		"last_check_error": status.Error,
+		"redirect_url":     status.RedirectURL,
+		"last_checked_at":  status.CheckedAt,
+	}).Error
+}
+
 func (r *repository) Update(node *URLNode) error {
 	return r.db.Save(node).Error
 }
@@ -118,3 +455,198 @@ func (r *repository) Update(node *URLNode) error {
 func (r *repository) SoftDelete(id string) error {
 	return r.db.Model(&URLNode{}).Where("id = ?", id).Update("deleted_at", time.Now()).Error
 }
+
+func (r *repository) Restore(id string) error {
+	return r.db.Model(&URLNode{}).Where("id = ?", id).Update("deleted_at", nil).Error
+}
+
+func (r *repository) GetPermissions(nodeIDs []string, userID string) ([]FolderPermission, error) {
+	var permissions []FolderPermission
+	err := r.db.Where("folder_id IN ? AND user_id = ?", nodeIDs, userID).Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *repository) GetPermission(folderID string, userID string) (*FolderPermission, error) {
+	var permission FolderPermission
+	err := r.db.Where("folder_id = ? AND user_id = ?", folderID, userID).First(&permission).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &permission, nil
+}
+
+func (r *repository) GetCollaborators(folderID string) ([]FolderPermission, error) {
+	var permissions []FolderPermission
+	err := r.db.Where("folder_id = ?", folderID).Order("created_at").Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *repository) GetPermissionsByUser(userID string) ([]FolderPermission, error) {
+	var permissions []FolderPermission
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *repository) CreatePermission(permission *FolderPermission) error {
+	return r.db.Create(permission).Error
+}
+
+func (r *repository) UpdatePermission(permission *FolderPermission) error {
+	return r.db.Save(permission).Error
+}
+
+func (r *repository) DeletePermission(folderID string, userID string) error {
+	return r.db.Where("folder_id = ? AND user_id = ?", folderID, userID).Delete(&FolderPermission{}).Error
+}
+
+func (r *repository) CreateAuditLog(entry *audit.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *repository) GetAuditLogs(nodeID string) ([]audit.AuditLog, error) {
+	var logs []audit.AuditLog
+	err := r.db.Where("node_id = ?", nodeID).Order("created_at DESC").Find(&logs).Error
+	return logs, err
+}
+
+// CreateRevision stores the revision with the next number of its node.
+func (r *repository) CreateRevision(revision *NodeRevision) error {
+	err := r.db.Model(&NodeRevision{}).
+		Select("COALESCE(MAX(revision), 0) + 1").
+		Where("node_id = ?", revision.NodeID).
+		Scan(&revision.Revision).Error
+	if err != nil {
+		return err
+	}
+	return r.db.Create(revision).Error
+}
+
+func (r *repository) GetRevisions(nodeID string) ([]NodeRevision, error) {
+	var revisions []NodeRevision
+	err := r.db.Where("node_id = ?", nodeID).Order("revision DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+func (r *repository) GetRevision(nodeID string, revision int) (*NodeRevision, error) {
+	var nodeRevision NodeRevision
+	err := r.db.Where("node_id = ? AND revision = ?", nodeID, revision).First(&nodeRevision).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &nodeRevision, nil
+}
+
+// PruneRevisions deletes revisions created before createdBefore, except the
+// keepLatest newest revisions of each node, and returns how many were deleted.
+func (r *repository) PruneRevisions(createdBefore time.Time, keepLatest int) (int64, error) {
+	result := r.db.Exec(`
+		DELETE FROM node_revisions r
+		WHERE r.created_at < ?
+		AND r.revision <= (SELECT MAX(latest.revision) FROM node_revisions latest WHERE latest.node_id = r.node_id) - ?`,
+		createdBefore, keepLatest)
+	return result.RowsAffected, result.Error
+}
+
+func (r *repository) CreateOperation(operation *Operation) error {
+	return r.db.Create(operation).Error
+}
+
+// GetLastOperation returns the most recent operation of the user created
+// after createdAfter that has not been undone.
+func (r *repository) GetLastOperation(userID string, createdAfter time.Time) (*Operation, error) {
+	var operation Operation
+	err := r.db.
+		Where("user_id = ? AND undone_at IS NULL AND created_at > ?", userID, createdAfter).
+		Order("created_at DESC").
+		First(&operation).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// GetLastUndoneOperation returns the operation of the user undone most
+// recently, if it was undone after undoneAfter.
+func (r *repository) GetLastUndoneOperation(userID string, undoneAfter time.Time) (*Operation, error) {
+	var operation Operation
+	err := r.db.
+		Where("user_id = ? AND undone_at > ?", userID, undoneAfter).
+		Order("undone_at DESC").
+		First(&operation).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
+
+func (r *repository) UpdateOperation(operation *Operation) error {
+	return r.db.Save(operation).Error
+}
+
+// DeleteStaleOperations deletes the operations of the user that can no longer
+// be undone or redone: those created before createdBefore and those undone.
+func (r *repository) DeleteStaleOperations(userID string, createdBefore time.Time) error {
+	return r.db.
+		Where("user_id = ? AND (undone_at IS NOT NULL OR created_at < ?)", userID, createdBefore).
+		Delete(&Operation{}).Error
+}
+
+// NextChangeSeq increments and returns the change sequence of the user. The
+// sequence row stays locked until the surrounding transaction ends.
+func (r *repository) NextChangeSeq(userID string) (int64, error) {
+	var seq int64
+	err := r.db.Raw(`
+		INSERT INTO change_sequences (user_id, last_seq) VALUES (?, 1)
+		ON CONFLICT (user_id) DO UPDATE SET last_seq = change_sequences.last_seq + 1
+		RETURNING last_seq`, userID).Scan(&seq).Error
+	return seq, err
+}
+
+func (r *repository) GetLastChangeSeq(userID string) (int64, error) {
+	var seq int64
+	err := r.db.Model(&ChangeSequence{}).
+		Select("COALESCE(MAX(last_seq), 0)").
+		Where("user_id = ?", userID).
+		Scan(&seq).Error
+	return seq, err
+}
+
+func (r *repository) CreateNodeChange(nodeChange *NodeChange) error {
+	return r.db.Create(nodeChange).Error
+}
+
+func (r *repository) GetNodeChanges(userID string, afterSeq int64, limit int) ([]NodeChange, error) {
+	var nodeChanges []NodeChange
+	err := r.db.
+		Where("user_id = ? AND seq > ?", userID, afterSeq).
+		Order("seq").
+		Limit(limit).
+		Find(&nodeChanges).Error
+	return nodeChanges, err
+}
+
+func (r *repository) GetWebhooks(userID string) ([]webhook.Webhook, error) {
+	var webhooks []webhook.Webhook
+	err := r.db.Where("user_id = ?", userID).Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *repository) CreateWebhookDelivery(delivery *webhook.Delivery) error {
+	return r.db.Create(delivery).Error
+}
+
+func (r *repository) CreateOutboxEvent(event *outbox.OutboxEvent) error {
+	return r.db.Create(event).Error
+}
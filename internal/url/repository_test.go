@@ -6,8 +6,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/audit"
 	"github.com/vera/vera-drive-service/internal/config"
 	"github.com/vera/vera-drive-service/internal/db"
+	"github.com/vera/vera-drive-service/internal/outbox"
+	"github.com/vera/vera-drive-service/internal/webhook"
 	"github.com/vera/vera-drive-service/test"
 
 	"github.com/google/uuid"
@@ -30,7 +34,7 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 
-	err = d.AutoMigrate(&URLNode{})
+	err = d.AutoMigrate(&URLNode{}, &Favicon{}, &FolderPermission{}, &audit.AuditLog{}, &NodeRevision{}, &Operation{}, &NodeChange{}, &ChangeSequence{}, &webhook.Webhook{}, &webhook.Delivery{}, &outbox.OutboxEvent{})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -63,7 +67,7 @@ func TestRepository_Create_Success(t *testing.T) {
 	require.NoError(t, err)
 	repo := NewRepository(d)
 	node := &URLNode{
-		UserID:   1,
+		UserID:   "1",
 		ParentID: nil,
 		Name:     "name",
 		Type:     "folder",
@@ -76,7 +80,7 @@ func TestRepository_Create_Success(t *testing.T) {
 	// Assert
 	require.NoError(t, err)
 	assert.Equal(t, node.ID, node.ID)
-	assert.Equal(t, 1, node.UserID)
+	assert.Equal(t, "1", node.UserID)
 	assert.Nil(t, node.ParentID)
 	assert.Equal(t, "name", node.Name)
 	assert.Equal(t, "folder", node.Type)
@@ -88,7 +92,7 @@ func TestRepository_Create_Success(t *testing.T) {
 	savedNode, err := repo.GetOne(node.ID)
 	require.NoError(t, err)
 	assert.Equal(t, node.ID, savedNode.ID)
-	assert.Equal(t, 1, savedNode.UserID)
+	assert.Equal(t, "1", savedNode.UserID)
 	assert.Nil(t, savedNode.ParentID)
 	assert.Equal(t, "name", savedNode.Name)
 	assert.Equal(t, "folder", savedNode.Type)
@@ -105,7 +109,7 @@ func TestRepository_Create_SpecificID(t *testing.T) {
 	specificID := uuid.New().String()
 	node := &URLNode{
 		ID:       specificID,
-		UserID:   1,
+		UserID:   "1",
 		ParentID: nil,
 		Name:     "name",
 		Type:     "url",
@@ -132,7 +136,7 @@ func TestRepository_Create_DuplicateID(t *testing.T) {
 	duplicateID := uuid.New().String()
 	node := &URLNode{
 		ID:       duplicateID,
-		UserID:   1,
+		UserID:   "1",
 		ParentID: nil,
 		Name:     "name",
 		Type:     "url",
@@ -156,7 +160,7 @@ func TestRepository_GetRoot_Success(t *testing.T) {
 
 	root := &URLNode{
 		ID:       uuid.New().String(),
-		UserID:   1,
+		UserID:   "1",
 		ParentID: nil,
 		Name:     "root",
 		Type:     "folder",
@@ -165,7 +169,7 @@ func TestRepository_GetRoot_Success(t *testing.T) {
 	require.NoError(t, err)
 
 	// Act
-	result, err := repo.GetRoot(1)
+	result, err := repo.GetRoot("1")
 
 	// Assert
 	require.NoError(t, err)
@@ -187,7 +191,7 @@ func TestRepository_GetRoot_NonExistentUser(t *testing.T) {
 	repo := NewRepository(d)
 
 	// Act
-	result, err := repo.GetRoot(-1)
+	result, err := repo.GetRoot("-1")
 
 	// Assert
 	require.NoError(t, err)
@@ -202,7 +206,7 @@ func TestRepository_GetOne_Success(t *testing.T) {
 
 	node := &URLNode{
 		ID:       uuid.New().String(),
-		UserID:   1,
+		UserID:   "1",
 		ParentID: nil,
 		Name:     "name",
 		Type:     "url",
@@ -250,7 +254,7 @@ func TestRepository_GetOne_FilterSoftDeleted(t *testing.T) {
 
 	node := &URLNode{
 		ID:        uuid.New().String(),
-		UserID:    1,
+		UserID:    "1",
 		ParentID:  nil,
 		Name:      "name",
 		Type:      "url",
@@ -276,7 +280,7 @@ func TestRepository_GetParentUpToRoot_Success(t *testing.T) {
 
 	root := &URLNode{
 		ID:       uuid.New().String(),
-		UserID:   1,
+		UserID:   "1",
 		ParentID: nil,
 		Name:     "Root",
 		Type:     "folder",
@@ -286,7 +290,7 @@ func TestRepository_GetParentUpToRoot_Success(t *testing.T) {
 
 	parent := &URLNode{
 		ID:       uuid.New().String(),
-		UserID:   1,
+		UserID:   "1",
 		ParentID: &root.ID,
 		Name:     "Parent",
 		Type:     "folder",
@@ -296,7 +300,7 @@ func TestRepository_GetParentUpToRoot_Success(t *testing.T) {
 
 	child := &URLNode{
 		ID:       uuid.New().String(),
-		UserID:   1,
+		UserID:   "1",
 		ParentID: &parent.ID,
 		Name:     "Child",
 		Type:     "folder",
@@ -339,7 +343,7 @@ func TestRepository_GetParentUpToRoot_NoParent(t *testing.T) {
 
 	root := &URLNode{
 		ID:       uuid.New().String(),
-		UserID:   1,
+		UserID:   "1",
 		ParentID: nil,
 		Name:     "Root",
 		Type:     "folder",
@@ -377,7 +381,7 @@ func TestRepository_GetParentUpToRoot_FilterSoftDeleted(t *testing.T) {
 
 	parent := &URLNode{
 		ID:     uuid.New().String(),
-		UserID: 1,
+		UserID: "1",
 		Name:   "Parent",
 		Type:   "folder",
 	}
@@ -386,7 +390,7 @@ func TestRepository_GetParentUpToRoot_FilterSoftDeleted(t *testing.T) {
 
 	child := &URLNode{
 		ID:        uuid.New().String(),
-		UserID:    1,
+		UserID:    "1",
 		ParentID:  &parent.ID,
 		Name:      "Child",
 		Type:      "folder",
@@ -402,6 +406,29 @@ func TestRepository_GetParentUpToRoot_FilterSoftDeleted(t *testing.T) {
 	require.NoError(t, err)
 	assert.Empty(t, parents)
 }
+func TestRepository_GetParentUpToRoot_DeletedAncestor(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	deletedAt := time.Now()
+	root := &URLNode{UserID: "1", Name: "Root", Type: "folder"}
+	require.NoError(t, d.Create(root).Error)
+	folder := &URLNode{UserID: "1", ParentID: &root.ID, Name: "Folder", Type: "folder", DeletedAt: &deletedAt}
+	require.NoError(t, d.Create(folder).Error)
+	child := &URLNode{UserID: "1", ParentID: &folder.ID, Name: "Child", Type: "url", URL: test.StringPtr("https://example.com")}
+	require.NoError(t, d.Create(child).Error)
+
+	// Act
+	parents, err := repo.GetParentUpToRoot(child.ID)
+
+	// Assert
+	assert.Nil(t, parents)
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.CodeURLNotFound, appErr.Code)
+}
 
 func TestRepository_GetChildren_Success(t *testing.T) {
 	// Arrange
@@ -411,7 +438,7 @@ func TestRepository_GetChildren_Success(t *testing.T) {
 
 	parent := &URLNode{
 		ID:       uuid.New().String(),
-		UserID:   1,
+		UserID:   "1",
 		ParentID: nil,
 		Name:     "parent",
 		Type:     "folder",
@@ -421,14 +448,14 @@ func TestRepository_GetChildren_Success(t *testing.T) {
 
 	child1 := &URLNode{
 		ID:       uuid.New().String(),
-		UserID:   1,
+		UserID:   "1",
 		ParentID: &parent.ID,
 		Name:     "child1",
 		Type:     "folder",
 	}
 	child2 := &URLNode{
 		ID:       uuid.New().String(),
-		UserID:   1,
+		UserID:   "1",
 		ParentID: &parent.ID,
 		Name:     "child2",
 		Type:     "url",
@@ -466,6 +493,59 @@ func TestRepository_GetChildren_Success(t *testing.T) {
 	assert.WithinDuration(t, time.Now().UTC(), children[1].UpdatedAt, time.Second)
 	assert.Nil(t, children[1].DeletedAt)
 }
+func TestRepository_GetSubtree_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	deletedAt := time.Now()
+	outside := &URLNode{ID: uuid.New().String(), UserID: "1", Name: "outside", Type: "folder"}
+	root := &URLNode{ID: uuid.New().String(), UserID: "1", ParentID: &outside.ID, Name: "root", Type: "folder"}
+	child := &URLNode{ID: uuid.New().String(), UserID: "1", ParentID: &root.ID, Name: "child", Type: "folder"}
+	grandchild := &URLNode{ID: uuid.New().String(), UserID: "1", ParentID: &child.ID, Name: "grandchild", Type: "url", URL: test.StringPtr("https://example.com")}
+	deleted := &URLNode{ID: uuid.New().String(), UserID: "1", ParentID: &root.ID, Name: "deleted", Type: "folder", DeletedAt: &deletedAt}
+	underDeleted := &URLNode{ID: uuid.New().String(), UserID: "1", ParentID: &deleted.ID, Name: "under deleted", Type: "folder"}
+	for _, node := range []*URLNode{outside, root, child, grandchild, deleted, underDeleted} {
+		err = d.Create(node).Error
+		require.NoError(t, err)
+	}
+
+	// Act
+	nodes, err := repo.GetSubtree(root.ID)
+
+	// Assert
+	require.NoError(t, err)
+	ids := []string{}
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+	assert.ElementsMatch(t, []string{root.ID, child.ID, grandchild.ID}, ids)
+}
+func TestRepository_GetSubtree_TrashedAncestor(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	deletedAt := time.Now()
+	trashed := &URLNode{ID: uuid.New().String(), UserID: "1", Name: "trashed", Type: "folder", DeletedAt: &deletedAt}
+	parent := &URLNode{ID: uuid.New().String(), UserID: "1", ParentID: &trashed.ID, Name: "parent", Type: "folder"}
+	folder := &URLNode{ID: uuid.New().String(), UserID: "1", ParentID: &parent.ID, Name: "folder", Type: "folder"}
+	child := &URLNode{ID: uuid.New().String(), UserID: "1", ParentID: &folder.ID, Name: "child", Type: "url", URL: test.StringPtr("https://example.com")}
+	for _, node := range []*URLNode{trashed, parent, folder, child} {
+		err = d.Create(node).Error
+		require.NoError(t, err)
+	}
+
+	// Act
+	nodes, err := repo.GetSubtree(folder.ID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, nodes)
+}
+
 func TestRepository_GetChildren_NoChildren(t *testing.T) {
 	// Arrange
 	err := test.CleanupTables(d)
@@ -474,7 +554,7 @@ func TestRepository_GetChildren_NoChildren(t *testing.T) {
 
 	node := &URLNode{
 		ID:       uuid.New().String(),
-		UserID:   1,
+		UserID:   "1",
 		ParentID: nil,
 		Name:     "node",
 		Type:     "folder",
@@ -512,7 +592,7 @@ func TestRepository_GetChildren_FilterSoftDeleted(t *testing.T) {
 
 	parent := &URLNode{
 		ID:       uuid.New().String(),
-		UserID:   1,
+		UserID:   "1",
 		ParentID: nil,
 		Name:     "parent",
 		Type:     "folder",
@@ -522,7 +602,7 @@ func TestRepository_GetChildren_FilterSoftDeleted(t *testing.T) {
 
 	child := &URLNode{
 		ID:        uuid.New().String(),
-		UserID:    1,
+		UserID:    "1",
 		ParentID:  &parent.ID,
 		Name:      "child",
 		Type:      "folder",
@@ -546,7 +626,7 @@ func TestRepository_Update_Success(t *testing.T) {
 	repo := NewRepository(d)
 
 	node := &URLNode{
-		UserID: 1,
+		UserID: "1",
 		Name:   "name",
 		Type:   "folder",
 	}
@@ -583,7 +663,7 @@ func TestRepository_Update_NonExistentNode(t *testing.T) {
 
 	node := &URLNode{
 		ID:     uuid.New().String(),
-		UserID: 1,
+		UserID: "1",
 		Name:   "non-existent",
 		Type:   "folder",
 	}
@@ -611,7 +691,7 @@ func TestRepository_SoftDelete_Success(t *testing.T) {
 	repo := NewRepository(d)
 
 	node := &URLNode{
-		UserID: 1,
+		UserID: "1",
 		Name:   "name",
 		Type:   "folder",
 	}
@@ -648,3 +728,821 @@ func TestRepository_SoftDelete_NonExistentNode(t *testing.T) {
 	// Assert
 	require.NoError(t, err)
 }
+
+func TestRepository_Transaction_Commit(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	node := &URLNode{
+		UserID: "1",
+		Name:   "name",
+		Type:   "folder",
+	}
+
+	// Act
+	err = repo.Transaction(func(repo Repository) error {
+		return repo.Create(node)
+	})
+
+	// Assert
+	require.NoError(t, err)
+
+	saved, err := repo.GetOne(node.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, saved)
+}
+func TestRepository_Transaction_Rollback(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	node := &URLNode{
+		UserID: "1",
+		Name:   "name",
+		Type:   "folder",
+	}
+
+	// Act
+	err = repo.Transaction(func(repo Repository) error {
+		if err := repo.Create(node); err != nil {
+			return err
+		}
+		return assert.AnError
+	})
+
+	// Assert
+	assert.Equal(t, assert.AnError, err)
+
+	saved, err := repo.GetOne(node.ID)
+	require.NoError(t, err)
+	assert.Nil(t, saved)
+}
+
+func TestRepository_GetByNormalizedURL_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	deletedAt := time.Now()
+	nodes := []URLNode{
+		{UserID: "1", Name: "a", Type: "url", URL: test.StringPtr("https://example.com"), NormalizedURL: test.StringPtr("https://example.com")},
+		{UserID: "1", Name: "b", Type: "url", URL: test.StringPtr("https://example.com/"), NormalizedURL: test.StringPtr("https://example.com")},
+		{UserID: "1", Name: "c", Type: "url", URL: test.StringPtr("https://other.com"), NormalizedURL: test.StringPtr("https://other.com")},
+		{UserID: "2", Name: "d", Type: "url", URL: test.StringPtr("https://example.com"), NormalizedURL: test.StringPtr("https://example.com")},
+		{UserID: "1", Name: "e", Type: "url", URL: test.StringPtr("https://example.com"), NormalizedURL: test.StringPtr("https://example.com"), DeletedAt: &deletedAt},
+	}
+	for i := range nodes {
+		err = d.Create(&nodes[i]).Error
+		require.NoError(t, err)
+	}
+
+	// Act
+	found, err := repo.GetByNormalizedURL("1", "https://example.com")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.ElementsMatch(t, []string{nodes[0].ID, nodes[1].ID}, []string{found[0].ID, found[1].ID})
+}
+
+func TestRepository_GetByNormalizedURLs_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	nodes := []URLNode{
+		{UserID: "1", Name: "a", Type: "url", NormalizedURL: test.StringPtr("https://example.com")},
+		{UserID: "1", Name: "b", Type: "url", NormalizedURL: test.StringPtr("https://other.com")},
+		{UserID: "1", Name: "c", Type: "url", NormalizedURL: test.StringPtr("https://unrelated.com")},
+		{UserID: "2", Name: "d", Type: "url", NormalizedURL: test.StringPtr("https://example.com")},
+	}
+	for i := range nodes {
+		err = d.Create(&nodes[i]).Error
+		require.NoError(t, err)
+	}
+
+	// Act
+	found, err := repo.GetByNormalizedURLs("1", []string{"https://example.com", "https://other.com"})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.ElementsMatch(t, []string{nodes[0].ID, nodes[1].ID}, []string{found[0].ID, found[1].ID})
+}
+func TestRepository_GetByNormalizedURLs_DeletedAncestor(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	deletedAt := time.Now()
+	root := &URLNode{UserID: "1", Name: "Root", Type: "folder"}
+	require.NoError(t, d.Create(root).Error)
+	folder := &URLNode{UserID: "1", ParentID: &root.ID, Name: "Folder", Type: "folder", DeletedAt: &deletedAt}
+	require.NoError(t, d.Create(folder).Error)
+	subfolder := &URLNode{UserID: "1", ParentID: &folder.ID, Name: "Subfolder", Type: "folder"}
+	require.NoError(t, d.Create(subfolder).Error)
+	nodes := []URLNode{
+		{UserID: "1", ParentID: &root.ID, Name: "a", Type: "url", NormalizedURL: test.StringPtr("https://example.com")},
+		{UserID: "1", ParentID: &folder.ID, Name: "b", Type: "url", NormalizedURL: test.StringPtr("https://example.com")},
+		{UserID: "1", ParentID: &subfolder.ID, Name: "c", Type: "url", NormalizedURL: test.StringPtr("https://example.com")},
+	}
+	for i := range nodes {
+		err = d.Create(&nodes[i]).Error
+		require.NoError(t, err)
+	}
+
+	// Act
+	found, err := repo.GetByNormalizedURLs("1", []string{"https://example.com"})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, nodes[0].ID, found[0].ID)
+}
+
+func TestRepository_GetDuplicateNormalizedURLs_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	deletedAt := time.Now()
+	nodes := []URLNode{
+		{UserID: "1", Name: "a", Type: "url", NormalizedURL: test.StringPtr("https://example.com")},
+		{UserID: "1", Name: "b", Type: "url", NormalizedURL: test.StringPtr("https://example.com")},
+		{UserID: "1", Name: "c", Type: "url", NormalizedURL: test.StringPtr("https://other.com")},
+		{UserID: "1", Name: "d", Type: "url", NormalizedURL: test.StringPtr("https://other.com"), DeletedAt: &deletedAt},
+		{UserID: "2", Name: "e", Type: "url", NormalizedURL: test.StringPtr("https://other.com")},
+		{UserID: "1", Name: "f", Type: "folder"},
+		{UserID: "1", Name: "g", Type: "folder"},
+	}
+	for i := range nodes {
+		err = d.Create(&nodes[i]).Error
+		require.NoError(t, err)
+	}
+
+	// Act
+	normalizedURLs, err := repo.GetDuplicateNormalizedURLs("1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com"}, normalizedURLs)
+}
+func TestRepository_GetDuplicateNormalizedURLs_DeletedAncestor(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	deletedAt := time.Now()
+	root := &URLNode{UserID: "1", Name: "Root", Type: "folder"}
+	require.NoError(t, d.Create(root).Error)
+	folder := &URLNode{UserID: "1", ParentID: &root.ID, Name: "Folder", Type: "folder", DeletedAt: &deletedAt}
+	require.NoError(t, d.Create(folder).Error)
+	nodes := []URLNode{
+		{UserID: "1", ParentID: &root.ID, Name: "a", Type: "url", NormalizedURL: test.StringPtr("https://example.com")},
+		{UserID: "1", ParentID: &folder.ID, Name: "b", Type: "url", NormalizedURL: test.StringPtr("https://example.com")},
+		{UserID: "1", ParentID: &root.ID, Name: "c", Type: "url", NormalizedURL: test.StringPtr("https://other.com")},
+		{UserID: "1", ParentID: &root.ID, Name: "d", Type: "url", NormalizedURL: test.StringPtr("https://other.com")},
+		{UserID: "1", ParentID: &folder.ID, Name: "e", Type: "url", NormalizedURL: test.StringPtr("https://other.com")},
+	}
+	for i := range nodes {
+		err = d.Create(&nodes[i]).Error
+		require.NoError(t, err)
+	}
+
+	// Act
+	normalizedURLs, err := repo.GetDuplicateNormalizedURLs("1")
+	require.NoError(t, err)
+	found, err := repo.GetByNormalizedURL("1", "https://other.com")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://other.com"}, normalizedURLs)
+	require.Len(t, found, 2)
+	assert.ElementsMatch(t, []string{nodes[2].ID, nodes[3].ID}, []string{found[0].ID, found[1].ID})
+}
+
+func TestRepository_GetUnnormalized_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	deletedAt := time.Now()
+	nodes := []URLNode{
+		{ID: "00000000-0000-0000-0000-000000000001", UserID: "1", Name: "a", Type: "url", URL: test.StringPtr("https://example.com")},
+		{ID: "00000000-0000-0000-0000-000000000002", UserID: "1", Name: "b", Type: "url", URL: test.StringPtr("https://example.com"), NormalizedURL: test.StringPtr("https://example.com")},
+		{ID: "00000000-0000-0000-0000-000000000003", UserID: "1", Name: "c", Type: "folder"},
+		{ID: "00000000-0000-0000-0000-000000000004", UserID: "2", Name: "d", Type: "url", URL: test.StringPtr("https://example.com"), DeletedAt: &deletedAt},
+		{ID: "00000000-0000-0000-0000-000000000005", UserID: "2", Name: "e", Type: "url", URL: test.StringPtr("https://example.com")},
+	}
+	for i := range nodes {
+		err = d.Create(&nodes[i]).Error
+		require.NoError(t, err)
+	}
+
+	// Act
+	first, err := repo.GetUnnormalized("00000000-0000-0000-0000-000000000000", 2)
+	require.NoError(t, err)
+	rest, err := repo.GetUnnormalized(first[len(first)-1].ID, 2)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.Equal(t, nodes[0].ID, first[0].ID)
+	assert.Equal(t, nodes[3].ID, first[1].ID)
+	require.Len(t, rest, 1)
+	assert.Equal(t, nodes[4].ID, rest[0].ID)
+}
+
+func TestRepository_SetNormalizedURL_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	updatedAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Microsecond)
+	node := &URLNode{UserID: "1", Name: "a", Type: "url", URL: test.StringPtr("https://example.com/")}
+	require.NoError(t, d.Create(node).Error)
+	require.NoError(t, d.Model(node).UpdateColumn("updated_at", updatedAt).Error)
+
+	// Act
+	err = repo.SetNormalizedURL(node.ID, "https://example.com")
+
+	// Assert
+	require.NoError(t, err)
+	var found URLNode
+	require.NoError(t, d.First(&found, "id = ?", node.ID).Error)
+	assert.Equal(t, "https://example.com", *found.NormalizedURL)
+	assert.True(t, updatedAt.Equal(found.UpdatedAt))
+}
+
+func TestRepository_GetDueForCheck_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	recent := time.Now().UTC()
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	deletedAt := time.Now()
+	nodes := []URLNode{
+		{UserID: "1", Name: "never", Type: "url", URL: test.StringPtr("https://a.com")},
+		{UserID: "1", Name: "old", Type: "url", URL: test.StringPtr("https://b.com"), LastCheckedAt: &old},
+		{UserID: "1", Name: "recent", Type: "url", URL: test.StringPtr("https://c.com"), LastCheckedAt: &recent},
+		{UserID: "1", Name: "deleted", Type: "url", URL: test.StringPtr("https://d.com"), DeletedAt: &deletedAt},
+		{UserID: "1", Name: "folder", Type: "folder"},
+	}
+	for i := range nodes {
+		err = d.Create(&nodes[i]).Error
+		require.NoError(t, err)
+	}
+
+	// Act
+	due, err := repo.GetDueForCheck(time.Now().UTC().Add(-24*time.Hour), 10)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, nodes[0].ID, due[0].ID)
+	assert.Equal(t, nodes[1].ID, due[1].ID)
+}
+func TestRepository_GetDueForCheck_DeletedAncestor(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	deletedAt := time.Now()
+	root := &URLNode{UserID: "1", Name: "Root", Type: "folder"}
+	require.NoError(t, d.Create(root).Error)
+	folder := &URLNode{UserID: "1", ParentID: &root.ID, Name: "Folder", Type: "folder", DeletedAt: &deletedAt}
+	require.NoError(t, d.Create(folder).Error)
+	kept := &URLNode{UserID: "1", ParentID: &root.ID, Name: "kept", Type: "url", URL: test.StringPtr("https://a.com")}
+	require.NoError(t, d.Create(kept).Error)
+	trashed := &URLNode{UserID: "1", ParentID: &folder.ID, Name: "trashed", Type: "url", URL: test.StringPtr("https://b.com")}
+	require.NoError(t, d.Create(trashed).Error)
+
+	// Act
+	due, err := repo.GetDueForCheck(time.Now().UTC(), 10)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, kept.ID, due[0].ID)
+}
+
+func TestRepository_GetBroken_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	checkedAt := time.Now().UTC()
+	ok := 200
+	notFound := 404
+	nodes := []URLNode{
+		{UserID: "1", Name: "ok", Type: "url", URL: test.StringPtr("https://a.com"), LastStatusCode: &ok, LastCheckedAt: &checkedAt},
+		{UserID: "1", Name: "missing", Type: "url", URL: test.StringPtr("https://b.com"), LastStatusCode: &notFound, LastCheckedAt: &checkedAt},
+		{UserID: "1", Name: "unreachable", Type: "url", URL: test.StringPtr("https://c.com"), LastCheckError: test.StringPtr("timeout"), LastCheckedAt: &checkedAt},
+		{UserID: "1", Name: "unchecked", Type: "url", URL: test.StringPtr("https://d.com")},
+		{UserID: "2", Name: "other user", Type: "url", URL: test.StringPtr("https://e.com"), LastStatusCode: &notFound, LastCheckedAt: &checkedAt},
+	}
+	for i := range nodes {
+		err = d.Create(&nodes[i]).Error
+		require.NoError(t, err)
+	}
+
+	// Act
+	broken, err := repo.GetBroken("1")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, broken, 2)
+	assert.ElementsMatch(t, []string{nodes[1].ID, nodes[2].ID}, []string{broken[0].ID, broken[1].ID})
+}
+func TestRepository_GetBroken_DeletedAncestor(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	checkedAt := time.Now().UTC()
+	notFound := 404
+	deletedAt := time.Now()
+	root := &URLNode{UserID: "1", Name: "Root", Type: "folder"}
+	require.NoError(t, d.Create(root).Error)
+	folder := &URLNode{UserID: "1", ParentID: &root.ID, Name: "Folder", Type: "folder", DeletedAt: &deletedAt}
+	require.NoError(t, d.Create(folder).Error)
+	kept := &URLNode{UserID: "1", ParentID: &root.ID, Name: "kept", Type: "url", URL: test.StringPtr("https://a.com"), LastStatusCode: &notFound, LastCheckedAt: &checkedAt}
+	require.NoError(t, d.Create(kept).Error)
+	trashed := &URLNode{UserID: "1", ParentID: &folder.ID, Name: "trashed", Type: "url", URL: test.StringPtr("https://b.com"), LastStatusCode: &notFound, LastCheckedAt: &checkedAt}
+	require.NoError(t, d.Create(trashed).Error)
+
+	// Act
+	broken, err := repo.GetBroken("1")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, broken, 1)
+	assert.Equal(t, kept.ID, broken[0].ID)
+}
+
+func TestRepository_UpdateLinkStatus_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	node := &URLNode{
+		UserID: "1",
+		Name:   "name",
+		Type:   "url",
+		URL:    test.StringPtr("https://example.com"),
+	}
+	err = d.Create(node).Error
+	require.NoError(t, err)
+
+	statusCode := 301
+	status := &LinkStatus{
+		StatusCode:  &statusCode,
+		RedirectURL: test.StringPtr("https://example.com/new"),
+		CheckedAt:   time.Now().UTC(),
+	}
+
+	// Act
+	err = repo.UpdateLinkStatus(node.ID, status)
+
+	// Assert
+	require.NoError(t, err)
+
+	updated, err := repo.GetOne(node.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 301, *updated.LastStatusCode)
+	assert.Nil(t, updated.LastCheckError)
+	assert.Equal(t, "https://example.com/new", *updated.RedirectURL)
+	assert.WithinDuration(t, status.CheckedAt, *updated.LastCheckedAt, time.Second)
+	assert.WithinDuration(t, node.UpdatedAt, updated.UpdatedAt, time.Millisecond)
+}
+
+func TestRepository_Permissions_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	folderID := uuid.New().String()
+	otherID := uuid.New().String()
+	editor := &FolderPermission{FolderID: folderID, UserID: "2", Role: RoleEditor}
+	viewer := &FolderPermission{FolderID: folderID, UserID: "3", Role: RoleViewer}
+	other := &FolderPermission{FolderID: otherID, UserID: "2", Role: RoleOwner}
+	for _, permission := range []*FolderPermission{editor, viewer, other} {
+		err = repo.CreatePermission(permission)
+		require.NoError(t, err)
+	}
+
+	// Act
+	granted, grantedErr := repo.GetPermissions([]string{folderID, otherID}, "2")
+	collaborators, collaboratorsErr := repo.GetCollaborators(folderID)
+	editor.Role = RoleOwner
+	updateErr := repo.UpdatePermission(editor)
+	updated, updatedErr := repo.GetPermission(folderID, "2")
+	deleteErr := repo.DeletePermission(folderID, "3")
+	deleted, deletedErr := repo.GetPermission(folderID, "3")
+
+	// Assert
+	require.NoError(t, grantedErr)
+	assert.Len(t, granted, 2)
+	require.NoError(t, collaboratorsErr)
+	assert.ElementsMatch(t, []string{"2", "3"}, []string{collaborators[0].UserID, collaborators[1].UserID})
+	require.NoError(t, updateErr)
+	require.NoError(t, updatedErr)
+	assert.Equal(t, RoleOwner, updated.Role)
+	require.NoError(t, deleteErr)
+	require.NoError(t, deletedErr)
+	assert.Nil(t, deleted)
+}
+func TestRepository_GetPermissionsByUser_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	first := &FolderPermission{FolderID: uuid.New().String(), UserID: "2", Role: RoleViewer}
+	second := &FolderPermission{FolderID: uuid.New().String(), UserID: "2", Role: RoleEditor}
+	other := &FolderPermission{FolderID: first.FolderID, UserID: "3", Role: RoleViewer}
+	for _, permission := range []*FolderPermission{first, second, other} {
+		err = repo.CreatePermission(permission)
+		require.NoError(t, err)
+	}
+
+	// Act
+	permissions, err := repo.GetPermissionsByUser("2")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, permissions, 2)
+	assert.Equal(t, first.FolderID, permissions[0].FolderID)
+	assert.Equal(t, second.FolderID, permissions[1].FolderID)
+}
+func TestRepository_CreatePermission_Duplicate(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	folderID := uuid.New().String()
+	err = repo.CreatePermission(&FolderPermission{FolderID: folderID, UserID: "2", Role: RoleViewer})
+	require.NoError(t, err)
+
+	// Act
+	err = repo.CreatePermission(&FolderPermission{FolderID: folderID, UserID: "2", Role: RoleEditor})
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestRepository_AuditLogs_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	nodeID := uuid.New().String()
+	snapshot := `{"name": "node"}`
+	requestID := "request-id"
+
+	// Act
+	createErr := repo.CreateAuditLog(&audit.AuditLog{NodeID: nodeID, OwnerID: "1", ActorID: "1", Action: audit.ActionCreate, After: &snapshot, RequestID: &requestID})
+	deleteErr := repo.CreateAuditLog(&audit.AuditLog{NodeID: nodeID, OwnerID: "1", ActorID: "2", Action: audit.ActionDelete, Before: &snapshot})
+	otherErr := repo.CreateAuditLog(&audit.AuditLog{NodeID: uuid.New().String(), OwnerID: "1", ActorID: "1", Action: audit.ActionCreate, After: &snapshot})
+	logs, err := repo.GetAuditLogs(nodeID)
+
+	// Assert
+	require.NoError(t, createErr)
+	require.NoError(t, deleteErr)
+	require.NoError(t, otherErr)
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	assert.Equal(t, audit.ActionDelete, logs[0].Action)
+	assert.Nil(t, logs[0].After)
+	assert.Equal(t, audit.ActionCreate, logs[1].Action)
+	assert.JSONEq(t, snapshot, *logs[1].After)
+	assert.Equal(t, "request-id", *logs[1].RequestID)
+}
+
+func TestRepository_Revisions_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	nodeID := uuid.New().String()
+	first := &NodeRevision{NodeID: nodeID, ActorID: "1", Snapshot: `{"name": "first"}`}
+	second := &NodeRevision{NodeID: nodeID, ActorID: "2", Snapshot: `{"name": "second"}`}
+	other := &NodeRevision{NodeID: uuid.New().String(), ActorID: "1", Snapshot: `{"name": "other"}`}
+
+	// Act
+	firstErr := repo.CreateRevision(first)
+	secondErr := repo.CreateRevision(second)
+	otherErr := repo.CreateRevision(other)
+	revisions, err := repo.GetRevisions(nodeID)
+	found, foundErr := repo.GetRevision(nodeID, 1)
+	missing, missingErr := repo.GetRevision(nodeID, 3)
+
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	require.NoError(t, otherErr)
+	require.NoError(t, err)
+	require.NoError(t, foundErr)
+	require.NoError(t, missingErr)
+	assert.Equal(t, 1, first.Revision)
+	assert.Equal(t, 2, second.Revision)
+	assert.Equal(t, 1, other.Revision)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 2, revisions[0].Revision)
+	assert.Equal(t, "2", revisions[0].ActorID)
+	assert.JSONEq(t, `{"name": "first"}`, found.Snapshot)
+	assert.Nil(t, missing)
+}
+func TestRepository_PruneRevisions_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	nodeID := uuid.New().String()
+	for i := 0; i < 4; i++ {
+		err = repo.CreateRevision(&NodeRevision{NodeID: nodeID, ActorID: "1", Snapshot: `{}`})
+		require.NoError(t, err)
+	}
+	recentNodeID := uuid.New().String()
+	err = repo.CreateRevision(&NodeRevision{NodeID: recentNodeID, ActorID: "1", Snapshot: `{}`})
+	require.NoError(t, err)
+	err = d.Model(&NodeRevision{}).Where("node_id = ?", nodeID).Update("created_at", time.Now().Add(-48*time.Hour)).Error
+	require.NoError(t, err)
+
+	// Act
+	deleted, err := repo.PruneRevisions(time.Now().Add(-24*time.Hour), 2)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	revisions, err := repo.GetRevisions(nodeID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 4, revisions[0].Revision)
+	assert.Equal(t, 3, revisions[1].Revision)
+	recent, err := repo.GetRevisions(recentNodeID)
+	require.NoError(t, err)
+	assert.Len(t, recent, 1)
+}
+
+func TestRepository_GetDeletedAndRestore_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	node := &URLNode{UserID: "1", Name: "node", Type: "folder"}
+	err = repo.Create(node)
+	require.NoError(t, err)
+
+	// Act
+	aliveDeleted, aliveErr := repo.GetDeleted(node.ID)
+	err = repo.SoftDelete(node.ID)
+	require.NoError(t, err)
+	deleted, deletedErr := repo.GetDeleted(node.ID)
+	restoreErr := repo.Restore(node.ID)
+	restored, restoredErr := repo.GetOne(node.ID)
+
+	// Assert
+	require.NoError(t, aliveErr)
+	require.NoError(t, deletedErr)
+	require.NoError(t, restoreErr)
+	require.NoError(t, restoredErr)
+	assert.Nil(t, aliveDeleted)
+	require.NotNil(t, deleted)
+	assert.NotNil(t, deleted.DeletedAt)
+	require.NotNil(t, restored)
+	assert.Nil(t, restored.DeletedAt)
+}
+
+func TestRepository_Operations_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	older := &Operation{UserID: "1", Changes: `[]`}
+	newer := &Operation{UserID: "1", Changes: `[]`}
+	other := &Operation{UserID: "2", Changes: `[]`}
+	for _, operation := range []*Operation{older, newer, other} {
+		err = repo.CreateOperation(operation)
+		require.NoError(t, err)
+	}
+	err = d.Model(&Operation{}).Where("id = ?", older.ID).Update("created_at", time.Now().Add(-time.Hour)).Error
+	require.NoError(t, err)
+
+	// Act
+	last, lastErr := repo.GetLastOperation("1", time.Now().Add(-2*time.Hour))
+	undoneAt := time.Now().UTC()
+	newer.UndoneAt = &undoneAt
+	updateErr := repo.UpdateOperation(newer)
+	lastAfterUndo, lastAfterUndoErr := repo.GetLastOperation("1", time.Now().Add(-2*time.Hour))
+	outsideWindow, outsideWindowErr := repo.GetLastOperation("1", time.Now().Add(-time.Minute))
+	undone, undoneErr := repo.GetLastUndoneOperation("1", time.Now().Add(-time.Minute))
+
+	// Assert
+	require.NoError(t, lastErr)
+	require.NoError(t, updateErr)
+	require.NoError(t, lastAfterUndoErr)
+	require.NoError(t, outsideWindowErr)
+	require.NoError(t, undoneErr)
+	assert.Equal(t, newer.ID, last.ID)
+	assert.Equal(t, older.ID, lastAfterUndo.ID)
+	assert.Nil(t, outsideWindow)
+	assert.Equal(t, newer.ID, undone.ID)
+}
+func TestRepository_DeleteStaleOperations_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	undoneAt := time.Now().UTC()
+	stale := &Operation{UserID: "1", Changes: `[]`}
+	undone := &Operation{UserID: "1", Changes: `[]`, UndoneAt: &undoneAt}
+	current := &Operation{UserID: "1", Changes: `[]`}
+	other := &Operation{UserID: "2", Changes: `[]`, UndoneAt: &undoneAt}
+	for _, operation := range []*Operation{stale, undone, current, other} {
+		err = repo.CreateOperation(operation)
+		require.NoError(t, err)
+	}
+	err = d.Model(&Operation{}).Where("id = ?", stale.ID).Update("created_at", time.Now().Add(-2*time.Hour)).Error
+	require.NoError(t, err)
+
+	// Act
+	err = repo.DeleteStaleOperations("1", time.Now().Add(-time.Hour))
+
+	// Assert
+	require.NoError(t, err)
+	var remaining []Operation
+	err = d.Order("user_id").Find(&remaining).Error
+	require.NoError(t, err)
+	require.Len(t, remaining, 2)
+	assert.Equal(t, current.ID, remaining[0].ID)
+	assert.Equal(t, other.ID, remaining[1].ID)
+}
+
+func TestRepository_GetByIDs_IncludesDeleted(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	alive := &URLNode{UserID: "1", Name: "alive", Type: "folder"}
+	deleted := &URLNode{UserID: "1", Name: "deleted", Type: "folder"}
+	for _, node := range []*URLNode{alive, deleted} {
+		err = repo.Create(node)
+		require.NoError(t, err)
+	}
+	err = repo.SoftDelete(deleted.ID)
+	require.NoError(t, err)
+
+	// Act
+	nodes, err := repo.GetByIDs([]string{alive.ID, deleted.ID, uuid.New().String()})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, nodes, 2)
+}
+
+func TestRepository_ChangeFeed_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	nodeID := uuid.New().String()
+
+	// Act
+	emptySeq, emptyErr := repo.GetLastChangeSeq("1")
+	var seqs []int64
+	for _, userID := range []string{"1", "1", "2", "1"} {
+		err = repo.Transaction(func(repo Repository) error {
+			seq, err := repo.NextChangeSeq(userID)
+			if err != nil {
+				return err
+			}
+			seqs = append(seqs, seq)
+			return repo.CreateNodeChange(&NodeChange{UserID: userID, Seq: seq, NodeID: nodeID, Action: audit.ActionUpdate})
+		})
+		require.NoError(t, err)
+	}
+	lastSeq, lastErr := repo.GetLastChangeSeq("1")
+	changes, changesErr := repo.GetNodeChanges("1", 1, 10)
+	limited, limitedErr := repo.GetNodeChanges("1", 0, 1)
+
+	// Assert
+	require.NoError(t, emptyErr)
+	require.NoError(t, lastErr)
+	require.NoError(t, changesErr)
+	require.NoError(t, limitedErr)
+	assert.Equal(t, int64(0), emptySeq)
+	assert.Equal(t, []int64{1, 2, 1, 3}, seqs)
+	assert.Equal(t, int64(3), lastSeq)
+	require.Len(t, changes, 2)
+	assert.Equal(t, int64(2), changes[0].Seq)
+	assert.Equal(t, int64(3), changes[1].Seq)
+	require.Len(t, limited, 1)
+	assert.Equal(t, int64(1), limited[0].Seq)
+}
+
+func TestRepository_Webhooks_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	hook := &webhook.Webhook{UserID: "1", URL: "https://example.com/hook", Secret: "secret", Events: `[]`}
+	err = d.Create(hook).Error
+	require.NoError(t, err)
+	err = d.Create(&webhook.Webhook{UserID: "2", URL: "https://example.com/other", Secret: "secret", Events: `[]`}).Error
+	require.NoError(t, err)
+
+	delivery, err := webhook.NewDelivery(hook, audit.ActionCreate, map[string]string{"node_id": uuid.New().String()})
+	require.NoError(t, err)
+
+	// Act
+	webhooks, getErr := repo.GetWebhooks("1")
+	createErr := repo.CreateWebhookDelivery(delivery)
+
+	// Assert
+	require.NoError(t, getErr)
+	require.NoError(t, createErr)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, hook.ID, webhooks[0].ID)
+	var stored webhook.Delivery
+	err = d.Where("id = ?", delivery.ID).First(&stored).Error
+	require.NoError(t, err)
+	assert.Equal(t, webhook.DeliveryPending, stored.Status)
+	assert.JSONEq(t, delivery.Payload, stored.Payload)
+}
+
+func TestRepository_CreateOutboxEvent_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	event, err := outbox.NewOutboxEvent(NodeDeleted{NodeEvent: NodeEvent{NodeID: uuid.New().String(), OwnerID: "1", ActorID: "1"}})
+	require.NoError(t, err)
+
+	// Act
+	err = repo.Transaction(func(repo Repository) error {
+		return repo.CreateOutboxEvent(event)
+	})
+
+	// Assert
+	require.NoError(t, err)
+	var stored outbox.OutboxEvent
+	err = d.Where("event_id = ?", event.EventID).First(&stored).Error
+	require.NoError(t, err)
+	assert.Equal(t, "node.deleted", stored.Type)
+	assert.Nil(t, stored.PublishedAt)
+}
+
+func TestFaviconStore_GetPut_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	store := NewFaviconStore(d)
+
+	favicon := &Favicon{Host: "example.com", ContentType: "image/png", Data: []byte("icon"), FetchedAt: time.Now().UTC()}
+
+	// Act
+	missing, missingErr := store.Get("example.com")
+	putErr := store.Put(favicon)
+	favicon.Data = []byte("new icon")
+	replaceErr := store.Put(favicon)
+	stored, getErr := store.Get("example.com")
+
+	// Assert
+	require.NoError(t, missingErr)
+	assert.Nil(t, missing)
+	require.NoError(t, putErr)
+	require.NoError(t, replaceErr)
+	require.NoError(t, getErr)
+	assert.Equal(t, "image/png", stored.ContentType)
+	assert.Equal(t, []byte("new icon"), stored.Data)
+	assert.WithinDuration(t, favicon.FetchedAt, stored.FetchedAt, time.Second)
+}
@@ -0,0 +1,76 @@
+package url
+
+import (
+	"context"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"go.uber.org/zap"
+)
+
+func recordRevision(repo Repository, node *URLNode, actorID string) error {
+	snapshot, err := snapshotOf(node)
+	if err != nil {
+		return err
+	}
+	return repo.CreateRevision(&NodeRevision{
+		NodeID:   node.ID,
+		ActorID:  actorID,
+		Snapshot: *snapshot,
+	})
+}
+
+// RevisionPruner periodically deletes revisions older than the retention
+// period. The newest revisions of each node are kept regardless of their age
+// so that every node can still be reverted.
+type RevisionPruner struct {
+	repo       Repository
+	logger     *zap.Logger
+	interval   time.Duration
+	retention  time.Duration
+	keepLatest int
+}
+
+func NewRevisionPruner(repo Repository, config *config.Config, logger *zap.Logger) *RevisionPruner {
+	return &RevisionPruner{
+		repo:       repo,
+		logger:     logger,
+		interval:   config.RevisionPruneInterval,
+		retention:  config.RevisionRetention,
+		keepLatest: max(config.RevisionKeepLatest, 1),
+	}
+}
+
+func (p *RevisionPruner) Run(ctx context.Context) {
+	if p.interval <= 0 || p.retention <= 0 {
+		p.logger.Info("revision pruner disabled")
+		return
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if err := p.PruneOnce(); err != nil {
+			p.logger.Error("failed to prune revisions", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PruneOnce deletes the revisions that fell out of the retention policy.
+func (p *RevisionPruner) PruneOnce() error {
+	deleted, err := p.repo.PruneRevisions(time.Now().UTC().Add(-p.retention), p.keepLatest)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		p.logger.Info("pruned revisions", zap.Int64("count", deleted))
+	}
+	return nil
+}
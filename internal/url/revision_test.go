@@ -0,0 +1,48 @@
+package url
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRevisionPruner_PruneOnce_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	pruner := NewRevisionPruner(mockRepo, &config.Config{
+		RevisionPruneInterval: time.Hour,
+		RevisionRetention:     24 * time.Hour,
+		RevisionKeepLatest:    5,
+	}, zap.NewNop())
+
+	mockRepo.On("PruneRevisions", mock.MatchedBy(func(createdBefore time.Time) bool {
+		return time.Since(createdBefore) > 23*time.Hour && time.Since(createdBefore) < 25*time.Hour
+	}), 5).Return(int64(3), nil)
+
+	// Act
+	err := pruner.PruneOnce()
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+func TestRevisionPruner_PruneOnce_KeepsAtLeastOne(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	pruner := NewRevisionPruner(mockRepo, &config.Config{RevisionRetention: time.Hour}, zap.NewNop())
+
+	mockRepo.On("PruneRevisions", mock.AnythingOfType("time.Time"), 1).Return(int64(0), nil)
+
+	// Act
+	err := pruner.PruneOnce()
+
+	// Assert
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
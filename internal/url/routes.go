@@ -7,13 +7,36 @@ import (
 )
 
 func RegisterRoutes(r *gin.Engine, h *Handler, authMiddleware middleware.AuthMiddleware) {
+	read := middleware.RequireScope(middleware.ScopeDriveRead)
+	write := middleware.RequireScope(middleware.ScopeDriveWrite)
+	admin := middleware.RequireScope(middleware.ScopeDriveAdmin)
+
 	g := r.Group("/urls")
 	g.Use(gin.HandlerFunc(authMiddleware))
 	{
-		g.POST("", h.CreateURL)
-		g.GET("/root-id", h.GetRootID)
-		g.GET("/:id", h.GetURL)
-		g.PUT("/:id", h.ReplaceURL)
-		g.DELETE("/:id", h.DeleteURL)
+		g.POST("", write, h.CreateURL)
+		g.GET("/root-id", read, h.GetRootID)
+		g.GET("/duplicates", read, h.GetDuplicates)
+		g.POST("/duplicates/merge", write, h.MergeDuplicates)
+		g.GET("/lookup", read, h.LookupURL)
+		g.POST("/lookup", read, h.LookupURLs)
+		g.GET("/broken", read, h.GetBrokenURLs)
+		g.GET("/changes", read, h.GetChanges)
+		g.GET("/events", read, h.StreamEvents)
+		g.POST("/undo", write, h.Undo)
+		g.POST("/redo", write, h.Redo)
+		g.GET("/shared-with-me", read, h.GetSharedWithMe)
+		g.POST("/shared-with-me/:id/mount", write, h.MountFolder)
+		g.GET("/:id", read, h.GetURL)
+		g.GET("/:id/favicon", read, h.GetFavicon)
+		g.GET("/:id/history", read, h.GetHistory)
+		g.GET("/:id/revisions", read, h.GetRevisions)
+		g.POST("/:id/revisions/:revision/revert", write, h.RevertURL)
+		g.GET("/:id/collaborators", read, h.GetCollaborators)
+		g.POST("/:id/collaborators", admin, h.AddCollaborator)
+		g.PUT("/:id/collaborators/:user_id", admin, h.UpdateCollaborator)
+		g.DELETE("/:id/collaborators/:user_id", admin, h.RemoveCollaborator)
+		g.PUT("/:id", write, h.ReplaceURL)
+		g.DELETE("/:id", write, h.DeleteURL)
 	}
 }
@@ -1,40 +1,74 @@
 package url
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	neturl "net/url"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/config"
 )
 
+// MaxNameLength is the longest name of a node, in characters.
+const MaxNameLength = 20
+
 type Service interface {
-	CreateURL(creates *RequestBody, userID int) error
-	GetRootID(userID int) (string, error)
-	GetURL(id string, userID int) (*URLResponse, error)
-	ReplaceURL(id string, updates *RequestBody, userID int) error
-	DeleteURL(id string, userID int) error
+	CreateURL(ctx context.Context, creates *RequestBody, userID string, requestID string) (*CreateURLResponse, error)
+	GetRootID(userID string) (string, error)
+	GetURL(id string, userID string) (*URLResponse, error)
+	ReplaceURL(ctx context.Context, id string, updates *RequestBody, userID string, requestID string) error
+	DeleteURL(id string, userID string, requestID string) error
+	GetDuplicates(userID string) ([]DuplicateGroup, error)
+	MergeDuplicates(merges *MergeDuplicatesRequestBody, userID string, requestID string) error
+	LookupURL(rawURL string, userID string) (*LookupResult, error)
+	LookupURLs(rawURLs []string, userID string) ([]LookupResult, error)
+	GetBrokenURLs(userID string) ([]BrokenURL, error)
+	GetFavicon(ctx context.Context, id string, userID string) (*Favicon, error)
+	GetCollaborators(folderID string, userID string) ([]Collaborator, error)
+	AddCollaborator(folderID string, adds *AddCollaboratorRequestBody, userID string) (*Collaborator, error)
+	UpdateCollaborator(folderID string, collaboratorID string, updates *UpdateCollaboratorRequestBody, userID string) error
+	RemoveCollaborator(folderID string, collaboratorID string, userID string) error
+	GetSharedWithMe(userID string) ([]SharedFolder, error)
+	MountFolder(folderID string, mounts *MountRequestBody, userID string, requestID string) (*BaseURL, error)
+	GetHistory(id string, userID string) ([]audit.Entry, error)
+	GetRevisions(id string, userID string) ([]Revision, error)
+	RevertURL(id string, revision int, userID string, requestID string) error
+	Undo(userID string, requestID string) (*OperationResponse, error)
+	Redo(userID string, requestID string) (*OperationResponse, error)
+	GetChanges(since string, limit int, userID string) (*ChangesResponse, error)
+	SubscribeEvents(userID string) (<-chan Event, func())
 }
 
 type service struct {
-	repo Repository
+	repo       Repository
+	fetcher    MetadataFetcher
+	favicons   *FaviconCache
+	broker     Broker
+	undoWindow time.Duration
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+func NewService(repo Repository, fetcher MetadataFetcher, favicons *FaviconCache, broker Broker, config *config.Config) Service {
+	return &service{repo: repo, fetcher: fetcher, favicons: favicons, broker: broker, undoWindow: config.UndoWindow}
 }
 
-func (s *service) validateOwnership(nodeID string, userID int) error {
-	node, err := s.repo.GetOne(nodeID)
-	if err != nil {
-		return err
-	}
-	if node == nil {
-		return apperror.New(apperror.CodeURLNotFound, "URL not found | id: "+nodeID)
-	}
-	if node.UserID != userID {
-		return apperror.New(
-			apperror.CodeURLAccessDenied, "Access denied | userID: "+strconv.Itoa(userID)+", nodeID: "+nodeID)
+func (s *service) authorize(nodeID string, userID string, required Role) error {
+	return authorize(s.repo, nodeID, userID, required)
+}
+
+func equalStringPtr(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
-	return nil
+	return *a == *b
 }
 
 func (s *service) validateNameUniqueness(name string, parentID string, excludeID *string) error {
@@ -53,7 +87,98 @@ func (s *service) validateNameUniqueness(name string, parentID string, excludeID
 	return nil
 }
 
-func (s *service) GetRootID(userID int) (string, error) {
+func (s *service) findDuplicates(node *URLNode) ([]URLNode, error) {
+	if node.NormalizedURL == nil {
+		return nil, nil
+	}
+
+	nodes, err := s.repo.GetByNormalizedURL(node.UserID, *node.NormalizedURL)
+	if err != nil {
+		return nil, err
+	}
+	duplicates := []URLNode{}
+	for _, n := range nodes {
+		if n.ID != node.ID {
+			duplicates = append(duplicates, n)
+		}
+	}
+	return duplicates, nil
+}
+
+// enrichMetadata fills in the page metadata of a URL node. Fetching is best
+// effort, a page that cannot be fetched simply leaves the metadata empty. It
+// runs before the transaction, since the name may be derived from the title,
+// and stops when ctx is canceled, like when the client goes away.
+func (s *service) enrichMetadata(ctx context.Context, node *URLNode) {
+	node.Title = nil
+	node.Description = nil
+	node.ImageURL = nil
+	node.FaviconURL = nil
+	if s.fetcher == nil || node.Type != "url" || node.URL == nil {
+		return
+	}
+
+	metadata, err := s.fetcher.Fetch(ctx, *node.URL)
+	if err != nil {
+		return
+	}
+	node.Title = metadata.Title
+	node.Description = metadata.Description
+	node.ImageURL = metadata.ImageURL
+	node.FaviconURL = metadata.FaviconURL
+}
+
+// resolveName returns the requested name after checking it is unique. When
+// the name of a URL is omitted, one is derived from the page title or host
+// and numbered if a sibling already uses it.
+func (s *service) resolveName(node *URLNode, name string, parentID string, excludeID *string) (string, error) {
+	if name != "" {
+		return name, s.validateNameUniqueness(name, parentID, excludeID)
+	}
+	if node.Type != "url" || node.URL == nil {
+		return "", apperror.New(apperror.CodeURLNameRequired, "Name is required | type: "+node.Type)
+	}
+
+	siblings, err := s.repo.GetChildren(parentID)
+	if err != nil {
+		return "", err
+	}
+	taken := map[string]bool{}
+	for _, sibling := range siblings {
+		if excludeID != nil && sibling.ID == *excludeID {
+			continue
+		}
+		taken[sibling.Name] = true
+	}
+
+	base := defaultName(node)
+	candidate := base
+	for i := 2; taken[candidate]; i++ {
+		suffix := " (" + strconv.Itoa(i) + ")"
+		candidate = TruncateName(base, MaxNameLength-utf8.RuneCountInString(suffix)) + suffix
+	}
+	return candidate, nil
+}
+
+func defaultName(node *URLNode) string {
+	if node.Title != nil {
+		return TruncateName(*node.Title, MaxNameLength)
+	}
+	if u, err := neturl.Parse(*node.URL); err == nil && u.Hostname() != "" {
+		return TruncateName(strings.TrimPrefix(u.Hostname(), "www."), MaxNameLength)
+	}
+	return TruncateName(*node.URL, MaxNameLength)
+}
+
+// TruncateName shortens name to at most limit characters.
+func TruncateName(name string, limit int) string {
+	if utf8.RuneCountInString(name) <= limit {
+		return name
+	}
+	return strings.TrimSpace(string([]rune(name)[:limit]))
+}
+
+func (s *service) GetRootID(userID string) (string, error) {
 	root, err := s.repo.GetRoot(userID)
 	if err != nil {
 		return "", err
@@ -71,8 +196,8 @@ func (s *service) GetRootID(userID int) (string, error) {
 	return root.ID, nil
 }
 
-func (s *service) GetURL(id string, userID int) (*URLResponse, error) {
-	if err := s.validateOwnership(id, userID); err != nil {
+func (s *service) GetURL(id string, userID string) (*URLResponse, error) {
+	if err := s.authorize(id, userID, RoleViewer); err != nil {
 		return nil, err
 	}
 
@@ -85,6 +210,12 @@ func (s *service) GetURL(id string, userID int) (*URLResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	if node.UserID != userID {
+		parents, err = sharedParents(s.repo, id, parents, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	children, err := s.repo.GetChildren(id)
 	if err != nil {
@@ -94,36 +225,62 @@ func (s *service) GetURL(id string, userID int) (*URLResponse, error) {
 	return newURLResponse(node, parents, children), nil
 }
 
-func (s *service) CreateURL(creates *RequestBody, userID int) error {
-	if err := s.validateOwnership(creates.ParentID, userID); err != nil {
-		return err
+func (s *service) CreateURL(ctx context.Context, creates *RequestBody, userID string, requestID string) (*CreateURLResponse, error) {
+	if creates.Type == "mount" {
+		return nil, apperror.New(apperror.CodeMountInvalid, "Mounts are created from folders shared with you")
 	}
-	if err := s.validateNameUniqueness(creates.Name, creates.ParentID, nil); err != nil {
-		return err
+	if err := s.authorize(creates.ParentID, userID, RoleEditor); err != nil {
+		return nil, err
+	}
+	parent, err := s.repo.GetOne(creates.ParentID)
+	if err != nil {
+		return nil, err
 	}
 
+	// Nodes added by collaborators belong to the owner of the tree.
 	node := &URLNode{
-		UserID:   userID,
+		UserID:   parent.UserID,
 		ParentID: &creates.ParentID,
-		Name:     creates.Name,
 		Type:     creates.Type,
 		URL:      creates.URL,
 	}
-	if err := s.repo.Create(node); err != nil {
-		return err
+	node.NormalizedURL = normalizedURLOf(node)
+	s.enrichMetadata(ctx, node)
+
+	name, err := s.resolveName(node, creates.Name, creates.ParentID, nil)
+	if err != nil {
+		return nil, err
 	}
+	node.Name = name
 
-	return nil
-}
+	err = s.transaction(func(repo Repository) error {
+		if err := repo.Create(node); err != nil {
+			return err
+		}
+		return s.recordOperation(repo, []change{{action: audit.ActionCreate, after: node}}, userID, requestID)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-func (s *service) ReplaceURL(id string, updates *RequestBody, userID int) error {
-	if err := s.validateOwnership(id, userID); err != nil {
-		return err
+	// Duplicates are searched in the whole tree of the owner, which a
+	// collaborator must not see.
+	if node.UserID != userID {
+		return newCreateURLResponse(node, nil), nil
+	}
+	duplicates, err := s.findDuplicates(node)
+	if err != nil {
+		return nil, err
 	}
-	if err := s.validateOwnership(updates.ParentID, userID); err != nil {
+
+	return newCreateURLResponse(node, duplicates), nil
+}
+
+func (s *service) ReplaceURL(ctx context.Context, id string, updates *RequestBody, userID string, requestID string) error {
+	if err := s.authorize(id, userID, RoleEditor); err != nil {
 		return err
 	}
-	if err := s.validateNameUniqueness(updates.Name, updates.ParentID, &id); err != nil {
+	if err := s.authorize(updates.ParentID, userID, RoleEditor); err != nil {
 		return err
 	}
 
@@ -134,28 +291,782 @@ func (s *service) ReplaceURL(id string, updates *RequestBody, userID int) error
 	if node == nil {
 		return apperror.New(apperror.CodeURLNotFound, "URL not found | id: "+id)
 	}
+	parent, err := s.repo.GetOne(updates.ParentID)
+	if err != nil {
+		return err
+	}
+	if parent.UserID != node.UserID {
+		return apperror.New(
+			apperror.CodeURLAccessDenied, "Cannot move a node into another user's tree | id: "+id+", parentID: "+updates.ParentID)
+	}
+	if (node.Type == "mount") != (updates.Type == "mount") || (updates.Type == "mount" && updates.URL != nil) {
+		return apperror.New(apperror.CodeMountInvalid, "Mounts can only be renamed or moved | id: "+id)
+	}
 
+	before := *node
+	urlChanged := !equalStringPtr(node.URL, updates.URL) || node.Type != updates.Type
 	node.ParentID = &updates.ParentID
-	node.Name = updates.Name
 	node.Type = updates.Type
 	node.URL = updates.URL
+	node.NormalizedURL = normalizedURLOf(node)
+	if urlChanged {
+		node.LastStatusCode = nil
+		node.LastCheckError = nil
+		node.RedirectURL = nil
+		node.LastCheckedAt = nil
+		s.enrichMetadata(ctx, node)
+	}
 
-	err = s.repo.Update(node)
+	name, err := s.resolveName(node, updates.Name, updates.ParentID, &id)
 	if err != nil {
 		return err
 	}
+	node.Name = name
 
-	return nil
+	action := audit.ActionUpdate
+	if !equalStringPtr(before.ParentID, node.ParentID) {
+		action = audit.ActionMove
+	}
+	return s.transaction(func(repo Repository) error {
+		if err := repo.Update(node); err != nil {
+			return err
+		}
+		return s.recordOperation(repo, []change{{action: action, before: &before, after: node}}, userID, requestID)
+	})
+}
+
+func (s *service) DeleteURL(id string, userID string, requestID string) error {
+	if err := s.authorize(id, userID, RoleEditor); err != nil {
+		return err
+	}
+	node, err := s.repo.GetOne(id)
+	if err != nil {
+		return err
+	}
+
+	return s.transaction(func(repo Repository) error {
+		if err := repo.SoftDelete(id); err != nil {
+			return err
+		}
+		return s.recordOperation(repo, []change{{action: audit.ActionDelete, before: node}}, userID, requestID)
+	})
+}
+
+func (s *service) GetDuplicates(userID string) ([]DuplicateGroup, error) {
+	normalizedURLs, err := s.repo.GetDuplicateNormalizedURLs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]DuplicateGroup, len(normalizedURLs))
+	for i, normalizedURL := range normalizedURLs {
+		nodes, err := s.repo.GetByNormalizedURL(userID, normalizedURL)
+		if err != nil {
+			return nil, err
+		}
+
+		urls := make([]URLWithParent, len(nodes))
+		for j, node := range nodes {
+			parents, err := s.repo.GetParentUpToRoot(node.ID)
+			if err != nil {
+				return nil, err
+			}
+			urls[j] = *newURLWithParent(&node, parents)
+		}
+		groups[i] = DuplicateGroup{NormalizedURL: normalizedURL, URLs: urls}
+	}
+
+	return groups, nil
+}
+
+func (s *service) MergeDuplicates(merges *MergeDuplicatesRequestBody, userID string, requestID string) error {
+	if err := s.authorize(merges.TargetID, userID, RoleEditor); err != nil {
+		return err
+	}
+	target, err := s.repo.GetOne(merges.TargetID)
+	if err != nil {
+		return err
+	}
+	if target.NormalizedURL == nil {
+		target.NormalizedURL = normalizedURLOf(target)
+	}
+	if target.NormalizedURL == nil {
+		return apperror.New(apperror.CodeURLNotDuplicate, "Target is not a URL | id: "+target.ID)
+	}
+
+	sources := make([]*URLNode, len(merges.SourceIDs))
+	for i, sourceID := range merges.SourceIDs {
+		if sourceID == target.ID {
+			return apperror.New(apperror.CodeURLNotDuplicate, "Source is the target itself | id: "+sourceID)
+		}
+		if err := s.authorize(sourceID, userID, RoleEditor); err != nil {
+			return err
+		}
+		source, err := s.repo.GetOne(sourceID)
+		if err != nil {
+			return err
+		}
+		normalizedURL := normalizedURLOf(source)
+		if normalizedURL == nil || *normalizedURL != *target.NormalizedURL {
+			return apperror.New(
+				apperror.CodeURLNotDuplicate, "Source is not a duplicate of target | sourceID: "+sourceID+", targetID: "+target.ID)
+		}
+		sources[i] = source
+	}
+
+	return s.transaction(func(repo Repository) error {
+		changes := make([]change, len(sources))
+		for i, source := range sources {
+			if err := repo.SoftDelete(source.ID); err != nil {
+				return err
+			}
+			changes[i] = change{action: audit.ActionDelete, before: source}
+		}
+		return s.recordOperation(repo, changes, userID, requestID)
+	})
+}
+
+func (s *service) LookupURL(rawURL string, userID string) (*LookupResult, error) {
+	results, err := s.LookupURLs([]string{rawURL}, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+func (s *service) LookupURLs(rawURLs []string, userID string) ([]LookupResult, error) {
+	// URLs that cannot be normalized can never match a saved node, they are
+	// reported without matches instead of failing the whole lookup.
+	normalizedURLs := make([]string, len(rawURLs))
+	validURLs := []string{}
+	for i, rawURL := range rawURLs {
+		normalized, err := NormalizeURL(rawURL)
+		if err != nil {
+			continue
+		}
+		normalizedURLs[i] = normalized
+		validURLs = append(validURLs, normalized)
+	}
+
+	matchesByURL := map[string][]URLWithParent{}
+	if len(validURLs) > 0 {
+		nodes, err := s.repo.GetByNormalizedURLs(userID, validURLs)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			parents, err := s.repo.GetParentUpToRoot(node.ID)
+			if err != nil {
+				return nil, err
+			}
+			matchesByURL[*node.NormalizedURL] = append(matchesByURL[*node.NormalizedURL], *newURLWithParent(&node, parents))
+		}
+	}
+
+	results := make([]LookupResult, len(rawURLs))
+	for i, rawURL := range rawURLs {
+		matches := []URLWithParent{}
+		if normalizedURLs[i] != "" && matchesByURL[normalizedURLs[i]] != nil {
+			matches = matchesByURL[normalizedURLs[i]]
+		}
+		results[i] = LookupResult{URL: rawURL, Matches: matches}
+	}
+	return results, nil
+}
+
+func (s *service) GetBrokenURLs(userID string) ([]BrokenURL, error) {
+	nodes, err := s.repo.GetBroken(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	brokenURLs := make([]BrokenURL, len(nodes))
+	for i, node := range nodes {
+		parents, err := s.repo.GetParentUpToRoot(node.ID)
+		if err != nil {
+			return nil, err
+		}
+		brokenURLs[i] = *newBrokenURL(&node, parents)
+	}
+	return brokenURLs, nil
+}
+
+func (s *service) GetFavicon(ctx context.Context, id string, userID string) (*Favicon, error) {
+	if err := s.authorize(id, userID, RoleViewer); err != nil {
+		return nil, err
+	}
+
+	node, err := s.repo.GetOne(id)
+	if err != nil {
+		return nil, err
+	}
+	if node.Type != "url" || node.URL == nil {
+		return letterIcon(node.Name), nil
+	}
+
+	return s.favicons.Get(ctx, *node.URL, node.FaviconURL)
+}
+
+// authorizeFolder checks that the node is a folder the user may manage with
+// the required role.
+func (s *service) authorizeFolder(folderID string, userID string, required Role) (*URLNode, error) {
+	if err := s.authorize(folderID, userID, required); err != nil {
+		return nil, err
+	}
+	folder, err := s.repo.GetOne(folderID)
+	if err != nil {
+		return nil, err
+	}
+	if folder.Type != "folder" {
+		return nil, apperror.New(apperror.CodeShareFolderOnly, "Only folders can be shared | id: "+folderID)
+	}
+	return folder, nil
+}
+
+func (s *service) GetCollaborators(folderID string, userID string) ([]Collaborator, error) {
+	if _, err := s.authorizeFolder(folderID, userID, RoleViewer); err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.repo.GetCollaborators(folderID)
+	if err != nil {
+		return nil, err
+	}
+	collaborators := make([]Collaborator, len(permissions))
+	for i, permission := range permissions {
+		collaborators[i] = *newCollaborator(&permission)
+	}
+	return collaborators, nil
 }
 
-func (s *service) DeleteURL(id string, userID int) error {
-	if err := s.validateOwnership(id, userID); err != nil {
+func (s *service) AddCollaborator(folderID string, adds *AddCollaboratorRequestBody, userID string) (*Collaborator, error) {
+	folder, err := s.authorizeFolder(folderID, userID, RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if adds.UserID == folder.UserID {
+		return nil, apperror.New(
+			apperror.CodeCollaboratorInvalid, "The owner cannot be added as a collaborator | userID: "+adds.UserID)
+	}
+
+	existing, err := s.repo.GetPermission(folderID, adds.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, apperror.New(
+			apperror.CodeCollaboratorAlreadyExists, "Collaborator already exists | folderID: "+folderID+", userID: "+adds.UserID)
+	}
+
+	permission := &FolderPermission{FolderID: folderID, UserID: adds.UserID, Role: adds.Role}
+	if err := s.repo.CreatePermission(permission); err != nil {
+		return nil, err
+	}
+	return newCollaborator(permission), nil
+}
+
+func (s *service) UpdateCollaborator(folderID string, collaboratorID string, updates *UpdateCollaboratorRequestBody, userID string) error {
+	if _, err := s.authorizeFolder(folderID, userID, RoleOwner); err != nil {
+		return err
+	}
+
+	permission, err := s.repo.GetPermission(folderID, collaboratorID)
+	if err != nil {
 		return err
 	}
+	if permission == nil {
+		return apperror.New(
+			apperror.CodeCollaboratorNotFound, "Collaborator not found | folderID: "+folderID+", userID: "+collaboratorID)
+	}
+
+	permission.Role = updates.Role
+	return s.repo.UpdatePermission(permission)
+}
 
-	if err := s.repo.SoftDelete(id); err != nil {
+// RemoveCollaborator revokes a collaborator's access. Collaborators may also
+// remove themselves to leave a folder shared with them.
+func (s *service) RemoveCollaborator(folderID string, collaboratorID string, userID string) error {
+	required := RoleOwner
+	if collaboratorID == userID {
+		required = RoleViewer
+	}
+	if _, err := s.authorizeFolder(folderID, userID, required); err != nil {
 		return err
 	}
 
+	permission, err := s.repo.GetPermission(folderID, collaboratorID)
+	if err != nil {
+		return err
+	}
+	if permission == nil {
+		return apperror.New(
+			apperror.CodeCollaboratorNotFound, "Collaborator not found | folderID: "+folderID+", userID: "+collaboratorID)
+	}
+
+	return s.repo.DeletePermission(folderID, collaboratorID)
+}
+
+// GetSharedWithMe lists the folders other users shared with userID. Folders
+// already reachable through a shared ancestor with at least the same role are
+// left out.
+func (s *service) GetSharedWithMe(userID string) ([]SharedFolder, error) {
+	permissions, err := s.repo.GetPermissionsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	roles := map[string]Role{}
+	for _, permission := range permissions {
+		roles[permission.FolderID] = permission.Role
+	}
+
+	folders := []SharedFolder{}
+	for _, permission := range permissions {
+		folder, err := s.repo.GetOne(permission.FolderID)
+		if err != nil {
+			return nil, err
+		}
+		if folder == nil {
+			continue
+		}
+		ancestors, err := s.repo.GetParentUpToRoot(folder.ID)
+		// A folder below a trashed folder is in the trash as well.
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) && appErr.Code == apperror.CodeURLNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		covered := false
+		for _, ancestor := range ancestors {
+			if role, ok := roles[ancestor.ID]; ok && role.Includes(permission.Role) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			folders = append(folders, *newSharedFolder(folder, &permission))
+		}
+	}
+	return folders, nil
+}
+
+// MountFolder pins a folder shared with userID into their own tree. The mount
+// is a link to the shared folder, its content stays in the owner's tree.
+func (s *service) MountFolder(folderID string, mounts *MountRequestBody, userID string, requestID string) (*BaseURL, error) {
+	folder, err := s.authorizeFolder(folderID, userID, RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if folder.UserID == userID {
+		return nil, apperror.New(apperror.CodeMountInvalid, "Only folders shared with you can be mounted | id: "+folderID)
+	}
+
+	if err := s.authorize(mounts.ParentID, userID, RoleEditor); err != nil {
+		return nil, err
+	}
+	parent, err := s.repo.GetOne(mounts.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.UserID != userID {
+		return nil, apperror.New(
+			apperror.CodeURLAccessDenied, "Mounts can only be added to your own tree | parentID: "+mounts.ParentID)
+	}
+
+	name := mounts.Name
+	if name == "" {
+		name = TruncateName(folder.Name, MaxNameLength)
+	}
+	if err := s.validateNameUniqueness(name, mounts.ParentID, nil); err != nil {
+		return nil, err
+	}
+
+	node := &URLNode{
+		UserID:   userID,
+		ParentID: &mounts.ParentID,
+		Name:     name,
+		Type:     "mount",
+		TargetID: &folderID,
+	}
+	err = s.transaction(func(repo Repository) error {
+		if err := repo.Create(node); err != nil {
+			return err
+		}
+		return s.recordOperation(repo, []change{{action: audit.ActionCreate, after: node}}, userID, requestID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newBaseURL(node), nil
+}
+
+// GetHistory returns the audit log of a node, newest first. The history of a
+// deleted node is only available to the owner of its tree.
+func (s *service) GetHistory(id string, userID string) ([]audit.Entry, error) {
+	node, err := s.repo.GetOne(id)
+	if err != nil {
+		return nil, err
+	}
+	if node != nil {
+		if err := s.authorize(id, userID, RoleViewer); err != nil {
+			return nil, err
+		}
+	}
+
+	logs, err := s.repo.GetAuditLogs(id)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil && (len(logs) == 0 || logs[0].OwnerID != userID) {
+		return nil, apperror.New(apperror.CodeURLNotFound, "URL not found | id: "+id)
+	}
+	return audit.NewEntries(logs), nil
+}
+
+func (s *service) GetRevisions(id string, userID string) ([]Revision, error) {
+	if err := s.authorize(id, userID, RoleViewer); err != nil {
+		return nil, err
+	}
+
+	nodeRevisions, err := s.repo.GetRevisions(id)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]Revision, len(nodeRevisions))
+	for i, nodeRevision := range nodeRevisions {
+		revisions[i] = *newRevision(&nodeRevision)
+	}
+	return revisions, nil
+}
+
+// validateParent checks that parentID is still a folder of the node's tree
+// the user may edit, and that moving the node there would not put it inside
+// itself.
+func (s *service) validateParent(node *URLNode, parentID string, userID string) error {
+	parent, err := s.repo.GetOne(parentID)
+	if err != nil {
+		return err
+	}
+	if parent == nil || parent.UserID != node.UserID || parent.Type != "folder" {
+		return apperror.New(
+			apperror.CodeRevisionParentInvalid, "Parent no longer exists | id: "+node.ID+", parentID: "+parentID)
+	}
+	if err := s.authorize(parentID, userID, RoleEditor); err != nil {
+		return err
+	}
+
+	ancestors, err := s.repo.GetParentUpToRoot(parentID)
+	if err != nil {
+		return err
+	}
+	for _, ancestor := range append(ancestors, *parent) {
+		if ancestor.ID == node.ID {
+			return apperror.New(
+				apperror.CodeRevisionParentInvalid, "Parent is now inside the node | id: "+node.ID+", parentID: "+parentID)
+		}
+	}
 	return nil
 }
+
+// applySnapshot sets the user editable fields of node to those of snapshot.
+// The link status is reset when the URL changes.
+func applySnapshot(node *URLNode, snapshot *NodeSnapshot) {
+	urlChanged := !equalStringPtr(node.URL, snapshot.URL) || node.Type != snapshot.Type
+	node.ParentID = snapshot.ParentID
+	node.Name = snapshot.Name
+	node.Type = snapshot.Type
+	node.URL = snapshot.URL
+	node.TargetID = snapshot.TargetID
+	node.Title = snapshot.Title
+	node.Description = snapshot.Description
+	node.ImageURL = snapshot.ImageURL
+	node.FaviconURL = snapshot.FaviconURL
+	node.NormalizedURL = normalizedURLOf(node)
+	if urlChanged {
+		node.LastStatusCode = nil
+		node.LastCheckError = nil
+		node.RedirectURL = nil
+		node.LastCheckedAt = nil
+	}
+}
+
+// RevertURL restores a node to the state recorded in one of its revisions.
+// The revert is itself a change and records a new revision.
+func (s *service) RevertURL(id string, revision int, userID string, requestID string) error {
+	if err := s.authorize(id, userID, RoleEditor); err != nil {
+		return err
+	}
+	node, err := s.repo.GetOne(id)
+	if err != nil {
+		return err
+	}
+
+	nodeRevision, err := s.repo.GetRevision(id, revision)
+	if err != nil {
+		return err
+	}
+	if nodeRevision == nil {
+		return apperror.New(apperror.CodeRevisionNotFound, "Revision not found | id: "+id+", revision: "+strconv.Itoa(revision))
+	}
+	var snapshot NodeSnapshot
+	if err := json.Unmarshal([]byte(nodeRevision.Snapshot), &snapshot); err != nil {
+		return err
+	}
+
+	if snapshot.ParentID != nil {
+		if err := s.validateParent(node, *snapshot.ParentID, userID); err != nil {
+			return err
+		}
+		if err := s.validateNameUniqueness(snapshot.Name, *snapshot.ParentID, &id); err != nil {
+			return err
+		}
+	}
+
+	before := *node
+	applySnapshot(node, &snapshot)
+
+	action := audit.ActionUpdate
+	if !equalStringPtr(before.ParentID, node.ParentID) {
+		action = audit.ActionMove
+	}
+	return s.transaction(func(repo Repository) error {
+		if err := repo.Update(node); err != nil {
+			return err
+		}
+		return s.recordOperation(repo, []change{{action: action, before: &before, after: node}}, userID, requestID)
+	})
+}
+
+// conflictOf reports a validation error raised while undoing or redoing as a
+// conflict, since the request itself was valid but the tree has changed.
+func conflictOf(err error) error {
+	var appErr *apperror.AppError
+	if errors.As(err, &appErr) && appErr.Status == http.StatusBadRequest {
+		return apperror.New(apperror.CodeUndoConflict, "The tree has changed since | "+appErr.Message)
+	}
+	return err
+}
+
+// applyChange moves a node from one recorded state to another, where a nil
+// state means the node is deleted. It fails with CodeUndoConflict when the
+// node is no longer in the from state or cannot be put into the to state.
+func (s *service) applyChange(nodeID string, from *NodeSnapshot, to *NodeSnapshot, userID string, requestID string) error {
+	if from == nil {
+		return s.restoreNode(nodeID, to, userID, requestID)
+	}
+
+	node, err := s.repo.GetOne(nodeID)
+	if err != nil {
+		return err
+	}
+	if node == nil || !reflect.DeepEqual(newNodeSnapshot(node), from) {
+		return apperror.New(apperror.CodeUndoConflict, "The node has changed since | id: "+nodeID)
+	}
+	if err := s.authorize(nodeID, userID, RoleEditor); err != nil {
+		return err
+	}
+
+	if to == nil {
+		children, err := s.repo.GetChildren(nodeID)
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			return apperror.New(apperror.CodeUndoConflict, "The folder is no longer empty | id: "+nodeID)
+		}
+		if err := s.repo.SoftDelete(nodeID); err != nil {
+			return err
+		}
+		return recordChange(s.repo, audit.ActionDelete, node, nil, userID, requestID)
+	}
+
+	if to.ParentID != nil {
+		if err := s.validateParent(node, *to.ParentID, userID); err != nil {
+			return conflictOf(err)
+		}
+		if err := s.validateNameUniqueness(to.Name, *to.ParentID, &nodeID); err != nil {
+			return conflictOf(err)
+		}
+	}
+	before := *node
+	applySnapshot(node, to)
+	action := audit.ActionUpdate
+	if !equalStringPtr(before.ParentID, node.ParentID) {
+		action = audit.ActionMove
+	}
+	if err := s.repo.Update(node); err != nil {
+		return err
+	}
+	return recordChange(s.repo, action, &before, node, userID, requestID)
+}
+
+// restoreNode brings a deleted node back in the given state.
+func (s *service) restoreNode(nodeID string, to *NodeSnapshot, userID string, requestID string) error {
+	node, err := s.repo.GetDeleted(nodeID)
+	if err != nil {
+		return err
+	}
+	if node == nil || !reflect.DeepEqual(newNodeSnapshot(node), to) {
+		return apperror.New(apperror.CodeUndoConflict, "The node has changed since | id: "+nodeID)
+	}
+	if to.ParentID != nil {
+		if err := s.validateParent(node, *to.ParentID, userID); err != nil {
+			return conflictOf(err)
+		}
+		if err := s.validateNameUniqueness(to.Name, *to.ParentID, &nodeID); err != nil {
+			return conflictOf(err)
+		}
+	}
+
+	if err := s.repo.Restore(nodeID); err != nil {
+		return err
+	}
+	node.DeletedAt = nil
+	return recordChange(s.repo, audit.ActionRestore, nil, node, userID, requestID)
+}
+
+func operationChanges(operation *Operation) ([]OperationChange, error) {
+	var changes []OperationChange
+	if err := json.Unmarshal([]byte(operation.Changes), &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Undo reverts the most recent operation the user made within the undo
+// window. All changes of the operation are reverted or none is.
+func (s *service) Undo(userID string, requestID string) (*OperationResponse, error) {
+	operation, err := s.repo.GetLastOperation(userID, time.Now().UTC().Add(-s.undoWindow))
+	if err != nil {
+		return nil, err
+	}
+	if operation == nil {
+		return nil, apperror.New(apperror.CodeNothingToUndo, "Nothing to undo | userID: "+userID)
+	}
+	changes, err := operationChanges(operation)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.transaction(func(repo Repository) error {
+		tx := &service{repo: repo}
+		for i := len(changes) - 1; i >= 0; i-- {
+			if err := tx.applyChange(changes[i].NodeID, changes[i].After, changes[i].Before, userID, requestID); err != nil {
+				return err
+			}
+		}
+		undoneAt := time.Now().UTC()
+		operation.UndoneAt = &undoneAt
+		return repo.UpdateOperation(operation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newOperationResponse(operation, changes), nil
+}
+
+// Redo applies again the operation the user undid most recently, as long as
+// it was undone within the undo window.
+func (s *service) Redo(userID string, requestID string) (*OperationResponse, error) {
+	operation, err := s.repo.GetLastUndoneOperation(userID, time.Now().UTC().Add(-s.undoWindow))
+	if err != nil {
+		return nil, err
+	}
+	if operation == nil {
+		return nil, apperror.New(apperror.CodeNothingToUndo, "Nothing to redo | userID: "+userID)
+	}
+	changes, err := operationChanges(operation)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.transaction(func(repo Repository) error {
+		tx := &service{repo: repo}
+		for _, c := range changes {
+			if err := tx.applyChange(c.NodeID, c.Before, c.After, userID, requestID); err != nil {
+				return err
+			}
+		}
+		operation.UndoneAt = nil
+		return repo.UpdateOperation(operation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newOperationResponse(operation, changes), nil
+}
+
+// GetChanges returns the nodes of the user's tree that changed after the sync
+// token, each once with its latest action and current state. Deleted nodes
+// and nodes now owned by another user are returned as tombstones without a
+// state. Without a token the feed starts at the beginning.
+func (s *service) GetChanges(since string, limit int, userID string) (*ChangesResponse, error) {
+	lastSeq, err := s.repo.GetLastChangeSeq(userID)
+	if err != nil {
+		return nil, err
+	}
+	afterSeq := int64(0)
+	if since != "" {
+		afterSeq, err = decodeSyncToken(since)
+		if err != nil {
+			return nil, err
+		}
+		if afterSeq > lastSeq {
+			return nil, apperror.New(apperror.CodeSyncTokenInvalid, "Sync token is ahead of the change feed | since: "+since)
+		}
+	}
+	if limit == 0 {
+		limit = defaultChangesLimit
+	}
+
+	nodeChanges, err := s.repo.GetNodeChanges(userID, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	nextSeq := afterSeq
+	latest := map[string]NodeChange{}
+	nodeIDs := []string{}
+	for _, nodeChange := range nodeChanges {
+		if _, ok := latest[nodeChange.NodeID]; !ok {
+			nodeIDs = append(nodeIDs, nodeChange.NodeID)
+		}
+		latest[nodeChange.NodeID] = nodeChange
+		nextSeq = nodeChange.Seq
+	}
+
+	nodesByID := map[string]URLNode{}
+	if len(nodeIDs) > 0 {
+		nodes, err := s.repo.GetByIDs(nodeIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			nodesByID[node.ID] = node
+		}
+	}
+
+	sort.Slice(nodeIDs, func(i, j int) bool { return latest[nodeIDs[i]].Seq < latest[nodeIDs[j]].Seq })
+	changes := make([]SyncChange, len(nodeIDs))
+	for i, nodeID := range nodeIDs {
+		changes[i] = SyncChange{NodeID: nodeID, Action: latest[nodeID].Action}
+		// Nodes transferred to another user are tombstones in this feed.
+		if node, ok := nodesByID[nodeID]; ok && node.DeletedAt == nil && node.UserID == userID {
+			changes[i].Node = newSyncNode(&node)
+		} else if !ok {
+			changes[i].Action = actionPurge
+		}
+	}
+
+	return &ChangesResponse{
+		Changes:   changes,
+		NextToken: encodeSyncToken(nextSeq),
+		HasMore:   nextSeq < lastSeq,
+	}, nil
+}
+
+// SubscribeEvents subscribes to the changes committed to the tree of the
+// user, including changes made by collaborators.
+func (s *service) SubscribeEvents(userID string) (<-chan Event, func()) {
+	return s.broker.Subscribe(userID)
+}
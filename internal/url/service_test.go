@@ -1,9 +1,16 @@
 package url
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/outbox"
+	"github.com/vera/vera-drive-service/internal/webhook"
 	"github.com/vera/vera-drive-service/test"
 
 	"github.com/stretchr/testify/assert"
@@ -15,11 +22,15 @@ type MockRepository struct {
 	mock.Mock
 }
 
+func (m *MockRepository) Transaction(fn func(repo Repository) error) error {
+	m.Called(fn)
+	return fn(m)
+}
 func (m *MockRepository) Create(node *URLNode) error {
 	args := m.Called(node)
 	return args.Error(0)
 }
-func (m *MockRepository) GetRoot(userID int) (*URLNode, error) {
+func (m *MockRepository) GetRoot(userID string) (*URLNode, error) {
 	args := m.Called(userID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -41,6 +52,169 @@ func (m *MockRepository) GetChildren(id string) ([]URLNode, error) {
 	args := m.Called(id)
 	return args.Get(0).([]URLNode), args.Error(1)
 }
+func (m *MockRepository) GetPermissions(nodeIDs []string, userID string) ([]FolderPermission, error) {
+	args := m.Called(nodeIDs, userID)
+	return args.Get(0).([]FolderPermission), args.Error(1)
+}
+func (m *MockRepository) GetPermission(folderID string, userID string) (*FolderPermission, error) {
+	args := m.Called(folderID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*FolderPermission), args.Error(1)
+}
+func (m *MockRepository) GetCollaborators(folderID string) ([]FolderPermission, error) {
+	args := m.Called(folderID)
+	return args.Get(0).([]FolderPermission), args.Error(1)
+}
+func (m *MockRepository) GetPermissionsByUser(userID string) ([]FolderPermission, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]FolderPermission), args.Error(1)
+}
+func (m *MockRepository) CreateAuditLog(entry *audit.AuditLog) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
+func (m *MockRepository) GetAuditLogs(nodeID string) ([]audit.AuditLog, error) {
+	args := m.Called(nodeID)
+	return args.Get(0).([]audit.AuditLog), args.Error(1)
+}
+func (m *MockRepository) CreateRevision(revision *NodeRevision) error {
+	args := m.Called(revision)
+	return args.Error(0)
+}
+func (m *MockRepository) GetRevisions(nodeID string) ([]NodeRevision, error) {
+	args := m.Called(nodeID)
+	return args.Get(0).([]NodeRevision), args.Error(1)
+}
+func (m *MockRepository) GetRevision(nodeID string, revision int) (*NodeRevision, error) {
+	args := m.Called(nodeID, revision)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*NodeRevision), args.Error(1)
+}
+func (m *MockRepository) PruneRevisions(createdBefore time.Time, keepLatest int) (int64, error) {
+	args := m.Called(createdBefore, keepLatest)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockRepository) GetDeleted(id string) (*URLNode, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*URLNode), args.Error(1)
+}
+func (m *MockRepository) Restore(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+func (m *MockRepository) CreateOperation(operation *Operation) error {
+	args := m.Called(operation)
+	return args.Error(0)
+}
+func (m *MockRepository) GetLastOperation(userID string, createdAfter time.Time) (*Operation, error) {
+	args := m.Called(userID, createdAfter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Operation), args.Error(1)
+}
+func (m *MockRepository) GetLastUndoneOperation(userID string, undoneAfter time.Time) (*Operation, error) {
+	args := m.Called(userID, undoneAfter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Operation), args.Error(1)
+}
+func (m *MockRepository) UpdateOperation(operation *Operation) error {
+	args := m.Called(operation)
+	return args.Error(0)
+}
+func (m *MockRepository) DeleteStaleOperations(userID string, createdBefore time.Time) error {
+	args := m.Called(userID, createdBefore)
+	return args.Error(0)
+}
+func (m *MockRepository) GetByIDs(ids []string) ([]URLNode, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]URLNode), args.Error(1)
+}
+func (m *MockRepository) NextChangeSeq(userID string) (int64, error) {
+	args := m.Called(userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockRepository) GetLastChangeSeq(userID string) (int64, error) {
+	args := m.Called(userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockRepository) CreateNodeChange(nodeChange *NodeChange) error {
+	args := m.Called(nodeChange)
+	return args.Error(0)
+}
+func (m *MockRepository) GetWebhooks(userID string) ([]webhook.Webhook, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]webhook.Webhook), args.Error(1)
+}
+func (m *MockRepository) CreateWebhookDelivery(delivery *webhook.Delivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+func (m *MockRepository) CreateOutboxEvent(event *outbox.OutboxEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+func (m *MockRepository) GetNodeChanges(userID string, afterSeq int64, limit int) ([]NodeChange, error) {
+	args := m.Called(userID, afterSeq, limit)
+	return args.Get(0).([]NodeChange), args.Error(1)
+}
+func (m *MockRepository) CreatePermission(permission *FolderPermission) error {
+	args := m.Called(permission)
+	return args.Error(0)
+}
+func (m *MockRepository) UpdatePermission(permission *FolderPermission) error {
+	args := m.Called(permission)
+	return args.Error(0)
+}
+func (m *MockRepository) DeletePermission(folderID string, userID string) error {
+	args := m.Called(folderID, userID)
+	return args.Error(0)
+}
+func (m *MockRepository) GetSubtree(id string) ([]URLNode, error) {
+	args := m.Called(id)
+	return args.Get(0).([]URLNode), args.Error(1)
+}
+func (m *MockRepository) GetByNormalizedURL(userID string, normalizedURL string) ([]URLNode, error) {
+	args := m.Called(userID, normalizedURL)
+	return args.Get(0).([]URLNode), args.Error(1)
+}
+func (m *MockRepository) GetByNormalizedURLs(userID string, normalizedURLs []string) ([]URLNode, error) {
+	args := m.Called(userID, normalizedURLs)
+	return args.Get(0).([]URLNode), args.Error(1)
+}
+func (m *MockRepository) GetDuplicateNormalizedURLs(userID string) ([]string, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]string), args.Error(1)
+}
+func (m *MockRepository) GetUnnormalized(afterID string, limit int) ([]URLNode, error) {
+	args := m.Called(afterID, limit)
+	return args.Get(0).([]URLNode), args.Error(1)
+}
+func (m *MockRepository) SetNormalizedURL(id string, normalizedURL string) error {
+	args := m.Called(id, normalizedURL)
+	return args.Error(0)
+}
+func (m *MockRepository) GetDueForCheck(checkedBefore time.Time, limit int) ([]URLNode, error) {
+	args := m.Called(checkedBefore, limit)
+	return args.Get(0).([]URLNode), args.Error(1)
+}
+func (m *MockRepository) GetBroken(userID string) ([]URLNode, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]URLNode), args.Error(1)
+}
+func (m *MockRepository) UpdateLinkStatus(id string, status *LinkStatus) error {
+	args := m.Called(id, status)
+	return args.Error(0)
+}
 func (m *MockRepository) Update(node *URLNode) error {
 	args := m.Called(node)
 	return args.Error(0)
@@ -50,24 +224,43 @@ func (m *MockRepository) SoftDelete(id string) error {
 	return args.Error(0)
 }
 
+type MockMetadataFetcher struct {
+	mock.Mock
+}
+
+func (m *MockMetadataFetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	args := m.Called(ctx, rawURL)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Metadata), args.Error(1)
+}
+
 func TestService_NewService_Success(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
+	mockFetcher := &MockMetadataFetcher{}
+	favicons := newTestFaviconCache(&MockFaviconStore{})
+	broker := NewMemoryBroker()
 
 	// Act
-	s := NewService(mockRepo)
+	s := NewService(mockRepo, mockFetcher, favicons, broker, &config.Config{UndoWindow: time.Hour})
 
 	// Assert
 	assert.IsType(t, &service{}, s)
 	assert.Equal(t, mockRepo, s.(*service).repo)
+	assert.Equal(t, mockFetcher, s.(*service).fetcher)
+	assert.Equal(t, favicons, s.(*service).favicons)
+	assert.Equal(t, broker, s.(*service).broker)
+	assert.Equal(t, time.Hour, s.(*service).undoWindow)
 }
 
-func TestService_validateOwnership_Success(t *testing.T) {
+func TestService_authorize_Success(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
-	userID := 1
+	userID := "1"
 
 	expectedNode := &URLNode{
 		ID:     nodeID,
@@ -79,36 +272,36 @@ func TestService_validateOwnership_Success(t *testing.T) {
 	mockRepo.On("GetOne", nodeID).Return(expectedNode, nil)
 
 	// Act
-	err := service.validateOwnership(nodeID, userID)
+	err := service.authorize(nodeID, userID, RoleViewer)
 
 	// Assert
 	require.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 }
-func TestService_validateOwnership_NodeNotFound(t *testing.T) {
+func TestService_authorize_NodeNotFound(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
-	userID := 1
+	userID := "1"
 
 	mockRepo.On("GetOne", nodeID).Return(nil, nil)
 
 	// Act
-	err := service.validateOwnership(nodeID, userID)
+	err := service.authorize(nodeID, userID, RoleViewer)
 
 	// Assert
 	assert.Error(t, err)
 	assert.Equal(t, apperror.CodeURLNotFound, err.(*apperror.AppError).Code)
 	mockRepo.AssertExpectations(t)
 }
-func TestService_validateOwnership_AccessDenied(t *testing.T) {
+func TestService_authorize_AccessDenied(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
-	userID := 1
-	ownerID := 2
+	userID := "1"
+	ownerID := "2"
 
 	expectedNode := &URLNode{
 		ID:     nodeID,
@@ -118,26 +311,90 @@ func TestService_validateOwnership_AccessDenied(t *testing.T) {
 	}
 
 	mockRepo.On("GetOne", nodeID).Return(expectedNode, nil)
+	mockRepo.On("GetParentUpToRoot", nodeID).Return([]URLNode{}, nil)
+	mockRepo.On("GetPermissions", []string{nodeID}, userID).Return([]FolderPermission{}, nil)
 
 	// Act
-	err := service.validateOwnership(nodeID, userID)
+	err := service.authorize(nodeID, userID, RoleViewer)
 
 	// Assert
 	assert.Error(t, err)
 	assert.Equal(t, apperror.CodeURLAccessDenied, err.(*apperror.AppError).Code)
 	mockRepo.AssertExpectations(t)
 }
-func TestService_validateOwnership_RepositoryError(t *testing.T) {
+func TestService_authorize_InheritedRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions []FolderPermission
+		required    Role
+		expectError bool
+	}{
+		{
+			name:        "viewer reads",
+			permissions: []FolderPermission{{FolderID: "root-id", Role: RoleViewer}},
+			required:    RoleViewer,
+		},
+		{
+			name:        "viewer cannot edit",
+			permissions: []FolderPermission{{FolderID: "root-id", Role: RoleViewer}},
+			required:    RoleEditor,
+			expectError: true,
+		},
+		{
+			name:        "strongest role wins",
+			permissions: []FolderPermission{{FolderID: "root-id", Role: RoleViewer}, {FolderID: "folder-id", Role: RoleEditor}},
+			required:    RoleEditor,
+		},
+		{
+			name:        "editor cannot manage",
+			permissions: []FolderPermission{{FolderID: "folder-id", Role: RoleEditor}},
+			required:    RoleOwner,
+			expectError: true,
+		},
+		{
+			name:        "owner role",
+			permissions: []FolderPermission{{FolderID: "root-id", Role: RoleOwner}},
+			required:    RoleOwner,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := &MockRepository{}
+			service := &service{repo: mockRepo}
+			userID := "1"
+			ancestors := []URLNode{{ID: "root-id", UserID: "2"}, {ID: "folder-id", UserID: "2"}}
+
+			mockRepo.On("GetOne", "node-id").Return(&URLNode{ID: "node-id", UserID: "2"}, nil)
+			mockRepo.On("GetParentUpToRoot", "node-id").Return(ancestors, nil)
+			mockRepo.On("GetPermissions", []string{"node-id", "root-id", "folder-id"}, userID).Return(tt.permissions, nil)
+
+			// Act
+			err := service.authorize("node-id", userID, tt.required)
+
+			// Assert
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Equal(t, apperror.CodeURLAccessDenied, err.(*apperror.AppError).Code)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+func TestService_authorize_RepositoryError(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
-	userID := 1
+	userID := "1"
 
 	mockRepo.On("GetOne", nodeID).Return(nil, assert.AnError)
 
 	// Act
-	err := service.validateOwnership(nodeID, userID)
+	err := service.authorize(nodeID, userID, RoleViewer)
 
 	// Assert
 	assert.Error(t, err)
@@ -225,7 +482,7 @@ func TestService_GetRootID_ExistingRoot(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
-	userID := 1
+	userID := "1"
 	expectedRoot := &URLNode{
 		ID:     "root-id",
 		UserID: userID,
@@ -247,7 +504,7 @@ func TestService_GetRootID_NonExistentRoot(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
-	userID := 1
+	userID := "1"
 	creates := &URLNode{
 		UserID: userID,
 		Name:   "",
@@ -274,7 +531,7 @@ func TestService_GetRootID_RepositoryError(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
-	userID := 1
+	userID := "1"
 
 	mockRepo.On("GetRoot", userID).Return(nil, assert.AnError)
 
@@ -290,7 +547,7 @@ func TestService_GetRootID_CreateRootError(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
-	userID := 1
+	userID := "1"
 
 	mockRepo.On("GetRoot", userID).Return(nil, nil)
 	mockRepo.On("Create", mock.AnythingOfType("*url.URLNode")).Return(assert.AnError)
@@ -309,7 +566,7 @@ func TestService_GetURL_Success(t *testing.T) {
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
-	userID := 1
+	userID := "1"
 	node := &URLNode{
 		ID:     nodeID,
 		UserID: userID,
@@ -348,12 +605,39 @@ func TestService_GetURL_Success(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+func TestService_GetURL_SharedBreadcrumbs(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "2"
+	node := &URLNode{ID: "node-id", UserID: "1", Name: "node", Type: "folder"}
+	parents := []URLNode{
+		{ID: "root-id", UserID: "1", Type: "folder"},
+		{ID: "private-id", UserID: "1", Name: "private", Type: "folder"},
+		{ID: "shared-id", UserID: "1", Name: "shared", Type: "folder"},
+	}
+
+	mockRepo.On("GetOne", "node-id").Return(node, nil)
+	mockRepo.On("GetParentUpToRoot", "node-id").Return(parents, nil)
+	mockRepo.On("GetPermissions", []string{"node-id", "root-id", "private-id", "shared-id"}, userID).
+		Return([]FolderPermission{{FolderID: "shared-id", UserID: userID, Role: RoleViewer}}, nil)
+	mockRepo.On("GetChildren", "node-id").Return([]URLNode{}, nil)
+
+	// Act
+	response, err := service.GetURL("node-id", userID)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, response.Parent, 1)
+	assert.Equal(t, "shared-id", response.Parent[0].ID)
+	mockRepo.AssertExpectations(t)
+}
 func TestService_GetURL_OwnershipError(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
-	userID := 1
+	userID := "1"
 
 	mockRepo.On("GetOne", nodeID).Return(nil, nil)
 
@@ -370,7 +654,7 @@ func TestService_GetURL_GetOneError(t *testing.T) {
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
-	userID := 1
+	userID := "1"
 	node := &URLNode{
 		ID:     nodeID,
 		UserID: userID,
@@ -394,7 +678,7 @@ func TestService_GetURL_GetParentUpToRootError(t *testing.T) {
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
-	userID := 1
+	userID := "1"
 	node := &URLNode{
 		ID:     nodeID,
 		UserID: userID,
@@ -418,7 +702,7 @@ func TestService_GetURL_GetChildrenError(t *testing.T) {
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
-	userID := 1
+	userID := "1"
 	node := &URLNode{
 		ID:     nodeID,
 		UserID: userID,
@@ -444,7 +728,7 @@ func TestService_CreateURL_Success(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
-	userID := 1
+	userID := "1"
 	creates := &RequestBody{
 		ParentID: "parent-id",
 		Name:     "new-url",
@@ -452,11 +736,12 @@ func TestService_CreateURL_Success(t *testing.T) {
 		URL:      test.StringPtr("https://example.com"),
 	}
 	createdNode := &URLNode{
-		UserID:   userID,
-		ParentID: test.StringPtr(creates.ParentID),
-		Name:     creates.Name,
-		Type:     creates.Type,
-		URL:      creates.URL,
+		UserID:        userID,
+		ParentID:      test.StringPtr(creates.ParentID),
+		Name:          creates.Name,
+		Type:          creates.Type,
+		URL:           creates.URL,
+		NormalizedURL: test.StringPtr("https://example.com"),
 	}
 	parentNode := &URLNode{
 		ID:     "parent-id",
@@ -468,19 +753,136 @@ func TestService_CreateURL_Success(t *testing.T) {
 	mockRepo.On("GetOne", "parent-id").Return(parentNode, nil)
 	mockRepo.On("GetChildren", "parent-id").Return([]URLNode{}, nil)
 	mockRepo.On("Create", createdNode).Return(nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("CreateAuditLog", mock.AnythingOfType("*audit.AuditLog")).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("CreateRevision", mock.AnythingOfType("*url.NodeRevision")).Return(nil)
+	mockRepo.On("DeleteStaleOperations", userID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("CreateOperation", mock.AnythingOfType("*url.Operation")).Return(nil)
+	mockRepo.On("GetByNormalizedURL", userID, "https://example.com").Return([]URLNode{*createdNode}, nil)
+
+	// Act
+	response, err := service.CreateURL(context.Background(), creates, userID, "request-id")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, creates.Name, response.Name)
+	assert.Equal(t, creates.URL, response.URL)
+	assert.Nil(t, response.Warning)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_CreateURL_ByCollaborator(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	ownerID := "2"
+	creates := &RequestBody{
+		ParentID: "parent-id",
+		Name:     "new-url",
+		Type:     "url",
+		URL:      test.StringPtr("https://example.com"),
+	}
+	parentNode := &URLNode{ID: "parent-id", UserID: ownerID, Name: "parent", Type: "folder"}
+
+	mockRepo.On("GetOne", "parent-id").Return(parentNode, nil)
+	mockRepo.On("GetParentUpToRoot", "parent-id").Return([]URLNode{}, nil)
+	mockRepo.On("GetPermissions", []string{"parent-id"}, userID).Return([]FolderPermission{{FolderID: "parent-id", UserID: userID, Role: RoleEditor}}, nil)
+	mockRepo.On("GetChildren", "parent-id").Return([]URLNode{}, nil)
+	mockRepo.On("Create", mock.MatchedBy(func(node *URLNode) bool { return node.UserID == ownerID })).Return(nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("CreateAuditLog", mock.AnythingOfType("*audit.AuditLog")).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("CreateRevision", mock.AnythingOfType("*url.NodeRevision")).Return(nil)
+	mockRepo.On("DeleteStaleOperations", userID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("CreateOperation", mock.AnythingOfType("*url.Operation")).Return(nil)
+
+	// Act
+	response, err := service.CreateURL(context.Background(), creates, userID, "request-id")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, creates.Name, response.Name)
+	assert.Nil(t, response.Warning)
+	mockRepo.AssertNotCalled(t, "GetByNormalizedURL", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_CreateURL_MountType(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	creates := &RequestBody{ParentID: "parent-id", Name: "mount", Type: "mount"}
+
+	// Act
+	response, err := service.CreateURL(context.Background(), creates, "1", "request-id")
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeMountInvalid, err.(*apperror.AppError).Code)
+	assert.Nil(t, response)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+func TestService_CreateURL_DuplicateWarning(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	creates := &RequestBody{
+		ParentID: "parent-id",
+		Name:     "new-url",
+		Type:     "url",
+		URL:      test.StringPtr("HTTPS://Example.com:443/?utm_source=mail"),
+	}
+	parentNode := &URLNode{
+		ID:     "parent-id",
+		UserID: userID,
+		Name:   "parent",
+		Type:   "folder",
+	}
+	savedNode := URLNode{
+		ID:            "saved-id",
+		UserID:        userID,
+		Name:          "saved",
+		Type:          "url",
+		URL:           test.StringPtr("https://example.com/"),
+		NormalizedURL: test.StringPtr("https://example.com"),
+	}
+
+	mockRepo.On("GetOne", "parent-id").Return(parentNode, nil)
+	mockRepo.On("GetChildren", "parent-id").Return([]URLNode{}, nil)
+	mockRepo.On("Create", mock.AnythingOfType("*url.URLNode")).Return(nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("CreateAuditLog", mock.AnythingOfType("*audit.AuditLog")).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("CreateRevision", mock.AnythingOfType("*url.NodeRevision")).Return(nil)
+	mockRepo.On("DeleteStaleOperations", userID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("CreateOperation", mock.AnythingOfType("*url.Operation")).Return(nil)
+	mockRepo.On("GetByNormalizedURL", userID, "https://example.com").Return([]URLNode{savedNode}, nil)
 
 	// Act
-	err := service.CreateURL(creates, userID)
+	response, err := service.CreateURL(context.Background(), creates, userID, "request-id")
 
 	// Assert
 	require.NoError(t, err)
+	require.NotNil(t, response.Warning)
+	require.Len(t, response.Warning.Duplicates, 1)
+	assert.Equal(t, "saved-id", response.Warning.Duplicates[0].ID)
 	mockRepo.AssertExpectations(t)
 }
 func TestService_CreateURL_ParentOwnershipError(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
-	userID := 1
+	userID := "1"
 
 	createReq := &RequestBody{
 		ParentID: "parent-id",
@@ -492,10 +894,11 @@ func TestService_CreateURL_ParentOwnershipError(t *testing.T) {
 	mockRepo.On("GetOne", "parent-id").Return(nil, nil)
 
 	// Act
-	err := service.CreateURL(createReq, userID)
+	response, err := service.CreateURL(context.Background(), createReq, userID, "request-id")
 
 	// Assert
 	assert.Error(t, err)
+	assert.Nil(t, response)
 	assert.Equal(t, apperror.CodeURLNotFound, err.(*apperror.AppError).Code)
 	mockRepo.AssertExpectations(t)
 }
@@ -503,7 +906,7 @@ func TestService_CreateURL_NameUniquenessError(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
-	userID := 1
+	userID := "1"
 	creates := &RequestBody{
 		ParentID: "parent-id",
 		Name:     "existing-name",
@@ -524,10 +927,11 @@ func TestService_CreateURL_NameUniquenessError(t *testing.T) {
 	mockRepo.On("GetChildren", "parent-id").Return(siblings, nil)
 
 	// Act
-	err := service.CreateURL(creates, userID)
+	response, err := service.CreateURL(context.Background(), creates, userID, "request-id")
 
 	// Assert
 	assert.Error(t, err)
+	assert.Nil(t, response)
 	assert.Equal(t, apperror.CodeURLNameAlreadyExists, err.(*apperror.AppError).Code)
 	mockRepo.AssertExpectations(t)
 }
@@ -535,7 +939,7 @@ func TestService_CreateURL_CreateError(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
-	userID := 1
+	userID := "1"
 	creates := &RequestBody{
 		ParentID: "parent-id",
 		Name:     "new-url",
@@ -552,129 +956,233 @@ func TestService_CreateURL_CreateError(t *testing.T) {
 	mockRepo.On("GetOne", "parent-id").Return(parentNode, nil)
 	mockRepo.On("GetChildren", "parent-id").Return([]URLNode{}, nil)
 	mockRepo.On("Create", mock.AnythingOfType("*url.URLNode")).Return(assert.AnError)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
 
 	// Act
-	err := service.CreateURL(creates, userID)
+	response, err := service.CreateURL(context.Background(), creates, userID, "request-id")
 
 	// Assert
 	assert.Error(t, err)
+	assert.Nil(t, response)
 	assert.Equal(t, assert.AnError, err)
 	mockRepo.AssertExpectations(t)
 }
 
-func TestService_ReplaceURL_Success(t *testing.T) {
+func TestService_CreateURL_EnrichesMetadata(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
-	service := &service{repo: mockRepo}
-	nodeID := "mock-node-id"
-	newParentID := "new-parent-id"
-	userID := 1
-	updates := &RequestBody{
-		ParentID: newParentID,
-		Name:     "updated-name",
+	mockFetcher := &MockMetadataFetcher{}
+	service := &service{repo: mockRepo, fetcher: mockFetcher}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	userID := "1"
+	creates := &RequestBody{
+		ParentID: "parent-id",
+		Name:     "new-url",
 		Type:     "url",
-		URL:      test.StringPtr("https://updated.com"),
-	}
-	node := &URLNode{
-		ID:     nodeID,
-		UserID: userID,
-		Name:   "old-name",
-		Type:   "folder",
-		URL:    nil,
-	}
-	updatedNode := &URLNode{
-		ID:       nodeID,
-		UserID:   userID,
-		ParentID: test.StringPtr(newParentID),
-		Name:     updates.Name,
-		Type:     updates.Type,
-		URL:      updates.URL,
+		URL:      test.StringPtr("https://example.com"),
 	}
-	newParentNode := &URLNode{
-		ID:     newParentID,
-		UserID: userID,
-		Name:   "new-parent",
-		Type:   "folder",
+	parentNode := &URLNode{ID: "parent-id", UserID: userID, Name: "parent", Type: "folder"}
+	metadata := &Metadata{
+		Title:       test.StringPtr("Example Domain"),
+		Description: test.StringPtr("An example page"),
+		ImageURL:    test.StringPtr("https://example.com/og.png"),
+		FaviconURL:  test.StringPtr("https://example.com/favicon.png"),
 	}
 
-	mockRepo.On("GetOne", nodeID).Return(node, nil).Once()
-	mockRepo.On("GetOne", newParentID).Return(newParentNode, nil).Once()
-	mockRepo.On("GetChildren", newParentID).Return([]URLNode{}, nil)
-	mockRepo.On("GetOne", nodeID).Return(node, nil).Once()
-	mockRepo.On("Update", updatedNode).Return(nil)
+	mockRepo.On("GetOne", "parent-id").Return(parentNode, nil)
+	mockRepo.On("GetChildren", "parent-id").Return([]URLNode{}, nil)
+	mockFetcher.On("Fetch", ctx, "https://example.com").Return(metadata, nil)
+	mockRepo.On("Create", mock.MatchedBy(func(node *URLNode) bool {
+		return node.Name == "new-url" &&
+			*node.Title == "Example Domain" &&
+			*node.Description == "An example page" &&
+			*node.ImageURL == "https://example.com/og.png" &&
+			*node.FaviconURL == "https://example.com/favicon.png"
+	})).Return(nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("CreateAuditLog", mock.AnythingOfType("*audit.AuditLog")).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("CreateRevision", mock.AnythingOfType("*url.NodeRevision")).Return(nil)
+	mockRepo.On("DeleteStaleOperations", userID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("CreateOperation", mock.AnythingOfType("*url.Operation")).Return(nil)
+	mockRepo.On("GetByNormalizedURL", userID, "https://example.com").Return([]URLNode{}, nil)
 
 	// Act
-	err := service.ReplaceURL(nodeID, updates, userID)
+	response, err := service.CreateURL(ctx, creates, userID, "request-id")
 
 	// Assert
 	require.NoError(t, err)
+	assert.Equal(t, "Example Domain", *response.Title)
 	mockRepo.AssertExpectations(t)
+	mockFetcher.AssertExpectations(t)
 }
-func TestService_ReplaceURL_UpdatedNodeOwnershipError(t *testing.T) {
+func TestService_CreateURL_FetchErrorIgnored(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
-	service := &service{repo: mockRepo}
-	nodeID := "mock-node-id"
-	userID := 1
-	updates := &RequestBody{
-		ParentID: "new-parent-id",
-		Name:     "updated-name",
+	mockFetcher := &MockMetadataFetcher{}
+	service := &service{repo: mockRepo, fetcher: mockFetcher}
+	userID := "1"
+	creates := &RequestBody{
+		ParentID: "parent-id",
+		Name:     "new-url",
 		Type:     "url",
-		URL:      test.StringPtr("https://updated.com"),
+		URL:      test.StringPtr("https://example.com"),
 	}
+	parentNode := &URLNode{ID: "parent-id", UserID: userID, Name: "parent", Type: "folder"}
 
-	mockRepo.On("GetOne", nodeID).Return(nil, nil)
+	mockRepo.On("GetOne", "parent-id").Return(parentNode, nil)
+	mockRepo.On("GetChildren", "parent-id").Return([]URLNode{}, nil)
+	mockFetcher.On("Fetch", mock.Anything, "https://example.com").Return(nil, assert.AnError)
+	mockRepo.On("Create", mock.MatchedBy(func(node *URLNode) bool {
+		return node.Title == nil && node.FaviconURL == nil
+	})).Return(nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("CreateAuditLog", mock.AnythingOfType("*audit.AuditLog")).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("CreateRevision", mock.AnythingOfType("*url.NodeRevision")).Return(nil)
+	mockRepo.On("DeleteStaleOperations", userID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("CreateOperation", mock.AnythingOfType("*url.Operation")).Return(nil)
+	mockRepo.On("GetByNormalizedURL", userID, "https://example.com").Return([]URLNode{}, nil)
 
 	// Act
-	err := service.ReplaceURL(nodeID, updates, userID)
+	response, err := service.CreateURL(context.Background(), creates, userID, "request-id")
 
 	// Assert
-	assert.Error(t, err)
-	assert.Equal(t, apperror.CodeURLNotFound, err.(*apperror.AppError).Code)
+	require.NoError(t, err)
+	assert.Nil(t, response.Title)
 	mockRepo.AssertExpectations(t)
+	mockFetcher.AssertExpectations(t)
 }
-func TestService_ReplaceURL_NewParentOwnershipError(t *testing.T) {
+func TestService_CreateURL_AutoName(t *testing.T) {
+	tests := []struct {
+		name         string
+		metadata     *Metadata
+		fetchErr     error
+		siblings     []URLNode
+		expectedName string
+	}{
+		{
+			name:         "from title",
+			metadata:     &Metadata{Title: test.StringPtr("Example Domain")},
+			siblings:     []URLNode{},
+			expectedName: "Example Domain",
+		},
+		{
+			name:         "long title is truncated",
+			metadata:     &Metadata{Title: test.StringPtr("A title that is far too long to fit")},
+			siblings:     []URLNode{},
+			expectedName: "A title that is far",
+		},
+		{
+			name:         "from host when title is missing",
+			metadata:     &Metadata{},
+			siblings:     []URLNode{},
+			expectedName: "example.com",
+		},
+		{
+			name:         "from host when fetching fails",
+			fetchErr:     assert.AnError,
+			siblings:     []URLNode{},
+			expectedName: "example.com",
+		},
+		{
+			name:         "numbered when taken",
+			metadata:     &Metadata{Title: test.StringPtr("Example Domain")},
+			siblings:     []URLNode{{ID: "a", Name: "Example Domain"}, {ID: "b", Name: "Example Domain (2)"}},
+			expectedName: "Example Domain (3)",
+		},
+		{
+			name:         "numbered and truncated when taken",
+			metadata:     &Metadata{Title: test.StringPtr("Twenty characters!!!")},
+			siblings:     []URLNode{{ID: "a", Name: "Twenty characters!!!"}},
+			expectedName: "Twenty character (2)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := &MockRepository{}
+			mockFetcher := &MockMetadataFetcher{}
+			service := &service{repo: mockRepo, fetcher: mockFetcher}
+			userID := "1"
+			creates := &RequestBody{
+				ParentID: "parent-id",
+				Type:     "url",
+				URL:      test.StringPtr("https://www.example.com/page"),
+			}
+			parentNode := &URLNode{ID: "parent-id", UserID: userID, Name: "parent", Type: "folder"}
+
+			mockRepo.On("GetOne", "parent-id").Return(parentNode, nil)
+			mockRepo.On("GetChildren", "parent-id").Return(tt.siblings, nil)
+			if tt.fetchErr != nil {
+				mockFetcher.On("Fetch", mock.Anything, "https://www.example.com/page").Return(nil, tt.fetchErr)
+			} else {
+				mockFetcher.On("Fetch", mock.Anything, "https://www.example.com/page").Return(tt.metadata, nil)
+			}
+			mockRepo.On("Create", mock.AnythingOfType("*url.URLNode")).Return(nil)
+			mockRepo.On("Transaction", mock.Anything).Return(nil)
+			mockRepo.On("CreateAuditLog", mock.AnythingOfType("*audit.AuditLog")).Return(nil)
+			mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+			mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+			mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+			mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+			mockRepo.On("DeleteStaleOperations", userID, mock.AnythingOfType("time.Time")).Return(nil)
+			mockRepo.On("CreateOperation", mock.AnythingOfType("*url.Operation")).Return(nil)
+			mockRepo.On("CreateRevision", mock.AnythingOfType("*url.NodeRevision")).Return(nil)
+			mockRepo.On("GetByNormalizedURL", userID, "https://www.example.com/page").Return([]URLNode{}, nil)
+
+			// Act
+			response, err := service.CreateURL(context.Background(), creates, userID, "request-id")
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedName, response.Name)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+func TestService_CreateURL_FolderNameRequired(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
-	nodeID := "mock-node-id"
-	newParentID := "new-parent-id"
-	userID := 1
-	node := &URLNode{
-		ID:     nodeID,
-		UserID: userID,
-		Name:   "old-name",
-		Type:   "url",
-		URL:    test.StringPtr("https://old.com"),
-	}
-	updates := &RequestBody{
-		ParentID: newParentID,
-		Name:     "updated-name",
-		Type:     "url",
-		URL:      test.StringPtr("https://updated.com"),
+	userID := "1"
+	creates := &RequestBody{
+		ParentID: "parent-id",
+		Type:     "folder",
 	}
+	parentNode := &URLNode{ID: "parent-id", UserID: userID, Name: "parent", Type: "folder"}
 
-	mockRepo.On("GetOne", nodeID).Return(node, nil)
-	mockRepo.On("GetOne", newParentID).Return(nil, nil)
+	mockRepo.On("GetOne", "parent-id").Return(parentNode, nil)
 
 	// Act
-	err := service.ReplaceURL(nodeID, updates, userID)
+	response, err := service.CreateURL(context.Background(), creates, userID, "request-id")
 
 	// Assert
 	assert.Error(t, err)
-	assert.Equal(t, apperror.CodeURLNotFound, err.(*apperror.AppError).Code)
+	assert.Nil(t, response)
+	assert.Equal(t, apperror.CodeURLNameRequired, err.(*apperror.AppError).Code)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
 	mockRepo.AssertExpectations(t)
 }
-func TestService_ReplaceURL_NameUniquenessError(t *testing.T) {
+
+func TestService_ReplaceURL_Success(t *testing.T) {
 	// Arrange
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
 	newParentID := "new-parent-id"
-	userID := 1
+	userID := "1"
 	updates := &RequestBody{
 		ParentID: newParentID,
-		Name:     "existing-name",
+		Name:     "updated-name",
 		Type:     "url",
 		URL:      test.StringPtr("https://updated.com"),
 	}
@@ -682,8 +1190,241 @@ func TestService_ReplaceURL_NameUniquenessError(t *testing.T) {
 		ID:     nodeID,
 		UserID: userID,
 		Name:   "old-name",
-		Type:   "url",
-		URL:    test.StringPtr("https://old.com"),
+		Type:   "folder",
+		URL:    nil,
+	}
+	updatedNode := &URLNode{
+		ID:            nodeID,
+		UserID:        userID,
+		ParentID:      test.StringPtr(newParentID),
+		Name:          updates.Name,
+		Type:          updates.Type,
+		URL:           updates.URL,
+		NormalizedURL: test.StringPtr("https://updated.com"),
+	}
+	newParentNode := &URLNode{
+		ID:     newParentID,
+		UserID: userID,
+		Name:   "new-parent",
+		Type:   "folder",
+	}
+
+	mockRepo.On("GetOne", nodeID).Return(node, nil).Once()
+	mockRepo.On("GetOne", newParentID).Return(newParentNode, nil).Once()
+	mockRepo.On("GetChildren", newParentID).Return([]URLNode{}, nil)
+	mockRepo.On("GetOne", nodeID).Return(node, nil).Once()
+	mockRepo.On("GetOne", newParentID).Return(newParentNode, nil).Once()
+	mockRepo.On("Update", updatedNode).Return(nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("CreateAuditLog", mock.MatchedBy(func(entry *audit.AuditLog) bool {
+		return entry.Action == audit.ActionMove &&
+			entry.NodeID == nodeID &&
+			strings.Contains(*entry.Before, `"name":"old-name"`) &&
+			strings.Contains(*entry.After, `"parent_id":"new-parent-id"`) &&
+			*entry.RequestID == "request-id"
+	})).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("CreateRevision", mock.AnythingOfType("*url.NodeRevision")).Return(nil)
+	mockRepo.On("DeleteStaleOperations", userID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("CreateOperation", mock.AnythingOfType("*url.Operation")).Return(nil)
+
+	// Act
+	err := service.ReplaceURL(context.Background(), nodeID, updates, userID, "request-id")
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_ReplaceURL_MoveToOtherTree(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	updates := &RequestBody{ParentID: "shared-folder-id", Name: "name", Type: "folder"}
+	node := &URLNode{ID: "node-id", UserID: userID, Name: "name", Type: "folder"}
+	sharedFolder := &URLNode{ID: "shared-folder-id", UserID: "2", Name: "shared", Type: "folder"}
+
+	mockRepo.On("GetOne", "node-id").Return(node, nil)
+	mockRepo.On("GetOne", "shared-folder-id").Return(sharedFolder, nil)
+	mockRepo.On("GetParentUpToRoot", "shared-folder-id").Return([]URLNode{}, nil)
+	mockRepo.On("GetPermissions", []string{"shared-folder-id"}, userID).Return([]FolderPermission{{FolderID: "shared-folder-id", UserID: userID, Role: RoleEditor}}, nil)
+
+	// Act
+	err := service.ReplaceURL(context.Background(), "node-id", updates, userID, "request-id")
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeURLAccessDenied, err.(*apperror.AppError).Code)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+func TestService_ReplaceURL_MountTypeChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    *URLNode
+		updates *RequestBody
+	}{
+		{
+			name:    "mount to folder",
+			node:    &URLNode{ID: "node-id", UserID: "1", Name: "name", Type: "mount", TargetID: test.StringPtr("shared-id")},
+			updates: &RequestBody{ParentID: "parent-id", Name: "name", Type: "folder"},
+		},
+		{
+			name:    "folder to mount",
+			node:    &URLNode{ID: "node-id", UserID: "1", Name: "name", Type: "folder"},
+			updates: &RequestBody{ParentID: "parent-id", Name: "name", Type: "mount"},
+		},
+		{
+			name:    "mount with url",
+			node:    &URLNode{ID: "node-id", UserID: "1", Name: "name", Type: "mount", TargetID: test.StringPtr("shared-id")},
+			updates: &RequestBody{ParentID: "parent-id", Name: "name", Type: "mount", URL: test.StringPtr("https://example.com")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := &MockRepository{}
+			service := &service{repo: mockRepo}
+
+			mockRepo.On("GetOne", "node-id").Return(tt.node, nil)
+			mockRepo.On("GetOne", "parent-id").Return(&URLNode{ID: "parent-id", UserID: "1", Type: "folder"}, nil)
+
+			// Act
+			err := service.ReplaceURL(context.Background(), "node-id", tt.updates, "1", "request-id")
+
+			// Assert
+			require.Error(t, err)
+			assert.Equal(t, apperror.CodeMountInvalid, err.(*apperror.AppError).Code)
+			mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+		})
+	}
+}
+func TestService_ReplaceURL_KeepsMetadataWhenURLUnchanged(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockFetcher := &MockMetadataFetcher{}
+	service := &service{repo: mockRepo, fetcher: mockFetcher}
+	nodeID := "mock-node-id"
+	parentID := "parent-id"
+	userID := "1"
+	updates := &RequestBody{
+		ParentID: parentID,
+		Name:     "renamed",
+		Type:     "url",
+		URL:      test.StringPtr("https://example.com"),
+	}
+	node := &URLNode{
+		ID:       nodeID,
+		UserID:   userID,
+		ParentID: test.StringPtr(parentID),
+		Name:     "old-name",
+		Type:     "url",
+		URL:      test.StringPtr("https://example.com"),
+		Title:    test.StringPtr("Example Domain"),
+	}
+	parentNode := &URLNode{ID: parentID, UserID: userID, Name: "parent", Type: "folder"}
+
+	mockRepo.On("GetOne", nodeID).Return(node, nil)
+	mockRepo.On("GetOne", parentID).Return(parentNode, nil)
+	mockRepo.On("GetChildren", parentID).Return([]URLNode{}, nil)
+	mockRepo.On("Update", mock.MatchedBy(func(node *URLNode) bool {
+		return node.Name == "renamed" && *node.Title == "Example Domain"
+	})).Return(nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("CreateAuditLog", mock.AnythingOfType("*audit.AuditLog")).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("CreateRevision", mock.AnythingOfType("*url.NodeRevision")).Return(nil)
+	mockRepo.On("DeleteStaleOperations", userID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("CreateOperation", mock.AnythingOfType("*url.Operation")).Return(nil)
+
+	// Act
+	err := service.ReplaceURL(context.Background(), nodeID, updates, userID, "request-id")
+
+	// Assert
+	require.NoError(t, err)
+	mockFetcher.AssertNotCalled(t, "Fetch", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_ReplaceURL_UpdatedNodeOwnershipError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	nodeID := "mock-node-id"
+	userID := "1"
+	updates := &RequestBody{
+		ParentID: "new-parent-id",
+		Name:     "updated-name",
+		Type:     "url",
+		URL:      test.StringPtr("https://updated.com"),
+	}
+
+	mockRepo.On("GetOne", nodeID).Return(nil, nil)
+
+	// Act
+	err := service.ReplaceURL(context.Background(), nodeID, updates, userID, "request-id")
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, apperror.CodeURLNotFound, err.(*apperror.AppError).Code)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_ReplaceURL_NewParentOwnershipError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	nodeID := "mock-node-id"
+	newParentID := "new-parent-id"
+	userID := "1"
+	node := &URLNode{
+		ID:     nodeID,
+		UserID: userID,
+		Name:   "old-name",
+		Type:   "url",
+		URL:    test.StringPtr("https://old.com"),
+	}
+	updates := &RequestBody{
+		ParentID: newParentID,
+		Name:     "updated-name",
+		Type:     "url",
+		URL:      test.StringPtr("https://updated.com"),
+	}
+
+	mockRepo.On("GetOne", nodeID).Return(node, nil)
+	mockRepo.On("GetOne", newParentID).Return(nil, nil)
+
+	// Act
+	err := service.ReplaceURL(context.Background(), nodeID, updates, userID, "request-id")
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, apperror.CodeURLNotFound, err.(*apperror.AppError).Code)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_ReplaceURL_NameUniquenessError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	nodeID := "mock-node-id"
+	newParentID := "new-parent-id"
+	userID := "1"
+	updates := &RequestBody{
+		ParentID: newParentID,
+		Name:     "existing-name",
+		Type:     "url",
+		URL:      test.StringPtr("https://updated.com"),
+	}
+	node := &URLNode{
+		ID:     nodeID,
+		UserID: userID,
+		Name:   "old-name",
+		Type:   "url",
+		URL:    test.StringPtr("https://old.com"),
 	}
 	newParentNode := &URLNode{
 		ID:     newParentID,
@@ -701,7 +1442,7 @@ func TestService_ReplaceURL_NameUniquenessError(t *testing.T) {
 	mockRepo.On("GetChildren", newParentID).Return(siblings, nil)
 
 	// Act
-	err := service.ReplaceURL(nodeID, updates, userID)
+	err := service.ReplaceURL(context.Background(), nodeID, updates, userID, "request-id")
 
 	// Assert
 	assert.Error(t, err)
@@ -714,7 +1455,7 @@ func TestService_ReplaceURL_UpdateError(t *testing.T) {
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
 	newParentID := "new-parent-id"
-	userID := 1
+	userID := "1"
 	updates := &RequestBody{
 		ParentID: newParentID,
 		Name:     "updated-name",
@@ -740,9 +1481,10 @@ func TestService_ReplaceURL_UpdateError(t *testing.T) {
 	mockRepo.On("GetOne", newParentID).Return(newParentNode, nil)
 	mockRepo.On("GetChildren", newParentID).Return([]URLNode{}, nil)
 	mockRepo.On("Update", mock.AnythingOfType("*url.URLNode")).Return(assert.AnError)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
 
 	// Act
-	err := service.ReplaceURL(nodeID, updates, userID)
+	err := service.ReplaceURL(context.Background(), nodeID, updates, userID, "request-id")
 
 	// Assert
 	assert.Error(t, err)
@@ -755,7 +1497,7 @@ func TestService_DeleteURL_Success(t *testing.T) {
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
-	userID := 1
+	userID := "1"
 	node := &URLNode{
 		ID:     nodeID,
 		UserID: userID,
@@ -765,9 +1507,24 @@ func TestService_DeleteURL_Success(t *testing.T) {
 
 	mockRepo.On("GetOne", nodeID).Return(node, nil)
 	mockRepo.On("SoftDelete", nodeID).Return(nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("CreateAuditLog", mock.MatchedBy(func(entry *audit.AuditLog) bool {
+		return entry.Action == audit.ActionDelete &&
+			entry.NodeID == nodeID &&
+			entry.OwnerID == userID &&
+			entry.ActorID == userID &&
+			entry.Before != nil &&
+			entry.After == nil
+	})).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("DeleteStaleOperations", userID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("CreateOperation", mock.AnythingOfType("*url.Operation")).Return(nil)
 
 	// Act
-	err := service.DeleteURL(nodeID, userID)
+	err := service.DeleteURL(nodeID, userID, "request-id")
 
 	// Assert
 	require.NoError(t, err)
@@ -778,12 +1535,12 @@ func TestService_DeleteURL_OwnershipError(t *testing.T) {
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
-	userID := 1
+	userID := "1"
 
 	mockRepo.On("GetOne", nodeID).Return(nil, nil)
 
 	// Act
-	err := service.DeleteURL(nodeID, userID)
+	err := service.DeleteURL(nodeID, userID, "request-id")
 
 	// Assert
 	assert.Error(t, err)
@@ -795,7 +1552,7 @@ func TestService_DeleteURL_SoftDeleteError(t *testing.T) {
 	mockRepo := &MockRepository{}
 	service := &service{repo: mockRepo}
 	nodeID := "mock-node-id"
-	userID := 1
+	userID := "1"
 	node := &URLNode{
 		ID:     nodeID,
 		UserID: userID,
@@ -805,12 +1562,1370 @@ func TestService_DeleteURL_SoftDeleteError(t *testing.T) {
 
 	mockRepo.On("GetOne", nodeID).Return(node, nil)
 	mockRepo.On("SoftDelete", nodeID).Return(assert.AnError)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
 
 	// Act
-	err := service.DeleteURL(nodeID, userID)
+	err := service.DeleteURL(nodeID, userID, "request-id")
 
 	// Assert
 	assert.Error(t, err)
 	assert.Equal(t, assert.AnError, err)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestService_GetDuplicates_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	parent := URLNode{ID: "parent-id", UserID: userID, Name: "parent", Type: "folder"}
+	nodes := []URLNode{
+		{ID: "url-1", UserID: userID, ParentID: test.StringPtr("parent-id"), Name: "a", Type: "url", URL: test.StringPtr("https://example.com")},
+		{ID: "url-2", UserID: userID, ParentID: test.StringPtr("parent-id"), Name: "b", Type: "url", URL: test.StringPtr("https://example.com/")},
+	}
+
+	mockRepo.On("GetDuplicateNormalizedURLs", userID).Return([]string{"https://example.com"}, nil)
+	mockRepo.On("GetByNormalizedURL", userID, "https://example.com").Return(nodes, nil)
+	mockRepo.On("GetParentUpToRoot", "url-1").Return([]URLNode{parent}, nil)
+	mockRepo.On("GetParentUpToRoot", "url-2").Return([]URLNode{parent}, nil)
+
+	// Act
+	groups, err := service.GetDuplicates(userID)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "https://example.com", groups[0].NormalizedURL)
+	require.Len(t, groups[0].URLs, 2)
+	assert.Equal(t, "url-1", groups[0].URLs[0].ID)
+	assert.Equal(t, "url-2", groups[0].URLs[1].ID)
+	require.Len(t, groups[0].URLs[0].Parent, 1)
+	assert.Equal(t, "parent-id", groups[0].URLs[0].Parent[0].ID)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_GetDuplicates_NoDuplicates(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+
+	mockRepo.On("GetDuplicateNormalizedURLs", userID).Return([]string{}, nil)
+
+	// Act
+	groups, err := service.GetDuplicates(userID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_GetDuplicates_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+
+	mockRepo.On("GetDuplicateNormalizedURLs", userID).Return([]string{}, assert.AnError)
+
+	// Act
+	groups, err := service.GetDuplicates(userID)
+
+	// Assert
+	assert.Equal(t, assert.AnError, err)
+	assert.Nil(t, groups)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_MergeDuplicates_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	target := &URLNode{ID: "target-id", UserID: userID, Type: "url", URL: test.StringPtr("https://example.com"), NormalizedURL: test.StringPtr("https://example.com")}
+	source := &URLNode{ID: "source-id", UserID: userID, Type: "url", URL: test.StringPtr("https://EXAMPLE.com/?utm_medium=x")}
+	merges := &MergeDuplicatesRequestBody{TargetID: "target-id", SourceIDs: []string{"source-id"}}
+
+	mockRepo.On("GetOne", "target-id").Return(target, nil)
+	mockRepo.On("GetOne", "source-id").Return(source, nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("SoftDelete", "source-id").Return(nil)
+	mockRepo.On("CreateAuditLog", mock.AnythingOfType("*audit.AuditLog")).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("DeleteStaleOperations", userID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("CreateOperation", mock.AnythingOfType("*url.Operation")).Return(nil)
+
+	// Act
+	err := service.MergeDuplicates(merges, userID, "request-id")
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_MergeDuplicates_NotDuplicate(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	target := &URLNode{ID: "target-id", UserID: userID, Type: "url", URL: test.StringPtr("https://example.com"), NormalizedURL: test.StringPtr("https://example.com")}
+	source := &URLNode{ID: "source-id", UserID: userID, Type: "url", URL: test.StringPtr("https://other.com")}
+	merges := &MergeDuplicatesRequestBody{TargetID: "target-id", SourceIDs: []string{"source-id"}}
+
+	mockRepo.On("GetOne", "target-id").Return(target, nil)
+	mockRepo.On("GetOne", "source-id").Return(source, nil)
+
+	// Act
+	err := service.MergeDuplicates(merges, userID, "request-id")
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, apperror.CodeURLNotDuplicate, err.(*apperror.AppError).Code)
+	mockRepo.AssertNotCalled(t, "SoftDelete", mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_MergeDuplicates_TargetIsFolder(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	target := &URLNode{ID: "target-id", UserID: userID, Type: "folder"}
+	merges := &MergeDuplicatesRequestBody{TargetID: "target-id", SourceIDs: []string{"source-id"}}
+
+	mockRepo.On("GetOne", "target-id").Return(target, nil)
+
+	// Act
+	err := service.MergeDuplicates(merges, userID, "request-id")
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, apperror.CodeURLNotDuplicate, err.(*apperror.AppError).Code)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_MergeDuplicates_SourceIsTarget(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	target := &URLNode{ID: "target-id", UserID: userID, Type: "url", URL: test.StringPtr("https://example.com"), NormalizedURL: test.StringPtr("https://example.com")}
+	merges := &MergeDuplicatesRequestBody{TargetID: "target-id", SourceIDs: []string{"target-id"}}
+
+	mockRepo.On("GetOne", "target-id").Return(target, nil)
+
+	// Act
+	err := service.MergeDuplicates(merges, userID, "request-id")
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, apperror.CodeURLNotDuplicate, err.(*apperror.AppError).Code)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_MergeDuplicates_SourceAccessDenied(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	target := &URLNode{ID: "target-id", UserID: userID, Type: "url", URL: test.StringPtr("https://example.com"), NormalizedURL: test.StringPtr("https://example.com")}
+	source := &URLNode{ID: "source-id", UserID: "2", Type: "url", URL: test.StringPtr("https://example.com")}
+	merges := &MergeDuplicatesRequestBody{TargetID: "target-id", SourceIDs: []string{"source-id"}}
+
+	mockRepo.On("GetOne", "target-id").Return(target, nil)
+	mockRepo.On("GetOne", "source-id").Return(source, nil)
+	mockRepo.On("GetParentUpToRoot", "source-id").Return([]URLNode{}, nil)
+	mockRepo.On("GetPermissions", []string{"source-id"}, userID).Return([]FolderPermission{}, nil)
+
+	// Act
+	err := service.MergeDuplicates(merges, userID, "request-id")
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, apperror.CodeURLAccessDenied, err.(*apperror.AppError).Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_LookupURL_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	parent := URLNode{ID: "parent-id", UserID: userID, Name: "parent", Type: "folder"}
+	node := URLNode{ID: "url-id", UserID: userID, ParentID: test.StringPtr("parent-id"), Name: "a", Type: "url", URL: test.StringPtr("https://example.com/"), NormalizedURL: test.StringPtr("https://example.com")}
+
+	mockRepo.On("GetByNormalizedURLs", userID, []string{"https://example.com"}).Return([]URLNode{node}, nil)
+	mockRepo.On("GetParentUpToRoot", "url-id").Return([]URLNode{parent}, nil)
+
+	// Act
+	result, err := service.LookupURL("HTTPS://example.com?utm_source=x", userID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "HTTPS://example.com?utm_source=x", result.URL)
+	require.Len(t, result.Matches, 1)
+	assert.Equal(t, "url-id", result.Matches[0].ID)
+	require.Len(t, result.Matches[0].Parent, 1)
+	assert.Equal(t, "parent-id", result.Matches[0].Parent[0].ID)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_LookupURL_InvalidURL(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+
+	// Act
+	result, err := service.LookupURL("about:blank", userID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, result.Matches)
+	mockRepo.AssertNotCalled(t, "GetByNormalizedURLs", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_LookupURLs_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	nodes := []URLNode{
+		{ID: "url-1", UserID: userID, Type: "url", NormalizedURL: test.StringPtr("https://example.com")},
+		{ID: "url-2", UserID: userID, Type: "url", NormalizedURL: test.StringPtr("https://example.com")},
+	}
+
+	mockRepo.On("GetByNormalizedURLs", userID, []string{"https://example.com", "https://other.com"}).Return(nodes, nil)
+	mockRepo.On("GetParentUpToRoot", "url-1").Return([]URLNode{}, nil)
+	mockRepo.On("GetParentUpToRoot", "url-2").Return([]URLNode{}, nil)
+
+	// Act
+	results, err := service.LookupURLs([]string{"https://example.com/", "https://other.com", "not a url"}, userID)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, "https://example.com/", results[0].URL)
+	assert.Len(t, results[0].Matches, 2)
+	assert.Equal(t, "https://other.com", results[1].URL)
+	assert.Empty(t, results[1].Matches)
+	assert.Equal(t, "not a url", results[2].URL)
+	assert.Empty(t, results[2].Matches)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_LookupURLs_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+
+	mockRepo.On("GetByNormalizedURLs", userID, []string{"https://example.com"}).Return([]URLNode{}, assert.AnError)
+
+	// Act
+	results, err := service.LookupURLs([]string{"https://example.com"}, userID)
+
+	// Assert
+	assert.Equal(t, assert.AnError, err)
+	assert.Nil(t, results)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_GetBrokenURLs_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	checkedAt := time.Unix(100, 0)
+	statusCode := 404
+	parent := URLNode{ID: "parent-id", UserID: userID, Name: "parent", Type: "folder"}
+	node := URLNode{
+		ID:             "url-id",
+		UserID:         userID,
+		ParentID:       test.StringPtr("parent-id"),
+		Name:           "a",
+		Type:           "url",
+		URL:            test.StringPtr("https://example.com/gone"),
+		LastStatusCode: &statusCode,
+		LastCheckedAt:  &checkedAt,
+	}
+
+	mockRepo.On("GetBroken", userID).Return([]URLNode{node}, nil)
+	mockRepo.On("GetParentUpToRoot", "url-id").Return([]URLNode{parent}, nil)
+
+	// Act
+	brokenURLs, err := service.GetBrokenURLs(userID)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, brokenURLs, 1)
+	assert.Equal(t, "url-id", brokenURLs[0].ID)
+	assert.Equal(t, 404, *brokenURLs[0].LastStatusCode)
+	assert.Equal(t, checkedAt.UTC().Format(time.RFC3339), brokenURLs[0].LastCheckedAt)
+	require.Len(t, brokenURLs[0].Parent, 1)
+	assert.Equal(t, "parent-id", brokenURLs[0].Parent[0].ID)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_GetBrokenURLs_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+
+	mockRepo.On("GetBroken", userID).Return([]URLNode{}, assert.AnError)
+
+	// Act
+	brokenURLs, err := service.GetBrokenURLs(userID)
+
+	// Assert
+	assert.Equal(t, assert.AnError, err)
+	assert.Nil(t, brokenURLs)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_GetFavicon_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockStore := &MockFaviconStore{}
+	service := &service{repo: mockRepo, favicons: newTestFaviconCache(mockStore)}
+	userID := "1"
+	node := &URLNode{ID: "url-id", UserID: userID, Name: "a", Type: "url", URL: test.StringPtr("https://example.com/page")}
+	cached := &Favicon{Host: "example.com", ContentType: "image/png", Data: pngIcon, FetchedAt: time.Now()}
+
+	mockRepo.On("GetOne", "url-id").Return(node, nil)
+	mockStore.On("Get", "example.com").Return(cached, nil)
+
+	// Act
+	favicon, err := service.GetFavicon(context.Background(), "url-id", userID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, cached, favicon)
+	mockRepo.AssertExpectations(t)
+	mockStore.AssertExpectations(t)
+}
+func TestService_GetFavicon_Folder(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockStore := &MockFaviconStore{}
+	service := &service{repo: mockRepo, favicons: newTestFaviconCache(mockStore)}
+	userID := "1"
+	node := &URLNode{ID: "folder-id", UserID: userID, Name: "work", Type: "folder"}
+
+	mockRepo.On("GetOne", "folder-id").Return(node, nil)
+
+	// Act
+	favicon, err := service.GetFavicon(context.Background(), "folder-id", userID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", favicon.ContentType)
+	assert.Contains(t, string(favicon.Data), ">W</text>")
+	mockStore.AssertNotCalled(t, "Get", mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_GetFavicon_NotFound(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+
+	mockRepo.On("GetOne", "url-id").Return(nil, nil)
+
+	// Act
+	favicon, err := service.GetFavicon(context.Background(), "url-id", "1")
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, apperror.CodeURLNotFound, err.(*apperror.AppError).Code)
+	assert.Nil(t, favicon)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_GetCollaborators_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	folder := &URLNode{ID: "folder-id", UserID: userID, Type: "folder"}
+	permissions := []FolderPermission{
+		{FolderID: "folder-id", UserID: "2", Role: RoleViewer, CreatedAt: time.Unix(0, 0), UpdatedAt: time.Unix(0, 0)},
+		{FolderID: "folder-id", UserID: "3", Role: RoleEditor, CreatedAt: time.Unix(0, 0), UpdatedAt: time.Unix(0, 0)},
+	}
+
+	mockRepo.On("GetOne", "folder-id").Return(folder, nil)
+	mockRepo.On("GetCollaborators", "folder-id").Return(permissions, nil)
+
+	// Act
+	collaborators, err := service.GetCollaborators("folder-id", userID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []Collaborator{
+		{UserID: "2", Role: RoleViewer, CreatedAt: "1970-01-01T00:00:00Z", UpdatedAt: "1970-01-01T00:00:00Z"},
+		{UserID: "3", Role: RoleEditor, CreatedAt: "1970-01-01T00:00:00Z", UpdatedAt: "1970-01-01T00:00:00Z"},
+	}, collaborators)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_AddCollaborator_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	folder := &URLNode{ID: "folder-id", UserID: userID, Type: "folder"}
+	adds := &AddCollaboratorRequestBody{UserID: "2", Role: RoleEditor}
+
+	mockRepo.On("GetOne", "folder-id").Return(folder, nil)
+	mockRepo.On("GetPermission", "folder-id", "2").Return(nil, nil)
+	mockRepo.On("CreatePermission", &FolderPermission{FolderID: "folder-id", UserID: "2", Role: RoleEditor}).Return(nil)
+
+	// Act
+	collaborator, err := service.AddCollaborator("folder-id", adds, userID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "2", collaborator.UserID)
+	assert.Equal(t, RoleEditor, collaborator.Role)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_AddCollaborator_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		folder       *URLNode
+		adds         *AddCollaboratorRequestBody
+		existing     *FolderPermission
+		expectedCode string
+	}{
+		{
+			name:         "not a folder",
+			folder:       &URLNode{ID: "folder-id", UserID: "1", Type: "url"},
+			adds:         &AddCollaboratorRequestBody{UserID: "2", Role: RoleViewer},
+			expectedCode: apperror.CodeShareFolderOnly,
+		},
+		{
+			name:         "owner invited",
+			folder:       &URLNode{ID: "folder-id", UserID: "1", Type: "folder"},
+			adds:         &AddCollaboratorRequestBody{UserID: "1", Role: RoleViewer},
+			expectedCode: apperror.CodeCollaboratorInvalid,
+		},
+		{
+			name:         "already a collaborator",
+			folder:       &URLNode{ID: "folder-id", UserID: "1", Type: "folder"},
+			adds:         &AddCollaboratorRequestBody{UserID: "2", Role: RoleViewer},
+			existing:     &FolderPermission{FolderID: "folder-id", UserID: "2", Role: RoleEditor},
+			expectedCode: apperror.CodeCollaboratorAlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := &MockRepository{}
+			service := &service{repo: mockRepo}
+
+			mockRepo.On("GetOne", "folder-id").Return(tt.folder, nil)
+			if tt.existing != nil {
+				mockRepo.On("GetPermission", "folder-id", tt.adds.UserID).Return(tt.existing, nil)
+			}
+
+			// Act
+			collaborator, err := service.AddCollaborator("folder-id", tt.adds, "1")
+
+			// Assert
+			require.Error(t, err)
+			assert.Equal(t, tt.expectedCode, err.(*apperror.AppError).Code)
+			assert.Nil(t, collaborator)
+			mockRepo.AssertNotCalled(t, "CreatePermission", mock.Anything)
+		})
+	}
+}
+func TestService_AddCollaborator_EditorDenied(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	folder := &URLNode{ID: "folder-id", UserID: "2", Type: "folder"}
+
+	mockRepo.On("GetOne", "folder-id").Return(folder, nil)
+	mockRepo.On("GetParentUpToRoot", "folder-id").Return([]URLNode{}, nil)
+	mockRepo.On("GetPermissions", []string{"folder-id"}, userID).Return([]FolderPermission{{FolderID: "folder-id", UserID: userID, Role: RoleEditor}}, nil)
+
+	// Act
+	collaborator, err := service.AddCollaborator("folder-id", &AddCollaboratorRequestBody{UserID: "3", Role: RoleViewer}, userID)
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeURLAccessDenied, err.(*apperror.AppError).Code)
+	assert.Nil(t, collaborator)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_UpdateCollaborator_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	folder := &URLNode{ID: "folder-id", UserID: userID, Type: "folder"}
+	permission := &FolderPermission{ID: "permission-id", FolderID: "folder-id", UserID: "2", Role: RoleViewer}
+
+	mockRepo.On("GetOne", "folder-id").Return(folder, nil)
+	mockRepo.On("GetPermission", "folder-id", "2").Return(permission, nil)
+	mockRepo.On("UpdatePermission", &FolderPermission{ID: "permission-id", FolderID: "folder-id", UserID: "2", Role: RoleOwner}).Return(nil)
+
+	// Act
+	err := service.UpdateCollaborator("folder-id", "2", &UpdateCollaboratorRequestBody{Role: RoleOwner}, userID)
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_UpdateCollaborator_NotFound(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	folder := &URLNode{ID: "folder-id", UserID: userID, Type: "folder"}
+
+	mockRepo.On("GetOne", "folder-id").Return(folder, nil)
+	mockRepo.On("GetPermission", "folder-id", "2").Return(nil, nil)
+
+	// Act
+	err := service.UpdateCollaborator("folder-id", "2", &UpdateCollaboratorRequestBody{Role: RoleOwner}, userID)
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeCollaboratorNotFound, err.(*apperror.AppError).Code)
+	mockRepo.AssertNotCalled(t, "UpdatePermission", mock.Anything)
+}
+
+func TestService_RemoveCollaborator_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	folder := &URLNode{ID: "folder-id", UserID: userID, Type: "folder"}
+
+	mockRepo.On("GetOne", "folder-id").Return(folder, nil)
+	mockRepo.On("GetPermission", "folder-id", "2").Return(&FolderPermission{FolderID: "folder-id", UserID: "2", Role: RoleEditor}, nil)
+	mockRepo.On("DeletePermission", "folder-id", "2").Return(nil)
+
+	// Act
+	err := service.RemoveCollaborator("folder-id", "2", userID)
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_RemoveCollaborator_Leave(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "2"
+	folder := &URLNode{ID: "folder-id", UserID: "1", Type: "folder"}
+	permission := &FolderPermission{FolderID: "folder-id", UserID: userID, Role: RoleViewer}
+
+	mockRepo.On("GetOne", "folder-id").Return(folder, nil)
+	mockRepo.On("GetParentUpToRoot", "folder-id").Return([]URLNode{}, nil)
+	mockRepo.On("GetPermissions", []string{"folder-id"}, userID).Return([]FolderPermission{*permission}, nil)
+	mockRepo.On("GetPermission", "folder-id", userID).Return(permission, nil)
+	mockRepo.On("DeletePermission", "folder-id", userID).Return(nil)
+
+	// Act
+	err := service.RemoveCollaborator("folder-id", userID, userID)
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_GetSharedWithMe_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "2"
+	permissions := []FolderPermission{
+		{FolderID: "team-id", UserID: userID, Role: RoleViewer, CreatedAt: time.Unix(0, 0)},
+		{FolderID: "docs-id", UserID: userID, Role: RoleViewer, CreatedAt: time.Unix(0, 0)},
+		{FolderID: "drafts-id", UserID: userID, Role: RoleEditor, CreatedAt: time.Unix(0, 0)},
+		{FolderID: "deleted-id", UserID: userID, Role: RoleViewer, CreatedAt: time.Unix(0, 0)},
+		{FolderID: "trashed-parent-id", UserID: userID, Role: RoleViewer, CreatedAt: time.Unix(0, 0)},
+	}
+	team := &URLNode{ID: "team-id", UserID: "1", Name: "team", Type: "folder"}
+	trashedParent := &URLNode{ID: "trashed-parent-id", UserID: "1", ParentID: test.StringPtr("trash-id"), Name: "old", Type: "folder"}
+	docs := &URLNode{ID: "docs-id", UserID: "1", ParentID: test.StringPtr("team-id"), Name: "docs", Type: "folder"}
+	drafts := &URLNode{ID: "drafts-id", UserID: "1", ParentID: test.StringPtr("team-id"), Name: "drafts", Type: "folder"}
+
+	mockRepo.On("GetPermissionsByUser", userID).Return(permissions, nil)
+	mockRepo.On("GetOne", "team-id").Return(team, nil)
+	mockRepo.On("GetOne", "docs-id").Return(docs, nil)
+	mockRepo.On("GetOne", "drafts-id").Return(drafts, nil)
+	mockRepo.On("GetOne", "deleted-id").Return(nil, nil)
+	mockRepo.On("GetOne", "trashed-parent-id").Return(trashedParent, nil)
+	mockRepo.On("GetParentUpToRoot", "trashed-parent-id").Return([]URLNode(nil), apperror.New(apperror.CodeURLNotFound, "URL is in the trash | id: trashed-parent-id"))
+	mockRepo.On("GetParentUpToRoot", "team-id").Return([]URLNode{{ID: "root-id", UserID: "1", Type: "folder"}}, nil)
+	mockRepo.On("GetParentUpToRoot", "docs-id").Return([]URLNode{{ID: "root-id"}, *team}, nil)
+	mockRepo.On("GetParentUpToRoot", "drafts-id").Return([]URLNode{{ID: "root-id"}, *team}, nil)
+
+	// Act
+	folders, err := service.GetSharedWithMe(userID)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, folders, 2)
+	assert.Equal(t, "team-id", folders[0].ID)
+	assert.Equal(t, "1", folders[0].OwnerID)
+	assert.Equal(t, RoleViewer, folders[0].Role)
+	assert.Equal(t, "1970-01-01T00:00:00Z", folders[0].SharedAt)
+	assert.Equal(t, "drafts-id", folders[1].ID)
+	assert.Equal(t, RoleEditor, folders[1].Role)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_GetSharedWithMe_Empty(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+
+	mockRepo.On("GetPermissionsByUser", "2").Return([]FolderPermission{}, nil)
+
+	// Act
+	folders, err := service.GetSharedWithMe("2")
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotNil(t, folders)
+	assert.Empty(t, folders)
+}
+
+func TestService_MountFolder_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "2"
+	folder := &URLNode{ID: "shared-id", UserID: "1", Name: "team", Type: "folder"}
+	parent := &URLNode{ID: "parent-id", UserID: userID, Type: "folder"}
+
+	mockRepo.On("GetOne", "shared-id").Return(folder, nil)
+	mockRepo.On("GetParentUpToRoot", "shared-id").Return([]URLNode{}, nil)
+	mockRepo.On("GetPermissions", []string{"shared-id"}, userID).Return([]FolderPermission{{FolderID: "shared-id", UserID: userID, Role: RoleViewer}}, nil)
+	mockRepo.On("GetOne", "parent-id").Return(parent, nil)
+	mockRepo.On("GetChildren", "parent-id").Return([]URLNode{}, nil)
+	mockRepo.On("Create", &URLNode{
+		UserID:   userID,
+		ParentID: test.StringPtr("parent-id"),
+		Name:     "team",
+		Type:     "mount",
+		TargetID: test.StringPtr("shared-id"),
+	}).Return(nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("CreateAuditLog", mock.AnythingOfType("*audit.AuditLog")).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("CreateRevision", mock.AnythingOfType("*url.NodeRevision")).Return(nil)
+	mockRepo.On("DeleteStaleOperations", userID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("CreateOperation", mock.AnythingOfType("*url.Operation")).Return(nil)
+
+	// Act
+	response, err := service.MountFolder("shared-id", &MountRequestBody{ParentID: "parent-id"}, userID, "request-id")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "team", response.Name)
+	assert.Equal(t, "mount", response.Type)
+	assert.Equal(t, "shared-id", *response.TargetID)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_MountFolder_OwnFolder(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+
+	mockRepo.On("GetOne", "folder-id").Return(&URLNode{ID: "folder-id", UserID: userID, Type: "folder"}, nil)
+
+	// Act
+	response, err := service.MountFolder("folder-id", &MountRequestBody{ParentID: "parent-id"}, userID, "request-id")
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeMountInvalid, err.(*apperror.AppError).Code)
+	assert.Nil(t, response)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+func TestService_MountFolder_IntoOtherTree(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "2"
+	permissions := []FolderPermission{
+		{FolderID: "shared-id", UserID: userID, Role: RoleEditor},
+	}
+
+	mockRepo.On("GetOne", "shared-id").Return(&URLNode{ID: "shared-id", UserID: "1", Name: "team", Type: "folder"}, nil)
+	mockRepo.On("GetParentUpToRoot", "shared-id").Return([]URLNode{}, nil)
+	mockRepo.On("GetPermissions", []string{"shared-id"}, userID).Return(permissions, nil)
+
+	// Act
+	response, err := service.MountFolder("shared-id", &MountRequestBody{ParentID: "shared-id"}, userID, "request-id")
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeURLAccessDenied, err.(*apperror.AppError).Code)
+	assert.Nil(t, response)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestService_GetHistory_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	node := &URLNode{ID: "node-id", UserID: userID, Name: "node", Type: "folder"}
+	logs := []audit.AuditLog{
+		{ID: "log-2", NodeID: "node-id", OwnerID: userID, ActorID: "2", Action: audit.ActionUpdate, Before: test.StringPtr(`{"name":"old"}`), After: test.StringPtr(`{"name":"node"}`), CreatedAt: time.Unix(60, 0)},
+		{ID: "log-1", NodeID: "node-id", OwnerID: userID, ActorID: userID, Action: audit.ActionCreate, After: test.StringPtr(`{"name":"old"}`), CreatedAt: time.Unix(0, 0)},
+	}
+
+	mockRepo.On("GetOne", "node-id").Return(node, nil)
+	mockRepo.On("GetAuditLogs", "node-id").Return(logs, nil)
+
+	// Act
+	history, err := service.GetHistory("node-id", userID)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, audit.ActionUpdate, history[0].Action)
+	assert.Equal(t, "2", history[0].ActorID)
+	assert.JSONEq(t, `{"name":"old"}`, string(history[0].Before))
+	assert.Nil(t, history[1].Before)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_GetHistory_DeletedNode(t *testing.T) {
+	tests := []struct {
+		name         string
+		userID       string
+		logs         []audit.AuditLog
+		expectedCode string
+	}{
+		{
+			name:   "owner",
+			userID: "1",
+			logs:   []audit.AuditLog{{ID: "log-1", NodeID: "node-id", OwnerID: "1", ActorID: "2", Action: audit.ActionDelete}},
+		},
+		{
+			name:         "collaborator",
+			userID:       "2",
+			logs:         []audit.AuditLog{{ID: "log-1", NodeID: "node-id", OwnerID: "1", ActorID: "2", Action: audit.ActionDelete}},
+			expectedCode: apperror.CodeURLNotFound,
+		},
+		{
+			name:         "unknown node",
+			userID:       "1",
+			logs:         []audit.AuditLog{},
+			expectedCode: apperror.CodeURLNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := &MockRepository{}
+			service := &service{repo: mockRepo}
+
+			mockRepo.On("GetOne", "node-id").Return(nil, nil)
+			mockRepo.On("GetAuditLogs", "node-id").Return(tt.logs, nil)
+
+			// Act
+			history, err := service.GetHistory("node-id", tt.userID)
+
+			// Assert
+			if tt.expectedCode != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedCode, err.(*apperror.AppError).Code)
+				assert.Nil(t, history)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, history, 1)
+		})
+	}
+}
+func TestService_GetHistory_AccessDenied(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "2"
+
+	mockRepo.On("GetOne", "node-id").Return(&URLNode{ID: "node-id", UserID: "1", Type: "folder"}, nil)
+	mockRepo.On("GetParentUpToRoot", "node-id").Return([]URLNode{}, nil)
+	mockRepo.On("GetPermissions", []string{"node-id"}, userID).Return([]FolderPermission{}, nil)
+
+	// Act
+	history, err := service.GetHistory("node-id", userID)
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeURLAccessDenied, err.(*apperror.AppError).Code)
+	assert.Nil(t, history)
+	mockRepo.AssertNotCalled(t, "GetAuditLogs", mock.Anything)
+}
+func TestService_GetRevisions_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	node := &URLNode{ID: "node-id", UserID: userID, Name: "node", Type: "folder"}
+	revisions := []NodeRevision{
+		{ID: "revision-2", NodeID: "node-id", Revision: 2, ActorID: "2", Snapshot: `{"name":"node"}`, CreatedAt: time.Unix(60, 0)},
+		{ID: "revision-1", NodeID: "node-id", Revision: 1, ActorID: userID, Snapshot: `{"name":"old"}`, CreatedAt: time.Unix(0, 0)},
+	}
+
+	mockRepo.On("GetOne", "node-id").Return(node, nil)
+	mockRepo.On("GetRevisions", "node-id").Return(revisions, nil)
+
+	// Act
+	result, err := service.GetRevisions("node-id", userID)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, 2, result[0].Revision)
+	assert.Equal(t, "2", result[0].ActorID)
+	assert.JSONEq(t, `{"name":"old"}`, string(result[1].Node))
+	assert.Equal(t, "1970-01-01T00:01:00Z", result[0].CreatedAt)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_GetRevisions_AccessDenied(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "2"
+
+	mockRepo.On("GetOne", "node-id").Return(&URLNode{ID: "node-id", UserID: "1", Type: "folder"}, nil)
+	mockRepo.On("GetParentUpToRoot", "node-id").Return([]URLNode{}, nil)
+	mockRepo.On("GetPermissions", []string{"node-id"}, userID).Return([]FolderPermission{}, nil)
+
+	// Act
+	result, err := service.GetRevisions("node-id", userID)
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeURLAccessDenied, err.(*apperror.AppError).Code)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "GetRevisions", mock.Anything)
+}
+
+func TestService_RevertURL_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	lastCheckedAt := time.Now()
+	node := &URLNode{
+		ID:            "node-id",
+		UserID:        userID,
+		ParentID:      test.StringPtr("new-parent-id"),
+		Name:          "renamed",
+		Type:          "url",
+		URL:           test.StringPtr("https://overwritten.com"),
+		LastCheckedAt: &lastCheckedAt,
+	}
+	oldParent := &URLNode{ID: "old-parent-id", UserID: userID, Name: "old-parent", Type: "folder"}
+	revision := &NodeRevision{
+		NodeID:   "node-id",
+		Revision: 1,
+		Snapshot: `{"parent_id":"old-parent-id","name":"original","type":"url","url":"https://example.com/","title":"Example"}`,
+	}
+	revertedNode := &URLNode{
+		ID:            "node-id",
+		UserID:        userID,
+		ParentID:      test.StringPtr("old-parent-id"),
+		Name:          "original",
+		Type:          "url",
+		URL:           test.StringPtr("https://example.com/"),
+		NormalizedURL: test.StringPtr("https://example.com"),
+		Title:         test.StringPtr("Example"),
+	}
+
+	mockRepo.On("GetOne", "node-id").Return(node, nil)
+	mockRepo.On("GetRevision", "node-id", 1).Return(revision, nil)
+	mockRepo.On("GetOne", "old-parent-id").Return(oldParent, nil)
+	mockRepo.On("GetParentUpToRoot", "old-parent-id").Return([]URLNode{{ID: "root-id", UserID: userID}}, nil)
+	mockRepo.On("GetChildren", "old-parent-id").Return([]URLNode{}, nil)
+	mockRepo.On("Update", revertedNode).Return(nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("CreateAuditLog", mock.MatchedBy(func(entry *audit.AuditLog) bool {
+		return entry.Action == audit.ActionMove && strings.Contains(*entry.Before, `"name":"renamed"`)
+	})).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("CreateRevision", mock.MatchedBy(func(revision *NodeRevision) bool {
+		return revision.NodeID == "node-id" && strings.Contains(revision.Snapshot, `"name":"original"`)
+	})).Return(nil)
+	mockRepo.On("DeleteStaleOperations", userID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("CreateOperation", mock.AnythingOfType("*url.Operation")).Return(nil)
+
+	// Act
+	err := service.RevertURL("node-id", 1, userID, "request-id")
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_RevertURL_RevisionNotFound(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+
+	mockRepo.On("GetOne", "node-id").Return(&URLNode{ID: "node-id", UserID: userID, Type: "folder"}, nil)
+	mockRepo.On("GetRevision", "node-id", 5).Return(nil, nil)
+
+	// Act
+	err := service.RevertURL("node-id", 5, userID, "request-id")
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeRevisionNotFound, err.(*apperror.AppError).Code)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+func TestService_RevertURL_ParentInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		parent    *URLNode
+		ancestors []URLNode
+	}{
+		{
+			name: "parent deleted",
+		},
+		{
+			name:   "parent in another tree",
+			parent: &URLNode{ID: "old-parent-id", UserID: "2", Type: "folder"},
+		},
+		{
+			name:      "parent moved inside the node",
+			parent:    &URLNode{ID: "old-parent-id", UserID: "1", Type: "folder"},
+			ancestors: []URLNode{{ID: "root-id", UserID: "1"}, {ID: "node-id", UserID: "1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := &MockRepository{}
+			service := &service{repo: mockRepo}
+			userID := "1"
+			node := &URLNode{ID: "node-id", UserID: userID, ParentID: test.StringPtr("root-id"), Name: "node", Type: "folder"}
+			revision := &NodeRevision{NodeID: "node-id", Revision: 1, Snapshot: `{"parent_id":"old-parent-id","name":"node","type":"folder"}`}
+
+			mockRepo.On("GetOne", "node-id").Return(node, nil)
+			mockRepo.On("GetRevision", "node-id", 1).Return(revision, nil)
+			if tt.parent == nil {
+				mockRepo.On("GetOne", "old-parent-id").Return(nil, nil)
+			} else {
+				mockRepo.On("GetOne", "old-parent-id").Return(tt.parent, nil)
+			}
+			mockRepo.On("GetParentUpToRoot", "old-parent-id").Return(tt.ancestors, nil)
+
+			// Act
+			err := service.RevertURL("node-id", 1, userID, "request-id")
+
+			// Assert
+			require.Error(t, err)
+			assert.Equal(t, apperror.CodeRevisionParentInvalid, err.(*apperror.AppError).Code)
+			mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+		})
+	}
+}
+func TestService_RevertURL_NameAlreadyExists(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	node := &URLNode{ID: "node-id", UserID: userID, ParentID: test.StringPtr("parent-id"), Name: "renamed", Type: "folder"}
+	parent := &URLNode{ID: "parent-id", UserID: userID, Type: "folder"}
+	revision := &NodeRevision{NodeID: "node-id", Revision: 1, Snapshot: `{"parent_id":"parent-id","name":"taken","type":"folder"}`}
+
+	mockRepo.On("GetOne", "node-id").Return(node, nil)
+	mockRepo.On("GetRevision", "node-id", 1).Return(revision, nil)
+	mockRepo.On("GetOne", "parent-id").Return(parent, nil)
+	mockRepo.On("GetParentUpToRoot", "parent-id").Return([]URLNode{}, nil)
+	mockRepo.On("GetChildren", "parent-id").Return([]URLNode{*node, {ID: "other-id", Name: "taken"}}, nil)
+
+	// Act
+	err := service.RevertURL("node-id", 1, userID, "request-id")
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeURLNameAlreadyExists, err.(*apperror.AppError).Code)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestService_recordOperation_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo, undoWindow: time.Hour}
+	userID := "1"
+	before := &URLNode{ID: "node-id", UserID: userID, ParentID: test.StringPtr("parent-id"), Name: "old", Type: "folder"}
+	after := &URLNode{ID: "node-id", UserID: userID, ParentID: test.StringPtr("parent-id"), Name: "new", Type: "folder"}
+	deleted := &URLNode{ID: "other-id", UserID: userID, ParentID: test.StringPtr("parent-id"), Name: "other", Type: "folder"}
+
+	mockRepo.On("CreateAuditLog", mock.AnythingOfType("*audit.AuditLog")).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("CreateRevision", mock.AnythingOfType("*url.NodeRevision")).Return(nil).Once()
+	mockRepo.On("DeleteStaleOperations", userID, mock.MatchedBy(func(createdBefore time.Time) bool {
+		return time.Since(createdBefore) > 59*time.Minute && time.Since(createdBefore) < 61*time.Minute
+	})).Return(nil)
+	mockRepo.On("CreateOperation", mock.MatchedBy(func(operation *Operation) bool {
+		return operation.UserID == userID &&
+			strings.Contains(operation.Changes, `{"node_id":"node-id","action":"update","before":{"parent_id":"parent-id","name":"old"`) &&
+			strings.Contains(operation.Changes, `{"node_id":"other-id","action":"delete","before":{"parent_id":"parent-id","name":"other"`) &&
+			strings.Contains(operation.Changes, `"after":null`)
+	})).Return(nil)
+
+	// Act
+	err := service.recordOperation(mockRepo, []change{
+		{action: audit.ActionUpdate, before: before, after: after},
+		{action: audit.ActionDelete, before: deleted},
+	}, userID, "request-id")
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNumberOfCalls(t, "CreateAuditLog", 2)
+}
+
+func TestService_Undo_Rename(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo, undoWindow: time.Hour}
+	userID := "1"
+	node := &URLNode{ID: "node-id", UserID: userID, ParentID: test.StringPtr("parent-id"), Name: "new", Type: "folder"}
+	parent := &URLNode{ID: "parent-id", UserID: userID, Type: "folder"}
+	operation := &Operation{
+		ID:      "operation-id",
+		UserID:  userID,
+		Changes: `[{"node_id":"node-id","action":"update","before":{"parent_id":"parent-id","name":"old","type":"folder"},"after":{"parent_id":"parent-id","name":"new","type":"folder"}}]`,
+	}
+
+	mockRepo.On("GetLastOperation", userID, mock.AnythingOfType("time.Time")).Return(operation, nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("GetOne", "node-id").Return(node, nil)
+	mockRepo.On("GetOne", "parent-id").Return(parent, nil)
+	mockRepo.On("GetParentUpToRoot", "parent-id").Return([]URLNode{}, nil)
+	mockRepo.On("GetChildren", "parent-id").Return([]URLNode{*node}, nil)
+	mockRepo.On("Update", mock.MatchedBy(func(node *URLNode) bool { return node.Name == "old" })).Return(nil)
+	mockRepo.On("CreateAuditLog", mock.MatchedBy(func(entry *audit.AuditLog) bool {
+		return entry.Action == audit.ActionUpdate && *entry.RequestID == "request-id"
+	})).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("CreateRevision", mock.AnythingOfType("*url.NodeRevision")).Return(nil)
+	mockRepo.On("UpdateOperation", mock.MatchedBy(func(operation *Operation) bool { return operation.UndoneAt != nil })).Return(nil)
+
+	// Act
+	response, err := service.Undo(userID, "request-id")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "operation-id", response.ID)
+	assert.Equal(t, []ChangeSummary{{NodeID: "node-id", Action: audit.ActionUpdate}}, response.Changes)
+	assert.NotNil(t, response.UndoneAt)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_Undo_Create(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo, undoWindow: time.Hour}
+	userID := "1"
+	node := &URLNode{ID: "node-id", UserID: userID, ParentID: test.StringPtr("parent-id"), Name: "new", Type: "folder"}
+	operation := &Operation{
+		ID:      "operation-id",
+		UserID:  userID,
+		Changes: `[{"node_id":"node-id","action":"create","before":null,"after":{"parent_id":"parent-id","name":"new","type":"folder"}}]`,
+	}
+
+	mockRepo.On("GetLastOperation", userID, mock.AnythingOfType("time.Time")).Return(operation, nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("GetOne", "node-id").Return(node, nil)
+	mockRepo.On("GetChildren", "node-id").Return([]URLNode{}, nil)
+	mockRepo.On("SoftDelete", "node-id").Return(nil)
+	mockRepo.On("CreateAuditLog", mock.MatchedBy(func(entry *audit.AuditLog) bool { return entry.Action == audit.ActionDelete })).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("UpdateOperation", operation).Return(nil)
+
+	// Act
+	response, err := service.Undo(userID, "request-id")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, audit.ActionCreate, response.Changes[0].Action)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "CreateRevision", mock.Anything)
+}
+func TestService_Undo_Delete(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo, undoWindow: time.Hour}
+	userID := "1"
+	deletedAt := time.Now()
+	node := &URLNode{ID: "node-id", UserID: userID, ParentID: test.StringPtr("parent-id"), Name: "gone", Type: "folder", DeletedAt: &deletedAt}
+	parent := &URLNode{ID: "parent-id", UserID: userID, Type: "folder"}
+	operation := &Operation{
+		ID:      "operation-id",
+		UserID:  userID,
+		Changes: `[{"node_id":"node-id","action":"delete","before":{"parent_id":"parent-id","name":"gone","type":"folder"},"after":null}]`,
+	}
+
+	mockRepo.On("GetLastOperation", userID, mock.AnythingOfType("time.Time")).Return(operation, nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("GetDeleted", "node-id").Return(node, nil)
+	mockRepo.On("GetOne", "parent-id").Return(parent, nil)
+	mockRepo.On("GetParentUpToRoot", "parent-id").Return([]URLNode{}, nil)
+	mockRepo.On("GetChildren", "parent-id").Return([]URLNode{}, nil)
+	mockRepo.On("Restore", "node-id").Return(nil)
+	mockRepo.On("CreateAuditLog", mock.MatchedBy(func(entry *audit.AuditLog) bool { return entry.Action == audit.ActionRestore })).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("CreateRevision", mock.AnythingOfType("*url.NodeRevision")).Return(nil)
+	mockRepo.On("UpdateOperation", operation).Return(nil)
+
+	// Act
+	_, err := service.Undo(userID, "request-id")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Nil(t, node.DeletedAt)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_Undo_NothingToUndo(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo, undoWindow: time.Hour}
+	userID := "1"
+
+	mockRepo.On("GetLastOperation", userID, mock.MatchedBy(func(createdAfter time.Time) bool {
+		return time.Since(createdAfter) > 59*time.Minute && time.Since(createdAfter) < 61*time.Minute
+	})).Return(nil, nil)
+
+	// Act
+	response, err := service.Undo(userID, "request-id")
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeNothingToUndo, err.(*apperror.AppError).Code)
+	assert.Nil(t, response)
+	mockRepo.AssertNotCalled(t, "Transaction", mock.Anything)
+}
+func TestService_Undo_Conflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     *URLNode
+		children []URLNode
+		siblings []URLNode
+		changes  string
+	}{
+		{
+			name:    "node changed since",
+			node:    &URLNode{ID: "node-id", UserID: "1", ParentID: test.StringPtr("parent-id"), Name: "renamed again", Type: "folder"},
+			changes: `[{"node_id":"node-id","action":"update","before":{"parent_id":"parent-id","name":"old","type":"folder"},"after":{"parent_id":"parent-id","name":"new","type":"folder"}}]`,
+		},
+		{
+			name:     "folder no longer empty",
+			node:     &URLNode{ID: "node-id", UserID: "1", ParentID: test.StringPtr("parent-id"), Name: "new", Type: "folder"},
+			children: []URLNode{{ID: "child-id", Name: "child"}},
+			changes:  `[{"node_id":"node-id","action":"create","before":null,"after":{"parent_id":"parent-id","name":"new","type":"folder"}}]`,
+		},
+		{
+			name:     "name taken",
+			node:     &URLNode{ID: "node-id", UserID: "1", ParentID: test.StringPtr("parent-id"), Name: "new", Type: "folder"},
+			siblings: []URLNode{{ID: "other-id", Name: "old"}},
+			changes:  `[{"node_id":"node-id","action":"update","before":{"parent_id":"parent-id","name":"old","type":"folder"},"after":{"parent_id":"parent-id","name":"new","type":"folder"}}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := &MockRepository{}
+			service := &service{repo: mockRepo, undoWindow: time.Hour}
+			userID := "1"
+			operation := &Operation{ID: "operation-id", UserID: userID, Changes: tt.changes}
+
+			mockRepo.On("GetLastOperation", userID, mock.AnythingOfType("time.Time")).Return(operation, nil)
+			mockRepo.On("Transaction", mock.Anything).Return(nil)
+			mockRepo.On("GetOne", "node-id").Return(tt.node, nil)
+			mockRepo.On("GetChildren", "node-id").Return(tt.children, nil)
+			mockRepo.On("GetOne", "parent-id").Return(&URLNode{ID: "parent-id", UserID: userID, Type: "folder"}, nil)
+			mockRepo.On("GetParentUpToRoot", "parent-id").Return([]URLNode{}, nil)
+			mockRepo.On("GetChildren", "parent-id").Return(tt.siblings, nil)
+
+			// Act
+			response, err := service.Undo(userID, "request-id")
+
+			// Assert
+			require.Error(t, err)
+			assert.Equal(t, apperror.CodeUndoConflict, err.(*apperror.AppError).Code)
+			assert.Nil(t, response)
+			mockRepo.AssertNotCalled(t, "UpdateOperation", mock.Anything)
+		})
+	}
+}
+
+func TestService_Redo_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo, undoWindow: time.Hour}
+	userID := "1"
+	undoneAt := time.Now()
+	node := &URLNode{ID: "node-id", UserID: userID, ParentID: test.StringPtr("parent-id"), Name: "gone", Type: "folder"}
+	operation := &Operation{
+		ID:       "operation-id",
+		UserID:   userID,
+		Changes:  `[{"node_id":"node-id","action":"delete","before":{"parent_id":"parent-id","name":"gone","type":"folder"},"after":null}]`,
+		UndoneAt: &undoneAt,
+	}
+
+	mockRepo.On("GetLastUndoneOperation", userID, mock.AnythingOfType("time.Time")).Return(operation, nil)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	mockRepo.On("GetOne", "node-id").Return(node, nil)
+	mockRepo.On("GetChildren", "node-id").Return([]URLNode{}, nil)
+	mockRepo.On("SoftDelete", "node-id").Return(nil)
+	mockRepo.On("CreateAuditLog", mock.AnythingOfType("*audit.AuditLog")).Return(nil)
+	mockRepo.On("NextChangeSeq", mock.AnythingOfType("string")).Return(int64(1), nil)
+	mockRepo.On("CreateNodeChange", mock.AnythingOfType("*url.NodeChange")).Return(nil)
+	mockRepo.On("GetWebhooks", mock.AnythingOfType("string")).Return([]webhook.Webhook{}, nil)
+	mockRepo.On("CreateOutboxEvent", mock.AnythingOfType("*outbox.OutboxEvent")).Return(nil)
+	mockRepo.On("UpdateOperation", mock.MatchedBy(func(operation *Operation) bool { return operation.UndoneAt == nil })).Return(nil)
+
+	// Act
+	response, err := service.Redo(userID, "request-id")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Nil(t, response.UndoneAt)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_Redo_NothingToRedo(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo, undoWindow: time.Hour}
+	userID := "1"
+
+	mockRepo.On("GetLastUndoneOperation", userID, mock.AnythingOfType("time.Time")).Return(nil, nil)
+
+	// Act
+	response, err := service.Redo(userID, "request-id")
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeNothingToUndo, err.(*apperror.AppError).Code)
+	assert.Nil(t, response)
+}
+
+func TestService_GetChanges_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+	deletedAt := time.Now()
+	nodeChanges := []NodeChange{
+		{UserID: userID, Seq: 3, NodeID: "renamed-id", Action: audit.ActionCreate},
+		{UserID: userID, Seq: 4, NodeID: "deleted-id", Action: audit.ActionCreate},
+		{UserID: userID, Seq: 5, NodeID: "renamed-id", Action: audit.ActionUpdate},
+		{UserID: userID, Seq: 6, NodeID: "deleted-id", Action: audit.ActionDelete},
+		{UserID: userID, Seq: 7, NodeID: "purged-id", Action: audit.ActionCreate},
+		{UserID: userID, Seq: 8, NodeID: "transferred-id", Action: audit.ActionDelete},
+	}
+	nodes := []URLNode{
+		{ID: "renamed-id", UserID: userID, ParentID: test.StringPtr("root-id"), Name: "renamed", Type: "folder"},
+		{ID: "deleted-id", UserID: userID, ParentID: test.StringPtr("root-id"), Name: "deleted", Type: "folder", DeletedAt: &deletedAt},
+		{ID: "transferred-id", UserID: "2", ParentID: test.StringPtr("other-root-id"), Name: "transferred", Type: "folder"},
+	}
+
+	mockRepo.On("GetLastChangeSeq", userID).Return(int64(9), nil)
+	mockRepo.On("GetNodeChanges", userID, int64(2), 6).Return(nodeChanges, nil)
+	mockRepo.On("GetByIDs", []string{"renamed-id", "deleted-id", "purged-id", "transferred-id"}).Return(nodes, nil)
+
+	// Act
+	response, err := service.GetChanges(encodeSyncToken(2), 6, userID)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, response.Changes, 4)
+	assert.Equal(t, "renamed-id", response.Changes[0].NodeID)
+	assert.Equal(t, audit.ActionUpdate, response.Changes[0].Action)
+	require.NotNil(t, response.Changes[0].Node)
+	assert.Equal(t, "renamed", response.Changes[0].Node.Name)
+	assert.Equal(t, "root-id", *response.Changes[0].Node.ParentID)
+	assert.Equal(t, "deleted-id", response.Changes[1].NodeID)
+	assert.Equal(t, audit.ActionDelete, response.Changes[1].Action)
+	assert.Nil(t, response.Changes[1].Node)
+	assert.Equal(t, "purged-id", response.Changes[2].NodeID)
+	assert.Equal(t, actionPurge, response.Changes[2].Action)
+	assert.Nil(t, response.Changes[2].Node)
+	assert.Equal(t, "transferred-id", response.Changes[3].NodeID)
+	assert.Equal(t, audit.ActionDelete, response.Changes[3].Action)
+	assert.Nil(t, response.Changes[3].Node)
+	assert.Equal(t, encodeSyncToken(8), response.NextToken)
+	assert.True(t, response.HasMore)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_GetChanges_NoChanges(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	userID := "1"
+
+	mockRepo.On("GetLastChangeSeq", userID).Return(int64(0), nil)
+	mockRepo.On("GetNodeChanges", userID, int64(0), defaultChangesLimit).Return([]NodeChange{}, nil)
+
+	// Act
+	response, err := service.GetChanges("", 0, userID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, response.Changes)
+	assert.Equal(t, encodeSyncToken(0), response.NextToken)
+	assert.False(t, response.HasMore)
+	mockRepo.AssertNotCalled(t, "GetByIDs", mock.Anything)
+}
+func TestService_GetChanges_InvalidToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		since string
+	}{
+		{name: "malformed", since: "not-a-token"},
+		{name: "ahead of the feed", since: encodeSyncToken(10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := &MockRepository{}
+			service := &service{repo: mockRepo}
+			userID := "1"
+
+			mockRepo.On("GetLastChangeSeq", userID).Return(int64(3), nil)
+
+			// Act
+			response, err := service.GetChanges(tt.since, 0, userID)
+
+			// Assert
+			require.Error(t, err)
+			assert.Equal(t, apperror.CodeSyncTokenInvalid, err.(*apperror.AppError).Code)
+			assert.Nil(t, response)
+			mockRepo.AssertNotCalled(t, "GetNodeChanges", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
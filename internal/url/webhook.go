@@ -0,0 +1,56 @@
+package url
+
+import (
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/webhook"
+)
+
+// WebhookData is the data of a webhook event about a change of a node, with
+// the states of the node before and after the change.
+type WebhookData struct {
+	NodeID  string        `json:"node_id"`
+	OwnerID string        `json:"owner_id"`
+	ActorID string        `json:"actor_id"`
+	Before  *NodeSnapshot `json:"before"`
+	After   *NodeSnapshot `json:"after"`
+}
+
+// recordWebhookDeliveries queues the change for the webhooks of the owner of
+// the tree that subscribed to the action. It must be called with the
+// repository of the transaction making the change, so that deliveries are
+// only sent for committed changes.
+func recordWebhookDeliveries(repo Repository, action audit.Action, before *URLNode, after *URLNode, actorID string) error {
+	node := after
+	if node == nil {
+		node = before
+	}
+	webhooks, err := repo.GetWebhooks(node.UserID)
+	if err != nil {
+		return err
+	}
+
+	data := WebhookData{
+		NodeID:  node.ID,
+		OwnerID: node.UserID,
+		ActorID: actorID,
+		Before:  newNodeSnapshot(before),
+		After:   newNodeSnapshot(after),
+	}
+	for _, w := range webhooks {
+		subscribed, err := w.Subscribed(action)
+		if err != nil {
+			return err
+		}
+		if !subscribed {
+			continue
+		}
+		delivery, err := webhook.NewDelivery(&w, action, data)
+		if err != nil {
+			return err
+		}
+		if err := repo.CreateWebhookDelivery(delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package url
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/webhook"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordWebhookDeliveries_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	before := &URLNode{ID: "node-id", UserID: "1", ParentID: test.StringPtr("parent-id"), Name: "old", Type: "folder"}
+	after := &URLNode{ID: "node-id", UserID: "1", ParentID: test.StringPtr("parent-id"), Name: "new", Type: "folder"}
+	webhooks := []webhook.Webhook{
+		{ID: "all-id", UserID: "1", Events: `[]`},
+		{ID: "update-id", UserID: "1", Events: `["update","delete"]`},
+		{ID: "create-id", UserID: "1", Events: `["create"]`},
+	}
+
+	var deliveries []*webhook.Delivery
+	mockRepo.On("GetWebhooks", "1").Return(webhooks, nil)
+	mockRepo.On("CreateWebhookDelivery", mock.AnythingOfType("*webhook.Delivery")).Run(func(args mock.Arguments) {
+		deliveries = append(deliveries, args.Get(0).(*webhook.Delivery))
+	}).Return(nil)
+
+	// Act
+	err := recordWebhookDeliveries(mockRepo, audit.ActionUpdate, before, after, "2")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, "all-id", deliveries[0].WebhookID)
+	assert.Equal(t, "update-id", deliveries[1].WebhookID)
+	assert.Equal(t, audit.ActionUpdate, deliveries[1].Event)
+
+	var payload struct {
+		ID    string       `json:"id"`
+		Event audit.Action `json:"event"`
+		Data  WebhookData  `json:"data"`
+	}
+	err = json.Unmarshal([]byte(deliveries[1].Payload), &payload)
+	require.NoError(t, err)
+	assert.Equal(t, deliveries[1].ID, payload.ID)
+	assert.Equal(t, audit.ActionUpdate, payload.Event)
+	assert.Equal(t, "node-id", payload.Data.NodeID)
+	assert.Equal(t, "1", payload.Data.OwnerID)
+	assert.Equal(t, "2", payload.Data.ActorID)
+	assert.Equal(t, "old", payload.Data.Before.Name)
+	assert.Equal(t, "new", payload.Data.After.Name)
+	mockRepo.AssertExpectations(t)
+}
+func TestRecordWebhookDeliveries_NoWebhooks(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	node := &URLNode{ID: "node-id", UserID: "1", Name: "deleted", Type: "url"}
+
+	mockRepo.On("GetWebhooks", "1").Return([]webhook.Webhook{}, nil)
+
+	// Act
+	err := recordWebhookDeliveries(mockRepo, audit.ActionDelete, node, nil, "1")
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "CreateWebhookDelivery", mock.Anything)
+}
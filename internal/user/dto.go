@@ -0,0 +1,61 @@
+package user
+
+import (
+	"time"
+)
+
+// PreferencesRequestBody replaces all preferences of the user. A missing or
+// null start_folder_id opens the root of the tree.
+type PreferencesRequestBody struct {
+	SortOrder     SortOrder `json:"sort_order" binding:"required,oneof=name_asc name_desc created_asc created_desc updated_asc updated_desc"`
+	ViewMode      ViewMode  `json:"view_mode" binding:"required,oneof=list grid"`
+	StartFolderID *string   `json:"start_folder_id" binding:"omitempty,uuid"`
+	Theme         Theme     `json:"theme" binding:"required,oneof=system light dark"`
+}
+
+type PreferencesResponse struct {
+	SortOrder     SortOrder `json:"sort_order"`
+	ViewMode      ViewMode  `json:"view_mode"`
+	StartFolderID *string   `json:"start_folder_id"`
+	Theme         Theme     `json:"theme"`
+	UpdatedAt     *string   `json:"updated_at"`
+}
+
+// ProfileResponse echoes the claims of the user token. Email and picture are
+// null for personal access tokens, which carry no profile.
+type ProfileResponse struct {
+	UserID  string  `json:"user_id"`
+	Email   *string `json:"email"`
+	Picture *string `json:"picture"`
+}
+
+// newPreferencesResponse formats the preferences. UpdatedAt is null for the
+// defaults of a user who never saved any.
+func newPreferencesResponse(preferences *Preferences) *PreferencesResponse {
+	response := &PreferencesResponse{
+		SortOrder:     preferences.SortOrder,
+		ViewMode:      preferences.ViewMode,
+		StartFolderID: preferences.StartFolderID,
+		Theme:         preferences.Theme,
+	}
+	if !preferences.UpdatedAt.IsZero() {
+		updatedAt := preferences.UpdatedAt.UTC().Format(time.RFC3339)
+		response.UpdatedAt = &updatedAt
+	}
+	return response
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func newProfileResponse(userID string, email string, picture string) *ProfileResponse {
+	return &ProfileResponse{
+		UserID:  userID,
+		Email:   optional(email),
+		Picture: optional(picture),
+	}
+}
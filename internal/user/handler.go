@@ -0,0 +1,47 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetProfile(c *gin.Context) {
+	c.JSON(http.StatusOK, newProfileResponse(c.GetString("user_id"), c.GetString("email"), c.GetString("picture")))
+}
+
+func (h *Handler) GetPreferences(c *gin.Context) {
+	userID := c.GetString("user_id")
+	response, err := h.service.GetPreferences(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) UpdatePreferences(c *gin.Context) {
+	var body PreferencesRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := h.service.UpdatePreferences(&body, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,189 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) GetPreferences(userID string) (*PreferencesResponse, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*PreferencesResponse), args.Error(1)
+}
+func (m *MockService) UpdatePreferences(updates *PreferencesRequestBody, userID string) (*PreferencesResponse, error) {
+	args := m.Called(updates, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*PreferencesResponse), args.Error(1)
+}
+
+func TestHandler_NewHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+
+	// Act
+	h := NewHandler(mockService)
+
+	// Assert
+	assert.IsType(t, &Handler{}, h)
+	assert.Equal(t, mockService, h.service)
+}
+
+func TestHandler_GetProfile_Success(t *testing.T) {
+	// Arrange
+	handler := NewHandler(&MockService{})
+	c, w := test.SetupContext()
+	c.Set("user_id", "1")
+	c.Set("email", "user@example.com")
+	c.Set("picture", "https://example.com/avatar.png")
+
+	// Act
+	handler.GetProfile(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":"1","email":"user@example.com","picture":"https://example.com/avatar.png"}`, w.Body.String())
+}
+func TestHandler_GetProfile_NoClaims(t *testing.T) {
+	// Arrange
+	handler := NewHandler(&MockService{})
+	c, w := test.SetupContext()
+	c.Set("user_id", "1")
+
+	// Act
+	handler.GetProfile(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":"1","email":null,"picture":null}`, w.Body.String())
+}
+
+func TestHandler_GetPreferences_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Set("user_id", "1")
+
+	expected := &PreferencesResponse{SortOrder: SortNameAsc, ViewMode: ViewList, Theme: ThemeSystem}
+	mockService.On("GetPreferences", "1").Return(expected, nil)
+
+	// Act
+	handler.GetPreferences(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	var response PreferencesResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expected, response)
+}
+func TestHandler_GetPreferences_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Set("user_id", "1")
+
+	mockService.On("GetPreferences", "1").Return(nil, errors.New("database error"))
+
+	// Act
+	handler.GetPreferences(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+}
+
+func TestHandler_UpdatePreferences_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Set("user_id", "1")
+
+	startFolderID := folderID
+	body := PreferencesRequestBody{SortOrder: SortCreatedDesc, ViewMode: ViewGrid, StartFolderID: &startFolderID, Theme: ThemeDark}
+	requestJSON, _ := json.Marshal(body)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	expected := &PreferencesResponse{SortOrder: SortCreatedDesc, ViewMode: ViewGrid, StartFolderID: &startFolderID, Theme: ThemeDark}
+	mockService.On("UpdatePreferences", &body, "1").Return(expected, nil)
+
+	// Act
+	handler.UpdatePreferences(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	var response PreferencesResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expected, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_UpdatePreferences_InvalidBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "missing fields", body: `{"sort_order":"name_asc"}`},
+		{name: "unknown sort order", body: `{"sort_order":"size_asc","view_mode":"list","theme":"system"}`},
+		{name: "unknown view mode", body: `{"sort_order":"name_asc","view_mode":"table","theme":"system"}`},
+		{name: "unknown theme", body: `{"sort_order":"name_asc","view_mode":"list","theme":"blue"}`},
+		{name: "invalid start folder", body: `{"sort_order":"name_asc","view_mode":"list","theme":"system","start_folder_id":"home"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := &MockService{}
+			handler := NewHandler(mockService)
+			c, w := test.SetupContext()
+			c.Set("user_id", "1")
+			c.Request.Body = io.NopCloser(bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			// Act
+			handler.UpdatePreferences(c)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			mockService.AssertNotCalled(t, "UpdatePreferences", mock.Anything, mock.Anything)
+		})
+	}
+}
+func TestHandler_UpdatePreferences_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Set("user_id", "1")
+	c.Request.Body = io.NopCloser(bytes.NewBufferString(`{"sort_order":"name_asc","view_mode":"list","theme":"system"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	mockService.On("UpdatePreferences", mock.Anything, "1").Return(nil, errors.New("database error"))
+
+	// Act
+	handler.UpdatePreferences(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+}
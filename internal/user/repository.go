@@ -0,0 +1,95 @@
+package user
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SortOrder string
+
+const (
+	SortNameAsc     SortOrder = "name_asc"
+	SortNameDesc    SortOrder = "name_desc"
+	SortCreatedAsc  SortOrder = "created_asc"
+	SortCreatedDesc SortOrder = "created_desc"
+	SortUpdatedAsc  SortOrder = "updated_asc"
+	SortUpdatedDesc SortOrder = "updated_desc"
+)
+
+type ViewMode string
+
+const (
+	ViewList ViewMode = "list"
+	ViewGrid ViewMode = "grid"
+)
+
+type Theme string
+
+const (
+	ThemeSystem Theme = "system"
+	ThemeLight  Theme = "light"
+	ThemeDark   Theme = "dark"
+)
+
+// Preferences are the view settings of a user, kept across devices. Users
+// without a row use the defaults of DefaultPreferences. StartFolderID is the
+// folder opened first, the root of the tree when it is nil.
+type Preferences struct {
+	UserID        string    `gorm:"type:varchar(255);primary_key"`
+	SortOrder     SortOrder `gorm:"type:varchar(20);not null;check:sort_order IN ('name_asc','name_desc','created_asc','created_desc','updated_asc','updated_desc')"`
+	ViewMode      ViewMode  `gorm:"type:varchar(10);not null;check:view_mode IN ('list','grid')"`
+	StartFolderID *string   `gorm:"type:uuid"`
+	Theme         Theme     `gorm:"type:varchar(10);not null;check:theme IN ('system','light','dark')"`
+	UpdatedAt     time.Time `gorm:"type:timestamptz;not null"`
+}
+
+func (Preferences) TableName() string {
+	return "user_preferences"
+}
+
+func (p *Preferences) BeforeSave(tx *gorm.DB) error {
+	p.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// DefaultPreferences returns the preferences of a user who never saved any.
+func DefaultPreferences(userID string) *Preferences {
+	return &Preferences{
+		UserID:    userID,
+		SortOrder: SortNameAsc,
+		ViewMode:  ViewList,
+		Theme:     ThemeSystem,
+	}
+}
+
+type Repository interface {
+	GetPreferences(userID string) (*Preferences, error)
+	SavePreferences(preferences *Preferences) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) GetPreferences(userID string) (*Preferences, error) {
+	var preferences Preferences
+	err := r.db.Where("user_id = ?", userID).First(&preferences).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &preferences, nil
+}
+
+// SavePreferences creates or replaces the preferences of the user.
+func (r *repository) SavePreferences(preferences *Preferences) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(preferences).Error
+}
@@ -0,0 +1,94 @@
+package user
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/db"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var d *gorm.DB
+
+func TestMain(m *testing.M) {
+	// Setup
+	dbURL, closeDB, err := test.SetupPostgresql()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	d, err = db.NewDatabase(&config.Config{DatabaseURL: dbURL})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = d.AutoMigrate(&Preferences{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Run
+	code := m.Run()
+
+	// Teardown
+	closeDB()
+
+	os.Exit(code)
+}
+
+func TestRepository_NewRepository_Success(t *testing.T) {
+	// Act
+	repo := NewRepository(d)
+
+	// Assert
+	assert.NotNil(t, repo)
+	assert.IsType(t, &repository{}, repo)
+}
+
+func TestRepository_GetPreferences_NotFound(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	// Act
+	preferences, err := repo.GetPreferences("1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Nil(t, preferences)
+}
+
+func TestRepository_SavePreferences_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+	startFolderID := "123e4567-e89b-12d3-a456-426614174000"
+
+	// Act
+	err = repo.SavePreferences(DefaultPreferences("1"))
+	require.NoError(t, err)
+	err = repo.SavePreferences(&Preferences{UserID: "1", SortOrder: SortUpdatedDesc, ViewMode: ViewGrid, StartFolderID: &startFolderID, Theme: ThemeDark})
+	require.NoError(t, err)
+	err = repo.SavePreferences(DefaultPreferences("2"))
+	require.NoError(t, err)
+	preferences, err := repo.GetPreferences("1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, SortUpdatedDesc, preferences.SortOrder)
+	assert.Equal(t, ViewGrid, preferences.ViewMode)
+	assert.Equal(t, &startFolderID, preferences.StartFolderID)
+	assert.Equal(t, ThemeDark, preferences.Theme)
+	assert.False(t, preferences.UpdatedAt.IsZero())
+	var count int64
+	require.NoError(t, d.Model(&Preferences{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
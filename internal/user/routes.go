@@ -0,0 +1,20 @@
+package user
+
+import (
+	"github.com/vera/vera-drive-service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, h *Handler, authMiddleware middleware.AuthMiddleware) {
+	read := middleware.RequireScope(middleware.ScopeDriveRead)
+	write := middleware.RequireScope(middleware.ScopeDriveWrite)
+
+	g := r.Group("/me")
+	g.Use(gin.HandlerFunc(authMiddleware))
+	{
+		g.GET("", read, h.GetProfile)
+		g.GET("/preferences", read, h.GetPreferences)
+		g.PUT("/preferences", write, h.UpdatePreferences)
+	}
+}
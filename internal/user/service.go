@@ -0,0 +1,77 @@
+package user
+
+import (
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/url"
+)
+
+// NodeRepository is the part of url.Repository needed to check the start
+// folder.
+type NodeRepository interface {
+	GetOne(id string) (*url.URLNode, error)
+}
+
+type Service interface {
+	GetPreferences(userID string) (*PreferencesResponse, error)
+	UpdatePreferences(updates *PreferencesRequestBody, userID string) (*PreferencesResponse, error)
+}
+
+type service struct {
+	repo       Repository
+	nodes      NodeRepository
+	authorizer url.Authorizer
+}
+
+func NewService(repo Repository, nodes NodeRepository, authorizer url.Authorizer) Service {
+	return &service{repo: repo, nodes: nodes, authorizer: authorizer}
+}
+
+// validateStartFolder checks that the node is a folder the user can view,
+// either in their own tree or in a folder shared with them.
+func (s *service) validateStartFolder(folderID string, userID string) error {
+	if err := s.authorizer.Authorize(folderID, userID, url.RoleViewer); err != nil {
+		return err
+	}
+	node, err := s.nodes.GetOne(folderID)
+	if err != nil {
+		return err
+	}
+	if node.Type != "folder" {
+		return apperror.New(apperror.CodeStartFolderInvalid, "Start folder must be a folder | id: "+folderID)
+	}
+	return nil
+}
+
+// GetPreferences returns the saved preferences of the user, or the defaults
+// when they never saved any. The start folder is returned as saved even if
+// it was deleted since, clients fall back to the root.
+func (s *service) GetPreferences(userID string) (*PreferencesResponse, error) {
+	preferences, err := s.repo.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	if preferences == nil {
+		preferences = DefaultPreferences(userID)
+	}
+	return newPreferencesResponse(preferences), nil
+}
+
+func (s *service) UpdatePreferences(updates *PreferencesRequestBody, userID string) (*PreferencesResponse, error) {
+	if updates.StartFolderID != nil {
+		if err := s.validateStartFolder(*updates.StartFolderID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	preferences := &Preferences{
+		UserID:        userID,
+		SortOrder:     updates.SortOrder,
+		ViewMode:      updates.ViewMode,
+		StartFolderID: updates.StartFolderID,
+		Theme:         updates.Theme,
+	}
+	if err := s.repo.SavePreferences(preferences); err != nil {
+		return nil, err
+	}
+	return newPreferencesResponse(preferences), nil
+}
@@ -0,0 +1,230 @@
+package user
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/url"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const folderID = "123e4567-e89b-12d3-a456-426614174000"
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) GetPreferences(userID string) (*Preferences, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Preferences), args.Error(1)
+}
+func (m *MockRepository) SavePreferences(preferences *Preferences) error {
+	args := m.Called(preferences)
+	return args.Error(0)
+}
+
+type MockNodeRepository struct {
+	mock.Mock
+}
+
+func (m *MockNodeRepository) GetOne(id string) (*url.URLNode, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*url.URLNode), args.Error(1)
+}
+
+type MockAuthorizer struct {
+	mock.Mock
+}
+
+func (m *MockAuthorizer) Authorize(nodeID string, userID string, required url.Role) error {
+	args := m.Called(nodeID, userID, required)
+	return args.Error(0)
+}
+
+func TestService_NewService_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+
+	// Act
+	s := NewService(mockRepo, mockNodes, mockAuthorizer)
+
+	// Assert
+	assert.IsType(t, &service{}, s)
+	assert.Equal(t, mockRepo, s.(*service).repo)
+	assert.Equal(t, mockNodes, s.(*service).nodes)
+	assert.Equal(t, mockAuthorizer, s.(*service).authorizer)
+}
+
+func TestService_GetPreferences_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	startFolderID := folderID
+	updatedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mockRepo.On("GetPreferences", "1").Return(&Preferences{
+		UserID:        "1",
+		SortOrder:     SortUpdatedDesc,
+		ViewMode:      ViewGrid,
+		StartFolderID: &startFolderID,
+		Theme:         ThemeDark,
+		UpdatedAt:     updatedAt,
+	}, nil)
+
+	// Act
+	response, err := service.GetPreferences("1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, SortUpdatedDesc, response.SortOrder)
+	assert.Equal(t, ViewGrid, response.ViewMode)
+	assert.Equal(t, &startFolderID, response.StartFolderID)
+	assert.Equal(t, ThemeDark, response.Theme)
+	require.NotNil(t, response.UpdatedAt)
+	assert.Equal(t, "2024-01-02T03:04:05Z", *response.UpdatedAt)
+}
+func TestService_GetPreferences_Defaults(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+
+	mockRepo.On("GetPreferences", "1").Return(nil, nil)
+
+	// Act
+	response, err := service.GetPreferences("1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, &PreferencesResponse{SortOrder: SortNameAsc, ViewMode: ViewList, Theme: ThemeSystem}, response)
+}
+func TestService_GetPreferences_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+
+	mockRepo.On("GetPreferences", "1").Return(nil, errors.New("database error"))
+
+	// Act
+	response, err := service.GetPreferences("1")
+
+	// Assert
+	assert.Nil(t, response)
+	assert.EqualError(t, err, "database error")
+}
+
+func TestService_UpdatePreferences_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+	service := &service{repo: mockRepo, nodes: mockNodes, authorizer: mockAuthorizer}
+	startFolderID := folderID
+	updates := &PreferencesRequestBody{SortOrder: SortNameDesc, ViewMode: ViewGrid, StartFolderID: &startFolderID, Theme: ThemeLight}
+
+	mockAuthorizer.On("Authorize", folderID, "1", url.RoleViewer).Return(nil)
+	mockNodes.On("GetOne", folderID).Return(&url.URLNode{ID: folderID, UserID: "2", Type: "folder"}, nil)
+	mockRepo.On("SavePreferences", mock.AnythingOfType("*user.Preferences")).Run(func(args mock.Arguments) {
+		args.Get(0).(*Preferences).UpdatedAt = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	}).Return(nil)
+
+	// Act
+	response, err := service.UpdatePreferences(updates, "1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, SortNameDesc, response.SortOrder)
+	assert.Equal(t, &startFolderID, response.StartFolderID)
+	require.NotNil(t, response.UpdatedAt)
+	saved := mockRepo.Calls[0].Arguments.Get(0).(*Preferences)
+	assert.Equal(t, "1", saved.UserID)
+	assert.Equal(t, ThemeLight, saved.Theme)
+	mockAuthorizer.AssertExpectations(t)
+}
+func TestService_UpdatePreferences_RootStartFolder(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+	service := &service{repo: mockRepo, nodes: mockNodes, authorizer: mockAuthorizer}
+	updates := &PreferencesRequestBody{SortOrder: SortNameAsc, ViewMode: ViewList, Theme: ThemeSystem}
+
+	mockRepo.On("SavePreferences", mock.AnythingOfType("*user.Preferences")).Return(nil)
+
+	// Act
+	response, err := service.UpdatePreferences(updates, "1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Nil(t, response.StartFolderID)
+	mockAuthorizer.AssertNotCalled(t, "Authorize", mock.Anything, mock.Anything, mock.Anything)
+}
+func TestService_UpdatePreferences_AccessDenied(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+	service := &service{repo: mockRepo, nodes: mockNodes, authorizer: mockAuthorizer}
+	startFolderID := folderID
+	updates := &PreferencesRequestBody{SortOrder: SortNameAsc, ViewMode: ViewList, StartFolderID: &startFolderID, Theme: ThemeSystem}
+	denied := apperror.New(apperror.CodeURLAccessDenied, "Access denied")
+
+	mockAuthorizer.On("Authorize", folderID, "1", url.RoleViewer).Return(denied)
+
+	// Act
+	response, err := service.UpdatePreferences(updates, "1")
+
+	// Assert
+	assert.Nil(t, response)
+	assert.Equal(t, denied, err)
+	mockNodes.AssertNotCalled(t, "GetOne", mock.Anything)
+	mockRepo.AssertNotCalled(t, "SavePreferences", mock.Anything)
+}
+func TestService_UpdatePreferences_NotFolder(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	mockNodes := &MockNodeRepository{}
+	mockAuthorizer := &MockAuthorizer{}
+	service := &service{repo: mockRepo, nodes: mockNodes, authorizer: mockAuthorizer}
+	startFolderID := folderID
+	updates := &PreferencesRequestBody{SortOrder: SortNameAsc, ViewMode: ViewList, StartFolderID: &startFolderID, Theme: ThemeSystem}
+
+	mockAuthorizer.On("Authorize", folderID, "1", url.RoleViewer).Return(nil)
+	mockNodes.On("GetOne", folderID).Return(&url.URLNode{ID: folderID, UserID: "1", Type: "url"}, nil)
+
+	// Act
+	response, err := service.UpdatePreferences(updates, "1")
+
+	// Assert
+	assert.Nil(t, response)
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeStartFolderInvalid, err.(*apperror.AppError).Code)
+	mockRepo.AssertNotCalled(t, "SavePreferences", mock.Anything)
+}
+func TestService_UpdatePreferences_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	updates := &PreferencesRequestBody{SortOrder: SortNameAsc, ViewMode: ViewList, Theme: ThemeSystem}
+
+	mockRepo.On("SavePreferences", mock.AnythingOfType("*user.Preferences")).Return(errors.New("database error"))
+
+	// Act
+	response, err := service.UpdatePreferences(updates, "1")
+
+	// Assert
+	assert.Nil(t, response)
+	assert.EqualError(t, err, "database error")
+}
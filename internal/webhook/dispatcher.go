@@ -0,0 +1,184 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/outbound"
+
+	"go.uber.org/zap"
+)
+
+const userAgent = "VeraDrive-Webhooks/1.0"
+
+// maxRetryDelay caps the exponential backoff between attempts.
+const maxRetryDelay = 6 * time.Hour
+
+// Sign returns the signature of a payload sent at timestamp, the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+// Receivers recompute it to check that the payload comes from us and was not
+// replayed long after timestamp.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher sends the pending deliveries of the outbox to their webhooks.
+// Failed attempts are retried with exponential backoff until WEBHOOK_MAX_ATTEMPTS is
+// reached. Deliveries are sent at least once. Receivers at internal addresses
+// are refused unless their network is in OUTBOUND_ALLOWED_NETWORKS.
+type Dispatcher struct {
+	repo           Repository
+	client         *http.Client
+	logger         *zap.Logger
+	interval       time.Duration
+	timeout        time.Duration
+	maxAttempts    int
+	retryBaseDelay time.Duration
+	batchSize      int
+}
+
+func NewDispatcher(repo Repository, config *config.Config, logger *zap.Logger) *Dispatcher {
+	return &Dispatcher{
+		repo:           repo,
+		client:         outbound.NewClient(config.WebhookTimeout, config.OutboundAllowedNetworks),
+		logger:         logger,
+		interval:       config.WebhookDispatchInterval,
+		timeout:        config.WebhookTimeout,
+		maxAttempts:    max(config.WebhookMaxAttempts, 1),
+		retryBaseDelay: config.WebhookRetryBaseDelay,
+		batchSize:      max(config.WebhookBatchSize, 1),
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	if d.interval <= 0 {
+		d.logger.Info("webhook dispatcher disabled")
+		return
+	}
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		if err := d.DispatchOnce(ctx); err != nil {
+			d.logger.Error("failed to dispatch webhooks", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce sends one batch of due deliveries and records the outcome of
+// each attempt.
+func (d *Dispatcher) DispatchOnce(ctx context.Context) error {
+	now := time.Now().UTC()
+	// The lease covers sending the whole batch one after another.
+	leaseUntil := now.Add(time.Duration(d.batchSize) * (d.timeout + time.Second))
+	deliveries, err := d.repo.ClaimDueDeliveries(now, leaseUntil, d.batchSize)
+	if err != nil || len(deliveries) == 0 {
+		return err
+	}
+
+	ids := make([]string, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.WebhookID
+	}
+	webhooks, err := d.repo.GetByIDs(ids)
+	if err != nil {
+		return err
+	}
+	webhooksByID := map[string]*Webhook{}
+	for i := range webhooks {
+		webhooksByID[webhooks[i].ID] = &webhooks[i]
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		webhook := webhooksByID[delivery.WebhookID]
+		if webhook == nil {
+			continue
+		}
+		d.attempt(ctx, webhook, delivery)
+		if err := d.repo.UpdateDelivery(delivery); err != nil {
+			d.logger.Error("failed to record webhook delivery", zap.String("delivery_id", delivery.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, webhook *Webhook, delivery *Delivery) {
+	delivery.Attempts++
+	status, err := d.send(ctx, webhook, delivery)
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+
+	now := time.Now().UTC()
+	if err == nil {
+		delivery.Status = DeliverySucceeded
+		delivery.Error = nil
+		delivery.DeliveredAt = &now
+		return
+	}
+	message := err.Error()
+	delivery.Error = &message
+	if delivery.Attempts >= d.maxAttempts {
+		delivery.Status = DeliveryFailed
+		d.logger.Warn("webhook delivery failed", zap.String("delivery_id", delivery.ID), zap.String("webhook_id", webhook.ID), zap.Error(err))
+		return
+	}
+	delivery.NextAttemptAt = now.Add(d.retryDelay(delivery.Attempts))
+}
+
+// retryDelay is the delay after the given number of failed attempts.
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.retryBaseDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// send posts the payload and returns the response status. Responses other
+// than 2xx are errors.
+func (d *Dispatcher) send(ctx context.Context, webhook *Webhook, delivery *Delivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("X-Webhook-ID", delivery.ID)
+	req.Header.Set("X-Webhook-Event", string(delivery.Event))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", Sign(webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.New("unexpected status | status: " + strconv.Itoa(resp.StatusCode))
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,234 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/outbound"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// setupReceiver starts a webhook receiver that answers with status and
+// records the requests it gets.
+func setupReceiver(status int) (*httptest.Server, chan receivedRequest) {
+	requests := make(chan receivedRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- receivedRequest{header: r.Header, body: body}
+		w.WriteHeader(status)
+	}))
+	return server, requests
+}
+
+func newTestDispatcher(repo Repository) *Dispatcher {
+	return NewDispatcher(repo, &config.Config{
+		WebhookDispatchInterval: time.Minute,
+		WebhookTimeout:          time.Second,
+		WebhookMaxAttempts:      3,
+		WebhookRetryBaseDelay:   time.Minute,
+		WebhookBatchSize:        10,
+		// The test receivers listen on the loopback address.
+		OutboundAllowedNetworks: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")},
+	}, zap.NewNop())
+}
+
+func TestSign_Success(t *testing.T) {
+	// Act
+	signature := Sign("secret", "1700000000", []byte(`{"id":"delivery-id"}`))
+
+	// Assert
+	assert.Equal(t, "sha256=2ac61162c9ea9fbcaaf54e1b805f2c48579b64bc39c9dc5263080318acacf1f8", signature)
+}
+
+func TestDispatcher_DispatchOnce_Success(t *testing.T) {
+	// Arrange
+	server, requests := setupReceiver(http.StatusNoContent)
+	defer server.Close()
+	mockRepo := &MockRepository{}
+	dispatcher := newTestDispatcher(mockRepo)
+
+	webhook := Webhook{ID: "webhook-id", URL: server.URL, Secret: "secret"}
+	delivery := Delivery{ID: "delivery-id", WebhookID: "webhook-id", Event: audit.ActionCreate, Payload: `{"id":"delivery-id"}`, Status: DeliveryPending}
+
+	mockRepo.On("ClaimDueDeliveries", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return([]Delivery{delivery}, nil)
+	mockRepo.On("GetByIDs", []string{"webhook-id"}).Return([]Webhook{webhook}, nil)
+	mockRepo.On("UpdateDelivery", mock.MatchedBy(func(d *Delivery) bool {
+		return d.Status == DeliverySucceeded && d.Attempts == 1 && *d.ResponseStatus == http.StatusNoContent && d.DeliveredAt != nil && d.Error == nil
+	})).Return(nil)
+
+	// Act
+	err := dispatcher.DispatchOnce(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	request := <-requests
+	assert.Equal(t, `{"id":"delivery-id"}`, string(request.body))
+	assert.Equal(t, "application/json", request.header.Get("Content-Type"))
+	assert.Equal(t, "delivery-id", request.header.Get("X-Webhook-ID"))
+	assert.Equal(t, "create", request.header.Get("X-Webhook-Event"))
+	timestamp := request.header.Get("X-Webhook-Timestamp")
+	assert.Equal(t, Sign("secret", timestamp, request.body), request.header.Get("X-Webhook-Signature"))
+	mockRepo.AssertExpectations(t)
+}
+func TestDispatcher_DispatchOnce_RetryWithBackoff(t *testing.T) {
+	// Arrange
+	server, requests := setupReceiver(http.StatusInternalServerError)
+	defer server.Close()
+	mockRepo := &MockRepository{}
+	dispatcher := newTestDispatcher(mockRepo)
+
+	webhook := Webhook{ID: "webhook-id", URL: server.URL, Secret: "secret"}
+	delivery := Delivery{ID: "delivery-id", WebhookID: "webhook-id", Event: audit.ActionMove, Payload: `{}`, Status: DeliveryPending, Attempts: 1}
+
+	var updated *Delivery
+	mockRepo.On("ClaimDueDeliveries", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return([]Delivery{delivery}, nil)
+	mockRepo.On("GetByIDs", []string{"webhook-id"}).Return([]Webhook{webhook}, nil)
+	mockRepo.On("UpdateDelivery", mock.AnythingOfType("*webhook.Delivery")).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(*Delivery)
+	}).Return(nil)
+
+	// Act
+	start := time.Now()
+	err := dispatcher.DispatchOnce(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, requests, 1)
+	require.NotNil(t, updated)
+	assert.Equal(t, DeliveryPending, updated.Status)
+	assert.Equal(t, 2, updated.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, *updated.ResponseStatus)
+	assert.Contains(t, *updated.Error, "500")
+	assert.WithinDuration(t, start.Add(2*time.Minute), updated.NextAttemptAt, 5*time.Second)
+}
+func TestDispatcher_DispatchOnce_GivesUpAfterMaxAttempts(t *testing.T) {
+	// Arrange
+	server, _ := setupReceiver(http.StatusBadGateway)
+	defer server.Close()
+	mockRepo := &MockRepository{}
+	dispatcher := newTestDispatcher(mockRepo)
+
+	webhook := Webhook{ID: "webhook-id", URL: server.URL, Secret: "secret"}
+	delivery := Delivery{ID: "delivery-id", WebhookID: "webhook-id", Event: audit.ActionMove, Payload: `{}`, Status: DeliveryPending, Attempts: 2}
+
+	mockRepo.On("ClaimDueDeliveries", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return([]Delivery{delivery}, nil)
+	mockRepo.On("GetByIDs", []string{"webhook-id"}).Return([]Webhook{webhook}, nil)
+	mockRepo.On("UpdateDelivery", mock.MatchedBy(func(d *Delivery) bool {
+		return d.Status == DeliveryFailed && d.Attempts == 3
+	})).Return(nil)
+
+	// Act
+	err := dispatcher.DispatchOnce(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+func TestDispatcher_DispatchOnce_ReceiverUnreachable(t *testing.T) {
+	// Arrange
+	server, _ := setupReceiver(http.StatusOK)
+	server.Close()
+	mockRepo := &MockRepository{}
+	dispatcher := newTestDispatcher(mockRepo)
+
+	webhook := Webhook{ID: "webhook-id", URL: server.URL, Secret: "secret"}
+	delivery := Delivery{ID: "delivery-id", WebhookID: "webhook-id", Event: audit.ActionMove, Payload: `{}`, Status: DeliveryPending}
+
+	mockRepo.On("ClaimDueDeliveries", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return([]Delivery{delivery}, nil)
+	mockRepo.On("GetByIDs", []string{"webhook-id"}).Return([]Webhook{webhook}, nil)
+	mockRepo.On("UpdateDelivery", mock.MatchedBy(func(d *Delivery) bool {
+		return d.Status == DeliveryPending && d.Attempts == 1 && d.ResponseStatus == nil && d.Error != nil
+	})).Return(nil)
+
+	// Act
+	err := dispatcher.DispatchOnce(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+func TestDispatcher_DispatchOnce_InternalAddress(t *testing.T) {
+	// Arrange
+	server, requests := setupReceiver(http.StatusOK)
+	defer server.Close()
+	mockRepo := &MockRepository{}
+	dispatcher := NewDispatcher(mockRepo, &config.Config{
+		WebhookTimeout:     time.Second,
+		WebhookMaxAttempts: 3,
+		WebhookBatchSize:   10,
+	}, zap.NewNop())
+
+	webhook := Webhook{ID: "webhook-id", URL: server.URL, Secret: "secret"}
+	delivery := Delivery{ID: "delivery-id", WebhookID: "webhook-id", Event: audit.ActionCreate, Payload: `{}`, Status: DeliveryPending}
+
+	mockRepo.On("ClaimDueDeliveries", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return([]Delivery{delivery}, nil)
+	mockRepo.On("GetByIDs", []string{"webhook-id"}).Return([]Webhook{webhook}, nil)
+	mockRepo.On("UpdateDelivery", mock.MatchedBy(func(d *Delivery) bool {
+		return d.Status == DeliveryPending && d.ResponseStatus == nil && strings.Contains(*d.Error, outbound.ErrAddressNotAllowed.Error())
+	})).Return(nil)
+
+	// Act
+	err := dispatcher.DispatchOnce(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, requests)
+	mockRepo.AssertExpectations(t)
+}
+func TestDispatcher_DispatchOnce_NothingDue(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	dispatcher := newTestDispatcher(mockRepo)
+
+	mockRepo.On("ClaimDueDeliveries", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return([]Delivery{}, nil)
+
+	// Act
+	err := dispatcher.DispatchOnce(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "GetByIDs", mock.Anything)
+}
+
+func TestDispatcher_retryDelay(t *testing.T) {
+	dispatcher := newTestDispatcher(&MockRepository{})
+
+	tests := []struct {
+		name     string
+		attempts int
+		expected time.Duration
+	}{
+		{name: "first retry", attempts: 1, expected: time.Minute},
+		{name: "doubles", attempts: 2, expected: 2 * time.Minute},
+		{name: "keeps doubling", attempts: 4, expected: 8 * time.Minute},
+		{name: "capped", attempts: 20, expected: maxRetryDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			delay := dispatcher.retryDelay(tt.attempts)
+
+			// Assert
+			assert.Equal(t, tt.expected, delay)
+		})
+	}
+}
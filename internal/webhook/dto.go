@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+)
+
+type RequestURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type CreateRequestBody struct {
+	URL    string         `json:"url" binding:"required,url,startswith=http"`
+	Events []audit.Action `json:"events" binding:"omitempty,unique,dive,oneof=create update move delete restore"`
+}
+
+type WebhookResponse struct {
+	ID        string         `json:"id"`
+	URL       string         `json:"url"`
+	Events    []audit.Action `json:"events"`
+	CreatedAt string         `json:"created_at"`
+}
+
+// CreateWebhookResponse includes the signing secret, which is only returned
+// when the webhook is created.
+type CreateWebhookResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+type DeliveryResponse struct {
+	ID             string          `json:"id"`
+	Event          audit.Action    `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *string         `json:"next_attempt_at"`
+	ResponseStatus *int            `json:"response_status"`
+	Error          *string         `json:"error"`
+	DeliveredAt    *string         `json:"delivered_at"`
+	CreatedAt      string          `json:"created_at"`
+}
+
+func newWebhookResponse(webhook *Webhook) (*WebhookResponse, error) {
+	events := []audit.Action{}
+	if err := json.Unmarshal([]byte(webhook.Events), &events); err != nil {
+		return nil, err
+	}
+	return &WebhookResponse{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Events:    events,
+		CreatedAt: webhook.CreatedAt.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+func newDeliveryResponse(delivery *Delivery) *DeliveryResponse {
+	response := &DeliveryResponse{
+		ID:             delivery.ID,
+		Event:          delivery.Event,
+		Payload:        json.RawMessage(delivery.Payload),
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		Error:          delivery.Error,
+		CreatedAt:      delivery.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if delivery.Status == DeliveryPending {
+		nextAttemptAt := delivery.NextAttemptAt.UTC().Format(time.RFC3339)
+		response.NextAttemptAt = &nextAttemptAt
+	}
+	if delivery.DeliveredAt != nil {
+		deliveredAt := delivery.DeliveredAt.UTC().Format(time.RFC3339)
+		response.DeliveredAt = &deliveredAt
+	}
+	return response
+}
@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+
+	"github.com/google/uuid"
+)
+
+// Payload is the body posted to a webhook. ID is the delivery id, which
+// stays the same across retries so that receivers can drop duplicates.
+type Payload struct {
+	ID        string       `json:"id"`
+	Event     audit.Action `json:"event"`
+	CreatedAt string       `json:"created_at"`
+	Data      any          `json:"data"`
+}
+
+// Subscribed reports whether the webhook wants events of the action.
+func (w *Webhook) Subscribed(action audit.Action) (bool, error) {
+	var events []audit.Action
+	if err := json.Unmarshal([]byte(w.Events), &events); err != nil {
+		return false, err
+	}
+	return len(events) == 0 || slices.Contains(events, action), nil
+}
+
+// NewDelivery queues an event with the given data for the webhook. The
+// delivery must be created in the transaction making the change it reports.
+func NewDelivery(webhook *Webhook, action audit.Action, data any) (*Delivery, error) {
+	now := time.Now().UTC()
+	delivery := &Delivery{
+		ID:            uuid.New().String(),
+		WebhookID:     webhook.ID,
+		Event:         action,
+		Status:        DeliveryPending,
+		NextAttemptAt: now,
+	}
+	payload, err := json.Marshal(Payload{
+		ID:        delivery.ID,
+		Event:     action,
+		CreatedAt: now.Format(time.RFC3339),
+		Data:      data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = string(payload)
+	return delivery, nil
+}
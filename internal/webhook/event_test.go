@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_Subscribed(t *testing.T) {
+	tests := []struct {
+		name     string
+		events   string
+		action   audit.Action
+		expected bool
+	}{
+		{name: "all events", events: `[]`, action: audit.ActionMove, expected: true},
+		{name: "listed event", events: `["create","move"]`, action: audit.ActionMove, expected: true},
+		{name: "unlisted event", events: `["create"]`, action: audit.ActionDelete, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			subscribed, err := (&Webhook{Events: tt.events}).Subscribed(tt.action)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, subscribed)
+		})
+	}
+}
+
+func TestNewDelivery_Success(t *testing.T) {
+	// Arrange
+	webhook := &Webhook{ID: "webhook-id"}
+
+	// Act
+	delivery, err := NewDelivery(webhook, audit.ActionCreate, map[string]string{"node_id": "node-id"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "webhook-id", delivery.WebhookID)
+	assert.Equal(t, DeliveryPending, delivery.Status)
+	assert.False(t, delivery.NextAttemptAt.IsZero())
+
+	var payload Payload
+	err = json.Unmarshal([]byte(delivery.Payload), &payload)
+	require.NoError(t, err)
+	assert.Equal(t, delivery.ID, payload.ID)
+	assert.Equal(t, audit.ActionCreate, payload.Event)
+	assert.Equal(t, map[string]any{"node_id": "node-id"}, payload.Data)
+}
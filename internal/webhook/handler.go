@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var body CreateRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := h.service.CreateWebhook(&body, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *Handler) GetWebhooks(c *gin.Context) {
+	userID := c.GetString("user_id")
+	response, err := h.service.GetWebhooks(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	uri := &RequestURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if err := h.service.DeleteWebhook(uri.ID, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) GetDeliveries(c *gin.Context) {
+	uri := &RequestURI{}
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request uri | " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	response, err := h.service.GetDeliveries(uri.ID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) CreateWebhook(creates *CreateRequestBody, userID string) (*CreateWebhookResponse, error) {
+	args := m.Called(creates, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*CreateWebhookResponse), args.Error(1)
+}
+func (m *MockService) GetWebhooks(userID string) ([]WebhookResponse, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]WebhookResponse), args.Error(1)
+}
+func (m *MockService) DeleteWebhook(id string, userID string) error {
+	args := m.Called(id, userID)
+	return args.Error(0)
+}
+func (m *MockService) GetDeliveries(id string, userID string) ([]DeliveryResponse, error) {
+	args := m.Called(id, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]DeliveryResponse), args.Error(1)
+}
+
+const webhookID = "123e4567-e89b-12d3-a456-426614174000"
+
+func TestHandler_NewHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+
+	// Act
+	h := NewHandler(mockService)
+
+	// Assert
+	assert.IsType(t, &Handler{}, h)
+	assert.Equal(t, mockService, h.service)
+}
+
+func TestHandler_CreateWebhook_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+
+	body := CreateRequestBody{URL: "https://example.com/hook", Events: []audit.Action{audit.ActionCreate}}
+	requestJSON, _ := json.Marshal(body)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestJSON))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", "1")
+
+	expected := &CreateWebhookResponse{
+		WebhookResponse: WebhookResponse{ID: webhookID, URL: body.URL, Events: body.Events},
+		Secret:          "secret",
+	}
+	mockService.On("CreateWebhook", &body, "1").Return(expected, nil)
+
+	// Act
+	handler.CreateWebhook(c)
+
+	// Assert
+	require.Equal(t, http.StatusCreated, w.Code)
+	var response CreateWebhookResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, *expected, response)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_CreateWebhook_InvalidBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "missing url", body: `{}`},
+		{name: "not a url", body: `{"url":"not a url"}`},
+		{name: "unsupported scheme", body: `{"url":"ftp://example.com/hook"}`},
+		{name: "unknown event", body: `{"url":"https://example.com/hook","events":["rename"]}`},
+		{name: "duplicate event", body: `{"url":"https://example.com/hook","events":["create","create"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := &MockService{}
+			handler := NewHandler(mockService)
+			c, w := test.SetupContext()
+
+			c.Request.Body = io.NopCloser(bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", "1")
+
+			// Act
+			handler.CreateWebhook(c)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			mockService.AssertNotCalled(t, "CreateWebhook", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandler_GetWebhooks_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Set("user_id", "1")
+
+	expected := []WebhookResponse{{ID: webhookID, URL: "https://example.com/hook", Events: []audit.Action{}}}
+	mockService.On("GetWebhooks", "1").Return(expected, nil)
+
+	// Act
+	handler.GetWebhooks(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	var response []WebhookResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, expected, response)
+}
+
+func TestHandler_DeleteWebhook_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "id", Value: webhookID}}
+	c.Set("user_id", "1")
+
+	mockService.On("DeleteWebhook", webhookID, "1").Return(nil)
+
+	// Act
+	handler.DeleteWebhook(c)
+	c.Writer.WriteHeaderNow()
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+func TestHandler_DeleteWebhook_InvalidURI(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "id", Value: "invalid"}}
+
+	// Act
+	handler.DeleteWebhook(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "DeleteWebhook", mock.Anything, mock.Anything)
+}
+
+func TestHandler_GetDeliveries_Success(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "id", Value: webhookID}}
+	c.Set("user_id", "1")
+
+	expected := []DeliveryResponse{{ID: "delivery-id", Event: audit.ActionDelete, Payload: json.RawMessage(`{"id":"delivery-id"}`), Status: DeliveryFailed, Attempts: 8}}
+	mockService.On("GetDeliveries", webhookID, "1").Return(expected, nil)
+
+	// Act
+	handler.GetDeliveries(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	var response []DeliveryResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, expected, response)
+}
+func TestHandler_GetDeliveries_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := &MockService{}
+	handler := NewHandler(mockService)
+	c, w := test.SetupContext()
+	c.Params = gin.Params{{Key: "id", Value: webhookID}}
+	c.Set("user_id", "1")
+
+	mockService.On("GetDeliveries", webhookID, "1").Return(nil, apperror.New(apperror.CodeWebhookNotFound, "Webhook not found"))
+
+	// Act
+	handler.GetDeliveries(c)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, c.Errors, 1)
+}
@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Webhook is a subscription of a user to the changes of their tree. Events
+// is a JSON array of the actions delivered, all actions when it is empty.
+type Webhook struct {
+	ID        string    `gorm:"type:uuid;primary_key"`
+	UserID    string    `gorm:"type:varchar(255);not null;index"`
+	URL       string    `gorm:"type:text;not null"`
+	Secret    string    `gorm:"type:varchar(64);not null"`
+	Events    string    `gorm:"type:jsonb;not null"`
+	CreatedAt time.Time `gorm:"type:timestamptz;not null"`
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+func (w *Webhook) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == "" {
+		w.ID = uuid.New().String()
+	}
+	w.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Delivery is an event queued for a webhook. Rows are written in the same
+// transaction as the change they report, and sent by the Dispatcher once the
+// transaction has committed.
+type Delivery struct {
+	ID             string         `gorm:"type:uuid;primary_key"`
+	WebhookID      string         `gorm:"type:uuid;not null;index"`
+	Event          audit.Action   `gorm:"type:varchar(10);not null"`
+	Payload        string         `gorm:"type:jsonb;not null"`
+	Status         DeliveryStatus `gorm:"type:varchar(10);not null;index:idx_webhook_deliveries_status_next_attempt_at;check:status IN ('pending','succeeded','failed')"`
+	Attempts       int            `gorm:"type:int;not null"`
+	NextAttemptAt  time.Time      `gorm:"type:timestamptz;not null;index:idx_webhook_deliveries_status_next_attempt_at"`
+	ResponseStatus *int           `gorm:"type:int"`
+	Error          *string        `gorm:"type:text"`
+	DeliveredAt    *time.Time     `gorm:"type:timestamptz"`
+	CreatedAt      time.Time      `gorm:"type:timestamptz;not null;index"`
+}
+
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+func (d *Delivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = uuid.New().String()
+	}
+	d.CreatedAt = time.Now().UTC()
+	if d.Status == "" {
+		d.Status = DeliveryPending
+	}
+	if d.NextAttemptAt.IsZero() {
+		d.NextAttemptAt = d.CreatedAt
+	}
+	return nil
+}
+
+type Repository interface {
+	Create(webhook *Webhook) error
+	GetOne(id string) (*Webhook, error)
+	GetByUser(userID string) ([]Webhook, error)
+	GetByIDs(ids []string) ([]Webhook, error)
+	Delete(id string) error
+	GetDeliveries(webhookID string, limit int) ([]Delivery, error)
+	ClaimDueDeliveries(now time.Time, leaseUntil time.Time, limit int) ([]Delivery, error)
+	UpdateDelivery(delivery *Delivery) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(webhook *Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+func (r *repository) GetOne(id string) (*Webhook, error) {
+	var webhook Webhook
+	err := r.db.Where("id = ?", id).First(&webhook).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *repository) GetByUser(userID string) ([]Webhook, error) {
+	var webhooks []Webhook
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *repository) GetByIDs(ids []string) ([]Webhook, error) {
+	var webhooks []Webhook
+	if len(ids) == 0 {
+		return webhooks, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&webhooks).Error
+	return webhooks, err
+}
+
+// Delete removes the webhook together with its deliveries.
+func (r *repository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&Delivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&Webhook{}).Error
+	})
+}
+
+func (r *repository) GetDeliveries(webhookID string, limit int) ([]Delivery, error) {
+	var deliveries []Delivery
+	err := r.db.Where("webhook_id = ?", webhookID).Order("created_at DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimDueDeliveries returns the pending deliveries due at now and postpones
+// their next attempt to leaseUntil, so that other replicas skip them while
+// they are being sent. Deliveries whose sender died are retried once the
+// lease ran out.
+func (r *repository) ClaimDueDeliveries(now time.Time, leaseUntil time.Time, limit int) ([]Delivery, error) {
+	var deliveries []Delivery
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", DeliveryPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+		ids := make([]string, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+		}
+		return tx.Model(&Delivery{}).Where("id IN ?", ids).Update("next_attempt_at", leaseUntil).Error
+	})
+	return deliveries, err
+}
+
+func (r *repository) UpdateDelivery(delivery *Delivery) error {
+	return r.db.Save(delivery).Error
+}
@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/audit"
+	"github.com/vera/vera-drive-service/internal/config"
+	"github.com/vera/vera-drive-service/internal/db"
+	"github.com/vera/vera-drive-service/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var d *gorm.DB
+
+func TestMain(m *testing.M) {
+	// Setup
+	dbURL, closeDB, err := test.SetupPostgresql()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	d, err = db.NewDatabase(&config.Config{DatabaseURL: dbURL})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = d.AutoMigrate(&Webhook{}, &Delivery{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Run
+	code := m.Run()
+
+	// Teardown
+	closeDB()
+
+	os.Exit(code)
+}
+
+func createTestDelivery(t *testing.T, webhook *Webhook, nextAttemptAt time.Time) *Delivery {
+	delivery, err := NewDelivery(webhook, audit.ActionCreate, map[string]string{})
+	require.NoError(t, err)
+	delivery.NextAttemptAt = nextAttemptAt
+	err = d.Create(delivery).Error
+	require.NoError(t, err)
+	return delivery
+}
+
+func TestRepository_NewRepository_Success(t *testing.T) {
+	// Act
+	repo := NewRepository(d)
+
+	// Assert
+	assert.NotNil(t, repo)
+	assert.IsType(t, &repository{}, repo)
+}
+
+func TestRepository_CreateAndGet_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	webhook := &Webhook{UserID: "1", URL: "https://example.com/hook", Secret: "secret", Events: `["create"]`}
+	other := &Webhook{UserID: "2", URL: "https://example.com/other", Secret: "secret", Events: `[]`}
+
+	// Act
+	err = repo.Create(webhook)
+	require.NoError(t, err)
+	err = repo.Create(other)
+	require.NoError(t, err)
+	byID, byIDErr := repo.GetOne(webhook.ID)
+	byUser, byUserErr := repo.GetByUser("1")
+	byIDs, byIDsErr := repo.GetByIDs([]string{webhook.ID, other.ID})
+	missing, missingErr := repo.GetOne("123e4567-e89b-12d3-a456-426614174000")
+
+	// Assert
+	require.NoError(t, byIDErr)
+	require.NoError(t, byUserErr)
+	require.NoError(t, byIDsErr)
+	require.NoError(t, missingErr)
+	assert.Equal(t, webhook.URL, byID.URL)
+	assert.JSONEq(t, `["create"]`, byID.Events)
+	require.Len(t, byUser, 1)
+	assert.Equal(t, webhook.ID, byUser[0].ID)
+	assert.Len(t, byIDs, 2)
+	assert.Nil(t, missing)
+}
+
+func TestRepository_Delete_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	webhook := &Webhook{UserID: "1", URL: "https://example.com/hook", Secret: "secret", Events: `[]`}
+	err = repo.Create(webhook)
+	require.NoError(t, err)
+	createTestDelivery(t, webhook, time.Now().UTC())
+
+	// Act
+	err = repo.Delete(webhook.ID)
+
+	// Assert
+	require.NoError(t, err)
+	deleted, err := repo.GetOne(webhook.ID)
+	require.NoError(t, err)
+	assert.Nil(t, deleted)
+	deliveries, err := repo.GetDeliveries(webhook.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+}
+
+func TestRepository_GetDeliveries_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	webhook := &Webhook{UserID: "1", URL: "https://example.com/hook", Secret: "secret", Events: `[]`}
+	err = repo.Create(webhook)
+	require.NoError(t, err)
+	first := createTestDelivery(t, webhook, time.Now().UTC())
+	second := createTestDelivery(t, webhook, time.Now().UTC())
+
+	// Act
+	deliveries, err := repo.GetDeliveries(webhook.ID, 1)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, second.ID, deliveries[0].ID)
+	assert.NotEqual(t, first.ID, deliveries[0].ID)
+}
+
+func TestRepository_ClaimDueDeliveries_Success(t *testing.T) {
+	// Arrange
+	err := test.CleanupTables(d)
+	require.NoError(t, err)
+	repo := NewRepository(d)
+
+	now := time.Now().UTC()
+	webhook := &Webhook{UserID: "1", URL: "https://example.com/hook", Secret: "secret", Events: `[]`}
+	err = repo.Create(webhook)
+	require.NoError(t, err)
+	due := createTestDelivery(t, webhook, now.Add(-time.Minute))
+	createTestDelivery(t, webhook, now.Add(time.Hour))
+	done := createTestDelivery(t, webhook, now.Add(-time.Minute))
+	done.Status = DeliverySucceeded
+	err = repo.UpdateDelivery(done)
+	require.NoError(t, err)
+
+	// Act
+	claimed, claimErr := repo.ClaimDueDeliveries(now, now.Add(time.Minute), 10)
+	claimedAgain, claimAgainErr := repo.ClaimDueDeliveries(now, now.Add(time.Minute), 10)
+
+	// Assert
+	require.NoError(t, claimErr)
+	require.NoError(t, claimAgainErr)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, due.ID, claimed[0].ID)
+	assert.Empty(t, claimedAgain)
+}
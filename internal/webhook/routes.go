@@ -0,0 +1,21 @@
+package webhook
+
+import (
+	"github.com/vera/vera-drive-service/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, h *Handler, authMiddleware middleware.AuthMiddleware) {
+	read := middleware.RequireScope(middleware.ScopeDriveRead)
+	admin := middleware.RequireScope(middleware.ScopeDriveAdmin)
+
+	g := r.Group("/webhooks")
+	g.Use(gin.HandlerFunc(authMiddleware))
+	{
+		g.POST("", admin, h.CreateWebhook)
+		g.GET("", read, h.GetWebhooks)
+		g.DELETE("/:id", admin, h.DeleteWebhook)
+		g.GET("/:id/deliveries", read, h.GetDeliveries)
+	}
+}
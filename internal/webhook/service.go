@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/audit"
+)
+
+// deliveryLogLimit is the number of most recent deliveries listed per
+// webhook.
+const deliveryLogLimit = 100
+
+type Service interface {
+	CreateWebhook(creates *CreateRequestBody, userID string) (*CreateWebhookResponse, error)
+	GetWebhooks(userID string) ([]WebhookResponse, error)
+	DeleteWebhook(id string, userID string) error
+	GetDeliveries(id string, userID string) ([]DeliveryResponse, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// getOwned returns the webhook if it belongs to the user. Webhooks of other
+// users are reported as not found.
+func (s *service) getOwned(id string, userID string) (*Webhook, error) {
+	webhook, err := s.repo.GetOne(id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil || webhook.UserID != userID {
+		return nil, apperror.New(apperror.CodeWebhookNotFound, "Webhook not found | id: "+id)
+	}
+	return webhook, nil
+}
+
+func (s *service) CreateWebhook(creates *CreateRequestBody, userID string) (*CreateWebhookResponse, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+	events := creates.Events
+	if events == nil {
+		events = []audit.Action{}
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &Webhook{
+		UserID: userID,
+		URL:    creates.URL,
+		Secret: secret,
+		Events: string(data),
+	}
+	if err := s.repo.Create(webhook); err != nil {
+		return nil, err
+	}
+	response, err := newWebhookResponse(webhook)
+	if err != nil {
+		return nil, err
+	}
+	return &CreateWebhookResponse{WebhookResponse: *response, Secret: secret}, nil
+}
+
+func (s *service) GetWebhooks(userID string) ([]WebhookResponse, error) {
+	webhooks, err := s.repo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]WebhookResponse, len(webhooks))
+	for i, webhook := range webhooks {
+		response, err := newWebhookResponse(&webhook)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = *response
+	}
+	return responses, nil
+}
+
+func (s *service) DeleteWebhook(id string, userID string) error {
+	if _, err := s.getOwned(id, userID); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// GetDeliveries returns the delivery log of the webhook, newest first.
+func (s *service) GetDeliveries(id string, userID string) ([]DeliveryResponse, error) {
+	if _, err := s.getOwned(id, userID); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.repo.GetDeliveries(id, deliveryLogLimit)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]DeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		responses[i] = *newDeliveryResponse(&delivery)
+	}
+	return responses, nil
+}
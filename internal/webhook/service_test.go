@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vera/vera-drive-service/internal/apperror"
+	"github.com/vera/vera-drive-service/internal/audit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Create(webhook *Webhook) error {
+	args := m.Called(webhook)
+	return args.Error(0)
+}
+func (m *MockRepository) GetOne(id string) (*Webhook, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Webhook), args.Error(1)
+}
+func (m *MockRepository) GetByUser(userID string) ([]Webhook, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]Webhook), args.Error(1)
+}
+func (m *MockRepository) GetByIDs(ids []string) ([]Webhook, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]Webhook), args.Error(1)
+}
+func (m *MockRepository) Delete(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+func (m *MockRepository) GetDeliveries(webhookID string, limit int) ([]Delivery, error) {
+	args := m.Called(webhookID, limit)
+	return args.Get(0).([]Delivery), args.Error(1)
+}
+func (m *MockRepository) ClaimDueDeliveries(now time.Time, leaseUntil time.Time, limit int) ([]Delivery, error) {
+	args := m.Called(now, leaseUntil, limit)
+	return args.Get(0).([]Delivery), args.Error(1)
+}
+func (m *MockRepository) UpdateDelivery(delivery *Delivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+
+func TestService_NewService_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+
+	// Act
+	s := NewService(mockRepo)
+
+	// Assert
+	assert.IsType(t, &service{}, s)
+	assert.Equal(t, mockRepo, s.(*service).repo)
+}
+
+func TestService_CreateWebhook_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	creates := &CreateRequestBody{URL: "https://example.com/hook", Events: []audit.Action{audit.ActionCreate}}
+
+	var created *Webhook
+	mockRepo.On("Create", mock.AnythingOfType("*webhook.Webhook")).Run(func(args mock.Arguments) {
+		created = args.Get(0).(*Webhook)
+		created.ID = "webhook-id"
+	}).Return(nil)
+
+	// Act
+	response, err := service.CreateWebhook(creates, "1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "1", created.UserID)
+	assert.Equal(t, `["create"]`, created.Events)
+	assert.Len(t, created.Secret, 43)
+	assert.Equal(t, "webhook-id", response.ID)
+	assert.Equal(t, creates.URL, response.URL)
+	assert.Equal(t, []audit.Action{audit.ActionCreate}, response.Events)
+	assert.Equal(t, created.Secret, response.Secret)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_CreateWebhook_AllEvents(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+
+	mockRepo.On("Create", mock.MatchedBy(func(webhook *Webhook) bool {
+		return webhook.Events == `[]`
+	})).Return(nil)
+
+	// Act
+	response, err := service.CreateWebhook(&CreateRequestBody{URL: "https://example.com/hook"}, "1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, response.Events)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_CreateWebhook_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+
+	mockRepo.On("Create", mock.AnythingOfType("*webhook.Webhook")).Return(errors.New("db error"))
+
+	// Act
+	response, err := service.CreateWebhook(&CreateRequestBody{URL: "https://example.com/hook"}, "1")
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, response)
+}
+
+func TestService_GetWebhooks_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+
+	mockRepo.On("GetByUser", "1").Return([]Webhook{
+		{ID: "webhook-1", UserID: "1", URL: "https://example.com/1", Secret: "secret", Events: `[]`},
+		{ID: "webhook-2", UserID: "1", URL: "https://example.com/2", Secret: "secret", Events: `["delete"]`},
+	}, nil)
+
+	// Act
+	response, err := service.GetWebhooks("1")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, response, 2)
+	assert.Equal(t, "webhook-1", response[0].ID)
+	assert.Equal(t, []audit.Action{audit.ActionDelete}, response[1].Events)
+}
+
+func TestService_DeleteWebhook_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+
+	mockRepo.On("GetOne", "webhook-id").Return(&Webhook{ID: "webhook-id", UserID: "1"}, nil)
+	mockRepo.On("Delete", "webhook-id").Return(nil)
+
+	// Act
+	err := service.DeleteWebhook("webhook-id", "1")
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+func TestService_DeleteWebhook_OtherUser(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+
+	mockRepo.On("GetOne", "webhook-id").Return(&Webhook{ID: "webhook-id", UserID: "2"}, nil)
+
+	// Act
+	err := service.DeleteWebhook("webhook-id", "1")
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, apperror.CodeWebhookNotFound, err.(*apperror.AppError).Code)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestService_GetDeliveries_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+	deliveredAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	status := 200
+
+	mockRepo.On("GetOne", "webhook-id").Return(&Webhook{ID: "webhook-id", UserID: "1"}, nil)
+	mockRepo.On("GetDeliveries", "webhook-id", deliveryLogLimit).Return([]Delivery{
+		{ID: "delivery-2", WebhookID: "webhook-id", Event: audit.ActionMove, Payload: `{"id":"delivery-2"}`, Status: DeliveryPending, Attempts: 1, NextAttemptAt: deliveredAt},
+		{ID: "delivery-1", WebhookID: "webhook-id", Event: audit.ActionCreate, Payload: `{"id":"delivery-1"}`, Status: DeliverySucceeded, Attempts: 1, ResponseStatus: &status, DeliveredAt: &deliveredAt},
+	}, nil)
+
+	// Act
+	response, err := service.GetDeliveries("webhook-id", "1")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, response, 2)
+	assert.Equal(t, "2024-01-01T00:00:00Z", *response[0].NextAttemptAt)
+	assert.Nil(t, response[0].DeliveredAt)
+	assert.JSONEq(t, `{"id":"delivery-1"}`, string(response[1].Payload))
+	assert.Nil(t, response[1].NextAttemptAt)
+	assert.Equal(t, "2024-01-01T00:00:00Z", *response[1].DeliveredAt)
+	assert.Equal(t, 200, *response[1].ResponseStatus)
+}
+func TestService_GetDeliveries_NotFound(t *testing.T) {
+	// Arrange
+	mockRepo := &MockRepository{}
+	service := &service{repo: mockRepo}
+
+	mockRepo.On("GetOne", "webhook-id").Return(nil, nil)
+
+	// Act
+	response, err := service.GetDeliveries("webhook-id", "1")
+
+	// Assert
+	require.Error(t, err)
+	assert.Nil(t, response)
+	assert.Equal(t, apperror.CodeWebhookNotFound, err.(*apperror.AppError).Code)
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_url_nodes_normalized_url;
+
+ALTER TABLE url_nodes DROP COLUMN IF EXISTS normalized_url;
@@ -0,0 +1,3 @@
+ALTER TABLE url_nodes ADD COLUMN normalized_url TEXT;
+
+CREATE INDEX idx_url_nodes_normalized_url ON url_nodes(normalized_url);
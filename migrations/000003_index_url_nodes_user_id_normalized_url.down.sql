@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_url_nodes_user_id_normalized_url;
+
+CREATE INDEX idx_url_nodes_normalized_url ON url_nodes(normalized_url);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_url_nodes_normalized_url;
+
+CREATE INDEX idx_url_nodes_user_id_normalized_url ON url_nodes(user_id, normalized_url)
+  WHERE deleted_at IS NULL;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_url_nodes_last_checked_at;
+
+ALTER TABLE url_nodes
+  DROP COLUMN IF EXISTS last_status_code,
+  DROP COLUMN IF EXISTS last_check_error,
+  DROP COLUMN IF EXISTS redirect_url,
+  DROP COLUMN IF EXISTS last_checked_at;
@@ -0,0 +1,7 @@
+ALTER TABLE url_nodes
+  ADD COLUMN last_status_code INTEGER,
+  ADD COLUMN last_check_error TEXT,
+  ADD COLUMN redirect_url TEXT,
+  ADD COLUMN last_checked_at TIMESTAMPTZ;
+
+CREATE INDEX idx_url_nodes_last_checked_at ON url_nodes(last_checked_at);
@@ -0,0 +1,5 @@
+ALTER TABLE url_nodes
+  DROP COLUMN IF EXISTS title,
+  DROP COLUMN IF EXISTS description,
+  DROP COLUMN IF EXISTS image_url,
+  DROP COLUMN IF EXISTS favicon_url;
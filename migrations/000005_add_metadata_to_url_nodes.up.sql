@@ -0,0 +1,5 @@
+ALTER TABLE url_nodes
+  ADD COLUMN title TEXT,
+  ADD COLUMN description TEXT,
+  ADD COLUMN image_url TEXT,
+  ADD COLUMN favicon_url TEXT;
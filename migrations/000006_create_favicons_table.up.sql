@@ -0,0 +1,6 @@
+CREATE TABLE favicons (
+  host TEXT PRIMARY KEY,
+  content_type TEXT NOT NULL,
+  data BYTEA,
+  fetched_at TIMESTAMPTZ NOT NULL
+);
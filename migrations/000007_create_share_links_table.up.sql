@@ -0,0 +1,15 @@
+CREATE TABLE share_links (
+  id UUID PRIMARY KEY,
+  token VARCHAR(64) NOT NULL,
+  folder_id UUID NOT NULL,
+  user_id INTEGER NOT NULL,
+  password_hash TEXT,
+  expires_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_share_links_token ON share_links(token);
+CREATE INDEX idx_share_links_folder_id ON share_links(folder_id);
+
+ALTER TABLE share_links ADD CONSTRAINT fk_share_links_folder
+  FOREIGN KEY (folder_id) REFERENCES url_nodes(id);
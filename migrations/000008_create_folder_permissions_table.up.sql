@@ -0,0 +1,14 @@
+CREATE TABLE folder_permissions (
+  id UUID PRIMARY KEY,
+  folder_id UUID NOT NULL,
+  user_id INTEGER NOT NULL,
+  role VARCHAR(10) NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_folder_permissions_folder_id_user_id ON folder_permissions(folder_id, user_id);
+CREATE INDEX idx_folder_permissions_user_id ON folder_permissions(user_id);
+
+ALTER TABLE folder_permissions ADD CONSTRAINT fk_folder_permissions_folder
+  FOREIGN KEY (folder_id) REFERENCES url_nodes(id);
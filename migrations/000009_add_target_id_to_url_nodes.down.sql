@@ -0,0 +1,8 @@
+DELETE FROM url_nodes WHERE type = 'mount';
+
+ALTER TABLE url_nodes DROP CONSTRAINT url_nodes_type_check;
+ALTER TABLE url_nodes ADD CONSTRAINT url_nodes_type_check CHECK (type IN ('folder', 'url'));
+
+DROP INDEX IF EXISTS idx_url_nodes_target_id;
+ALTER TABLE url_nodes DROP CONSTRAINT IF EXISTS fk_url_nodes_target;
+ALTER TABLE url_nodes DROP COLUMN IF EXISTS target_id;
@@ -0,0 +1,9 @@
+ALTER TABLE url_nodes ADD COLUMN target_id UUID;
+
+ALTER TABLE url_nodes ADD CONSTRAINT fk_url_nodes_target
+  FOREIGN KEY (target_id) REFERENCES url_nodes(id);
+
+CREATE INDEX idx_url_nodes_target_id ON url_nodes(target_id);
+
+ALTER TABLE url_nodes DROP CONSTRAINT url_nodes_type_check;
+ALTER TABLE url_nodes ADD CONSTRAINT url_nodes_type_check CHECK (type IN ('folder', 'url', 'mount'));
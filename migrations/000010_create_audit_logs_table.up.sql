@@ -0,0 +1,27 @@
+CREATE TABLE audit_logs (
+  id UUID PRIMARY KEY,
+  node_id UUID NOT NULL,
+  owner_id INTEGER NOT NULL,
+  actor_id INTEGER NOT NULL,
+  action VARCHAR(10) NOT NULL CHECK (action IN ('create', 'update', 'move', 'delete', 'restore')),
+  before JSONB,
+  after JSONB,
+  request_id VARCHAR(64),
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_audit_logs_node_id ON audit_logs(node_id);
+CREATE INDEX idx_audit_logs_owner_id ON audit_logs(owner_id);
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs(actor_id);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs(created_at);
+
+-- Audit entries are append-only.
+CREATE FUNCTION audit_logs_reject_update() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_no_update
+  BEFORE UPDATE ON audit_logs
+  FOR EACH ROW EXECUTE FUNCTION audit_logs_reject_update();
@@ -0,0 +1,14 @@
+CREATE TABLE node_revisions (
+  id UUID PRIMARY KEY,
+  node_id UUID NOT NULL,
+  revision INTEGER NOT NULL,
+  actor_id INTEGER NOT NULL,
+  snapshot JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_node_revisions_node_id_revision ON node_revisions(node_id, revision);
+CREATE INDEX idx_node_revisions_created_at ON node_revisions(created_at);
+
+ALTER TABLE node_revisions ADD CONSTRAINT fk_node_revisions_node
+  FOREIGN KEY (node_id) REFERENCES url_nodes(id);
@@ -0,0 +1,10 @@
+CREATE TABLE operations (
+  id UUID PRIMARY KEY,
+  user_id INTEGER NOT NULL,
+  changes JSONB NOT NULL,
+  undone_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_operations_user_id ON operations(user_id);
+CREATE INDEX idx_operations_created_at ON operations(created_at);
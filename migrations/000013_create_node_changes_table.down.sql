@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS node_changes;
+DROP TABLE IF EXISTS change_sequences;
@@ -0,0 +1,30 @@
+CREATE TABLE change_sequences (
+  user_id INTEGER PRIMARY KEY,
+  last_seq BIGINT NOT NULL
+);
+
+CREATE TABLE node_changes (
+  user_id INTEGER NOT NULL,
+  seq BIGINT NOT NULL,
+  node_id UUID NOT NULL,
+  action VARCHAR(10) NOT NULL CHECK (action IN ('create', 'update', 'move', 'delete', 'restore', 'purge')),
+  created_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (user_id, seq)
+);
+
+CREATE INDEX idx_node_changes_node_id ON node_changes(node_id);
+
+-- Existing nodes start the feed, so that syncing from the beginning returns
+-- the whole tree.
+INSERT INTO node_changes (user_id, seq, node_id, action, created_at)
+SELECT
+  user_id,
+  ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at, id),
+  id,
+  CASE WHEN deleted_at IS NULL THEN 'create' ELSE 'delete' END,
+  NOW()
+FROM url_nodes
+WHERE parent_id IS NOT NULL;
+
+INSERT INTO change_sequences (user_id, last_seq)
+SELECT user_id, MAX(seq) FROM node_changes GROUP BY user_id;
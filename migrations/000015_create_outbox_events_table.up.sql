@@ -0,0 +1,14 @@
+CREATE TABLE outbox_events (
+  id BIGSERIAL PRIMARY KEY,
+  event_id UUID NOT NULL,
+  type VARCHAR(50) NOT NULL,
+  payload JSONB NOT NULL,
+  attempts INTEGER NOT NULL,
+  last_error TEXT,
+  published_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_outbox_events_event_id ON outbox_events(event_id);
+CREATE INDEX idx_outbox_events_published_at ON outbox_events(published_at);
+CREATE INDEX idx_outbox_events_unpublished ON outbox_events(id) WHERE published_at IS NULL;
@@ -0,0 +1,14 @@
+CREATE TABLE access_tokens (
+  id UUID PRIMARY KEY,
+  user_id INTEGER NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  token_hash CHAR(64) NOT NULL,
+  prefix VARCHAR(16) NOT NULL,
+  scope VARCHAR(10) NOT NULL CHECK (scope IN ('read', 'write')),
+  expires_at TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_access_tokens_user_id ON access_tokens(user_id);
+CREATE UNIQUE INDEX idx_access_tokens_token_hash ON access_tokens(token_hash);
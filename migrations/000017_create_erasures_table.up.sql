@@ -0,0 +1,9 @@
+CREATE TABLE erasures (
+  id UUID PRIMARY KEY,
+  user_id INTEGER NOT NULL,
+  requested_by VARCHAR(100) NOT NULL,
+  deleted_rows JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_erasures_user_id ON erasures(user_id);
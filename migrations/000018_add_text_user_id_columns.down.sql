@@ -0,0 +1,46 @@
+DROP TRIGGER IF EXISTS erasures_copy_user_ids ON erasures;
+ALTER TABLE erasures
+  DROP COLUMN IF EXISTS user_id_text;
+
+DROP TRIGGER IF EXISTS access_tokens_copy_user_ids ON access_tokens;
+ALTER TABLE access_tokens
+  DROP COLUMN IF EXISTS user_id_text;
+
+DROP TRIGGER IF EXISTS webhooks_copy_user_ids ON webhooks;
+ALTER TABLE webhooks
+  DROP COLUMN IF EXISTS user_id_text;
+
+DROP TRIGGER IF EXISTS node_changes_copy_user_ids ON node_changes;
+ALTER TABLE node_changes
+  DROP COLUMN IF EXISTS user_id_text;
+
+DROP TRIGGER IF EXISTS change_sequences_copy_user_ids ON change_sequences;
+ALTER TABLE change_sequences
+  DROP COLUMN IF EXISTS user_id_text;
+
+DROP TRIGGER IF EXISTS operations_copy_user_ids ON operations;
+ALTER TABLE operations
+  DROP COLUMN IF EXISTS user_id_text;
+
+DROP TRIGGER IF EXISTS node_revisions_copy_user_ids ON node_revisions;
+ALTER TABLE node_revisions
+  DROP COLUMN IF EXISTS actor_id_text;
+
+DROP TRIGGER IF EXISTS audit_logs_copy_user_ids ON audit_logs;
+ALTER TABLE audit_logs
+  DROP COLUMN IF EXISTS owner_id_text,
+  DROP COLUMN IF EXISTS actor_id_text;
+
+DROP TRIGGER IF EXISTS folder_permissions_copy_user_ids ON folder_permissions;
+ALTER TABLE folder_permissions
+  DROP COLUMN IF EXISTS user_id_text;
+
+DROP TRIGGER IF EXISTS share_links_copy_user_ids ON share_links;
+ALTER TABLE share_links
+  DROP COLUMN IF EXISTS user_id_text;
+
+DROP TRIGGER IF EXISTS url_nodes_copy_user_ids ON url_nodes;
+ALTER TABLE url_nodes
+  DROP COLUMN IF EXISTS user_id_text;
+
+DROP FUNCTION IF EXISTS copy_user_ids();
@@ -0,0 +1,128 @@
+-- User IDs become opaque strings, so that identity providers issuing UUID or
+-- other non-numeric subjects are supported. Changing the column types in
+-- place would lock and rewrite the tables, so the conversion runs while the
+-- previous release serves traffic, in steps committed separately:
+--
+--   000018      adds a VARCHAR copy of every user ID column, filled by a
+--               trigger for rows written from then on
+--   000019      backfills the copies of existing rows
+--   000020-031  index the copies concurrently, one migration per index as
+--               CREATE INDEX CONCURRENTLY cannot run in a transaction
+--   000032      swaps the copies in, to apply with the release reading
+--               string user IDs
+--
+-- Each statement here only changes the catalog.
+
+CREATE FUNCTION copy_user_ids() RETURNS trigger AS $$
+DECLARE
+  copied JSONB := to_jsonb(NEW);
+  name TEXT;
+BEGIN
+  FOREACH name IN ARRAY TG_ARGV LOOP
+    copied := jsonb_set(copied, ARRAY[name || '_text'], to_jsonb(copied ->> name));
+  END LOOP;
+  NEW := jsonb_populate_record(NEW, copied);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE url_nodes
+  ADD COLUMN user_id_text VARCHAR(255);
+
+CREATE TRIGGER url_nodes_copy_user_ids
+  BEFORE INSERT OR UPDATE ON url_nodes
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER TABLE url_nodes ADD CONSTRAINT url_nodes_user_id_text_not_null CHECK (user_id_text IS NOT NULL) NOT VALID;
+
+ALTER TABLE share_links
+  ADD COLUMN user_id_text VARCHAR(255);
+
+CREATE TRIGGER share_links_copy_user_ids
+  BEFORE INSERT OR UPDATE ON share_links
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER TABLE share_links ADD CONSTRAINT share_links_user_id_text_not_null CHECK (user_id_text IS NOT NULL) NOT VALID;
+
+ALTER TABLE folder_permissions
+  ADD COLUMN user_id_text VARCHAR(255);
+
+CREATE TRIGGER folder_permissions_copy_user_ids
+  BEFORE INSERT OR UPDATE ON folder_permissions
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER TABLE folder_permissions ADD CONSTRAINT folder_permissions_user_id_text_not_null CHECK (user_id_text IS NOT NULL) NOT VALID;
+
+ALTER TABLE audit_logs
+  ADD COLUMN owner_id_text VARCHAR(255),
+  ADD COLUMN actor_id_text VARCHAR(255);
+
+CREATE TRIGGER audit_logs_copy_user_ids
+  BEFORE INSERT ON audit_logs
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('owner_id', 'actor_id');
+
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_owner_id_text_not_null CHECK (owner_id_text IS NOT NULL) NOT VALID;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_actor_id_text_not_null CHECK (actor_id_text IS NOT NULL) NOT VALID;
+
+ALTER TABLE node_revisions
+  ADD COLUMN actor_id_text VARCHAR(255);
+
+CREATE TRIGGER node_revisions_copy_user_ids
+  BEFORE INSERT OR UPDATE ON node_revisions
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('actor_id');
+
+ALTER TABLE node_revisions ADD CONSTRAINT node_revisions_actor_id_text_not_null CHECK (actor_id_text IS NOT NULL) NOT VALID;
+
+ALTER TABLE operations
+  ADD COLUMN user_id_text VARCHAR(255);
+
+CREATE TRIGGER operations_copy_user_ids
+  BEFORE INSERT OR UPDATE ON operations
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER TABLE operations ADD CONSTRAINT operations_user_id_text_not_null CHECK (user_id_text IS NOT NULL) NOT VALID;
+
+ALTER TABLE change_sequences
+  ADD COLUMN user_id_text VARCHAR(255);
+
+CREATE TRIGGER change_sequences_copy_user_ids
+  BEFORE INSERT OR UPDATE ON change_sequences
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER TABLE change_sequences ADD CONSTRAINT change_sequences_user_id_text_not_null CHECK (user_id_text IS NOT NULL) NOT VALID;
+
+ALTER TABLE node_changes
+  ADD COLUMN user_id_text VARCHAR(255);
+
+CREATE TRIGGER node_changes_copy_user_ids
+  BEFORE INSERT OR UPDATE ON node_changes
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER TABLE node_changes ADD CONSTRAINT node_changes_user_id_text_not_null CHECK (user_id_text IS NOT NULL) NOT VALID;
+
+ALTER TABLE webhooks
+  ADD COLUMN user_id_text VARCHAR(255);
+
+CREATE TRIGGER webhooks_copy_user_ids
+  BEFORE INSERT OR UPDATE ON webhooks
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER TABLE webhooks ADD CONSTRAINT webhooks_user_id_text_not_null CHECK (user_id_text IS NOT NULL) NOT VALID;
+
+ALTER TABLE access_tokens
+  ADD COLUMN user_id_text VARCHAR(255);
+
+CREATE TRIGGER access_tokens_copy_user_ids
+  BEFORE INSERT OR UPDATE ON access_tokens
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER TABLE access_tokens ADD CONSTRAINT access_tokens_user_id_text_not_null CHECK (user_id_text IS NOT NULL) NOT VALID;
+
+ALTER TABLE erasures
+  ADD COLUMN user_id_text VARCHAR(255);
+
+CREATE TRIGGER erasures_copy_user_ids
+  BEFORE INSERT OR UPDATE ON erasures
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER TABLE erasures ADD CONSTRAINT erasures_user_id_text_not_null CHECK (user_id_text IS NOT NULL) NOT VALID;
@@ -0,0 +1 @@
+-- The copies are dropped with their columns by the down migration of 000018.
@@ -0,0 +1,40 @@
+-- Fills the copies added by 000018 for rows written before it. Validating
+-- the NOT NULL checks scans the tables without blocking reads or writes, and
+-- lets 000032 set the columns NOT NULL without scanning them again.
+
+UPDATE url_nodes SET user_id_text = user_id::text WHERE user_id_text IS NULL;
+ALTER TABLE url_nodes VALIDATE CONSTRAINT url_nodes_user_id_text_not_null;
+
+UPDATE share_links SET user_id_text = user_id::text WHERE user_id_text IS NULL;
+ALTER TABLE share_links VALIDATE CONSTRAINT share_links_user_id_text_not_null;
+
+UPDATE folder_permissions SET user_id_text = user_id::text WHERE user_id_text IS NULL;
+ALTER TABLE folder_permissions VALIDATE CONSTRAINT folder_permissions_user_id_text_not_null;
+
+-- The backfill is the only update audit entries get.
+ALTER TABLE audit_logs DISABLE TRIGGER audit_logs_no_update;
+UPDATE audit_logs SET owner_id_text = owner_id::text, actor_id_text = actor_id::text WHERE owner_id_text IS NULL OR actor_id_text IS NULL;
+ALTER TABLE audit_logs ENABLE TRIGGER audit_logs_no_update;
+ALTER TABLE audit_logs VALIDATE CONSTRAINT audit_logs_owner_id_text_not_null;
+ALTER TABLE audit_logs VALIDATE CONSTRAINT audit_logs_actor_id_text_not_null;
+
+UPDATE node_revisions SET actor_id_text = actor_id::text WHERE actor_id_text IS NULL;
+ALTER TABLE node_revisions VALIDATE CONSTRAINT node_revisions_actor_id_text_not_null;
+
+UPDATE operations SET user_id_text = user_id::text WHERE user_id_text IS NULL;
+ALTER TABLE operations VALIDATE CONSTRAINT operations_user_id_text_not_null;
+
+UPDATE change_sequences SET user_id_text = user_id::text WHERE user_id_text IS NULL;
+ALTER TABLE change_sequences VALIDATE CONSTRAINT change_sequences_user_id_text_not_null;
+
+UPDATE node_changes SET user_id_text = user_id::text WHERE user_id_text IS NULL;
+ALTER TABLE node_changes VALIDATE CONSTRAINT node_changes_user_id_text_not_null;
+
+UPDATE webhooks SET user_id_text = user_id::text WHERE user_id_text IS NULL;
+ALTER TABLE webhooks VALIDATE CONSTRAINT webhooks_user_id_text_not_null;
+
+UPDATE access_tokens SET user_id_text = user_id::text WHERE user_id_text IS NULL;
+ALTER TABLE access_tokens VALIDATE CONSTRAINT access_tokens_user_id_text_not_null;
+
+UPDATE erasures SET user_id_text = user_id::text WHERE user_id_text IS NULL;
+ALTER TABLE erasures VALIDATE CONSTRAINT erasures_user_id_text_not_null;
@@ -0,0 +1 @@
+DROP INDEX CONCURRENTLY IF EXISTS idx_url_nodes_user_id_text;
@@ -0,0 +1 @@
+CREATE INDEX CONCURRENTLY idx_url_nodes_user_id_text ON url_nodes(user_id_text);
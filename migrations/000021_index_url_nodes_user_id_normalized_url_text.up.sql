@@ -0,0 +1,2 @@
+CREATE INDEX CONCURRENTLY idx_url_nodes_user_id_normalized_url_text ON url_nodes(user_id_text, normalized_url)
+  WHERE deleted_at IS NULL;
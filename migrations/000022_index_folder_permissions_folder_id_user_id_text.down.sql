@@ -0,0 +1 @@
+DROP INDEX CONCURRENTLY IF EXISTS idx_folder_permissions_folder_id_user_id_text;
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX CONCURRENTLY idx_folder_permissions_folder_id_user_id_text ON folder_permissions(folder_id, user_id_text);
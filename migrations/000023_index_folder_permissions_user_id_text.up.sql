@@ -0,0 +1 @@
+CREATE INDEX CONCURRENTLY idx_folder_permissions_user_id_text ON folder_permissions(user_id_text);
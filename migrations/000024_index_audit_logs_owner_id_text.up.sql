@@ -0,0 +1 @@
+CREATE INDEX CONCURRENTLY idx_audit_logs_owner_id_text ON audit_logs(owner_id_text);
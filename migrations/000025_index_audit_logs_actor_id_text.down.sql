@@ -0,0 +1 @@
+DROP INDEX CONCURRENTLY IF EXISTS idx_audit_logs_actor_id_text;
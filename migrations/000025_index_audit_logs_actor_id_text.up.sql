@@ -0,0 +1 @@
+CREATE INDEX CONCURRENTLY idx_audit_logs_actor_id_text ON audit_logs(actor_id_text);
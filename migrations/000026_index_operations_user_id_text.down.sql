@@ -0,0 +1 @@
+DROP INDEX CONCURRENTLY IF EXISTS idx_operations_user_id_text;
@@ -0,0 +1 @@
+CREATE INDEX CONCURRENTLY idx_operations_user_id_text ON operations(user_id_text);
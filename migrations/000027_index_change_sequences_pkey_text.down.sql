@@ -0,0 +1 @@
+DROP INDEX CONCURRENTLY IF EXISTS change_sequences_pkey_text;
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX CONCURRENTLY change_sequences_pkey_text ON change_sequences(user_id_text);
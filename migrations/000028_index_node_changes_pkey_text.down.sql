@@ -0,0 +1 @@
+DROP INDEX CONCURRENTLY IF EXISTS node_changes_pkey_text;
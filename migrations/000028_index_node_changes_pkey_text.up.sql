@@ -0,0 +1 @@
+CREATE UNIQUE INDEX CONCURRENTLY node_changes_pkey_text ON node_changes(user_id_text, seq);
@@ -0,0 +1 @@
+DROP INDEX CONCURRENTLY IF EXISTS idx_webhooks_user_id_text;
@@ -0,0 +1 @@
+CREATE INDEX CONCURRENTLY idx_webhooks_user_id_text ON webhooks(user_id_text);
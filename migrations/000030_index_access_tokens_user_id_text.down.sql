@@ -0,0 +1 @@
+DROP INDEX CONCURRENTLY IF EXISTS idx_access_tokens_user_id_text;
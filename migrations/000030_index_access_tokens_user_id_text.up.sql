@@ -0,0 +1 @@
+CREATE INDEX CONCURRENTLY idx_access_tokens_user_id_text ON access_tokens(user_id_text);
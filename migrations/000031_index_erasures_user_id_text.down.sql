@@ -0,0 +1 @@
+DROP INDEX CONCURRENTLY IF EXISTS idx_erasures_user_id_text;
@@ -0,0 +1 @@
+CREATE INDEX CONCURRENTLY idx_erasures_user_id_text ON erasures(user_id_text);
@@ -0,0 +1,192 @@
+-- Restores the integer user ID columns next to their VARCHAR copies. Fails
+-- when a user ID is not a number.
+
+CREATE FUNCTION copy_user_ids() RETURNS trigger AS $$
+DECLARE
+  copied JSONB := to_jsonb(NEW);
+  name TEXT;
+BEGIN
+  FOREACH name IN ARRAY TG_ARGV LOOP
+    copied := jsonb_set(copied, ARRAY[name || '_text'], to_jsonb(copied ->> name));
+  END LOOP;
+  NEW := jsonb_populate_record(NEW, copied);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER INDEX idx_url_nodes_user_id RENAME TO idx_url_nodes_user_id_text;
+ALTER INDEX idx_url_nodes_user_id_normalized_url RENAME TO idx_url_nodes_user_id_normalized_url_text;
+ALTER TABLE url_nodes RENAME COLUMN user_id TO user_id_text;
+ALTER TABLE url_nodes
+  ALTER COLUMN user_id_text DROP NOT NULL;
+ALTER TABLE url_nodes
+  ADD COLUMN user_id INTEGER;
+UPDATE url_nodes SET user_id = user_id_text::integer;
+ALTER TABLE url_nodes
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE url_nodes ADD CONSTRAINT url_nodes_user_id_text_not_null CHECK (user_id_text IS NOT NULL);
+CREATE INDEX idx_url_nodes_user_id ON url_nodes(user_id);
+CREATE INDEX idx_url_nodes_user_id_normalized_url ON url_nodes(user_id, normalized_url)
+  WHERE deleted_at IS NULL;
+CREATE TRIGGER url_nodes_copy_user_ids
+  BEFORE INSERT OR UPDATE ON url_nodes
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER TABLE share_links RENAME COLUMN user_id TO user_id_text;
+ALTER TABLE share_links
+  ALTER COLUMN user_id_text DROP NOT NULL;
+ALTER TABLE share_links
+  ADD COLUMN user_id INTEGER;
+UPDATE share_links SET user_id = user_id_text::integer;
+ALTER TABLE share_links
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE share_links ADD CONSTRAINT share_links_user_id_text_not_null CHECK (user_id_text IS NOT NULL);
+CREATE TRIGGER share_links_copy_user_ids
+  BEFORE INSERT OR UPDATE ON share_links
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER INDEX idx_folder_permissions_folder_id_user_id RENAME TO idx_folder_permissions_folder_id_user_id_text;
+ALTER INDEX idx_folder_permissions_user_id RENAME TO idx_folder_permissions_user_id_text;
+ALTER TABLE folder_permissions RENAME COLUMN user_id TO user_id_text;
+ALTER TABLE folder_permissions
+  ALTER COLUMN user_id_text DROP NOT NULL;
+ALTER TABLE folder_permissions
+  ADD COLUMN user_id INTEGER;
+UPDATE folder_permissions SET user_id = user_id_text::integer;
+ALTER TABLE folder_permissions
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE folder_permissions ADD CONSTRAINT folder_permissions_user_id_text_not_null CHECK (user_id_text IS NOT NULL);
+CREATE UNIQUE INDEX idx_folder_permissions_folder_id_user_id ON folder_permissions(folder_id, user_id);
+CREATE INDEX idx_folder_permissions_user_id ON folder_permissions(user_id);
+CREATE TRIGGER folder_permissions_copy_user_ids
+  BEFORE INSERT OR UPDATE ON folder_permissions
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER INDEX idx_audit_logs_owner_id RENAME TO idx_audit_logs_owner_id_text;
+ALTER INDEX idx_audit_logs_actor_id RENAME TO idx_audit_logs_actor_id_text;
+ALTER TABLE audit_logs RENAME COLUMN owner_id TO owner_id_text;
+ALTER TABLE audit_logs RENAME COLUMN actor_id TO actor_id_text;
+ALTER TABLE audit_logs
+  ALTER COLUMN owner_id_text DROP NOT NULL,
+  ALTER COLUMN actor_id_text DROP NOT NULL;
+ALTER TABLE audit_logs
+  ADD COLUMN owner_id INTEGER,
+  ADD COLUMN actor_id INTEGER;
+ALTER TABLE audit_logs DISABLE TRIGGER audit_logs_no_update;
+UPDATE audit_logs SET owner_id = owner_id_text::integer, actor_id = actor_id_text::integer;
+ALTER TABLE audit_logs ENABLE TRIGGER audit_logs_no_update;
+ALTER TABLE audit_logs
+  ALTER COLUMN owner_id SET NOT NULL,
+  ALTER COLUMN actor_id SET NOT NULL;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_owner_id_text_not_null CHECK (owner_id_text IS NOT NULL);
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_actor_id_text_not_null CHECK (actor_id_text IS NOT NULL);
+CREATE INDEX idx_audit_logs_owner_id ON audit_logs(owner_id);
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs(actor_id);
+CREATE TRIGGER audit_logs_copy_user_ids
+  BEFORE INSERT ON audit_logs
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('owner_id', 'actor_id');
+
+ALTER TABLE node_revisions RENAME COLUMN actor_id TO actor_id_text;
+ALTER TABLE node_revisions
+  ALTER COLUMN actor_id_text DROP NOT NULL;
+ALTER TABLE node_revisions
+  ADD COLUMN actor_id INTEGER;
+UPDATE node_revisions SET actor_id = actor_id_text::integer;
+ALTER TABLE node_revisions
+  ALTER COLUMN actor_id SET NOT NULL;
+ALTER TABLE node_revisions ADD CONSTRAINT node_revisions_actor_id_text_not_null CHECK (actor_id_text IS NOT NULL);
+CREATE TRIGGER node_revisions_copy_user_ids
+  BEFORE INSERT OR UPDATE ON node_revisions
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('actor_id');
+
+ALTER INDEX idx_operations_user_id RENAME TO idx_operations_user_id_text;
+ALTER TABLE operations RENAME COLUMN user_id TO user_id_text;
+ALTER TABLE operations
+  ALTER COLUMN user_id_text DROP NOT NULL;
+ALTER TABLE operations
+  ADD COLUMN user_id INTEGER;
+UPDATE operations SET user_id = user_id_text::integer;
+ALTER TABLE operations
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE operations ADD CONSTRAINT operations_user_id_text_not_null CHECK (user_id_text IS NOT NULL);
+CREATE INDEX idx_operations_user_id ON operations(user_id);
+CREATE TRIGGER operations_copy_user_ids
+  BEFORE INSERT OR UPDATE ON operations
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER TABLE change_sequences DROP CONSTRAINT change_sequences_pkey;
+ALTER TABLE change_sequences RENAME COLUMN user_id TO user_id_text;
+ALTER TABLE change_sequences
+  ALTER COLUMN user_id_text DROP NOT NULL;
+ALTER TABLE change_sequences
+  ADD COLUMN user_id INTEGER;
+UPDATE change_sequences SET user_id = user_id_text::integer;
+ALTER TABLE change_sequences
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE change_sequences ADD CONSTRAINT change_sequences_user_id_text_not_null CHECK (user_id_text IS NOT NULL);
+ALTER TABLE change_sequences ADD CONSTRAINT change_sequences_pkey PRIMARY KEY (user_id);
+CREATE UNIQUE INDEX change_sequences_pkey_text ON change_sequences(user_id_text);
+CREATE TRIGGER change_sequences_copy_user_ids
+  BEFORE INSERT OR UPDATE ON change_sequences
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER TABLE node_changes DROP CONSTRAINT node_changes_pkey;
+ALTER TABLE node_changes RENAME COLUMN user_id TO user_id_text;
+ALTER TABLE node_changes
+  ALTER COLUMN user_id_text DROP NOT NULL;
+ALTER TABLE node_changes
+  ADD COLUMN user_id INTEGER;
+UPDATE node_changes SET user_id = user_id_text::integer;
+ALTER TABLE node_changes
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE node_changes ADD CONSTRAINT node_changes_user_id_text_not_null CHECK (user_id_text IS NOT NULL);
+ALTER TABLE node_changes ADD CONSTRAINT node_changes_pkey PRIMARY KEY (user_id, seq);
+CREATE UNIQUE INDEX node_changes_pkey_text ON node_changes(user_id_text, seq);
+CREATE TRIGGER node_changes_copy_user_ids
+  BEFORE INSERT OR UPDATE ON node_changes
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER INDEX idx_webhooks_user_id RENAME TO idx_webhooks_user_id_text;
+ALTER TABLE webhooks RENAME COLUMN user_id TO user_id_text;
+ALTER TABLE webhooks
+  ALTER COLUMN user_id_text DROP NOT NULL;
+ALTER TABLE webhooks
+  ADD COLUMN user_id INTEGER;
+UPDATE webhooks SET user_id = user_id_text::integer;
+ALTER TABLE webhooks
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE webhooks ADD CONSTRAINT webhooks_user_id_text_not_null CHECK (user_id_text IS NOT NULL);
+CREATE INDEX idx_webhooks_user_id ON webhooks(user_id);
+CREATE TRIGGER webhooks_copy_user_ids
+  BEFORE INSERT OR UPDATE ON webhooks
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER INDEX idx_access_tokens_user_id RENAME TO idx_access_tokens_user_id_text;
+ALTER TABLE access_tokens RENAME COLUMN user_id TO user_id_text;
+ALTER TABLE access_tokens
+  ALTER COLUMN user_id_text DROP NOT NULL;
+ALTER TABLE access_tokens
+  ADD COLUMN user_id INTEGER;
+UPDATE access_tokens SET user_id = user_id_text::integer;
+ALTER TABLE access_tokens
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE access_tokens ADD CONSTRAINT access_tokens_user_id_text_not_null CHECK (user_id_text IS NOT NULL);
+CREATE INDEX idx_access_tokens_user_id ON access_tokens(user_id);
+CREATE TRIGGER access_tokens_copy_user_ids
+  BEFORE INSERT OR UPDATE ON access_tokens
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
+
+ALTER INDEX idx_erasures_user_id RENAME TO idx_erasures_user_id_text;
+ALTER TABLE erasures RENAME COLUMN user_id TO user_id_text;
+ALTER TABLE erasures
+  ALTER COLUMN user_id_text DROP NOT NULL;
+ALTER TABLE erasures
+  ADD COLUMN user_id INTEGER;
+UPDATE erasures SET user_id = user_id_text::integer;
+ALTER TABLE erasures
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE erasures ADD CONSTRAINT erasures_user_id_text_not_null CHECK (user_id_text IS NOT NULL);
+CREATE INDEX idx_erasures_user_id ON erasures(user_id);
+CREATE TRIGGER erasures_copy_user_ids
+  BEFORE INSERT OR UPDATE ON erasures
+  FOR EACH ROW EXECUTE FUNCTION copy_user_ids('user_id');
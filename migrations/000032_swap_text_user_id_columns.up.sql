@@ -0,0 +1,120 @@
+-- Swaps the VARCHAR user ID columns added by 000018 in for the integer ones.
+-- The copies are backfilled, indexed and checked for NULLs already, so only
+-- the catalog changes and each table is locked for a moment.
+
+DROP TRIGGER url_nodes_copy_user_ids ON url_nodes;
+ALTER TABLE url_nodes
+  DROP COLUMN user_id;
+ALTER TABLE url_nodes RENAME COLUMN user_id_text TO user_id;
+ALTER TABLE url_nodes
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE url_nodes
+  DROP CONSTRAINT url_nodes_user_id_text_not_null;
+ALTER INDEX idx_url_nodes_user_id_text RENAME TO idx_url_nodes_user_id;
+ALTER INDEX idx_url_nodes_user_id_normalized_url_text RENAME TO idx_url_nodes_user_id_normalized_url;
+
+DROP TRIGGER share_links_copy_user_ids ON share_links;
+ALTER TABLE share_links
+  DROP COLUMN user_id;
+ALTER TABLE share_links RENAME COLUMN user_id_text TO user_id;
+ALTER TABLE share_links
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE share_links
+  DROP CONSTRAINT share_links_user_id_text_not_null;
+
+DROP TRIGGER folder_permissions_copy_user_ids ON folder_permissions;
+ALTER TABLE folder_permissions
+  DROP COLUMN user_id;
+ALTER TABLE folder_permissions RENAME COLUMN user_id_text TO user_id;
+ALTER TABLE folder_permissions
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE folder_permissions
+  DROP CONSTRAINT folder_permissions_user_id_text_not_null;
+ALTER INDEX idx_folder_permissions_folder_id_user_id_text RENAME TO idx_folder_permissions_folder_id_user_id;
+ALTER INDEX idx_folder_permissions_user_id_text RENAME TO idx_folder_permissions_user_id;
+
+DROP TRIGGER audit_logs_copy_user_ids ON audit_logs;
+ALTER TABLE audit_logs
+  DROP COLUMN owner_id,
+  DROP COLUMN actor_id;
+ALTER TABLE audit_logs RENAME COLUMN owner_id_text TO owner_id;
+ALTER TABLE audit_logs RENAME COLUMN actor_id_text TO actor_id;
+ALTER TABLE audit_logs
+  ALTER COLUMN owner_id SET NOT NULL,
+  ALTER COLUMN actor_id SET NOT NULL;
+ALTER TABLE audit_logs
+  DROP CONSTRAINT audit_logs_owner_id_text_not_null,
+  DROP CONSTRAINT audit_logs_actor_id_text_not_null;
+ALTER INDEX idx_audit_logs_owner_id_text RENAME TO idx_audit_logs_owner_id;
+ALTER INDEX idx_audit_logs_actor_id_text RENAME TO idx_audit_logs_actor_id;
+
+DROP TRIGGER node_revisions_copy_user_ids ON node_revisions;
+ALTER TABLE node_revisions
+  DROP COLUMN actor_id;
+ALTER TABLE node_revisions RENAME COLUMN actor_id_text TO actor_id;
+ALTER TABLE node_revisions
+  ALTER COLUMN actor_id SET NOT NULL;
+ALTER TABLE node_revisions
+  DROP CONSTRAINT node_revisions_actor_id_text_not_null;
+
+DROP TRIGGER operations_copy_user_ids ON operations;
+ALTER TABLE operations
+  DROP COLUMN user_id;
+ALTER TABLE operations RENAME COLUMN user_id_text TO user_id;
+ALTER TABLE operations
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE operations
+  DROP CONSTRAINT operations_user_id_text_not_null;
+ALTER INDEX idx_operations_user_id_text RENAME TO idx_operations_user_id;
+
+DROP TRIGGER change_sequences_copy_user_ids ON change_sequences;
+ALTER TABLE change_sequences
+  DROP COLUMN user_id;
+ALTER TABLE change_sequences RENAME COLUMN user_id_text TO user_id;
+ALTER TABLE change_sequences
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE change_sequences
+  DROP CONSTRAINT change_sequences_user_id_text_not_null;
+ALTER TABLE change_sequences ADD CONSTRAINT change_sequences_pkey PRIMARY KEY USING INDEX change_sequences_pkey_text;
+
+DROP TRIGGER node_changes_copy_user_ids ON node_changes;
+ALTER TABLE node_changes
+  DROP COLUMN user_id;
+ALTER TABLE node_changes RENAME COLUMN user_id_text TO user_id;
+ALTER TABLE node_changes
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE node_changes
+  DROP CONSTRAINT node_changes_user_id_text_not_null;
+ALTER TABLE node_changes ADD CONSTRAINT node_changes_pkey PRIMARY KEY USING INDEX node_changes_pkey_text;
+
+DROP TRIGGER webhooks_copy_user_ids ON webhooks;
+ALTER TABLE webhooks
+  DROP COLUMN user_id;
+ALTER TABLE webhooks RENAME COLUMN user_id_text TO user_id;
+ALTER TABLE webhooks
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE webhooks
+  DROP CONSTRAINT webhooks_user_id_text_not_null;
+ALTER INDEX idx_webhooks_user_id_text RENAME TO idx_webhooks_user_id;
+
+DROP TRIGGER access_tokens_copy_user_ids ON access_tokens;
+ALTER TABLE access_tokens
+  DROP COLUMN user_id;
+ALTER TABLE access_tokens RENAME COLUMN user_id_text TO user_id;
+ALTER TABLE access_tokens
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE access_tokens
+  DROP CONSTRAINT access_tokens_user_id_text_not_null;
+ALTER INDEX idx_access_tokens_user_id_text RENAME TO idx_access_tokens_user_id;
+
+DROP TRIGGER erasures_copy_user_ids ON erasures;
+ALTER TABLE erasures
+  DROP COLUMN user_id;
+ALTER TABLE erasures RENAME COLUMN user_id_text TO user_id;
+ALTER TABLE erasures
+  ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE erasures
+  DROP CONSTRAINT erasures_user_id_text_not_null;
+ALTER INDEX idx_erasures_user_id_text RENAME TO idx_erasures_user_id;
+
+DROP FUNCTION copy_user_ids();
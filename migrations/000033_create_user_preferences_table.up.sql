@@ -0,0 +1,8 @@
+CREATE TABLE user_preferences (
+  user_id VARCHAR(255) PRIMARY KEY,
+  sort_order VARCHAR(20) NOT NULL CHECK (sort_order IN ('name_asc', 'name_desc', 'created_asc', 'created_desc', 'updated_asc', 'updated_desc')),
+  view_mode VARCHAR(10) NOT NULL CHECK (view_mode IN ('list', 'grid')),
+  start_folder_id UUID,
+  theme VARCHAR(10) NOT NULL CHECK (theme IN ('system', 'light', 'dark')),
+  updated_at TIMESTAMPTZ NOT NULL
+);
@@ -1,20 +1,30 @@
 package test
 
 import (
+	"archive/zip"
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
-	"strconv"
 	"testing"
 	"time"
 
+	"github.com/vera/vera-drive-service/internal/accesstoken"
+	"github.com/vera/vera-drive-service/internal/account"
 	"github.com/vera/vera-drive-service/internal/app"
+	"github.com/vera/vera-drive-service/internal/audit"
 	"github.com/vera/vera-drive-service/internal/middleware"
+	"github.com/vera/vera-drive-service/internal/outbox"
+	"github.com/vera/vera-drive-service/internal/share"
 	"github.com/vera/vera-drive-service/internal/url"
+	"github.com/vera/vera-drive-service/internal/user"
+	"github.com/vera/vera-drive-service/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -37,10 +47,15 @@ func TestMain(m *testing.M) {
 	identityService := SetupIdentityService("mock-token-secret")
 
 	envs := map[string]string{
-		"PORT":                 "8082",
-		"DATABASE_URL":         dbURL,
-		"IDENTITY_SERVICE_URL": identityService.URL,
-		"ALLOWED_ORIGIN":       "http://mock-origin-1, http://mock-origin-2",
+		"PORT":                      "8082",
+		"DATABASE_URL":              dbURL,
+		"IDENTITY_SERVICE_URL":      identityService.URL,
+		"ALLOWED_ORIGIN":            "http://mock-origin-1, http://mock-origin-2",
+		"METADATA_FETCH_ENABLED":    "false",
+		"ADMIN_USER_IDS":            "99",
+		"SERVICE_SHARED_SECRET":     "mock-service-secret",
+		"RATE_LIMIT_IP_LIMIT":       "100000",
+		"OUTBOUND_ALLOWED_NETWORKS": "127.0.0.1/32",
 	}
 	for key, value := range envs {
 		err = os.Setenv(key, value)
@@ -54,7 +69,7 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 
-	err = a.DB.AutoMigrate(&url.URLNode{})
+	err = a.DB.AutoMigrate(&url.URLNode{}, &url.Favicon{}, &url.FolderPermission{}, &share.ShareLink{}, &audit.AuditLog{}, &url.NodeRevision{}, &url.Operation{}, &url.NodeChange{}, &url.ChangeSequence{}, &webhook.Webhook{}, &webhook.Delivery{}, &outbox.OutboxEvent{}, &accesstoken.AccessToken{}, &account.Erasure{}, &user.Preferences{}, &middleware.RateLimitBucket{})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -99,7 +114,7 @@ func TestAPI_GetRootID_Success(t *testing.T) {
 	err := CleanupTables(a.DB)
 	require.NoError(t, err)
 
-	userID := 1
+	userID := "1"
 	rootID := "123e4567-e89b-12d3-a456-426614174001"
 	root := url.URLNode{
 		ID:     rootID,
@@ -115,7 +130,7 @@ func TestAPI_GetRootID_Success(t *testing.T) {
 		jwt.SigningMethodHS256,
 		middleware.UserClaims{
 			RegisteredClaims: jwt.RegisteredClaims{
-				Subject: strconv.Itoa(userID),
+				Subject: userID,
 			},
 		},
 	).SignedString([]byte("mock-token-secret"))
@@ -137,12 +152,12 @@ func TestAPI_GetRootID_NoRoot(t *testing.T) {
 	err := CleanupTables(a.DB)
 	require.NoError(t, err)
 
-	userID := 1
+	userID := "1"
 	token, err := jwt.NewWithClaims(
 		jwt.SigningMethodHS256,
 		middleware.UserClaims{
 			RegisteredClaims: jwt.RegisteredClaims{
-				Subject: strconv.Itoa(userID),
+				Subject: userID,
 			},
 		},
 	).SignedString([]byte("mock-token-secret"))
@@ -165,7 +180,7 @@ func TestAPI_GetURL_Success(t *testing.T) {
 	err := CleanupTables(a.DB)
 	require.NoError(t, err)
 
-	userID := 1
+	userID := "1"
 	nodeID := uuid.New().String()
 	parent := url.URLNode{
 		ID:        uuid.New().String(),
@@ -207,7 +222,7 @@ func TestAPI_GetURL_Success(t *testing.T) {
 		jwt.SigningMethodHS256,
 		middleware.UserClaims{
 			RegisteredClaims: jwt.RegisteredClaims{
-				Subject: strconv.Itoa(userID),
+				Subject: userID,
 			},
 		},
 	).SignedString([]byte("mock-token-secret"))
@@ -255,7 +270,7 @@ func TestAPI_CreateURL_Success(t *testing.T) {
 	err := CleanupTables(a.DB)
 	require.NoError(t, err)
 
-	userID := 1
+	userID := "1"
 	parentID := uuid.New().String()
 	parent := url.URLNode{
 		ID:     parentID,
@@ -275,7 +290,7 @@ func TestAPI_CreateURL_Success(t *testing.T) {
 	}
 	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			Subject: strconv.Itoa(userID),
+			Subject: userID,
 		},
 	}).SignedString([]byte("mock-token-secret"))
 	require.NoError(t, err)
@@ -288,7 +303,7 @@ func TestAPI_CreateURL_Success(t *testing.T) {
 	a.Router.ServeHTTP(w, req)
 
 	// Assert
-	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, http.StatusCreated, w.Code)
 
 	req, err = createTestRequest("GET", "/urls/"+parentID, nil, token)
 	require.NoError(t, err)
@@ -316,7 +331,7 @@ func TestAPI_CreateURL_WithURL(t *testing.T) {
 	err := CleanupTables(a.DB)
 	require.NoError(t, err)
 
-	userID := 1
+	userID := "1"
 	parentID := uuid.New().String()
 	parent := url.URLNode{
 		ID:     parentID,
@@ -336,7 +351,7 @@ func TestAPI_CreateURL_WithURL(t *testing.T) {
 	}
 	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			Subject: strconv.Itoa(userID),
+			Subject: userID,
 		},
 	}).SignedString([]byte("mock-token-secret"))
 	require.NoError(t, err)
@@ -349,7 +364,7 @@ func TestAPI_CreateURL_WithURL(t *testing.T) {
 	a.Router.ServeHTTP(w, req)
 
 	// Assert
-	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, http.StatusCreated, w.Code)
 
 	req, err = createTestRequest("GET", "/urls/"+parentID, nil, token)
 	require.NoError(t, err)
@@ -373,12 +388,55 @@ func TestAPI_CreateURL_WithURL(t *testing.T) {
 	assert.WithinDuration(t, time.Now(), updatedAt, time.Second)
 }
 
+func TestAPI_CreateURL_AutoName(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	parentID := uuid.New().String()
+	nodes := []url.URLNode{
+		{ID: parentID, UserID: userID, Name: "name", Type: "folder"},
+		{ID: uuid.New().String(), UserID: userID, ParentID: &parentID, Name: "example.com", Type: "url", URL: StringPtr("https://example.com/other")},
+	}
+	err = a.DB.Create(&nodes).Error
+	require.NoError(t, err)
+
+	requestBody := url.RequestBody{
+		ParentID: parentID,
+		Type:     "url",
+		URL:      StringPtr("https://www.example.com/page"),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	// Act
+	req, err := createTestRequest("POST", "/urls", requestBody, token)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+
+	// Assert
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var resp url.CreateURLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com (2)", resp.Name)
+	assert.Nil(t, resp.Title)
+}
+
 func TestAPI_ReplaceURL_Success(t *testing.T) {
 	// Arrange
 	err := CleanupTables(a.DB)
 	require.NoError(t, err)
 
-	userID := 1
+	userID := "1"
 	nodeID := uuid.New().String()
 	parentID := uuid.New().String()
 	parent := url.URLNode{
@@ -408,7 +466,7 @@ func TestAPI_ReplaceURL_Success(t *testing.T) {
 	}
 	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			Subject: strconv.Itoa(userID),
+			Subject: userID,
 		},
 	}).SignedString([]byte("mock-token-secret"))
 	require.NoError(t, err)
@@ -452,7 +510,7 @@ func TestAPI_DeleteURL_Success(t *testing.T) {
 	err := CleanupTables(a.DB)
 	require.NoError(t, err)
 
-	userID := 1
+	userID := "1"
 	nodeID := uuid.New().String()
 	node := url.URLNode{
 		ID:     nodeID,
@@ -466,7 +524,7 @@ func TestAPI_DeleteURL_Success(t *testing.T) {
 
 	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			Subject: strconv.Itoa(userID),
+			Subject: userID,
 		},
 	}).SignedString([]byte("mock-token-secret"))
 	require.NoError(t, err)
@@ -488,6 +546,1707 @@ func TestAPI_DeleteURL_Success(t *testing.T) {
 	require.Equal(t, http.StatusNotFound, w.Code)
 }
 
+func TestAPI_CreateURL_DuplicateWarning(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	parentID := uuid.New().String()
+	parent := url.URLNode{
+		ID:     parentID,
+		UserID: userID,
+		Name:   "name",
+		Type:   "folder",
+	}
+	existing := url.URLNode{
+		ID:       uuid.New().String(),
+		UserID:   userID,
+		ParentID: &parentID,
+		Name:     "existing",
+		Type:     "url",
+		URL:      StringPtr("https://example.com/"),
+	}
+	err = a.DB.Create(&parent).Error
+	require.NoError(t, err)
+	err = a.DB.Create(&existing).Error
+	require.NoError(t, err)
+	// The node was saved before normalized URLs were stored.
+	err = url.NewNormalizedURLBackfill(url.NewRepository(a.DB), a.Logger).BackfillOnce(context.Background())
+	require.NoError(t, err)
+
+	requestBody := url.RequestBody{
+		ParentID: parentID,
+		Name:     "new url",
+		Type:     "url",
+		URL:      StringPtr("HTTPS://EXAMPLE.com?utm_source=newsletter"),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	// Act
+	req, err := createTestRequest("POST", "/urls", requestBody, token)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+
+	// Assert
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var resp url.CreateURLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	assert.Equal(t, "new url", resp.Name)
+	require.NotNil(t, resp.Warning)
+	require.Len(t, resp.Warning.Duplicates, 1)
+	assert.Equal(t, existing.ID, resp.Warning.Duplicates[0].ID)
+}
+
+func TestAPI_Duplicates_ListAndMerge(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	rootID := uuid.New().String()
+	root := url.URLNode{
+		ID:     rootID,
+		UserID: userID,
+		Name:   "",
+		Type:   "folder",
+	}
+	first := url.URLNode{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		ParentID:      &rootID,
+		Name:          "first",
+		Type:          "url",
+		URL:           StringPtr("https://example.com/a"),
+		NormalizedURL: StringPtr("https://example.com/a"),
+	}
+	second := url.URLNode{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		ParentID:      &rootID,
+		Name:          "second",
+		Type:          "url",
+		URL:           StringPtr("https://example.com/a/"),
+		NormalizedURL: StringPtr("https://example.com/a"),
+	}
+	for _, node := range []*url.URLNode{&root, &first, &second} {
+		err = a.DB.Create(node).Error
+		require.NoError(t, err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	// Act
+	req, err := createTestRequest("GET", "/urls/duplicates", nil, token)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var groups []url.DuplicateGroup
+	err = json.Unmarshal(w.Body.Bytes(), &groups)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "https://example.com/a", groups[0].NormalizedURL)
+	require.Len(t, groups[0].URLs, 2)
+	require.Len(t, groups[0].URLs[0].Parent, 1)
+	assert.Equal(t, rootID, groups[0].URLs[0].Parent[0].ID)
+
+	// Act
+	mergeBody := url.MergeDuplicatesRequestBody{
+		TargetID:  first.ID,
+		SourceIDs: []string{second.ID},
+	}
+	req, err = createTestRequest("POST", "/urls/duplicates/merge", mergeBody, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+
+	// Assert
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	req, err = createTestRequest("GET", "/urls/"+rootID, nil, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp url.URLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	require.Len(t, resp.Children, 1)
+	assert.Equal(t, first.ID, resp.Children[0].ID)
+}
+
+func TestAPI_LookupURL_Success(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	rootID := uuid.New().String()
+	root := url.URLNode{
+		ID:     rootID,
+		UserID: userID,
+		Name:   "",
+		Type:   "folder",
+	}
+	saved := url.URLNode{
+		ID:       uuid.New().String(),
+		UserID:   userID,
+		ParentID: &rootID,
+		Name:     "saved",
+		Type:     "url",
+		URL:      StringPtr("https://example.com/page/"),
+	}
+	for _, node := range []*url.URLNode{&root, &saved} {
+		err = a.DB.Create(node).Error
+		require.NoError(t, err)
+	}
+	err = url.NewNormalizedURLBackfill(url.NewRepository(a.DB), a.Logger).BackfillOnce(context.Background())
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	// Act
+	req, err := createTestRequest("GET", "/urls/lookup?url=https%3A%2F%2FEXAMPLE.com%2Fpage%3Futm_source%3Dx", nil, token)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var result url.LookupResult
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	require.NoError(t, err)
+	require.Len(t, result.Matches, 1)
+	assert.Equal(t, saved.ID, result.Matches[0].ID)
+	require.Len(t, result.Matches[0].Parent, 1)
+	assert.Equal(t, rootID, result.Matches[0].Parent[0].ID)
+
+	// Act
+	lookupBody := url.LookupRequestBody{URLs: []string{"https://example.com/page", "https://example.org"}}
+	req, err = createTestRequest("POST", "/urls/lookup", lookupBody, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var results []url.LookupResult
+	err = json.Unmarshal(w.Body.Bytes(), &results)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Len(t, results[0].Matches, 1)
+	assert.Empty(t, results[1].Matches)
+}
+
+func TestAPI_LookupURL_DeletedFolder(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	rootID := uuid.New().String()
+	folderID := uuid.New().String()
+	nodes := []url.URLNode{
+		{ID: rootID, UserID: userID, Name: "", Type: "folder"},
+		{ID: folderID, UserID: userID, ParentID: &rootID, Name: "folder", Type: "folder"},
+		{ID: uuid.New().String(), UserID: userID, ParentID: &folderID, Name: "saved", Type: "url", URL: StringPtr("https://example.com/page"), NormalizedURL: StringPtr("https://example.com/page")},
+	}
+	for i := range nodes {
+		err = a.DB.Create(&nodes[i]).Error
+		require.NoError(t, err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	req, err := createTestRequest("DELETE", "/urls/"+folderID, nil, token)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	// Act
+	req, err = createTestRequest("POST", "/urls/lookup", url.LookupRequestBody{URLs: []string{"https://example.com/page"}}, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	var results []url.LookupResult
+	err = json.Unmarshal(w.Body.Bytes(), &results)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].Matches)
+}
+
+func TestAPI_GetBrokenURLs_Success(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	rootID := uuid.New().String()
+	checkedAt := time.Now().UTC()
+	notFound := http.StatusNotFound
+	ok := http.StatusOK
+	nodes := []url.URLNode{
+		{ID: rootID, UserID: userID, Name: "", Type: "folder"},
+		{ID: uuid.New().String(), UserID: userID, ParentID: &rootID, Name: "gone", Type: "url", URL: StringPtr("https://example.com/gone"), LastStatusCode: &notFound, LastCheckedAt: &checkedAt},
+		{ID: uuid.New().String(), UserID: userID, ParentID: &rootID, Name: "fine", Type: "url", URL: StringPtr("https://example.com"), LastStatusCode: &ok, LastCheckedAt: &checkedAt},
+	}
+	for i := range nodes {
+		err = a.DB.Create(&nodes[i]).Error
+		require.NoError(t, err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	// Act
+	req, err := createTestRequest("GET", "/urls/broken", nil, token)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var brokenURLs []url.BrokenURL
+	err = json.Unmarshal(w.Body.Bytes(), &brokenURLs)
+	require.NoError(t, err)
+	require.Len(t, brokenURLs, 1)
+	assert.Equal(t, nodes[1].ID, brokenURLs[0].ID)
+	assert.Equal(t, http.StatusNotFound, *brokenURLs[0].LastStatusCode)
+}
+
+func TestAPI_GetFavicon_Success(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/favicon.ico" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/x-icon")
+		w.Write([]byte("mock-icon"))
+	}))
+	defer site.Close()
+
+	userID := "1"
+	nodeID := uuid.New().String()
+	node := url.URLNode{ID: nodeID, UserID: userID, Name: "site", Type: "url", URL: StringPtr(site.URL + "/page")}
+	err = a.DB.Create(&node).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	// Act
+	req, err := createTestRequest("GET", "/urls/"+nodeID+"/favicon", nil, token)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/x-icon", w.Header().Get("Content-Type"))
+	assert.Equal(t, "mock-icon", w.Body.String())
+
+	var cached url.Favicon
+	err = a.DB.First(&cached).Error
+	require.NoError(t, err)
+	assert.Equal(t, []byte("mock-icon"), cached.Data)
+}
+
+func TestAPI_ShareLink_CreateAccessRevoke(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	deletedAt := time.Now()
+	folderID := uuid.New().String()
+	nodes := []url.URLNode{
+		{ID: folderID, UserID: userID, Name: "shared", Type: "folder"},
+		{ID: uuid.New().String(), UserID: userID, ParentID: &folderID, Name: "visible", Type: "url", URL: StringPtr("https://example.com")},
+		{ID: uuid.New().String(), UserID: userID, ParentID: &folderID, Name: "deleted", Type: "url", URL: StringPtr("https://example.org"), DeletedAt: &deletedAt},
+	}
+	err = a.DB.Create(&nodes).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	// Act
+	req, err := createTestRequest("POST", "/urls/"+folderID+"/shares", share.CreateRequestBody{Password: "secret-password"}, token)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var link share.ShareLinkResponse
+	err = json.Unmarshal(w.Body.Bytes(), &link)
+	require.NoError(t, err)
+
+	req, err = createTestRequest("GET", "/shared/"+link.Token, nil, "")
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	noPasswordCode := w.Code
+
+	req, err = createTestRequest("GET", "/shared/"+link.Token, nil, "")
+	require.NoError(t, err)
+	req.Header.Set("X-Share-Password", "secret-password")
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var tree share.SharedNode
+	err = json.Unmarshal(w.Body.Bytes(), &tree)
+	require.NoError(t, err)
+
+	req, err = createTestRequest("DELETE", "/urls/"+folderID+"/shares/"+link.ID, nil, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	req, err = createTestRequest("GET", "/shared/"+link.Token, nil, "")
+	require.NoError(t, err)
+	req.Header.Set("X-Share-Password", "secret-password")
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	revokedCode := w.Code
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, noPasswordCode)
+	assert.Equal(t, folderID, tree.ID)
+	require.Len(t, tree.Children, 1)
+	assert.Equal(t, "visible", tree.Children[0].Name)
+	assert.Equal(t, http.StatusNotFound, revokedCode)
+}
+func TestAPI_ShareLink_TrashedParent(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	rootID := uuid.New().String()
+	parentID := uuid.New().String()
+	folderID := uuid.New().String()
+	nodes := []url.URLNode{
+		{ID: rootID, UserID: userID, Name: "root", Type: "folder"},
+		{ID: parentID, UserID: userID, ParentID: &rootID, Name: "parent", Type: "folder"},
+		{ID: folderID, UserID: userID, ParentID: &parentID, Name: "shared", Type: "folder"},
+		{ID: uuid.New().String(), UserID: userID, ParentID: &folderID, Name: "visible", Type: "url", URL: StringPtr("https://example.com")},
+	}
+	err = a.DB.Create(&nodes).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	req, err := createTestRequest("POST", "/urls/"+folderID+"/shares", share.CreateRequestBody{}, token)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var link share.ShareLinkResponse
+	err = json.Unmarshal(w.Body.Bytes(), &link)
+	require.NoError(t, err)
+
+	req, err = createTestRequest("DELETE", "/urls/"+parentID, nil, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	// Act
+	req, err = createTestRequest("GET", "/shared/"+link.Token, nil, "")
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NotContains(t, w.Body.String(), "visible")
+}
+
+func TestAPI_Collaborator_InviteEditRemove(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	ownerID := "1"
+	collaboratorID := "2"
+	folderID := uuid.New().String()
+	folder := url.URLNode{ID: folderID, UserID: ownerID, Name: "team", Type: "folder"}
+	err = a.DB.Create(&folder).Error
+	require.NoError(t, err)
+
+	ownerToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: ownerID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+	collaboratorToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: collaboratorID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	// Act
+	req, err := createTestRequest("GET", "/urls/"+folderID, nil, collaboratorToken)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	beforeInviteCode := w.Code
+
+	req, err = createTestRequest("POST", "/urls/"+folderID+"/collaborators", url.AddCollaboratorRequestBody{UserID: collaboratorID, Role: url.RoleEditor}, ownerToken)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	req, err = createTestRequest("POST", "/urls", url.RequestBody{ParentID: folderID, Name: "shared link", Type: "url", URL: StringPtr("https://example.com")}, collaboratorToken)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	req, err = createTestRequest("GET", "/urls/"+folderID, nil, collaboratorToken)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp url.URLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	require.NoError(t, err)
+
+	req, err = createTestRequest("DELETE", "/urls/"+folderID+"/collaborators/"+collaboratorID, nil, ownerToken)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	req, err = createTestRequest("GET", "/urls/"+folderID, nil, collaboratorToken)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	afterRemoveCode := w.Code
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, beforeInviteCode)
+	require.Len(t, resp.Children, 1)
+	assert.Equal(t, "shared link", resp.Children[0].Name)
+	var created url.URLNode
+	err = a.DB.First(&created, "id = ?", resp.Children[0].ID).Error
+	require.NoError(t, err)
+	assert.Equal(t, ownerID, created.UserID)
+	assert.Equal(t, http.StatusForbidden, afterRemoveCode)
+}
+
+func TestAPI_SharedWithMe_ListAndMount(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	ownerID := "1"
+	collaboratorID := "2"
+	ownerRootID := uuid.New().String()
+	privateID := uuid.New().String()
+	teamID := uuid.New().String()
+	docsID := uuid.New().String()
+	collaboratorRootID := uuid.New().String()
+	nodes := []url.URLNode{
+		{ID: ownerRootID, UserID: ownerID, Name: "", Type: "folder"},
+		{ID: privateID, UserID: ownerID, ParentID: &ownerRootID, Name: "private", Type: "folder"},
+		{ID: teamID, UserID: ownerID, ParentID: &privateID, Name: "team", Type: "folder"},
+		{ID: docsID, UserID: ownerID, ParentID: &teamID, Name: "docs", Type: "folder"},
+		{ID: collaboratorRootID, UserID: collaboratorID, Name: "", Type: "folder"},
+	}
+	err = a.DB.Create(&nodes).Error
+	require.NoError(t, err)
+	err = a.DB.Create(&url.FolderPermission{FolderID: teamID, UserID: collaboratorID, Role: url.RoleViewer}).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: collaboratorID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	// Act
+	req, err := createTestRequest("GET", "/urls/shared-with-me", nil, token)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var shared []url.SharedFolder
+	err = json.Unmarshal(w.Body.Bytes(), &shared)
+	require.NoError(t, err)
+
+	req, err = createTestRequest("GET", "/urls/"+docsID, nil, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var docs url.URLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &docs)
+	require.NoError(t, err)
+
+	req, err = createTestRequest("POST", "/urls/shared-with-me/"+teamID+"/mount", url.MountRequestBody{ParentID: collaboratorRootID}, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	req, err = createTestRequest("GET", "/urls/"+collaboratorRootID, nil, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var root url.URLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &root)
+	require.NoError(t, err)
+
+	// Assert
+	require.Len(t, shared, 1)
+	assert.Equal(t, teamID, shared[0].ID)
+	assert.Equal(t, ownerID, shared[0].OwnerID)
+	assert.Equal(t, url.RoleViewer, shared[0].Role)
+	require.Len(t, docs.Parent, 1)
+	assert.Equal(t, teamID, docs.Parent[0].ID)
+	require.Len(t, root.Children, 1)
+	assert.Equal(t, "team", root.Children[0].Name)
+	assert.Equal(t, "mount", root.Children[0].Type)
+	assert.Equal(t, teamID, *root.Children[0].TargetID)
+}
+
+func TestAPI_AuditLog_HistoryAndAdminQuery(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	adminID := "99"
+	parentID := uuid.New().String()
+	err = a.DB.Create(&url.URLNode{ID: parentID, UserID: userID, Name: "parent", Type: "folder"}).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+	adminToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: adminID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	// Act
+	req, err := createTestRequest("POST", "/urls", url.RequestBody{ParentID: parentID, Name: "folder", Type: "folder"}, token)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	createRequestID := w.Header().Get("X-Request-ID")
+
+	var created url.CreateURLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &created)
+	require.NoError(t, err)
+
+	req, err = createTestRequest("PUT", "/urls/"+created.ID, url.RequestBody{ParentID: parentID, Name: "renamed", Type: "folder"}, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	req, err = createTestRequest("DELETE", "/urls/"+created.ID, nil, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	req, err = createTestRequest("GET", "/urls/"+created.ID+"/history", nil, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var history []audit.Entry
+	err = json.Unmarshal(w.Body.Bytes(), &history)
+	require.NoError(t, err)
+
+	req, err = createTestRequest("GET", "/admin/audit-logs?node_id="+created.ID, nil, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	nonAdminCode := w.Code
+
+	req, err = createTestRequest("GET", "/admin/audit-logs?node_id="+created.ID, nil, adminToken)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var entries []audit.Entry
+	err = json.Unmarshal(w.Body.Bytes(), &entries)
+	require.NoError(t, err)
+
+	// Assert
+	require.Len(t, history, 3)
+	assert.Equal(t, audit.ActionDelete, history[0].Action)
+	assert.Equal(t, audit.ActionUpdate, history[1].Action)
+	assert.Equal(t, audit.ActionCreate, history[2].Action)
+	assert.Equal(t, userID, history[2].ActorID)
+	require.NotNil(t, history[2].RequestID)
+	assert.Equal(t, createRequestID, *history[2].RequestID)
+	var renamed url.NodeSnapshot
+	err = json.Unmarshal(history[1].After, &renamed)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", renamed.Name)
+	assert.Equal(t, http.StatusForbidden, nonAdminCode)
+	assert.Len(t, entries, 3)
+}
+
+func TestAPI_Revisions_ListAndRevert(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	parentID := uuid.New().String()
+	otherParentID := uuid.New().String()
+	err = a.DB.Create(&url.URLNode{ID: parentID, UserID: userID, Name: "parent", Type: "folder"}).Error
+	require.NoError(t, err)
+	err = a.DB.Create(&url.URLNode{ID: otherParentID, UserID: userID, Name: "other", Type: "folder"}).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	// Act
+	req, err := createTestRequest("POST", "/urls", url.RequestBody{ParentID: parentID, Name: "original", Type: "folder"}, token)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created url.CreateURLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &created)
+	require.NoError(t, err)
+
+	req, err = createTestRequest("PUT", "/urls/"+created.ID, url.RequestBody{ParentID: otherParentID, Name: "renamed", Type: "folder"}, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	req, err = createTestRequest("POST", "/urls/"+created.ID+"/revisions/1/revert", nil, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	revertCode := w.Code
+
+	req, err = createTestRequest("POST", "/urls", url.RequestBody{ParentID: otherParentID, Name: "renamed", Type: "folder"}, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	req, err = createTestRequest("POST", "/urls/"+created.ID+"/revisions/2/revert", nil, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	conflictCode := w.Code
+
+	req, err = createTestRequest("POST", "/urls/"+created.ID+"/revisions/9/revert", nil, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	missingCode := w.Code
+
+	req, err = createTestRequest("GET", "/urls/"+created.ID+"/revisions", nil, token)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var revisions []url.Revision
+	err = json.Unmarshal(w.Body.Bytes(), &revisions)
+	require.NoError(t, err)
+
+	var reverted url.URLNode
+	err = a.DB.Where("id = ?", created.ID).First(&reverted).Error
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, revertCode)
+	assert.Equal(t, http.StatusBadRequest, conflictCode)
+	assert.Equal(t, http.StatusNotFound, missingCode)
+	assert.Equal(t, "original", reverted.Name)
+	assert.Equal(t, parentID, *reverted.ParentID)
+	require.Len(t, revisions, 3)
+	assert.Equal(t, 3, revisions[0].Revision)
+	var latest url.NodeSnapshot
+	err = json.Unmarshal(revisions[0].Node, &latest)
+	require.NoError(t, err)
+	assert.Equal(t, "original", latest.Name)
+	assert.Equal(t, parentID, *latest.ParentID)
+}
+
+func TestAPI_UndoRedo_Success(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	parentID := uuid.New().String()
+	err = a.DB.Create(&url.URLNode{ID: parentID, UserID: userID, Name: "parent", Type: "folder"}).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	send := func(method string, path string, body interface{}) *httptest.ResponseRecorder {
+		req, err := createTestRequest(method, path, body, token)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w
+	}
+	nameOf := func(id string) string {
+		var node url.URLNode
+		err := a.DB.Where("id = ? AND deleted_at IS NULL", id).First(&node).Error
+		if err != nil {
+			return ""
+		}
+		return node.Name
+	}
+
+	// Act
+	w := send("POST", "/urls", url.RequestBody{ParentID: parentID, Name: "original", Type: "folder"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created url.CreateURLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &created)
+	require.NoError(t, err)
+
+	w = send("PUT", "/urls/"+created.ID, url.RequestBody{ParentID: parentID, Name: "renamed", Type: "folder"})
+	require.Equal(t, http.StatusNoContent, w.Code)
+	w = send("DELETE", "/urls/"+created.ID, nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	undoDeleteCode := send("POST", "/urls/undo", nil).Code
+	afterUndoDelete := nameOf(created.ID)
+	w = send("POST", "/urls/undo", nil)
+	undoRenameCode := w.Code
+	var undone url.OperationResponse
+	err = json.Unmarshal(w.Body.Bytes(), &undone)
+	require.NoError(t, err)
+	afterUndoRename := nameOf(created.ID)
+
+	redoCode := send("POST", "/urls/redo", nil).Code
+	afterRedo := nameOf(created.ID)
+
+	w = send("POST", "/urls", url.RequestBody{ParentID: parentID, Name: "original", Type: "folder"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	nothingToRedoCode := send("POST", "/urls/redo", nil).Code
+	undoCreateCode := send("POST", "/urls/undo", nil).Code
+	err = a.DB.Create(&url.URLNode{UserID: userID, ParentID: &parentID, Name: "original", Type: "folder"}).Error
+	require.NoError(t, err)
+	conflictCode := send("POST", "/urls/undo", nil).Code
+
+	// Assert
+	assert.Equal(t, http.StatusOK, undoDeleteCode)
+	assert.Equal(t, "renamed", afterUndoDelete)
+	assert.Equal(t, http.StatusOK, undoRenameCode)
+	assert.Equal(t, []url.ChangeSummary{{NodeID: created.ID, Action: audit.ActionUpdate}}, undone.Changes)
+	assert.NotNil(t, undone.UndoneAt)
+	assert.Equal(t, "original", afterUndoRename)
+	assert.Equal(t, http.StatusOK, redoCode)
+	assert.Equal(t, "renamed", afterRedo)
+	assert.Equal(t, http.StatusNotFound, nothingToRedoCode)
+	assert.Equal(t, http.StatusOK, undoCreateCode)
+	assert.Equal(t, http.StatusConflict, conflictCode)
+}
+
+func TestAPI_Changes_AfterTransfer(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	rootID := uuid.New().String()
+	err = a.DB.Create(&url.URLNode{ID: rootID, UserID: "1", Name: "", Type: "folder"}).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: "1",
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	send := func(method string, path string, body interface{}, token string) *httptest.ResponseRecorder {
+		req, err := createTestRequest(method, path, body, token)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/urls", url.RequestBody{ParentID: rootID, Name: "private", Type: "folder"}, token)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created url.CreateURLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &created)
+	require.NoError(t, err)
+	w = send("GET", "/urls/changes", nil, token)
+	require.Equal(t, http.StatusOK, w.Code)
+	var initial url.ChangesResponse
+	err = json.Unmarshal(w.Body.Bytes(), &initial)
+	require.NoError(t, err)
+
+	w = send("POST", "/internal/users/1/transfer", account.TransferRequestBody{ToUserID: "2"}, "mock-service-secret")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Act
+	w = send("GET", "/urls/changes?since="+initial.NextToken, nil, token)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+	var changes url.ChangesResponse
+	err = json.Unmarshal(w.Body.Bytes(), &changes)
+	require.NoError(t, err)
+	require.NotEmpty(t, changes.Changes)
+	for _, change := range changes.Changes {
+		assert.Nil(t, change.Node)
+	}
+	assert.NotContains(t, w.Body.String(), "private")
+}
+
+func TestAPI_Changes_IncrementalSync(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	parentID := uuid.New().String()
+	err = a.DB.Create(&url.URLNode{ID: parentID, UserID: userID, Name: "parent", Type: "folder"}).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	send := func(method string, path string, body interface{}) *httptest.ResponseRecorder {
+		req, err := createTestRequest(method, path, body, token)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w
+	}
+	getChanges := func(path string) url.ChangesResponse {
+		w := send("GET", path, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response url.ChangesResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		return response
+	}
+
+	// Act
+	w := send("POST", "/urls", url.RequestBody{ParentID: parentID, Name: "kept", Type: "folder"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var kept url.CreateURLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &kept)
+	require.NoError(t, err)
+	w = send("POST", "/urls", url.RequestBody{ParentID: parentID, Name: "removed", Type: "folder"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var removed url.CreateURLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &removed)
+	require.NoError(t, err)
+
+	initial := getChanges("/urls/changes")
+
+	w = send("PUT", "/urls/"+kept.ID, url.RequestBody{ParentID: parentID, Name: "renamed", Type: "folder"})
+	require.Equal(t, http.StatusNoContent, w.Code)
+	w = send("DELETE", "/urls/"+removed.ID, nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	incremental := getChanges("/urls/changes?since=" + initial.NextToken)
+	paged := getChanges("/urls/changes?since=" + initial.NextToken + "&limit=1")
+	upToDate := getChanges("/urls/changes?since=" + incremental.NextToken)
+	invalidCode := send("GET", "/urls/changes?since=invalid", nil).Code
+
+	// Assert
+	require.Len(t, initial.Changes, 2)
+	assert.Equal(t, audit.ActionCreate, initial.Changes[0].Action)
+	assert.False(t, initial.HasMore)
+
+	require.Len(t, incremental.Changes, 2)
+	assert.Equal(t, kept.ID, incremental.Changes[0].NodeID)
+	assert.Equal(t, audit.ActionUpdate, incremental.Changes[0].Action)
+	require.NotNil(t, incremental.Changes[0].Node)
+	assert.Equal(t, "renamed", incremental.Changes[0].Node.Name)
+	assert.Equal(t, parentID, *incremental.Changes[0].Node.ParentID)
+	assert.Equal(t, removed.ID, incremental.Changes[1].NodeID)
+	assert.Equal(t, audit.ActionDelete, incremental.Changes[1].Action)
+	assert.Nil(t, incremental.Changes[1].Node)
+
+	require.Len(t, paged.Changes, 1)
+	assert.True(t, paged.HasMore)
+	assert.Empty(t, upToDate.Changes)
+	assert.Equal(t, incremental.NextToken, upToDate.NextToken)
+	assert.Equal(t, http.StatusBadRequest, invalidCode)
+}
+
+func TestAPI_Events_Stream(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	parentID := uuid.New().String()
+	err = a.DB.Create(&url.URLNode{ID: parentID, UserID: userID, Name: "parent", Type: "folder"}).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(a.Router)
+	defer server.Close()
+	streamReq, err := createTestRequest("GET", server.URL+"/urls/events", nil, token)
+	require.NoError(t, err)
+	stream, err := http.DefaultClient.Do(streamReq)
+	require.NoError(t, err)
+	defer stream.Body.Close()
+	require.Equal(t, http.StatusOK, stream.StatusCode)
+
+	// Act
+	req, err := createTestRequest("POST", "/urls", url.RequestBody{ParentID: parentID, Name: "created", Type: "folder"}, token)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created url.CreateURLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &created)
+	require.NoError(t, err)
+
+	lines := make(chan []string, 1)
+	go func() {
+		var event []string
+		scanner := bufio.NewScanner(stream.Body)
+		for scanner.Scan() {
+			if scanner.Text() == "" && len(event) > 0 {
+				lines <- event
+				return
+			}
+			event = append(event, scanner.Text())
+		}
+	}()
+
+	// Assert
+	assert.Equal(t, "text/event-stream", stream.Header.Get("Content-Type"))
+	select {
+	case event := <-lines:
+		require.Len(t, event, 3)
+		assert.Regexp(t, `^id:\S+$`, event[0])
+		assert.Equal(t, "event:change", event[1])
+		assert.JSONEq(t, `{"node_id":"`+created.ID+`","action":"create"}`, event[2][len("data:"):])
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+}
+
+func TestAPI_Webhooks_Delivery(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	parentID := uuid.New().String()
+	err = a.DB.Create(&url.URLNode{ID: parentID, UserID: userID, Name: "parent", Type: "folder"}).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header, body: body}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	send := func(method string, path string, body interface{}) *httptest.ResponseRecorder {
+		req, err := createTestRequest(method, path, body, token)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Act
+	w := send("POST", "/webhooks", webhook.CreateRequestBody{URL: receiver.URL, Events: []audit.Action{audit.ActionCreate}})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created webhook.CreateWebhookResponse
+	err = json.Unmarshal(w.Body.Bytes(), &created)
+	require.NoError(t, err)
+
+	w = send("POST", "/urls", url.RequestBody{ParentID: parentID, Name: "folder", Type: "folder"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var node url.CreateURLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &node)
+	require.NoError(t, err)
+	w = send("DELETE", "/urls/"+node.ID, nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	dispatcher := webhook.NewDispatcher(webhook.NewRepository(a.DB), a.Config, a.Logger)
+	err = dispatcher.DispatchOnce(context.Background())
+	require.NoError(t, err)
+
+	deliveriesResp := send("GET", "/webhooks/"+created.ID+"/deliveries", nil)
+	otherToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: "2",
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+	otherReq, err := createTestRequest("GET", "/webhooks/"+created.ID+"/deliveries", nil, otherToken)
+	require.NoError(t, err)
+	otherResp := httptest.NewRecorder()
+	a.Router.ServeHTTP(otherResp, otherReq)
+
+	// Assert
+	require.Len(t, requests, 1)
+	request := <-requests
+	assert.Equal(t, "create", request.header.Get("X-Webhook-Event"))
+	timestamp := request.header.Get("X-Webhook-Timestamp")
+	assert.Equal(t, webhook.Sign(created.Secret, timestamp, request.body), request.header.Get("X-Webhook-Signature"))
+	var payload struct {
+		Event audit.Action    `json:"event"`
+		Data  url.WebhookData `json:"data"`
+	}
+	err = json.Unmarshal(request.body, &payload)
+	require.NoError(t, err)
+	assert.Equal(t, audit.ActionCreate, payload.Event)
+	assert.Equal(t, node.ID, payload.Data.NodeID)
+	assert.Nil(t, payload.Data.Before)
+	assert.Equal(t, "folder", payload.Data.After.Name)
+
+	require.Equal(t, http.StatusOK, deliveriesResp.Code)
+	var deliveries []webhook.DeliveryResponse
+	err = json.Unmarshal(deliveriesResp.Body.Bytes(), &deliveries)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, webhook.DeliverySucceeded, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusOK, *deliveries[0].ResponseStatus)
+
+	assert.Equal(t, http.StatusNotFound, otherResp.Code)
+}
+
+func TestAPI_DomainEvents_Relayed(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	parentID := uuid.New().String()
+	otherParentID := uuid.New().String()
+	err = a.DB.Create(&url.URLNode{ID: parentID, UserID: userID, Name: "parent", Type: "folder"}).Error
+	require.NoError(t, err)
+	err = a.DB.Create(&url.URLNode{ID: otherParentID, UserID: userID, Name: "other", Type: "folder"}).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	send := func(method string, path string, body interface{}) *httptest.ResponseRecorder {
+		req, err := createTestRequest(method, path, body, token)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	publisher := outbox.NewMemoryPublisher()
+	var messages []outbox.Message
+	publisher.Subscribe(func(message outbox.Message) {
+		messages = append(messages, message)
+	})
+	relay := outbox.NewRelay(outbox.NewRepository(a.DB), publisher, a.Config, a.Logger)
+
+	// Act
+	w := send("POST", "/urls", url.RequestBody{ParentID: parentID, Name: "folder", Type: "folder"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var node url.CreateURLResponse
+	err = json.Unmarshal(w.Body.Bytes(), &node)
+	require.NoError(t, err)
+	w = send("PUT", "/urls/"+node.ID, url.RequestBody{ParentID: otherParentID, Name: "folder", Type: "folder"})
+	require.Equal(t, http.StatusNoContent, w.Code)
+	w = send("DELETE", "/urls/"+node.ID, nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	var unpublished int64
+	err = a.DB.Model(&outbox.OutboxEvent{}).Where("published_at IS NULL").Count(&unpublished).Error
+	require.NoError(t, err)
+
+	// Assert
+	require.Len(t, messages, 3)
+	assert.Equal(t, "node.created", messages[0].Type)
+	assert.Equal(t, "node.moved", messages[1].Type)
+	assert.Equal(t, "node.deleted", messages[2].Type)
+
+	var moved url.NodeMoved
+	err = json.Unmarshal(messages[1].Data, &moved)
+	require.NoError(t, err)
+	assert.Equal(t, node.ID, moved.NodeID)
+	assert.Equal(t, parentID, *moved.FromParentID)
+	assert.Equal(t, otherParentID, *moved.ToParentID)
+	assert.Equal(t, int64(0), unpublished)
+}
+
+func TestAPI_AccessTokens_Authenticate(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	parentID := uuid.New().String()
+	err = a.DB.Create(&url.URLNode{ID: parentID, UserID: userID, Name: "parent", Type: "folder"}).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	send := func(method string, path string, body interface{}, token string) *httptest.ResponseRecorder {
+		req, err := createTestRequest(method, path, body, token)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w
+	}
+	create := func(scope accesstoken.Scope) accesstoken.CreateAccessTokenResponse {
+		w := send("POST", "/access-tokens", accesstoken.CreateRequestBody{Name: "script", Scope: scope}, token)
+		require.Equal(t, http.StatusCreated, w.Code)
+		var created accesstoken.CreateAccessTokenResponse
+		err := json.Unmarshal(w.Body.Bytes(), &created)
+		require.NoError(t, err)
+		return created
+	}
+
+	// Act
+	readToken := create(accesstoken.ScopeRead)
+	writeToken := create(accesstoken.ScopeWrite)
+
+	readResp := send("GET", "/urls/"+parentID, nil, readToken.Token)
+	readOnlyWriteResp := send("POST", "/urls", url.RequestBody{ParentID: parentID, Name: "folder", Type: "folder"}, readToken.Token)
+	writeResp := send("POST", "/urls", url.RequestBody{ParentID: parentID, Name: "folder", Type: "folder"}, writeToken.Token)
+	listResp := send("GET", "/access-tokens", nil, token)
+	revokeResp := send("DELETE", "/access-tokens/"+writeToken.ID, nil, token)
+	revokedResp := send("GET", "/urls/"+parentID, nil, writeToken.Token)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, readResp.Code)
+	assert.Equal(t, http.StatusForbidden, readOnlyWriteResp.Code)
+	assert.Contains(t, readOnlyWriteResp.Body.String(), "403_02_028")
+	assert.Equal(t, http.StatusCreated, writeResp.Code)
+
+	require.Equal(t, http.StatusOK, listResp.Code)
+	var tokens []accesstoken.AccessTokenResponse
+	err = json.Unmarshal(listResp.Body.Bytes(), &tokens)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, readToken.ID, tokens[0].ID)
+	assert.NotNil(t, tokens[0].LastUsedAt)
+	assert.NotContains(t, listResp.Body.String(), readToken.Token)
+
+	assert.Equal(t, http.StatusNoContent, revokeResp.Code)
+	assert.Equal(t, http.StatusUnauthorized, revokedResp.Code)
+	assert.Contains(t, revokedResp.Body.String(), "401_02_025")
+}
+
+func TestAPI_Scopes_Enforced(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	parentID := uuid.New().String()
+	err = a.DB.Create(&url.URLNode{ID: parentID, UserID: userID, Name: "parent", Type: "folder"}).Error
+	require.NoError(t, err)
+
+	tokenWithScope := func(scope string) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject: userID,
+			},
+			Scope: scope,
+		}).SignedString([]byte("mock-token-secret"))
+		require.NoError(t, err)
+		return token
+	}
+	readToken := tokenWithScope("drive:read")
+	writeToken := tokenWithScope("drive:write")
+	defaultToken := tokenWithScope("")
+
+	send := func(method string, path string, body interface{}, token string) *httptest.ResponseRecorder {
+		req, err := createTestRequest(method, path, body, token)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Act
+	readResp := send("GET", "/urls/"+parentID, nil, readToken)
+	readWriteResp := send("POST", "/urls", url.RequestBody{ParentID: parentID, Name: "folder", Type: "folder"}, readToken)
+	writeResp := send("POST", "/urls", url.RequestBody{ParentID: parentID, Name: "folder", Type: "folder"}, writeToken)
+	writeAdminResp := send("POST", "/access-tokens", accesstoken.CreateRequestBody{Name: "cli", Scope: accesstoken.ScopeRead}, writeToken)
+	defaultAdminResp := send("POST", "/access-tokens", accesstoken.CreateRequestBody{Name: "cli", Scope: accesstoken.ScopeRead}, defaultToken)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, readResp.Code)
+	assert.Equal(t, http.StatusForbidden, readWriteResp.Code)
+	assert.Contains(t, readWriteResp.Body.String(), "403_02_028")
+	assert.Equal(t, http.StatusCreated, writeResp.Code)
+	assert.Equal(t, http.StatusForbidden, writeAdminResp.Code)
+	assert.Equal(t, http.StatusCreated, defaultAdminResp.Code)
+}
+
+func TestAPI_Internal_UserAdmin(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	rootID := uuid.New().String()
+	folderID := uuid.New().String()
+	link := "https://example.com"
+	nodes := []url.URLNode{
+		{ID: rootID, UserID: "1", Name: "", Type: "folder"},
+		{ID: folderID, UserID: "1", ParentID: &rootID, Name: "Work", Type: "folder"},
+		{UserID: "1", ParentID: &folderID, Name: "Example", Type: "url", URL: &link},
+	}
+	for _, node := range nodes {
+		err = a.DB.Create(&node).Error
+		require.NoError(t, err)
+	}
+
+	userToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: "99",
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	send := func(method string, path string, body interface{}, token string) *httptest.ResponseRecorder {
+		req, err := createTestRequest(method, path, body, token)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Act
+	userResp := send("GET", "/internal/users/1/stats", nil, userToken)
+	statsResp := send("GET", "/internal/users/1/stats", nil, "mock-service-secret")
+	transferResp := send("POST", "/internal/users/1/transfer", account.TransferRequestBody{ToUserID: "2"}, "mock-service-secret")
+	transferredStatsResp := send("GET", "/internal/users/2/stats", nil, "mock-service-secret")
+	deleteResp := send("DELETE", "/internal/users/2", nil, "mock-service-secret")
+	deletedStatsResp := send("GET", "/internal/users/2/stats", nil, "mock-service-secret")
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, userResp.Code)
+	assert.Contains(t, userResp.Body.String(), "401_02_029")
+
+	require.Equal(t, http.StatusOK, statsResp.Code)
+	var stats account.StatsResponse
+	err = json.Unmarshal(statsResp.Body.Bytes(), &stats)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Folders)
+	assert.Equal(t, int64(1), stats.URLs)
+
+	require.Equal(t, http.StatusOK, transferResp.Code)
+	var transferred account.TransferResponse
+	err = json.Unmarshal(transferResp.Body.Bytes(), &transferred)
+	require.NoError(t, err)
+	assert.Equal(t, rootID, transferred.FolderID)
+	assert.Equal(t, "From user 1", transferred.Name)
+	assert.Equal(t, int64(3), transferred.Nodes)
+
+	require.Equal(t, http.StatusOK, transferredStatsResp.Code)
+	err = json.Unmarshal(transferredStatsResp.Body.Bytes(), &stats)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Folders)
+	assert.Equal(t, int64(1), stats.URLs)
+
+	require.Equal(t, http.StatusOK, deleteResp.Code)
+	var erasure account.ErasureResponse
+	err = json.Unmarshal(deleteResp.Body.Bytes(), &erasure)
+	require.NoError(t, err)
+	assert.Equal(t, "2", erasure.UserID)
+	assert.Equal(t, "service:shared-secret", erasure.RequestedBy)
+	require.Equal(t, http.StatusOK, deletedStatsResp.Code)
+	err = json.Unmarshal(deletedStatsResp.Body.Bytes(), &stats)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Folders)
+	assert.Zero(t, stats.URLs)
+	assert.Nil(t, stats.LastChangedAt)
+}
+
+func TestAPI_Account_ExportAndErase(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	rootID := uuid.New().String()
+	link := "https://example.com"
+	deletedAt := time.Now()
+	nodes := []url.URLNode{
+		{ID: rootID, UserID: "1", Name: "", Type: "folder"},
+		{UserID: "1", ParentID: &rootID, Name: "Example", Type: "url", URL: &link},
+		{UserID: "1", ParentID: &rootID, Name: "Trashed", Type: "url", URL: &link, DeletedAt: &deletedAt},
+	}
+	for _, node := range nodes {
+		err = a.DB.Create(&node).Error
+		require.NoError(t, err)
+	}
+	err = a.DB.Create(&webhook.Webhook{UserID: "1", URL: "https://example.com/hook", Secret: "webhook-secret", Events: "[]"}).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: "1",
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	send := func(method string, path string) *httptest.ResponseRecorder {
+		req, err := createTestRequest(method, path, nil, token)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w
+	}
+	readArchive := func(w *httptest.ResponseRecorder) map[string][]byte {
+		archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		require.NoError(t, err)
+		files := map[string][]byte{}
+		for _, file := range archive.File {
+			r, err := file.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(r)
+			require.NoError(t, err)
+			files[file.Name] = content
+		}
+		return files
+	}
+
+	// Act
+	exportResp := send("GET", "/account/export")
+	eraseResp := send("DELETE", "/account")
+	erasedExportResp := send("GET", "/account/export")
+
+	// Assert
+	require.Equal(t, http.StatusOK, exportResp.Code)
+	assert.Equal(t, "application/zip", exportResp.Header().Get("Content-Type"))
+	assert.Contains(t, exportResp.Header().Get("Content-Disposition"), "vera-drive-export-1-")
+	files := readArchive(exportResp)
+	require.Contains(t, files, "manifest.json")
+	var exported []map[string]interface{}
+	err = json.Unmarshal(files["nodes.json"], &exported)
+	require.NoError(t, err)
+	assert.Len(t, exported, 3)
+	assert.Contains(t, string(files["webhooks.json"]), "https://example.com/hook")
+	assert.NotContains(t, string(files["webhooks.json"]), "webhook-secret")
+
+	require.Equal(t, http.StatusOK, eraseResp.Code)
+	var erasure account.ErasureResponse
+	err = json.Unmarshal(eraseResp.Body.Bytes(), &erasure)
+	require.NoError(t, err)
+	assert.Equal(t, "1", erasure.UserID)
+	assert.Equal(t, "user", erasure.RequestedBy)
+	var deletedRows map[string]int64
+	err = json.Unmarshal(erasure.DeletedRows, &deletedRows)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deletedRows["url_nodes"])
+	assert.Equal(t, int64(1), deletedRows["webhooks"])
+	var remaining int64
+	require.NoError(t, a.DB.Model(&url.URLNode{}).Where("user_id = ?", "1").Count(&remaining).Error)
+	assert.Zero(t, remaining)
+
+	require.Equal(t, http.StatusOK, erasedExportResp.Code)
+	files = readArchive(erasedExportResp)
+	assert.JSONEq(t, "[]", string(files["nodes.json"]))
+	assert.Contains(t, string(files["erasures.json"]), erasure.ID)
+}
+
+func TestAPI_User_ProfileAndPreferences(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	userID := "1"
+	folderID := uuid.New().String()
+	urlID := uuid.New().String()
+	err = a.DB.Create(&url.URLNode{ID: folderID, UserID: userID, Name: "folder", Type: "folder"}).Error
+	require.NoError(t, err)
+	err = a.DB.Create(&url.URLNode{ID: urlID, UserID: userID, ParentID: &folderID, Name: "link", Type: "url"}).Error
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID,
+		},
+		Email:   "user@example.com",
+		Picture: "https://example.com/avatar.png",
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	send := func(method string, path string, body interface{}) *httptest.ResponseRecorder {
+		req, err := createTestRequest(method, path, body, token)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Act
+	profileResp := send("GET", "/me", nil)
+	defaultsResp := send("GET", "/me/preferences", nil)
+	updateResp := send("PUT", "/me/preferences", user.PreferencesRequestBody{SortOrder: user.SortUpdatedDesc, ViewMode: user.ViewGrid, StartFolderID: &folderID, Theme: user.ThemeDark})
+	notFolderResp := send("PUT", "/me/preferences", user.PreferencesRequestBody{SortOrder: user.SortNameAsc, ViewMode: user.ViewList, StartFolderID: &urlID, Theme: user.ThemeSystem})
+	savedResp := send("GET", "/me/preferences", nil)
+
+	// Assert
+	require.Equal(t, http.StatusOK, profileResp.Code)
+	assert.JSONEq(t, `{"user_id":"1","email":"user@example.com","picture":"https://example.com/avatar.png"}`, profileResp.Body.String())
+
+	require.Equal(t, http.StatusOK, defaultsResp.Code)
+	assert.JSONEq(t, `{"sort_order":"name_asc","view_mode":"list","start_folder_id":null,"theme":"system","updated_at":null}`, defaultsResp.Body.String())
+
+	assert.Equal(t, http.StatusOK, updateResp.Code)
+	assert.Equal(t, http.StatusBadRequest, notFolderResp.Code)
+	assert.Contains(t, notFolderResp.Body.String(), "400_02_032")
+
+	require.Equal(t, http.StatusOK, savedResp.Code)
+	var saved user.PreferencesResponse
+	err = json.Unmarshal(savedResp.Body.Bytes(), &saved)
+	require.NoError(t, err)
+	assert.Equal(t, user.SortUpdatedDesc, saved.SortOrder)
+	assert.Equal(t, user.ViewGrid, saved.ViewMode)
+	assert.Equal(t, &folderID, saved.StartFolderID)
+	assert.Equal(t, user.ThemeDark, saved.Theme)
+	assert.NotNil(t, saved.UpdatedAt)
+}
+
+func TestAPI_RateLimit_PerUser(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: "rate-limited",
+		},
+	}).SignedString([]byte("mock-token-secret"))
+	require.NoError(t, err)
+
+	send := func() *httptest.ResponseRecorder {
+		req, err := createTestRequest("GET", "/me", nil, token)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Act
+	first := send()
+	var limited *httptest.ResponseRecorder
+	allowed := 1
+	// The bucket refills while the requests are sent, so a few more than the
+	// limit may pass.
+	for i := 0; i < 700; i++ {
+		w := send()
+		if w.Code == http.StatusTooManyRequests {
+			limited = w
+			break
+		}
+		require.Equal(t, http.StatusOK, w.Code)
+		allowed++
+	}
+
+	// Assert
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "600", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "599", first.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "600;w=60", first.Header().Get("RateLimit-Policy"))
+	require.NotNil(t, limited)
+	assert.GreaterOrEqual(t, allowed, 600)
+	assert.Contains(t, limited.Body.String(), "429_02_033")
+	assert.NotEmpty(t, limited.Header().Get("Retry-After"))
+}
+
+func TestAPI_RateLimit_PostgresStore(t *testing.T) {
+	// Arrange
+	err := CleanupTables(a.DB)
+	require.NoError(t, err)
+	store := middleware.NewPostgresRateLimitStore(a.DB, time.Minute, a.Logger)
+	bucket := middleware.TokenBucket{Capacity: 3, Window: time.Minute}
+	now := time.Now().UTC()
+	ctx := context.Background()
+
+	// Act
+	first, firstTaken, firstErr := store.Take(ctx, "user:1", bucket, 2, now)
+	denied, deniedTaken, deniedErr := store.Take(ctx, "user:1", bucket, 2, now)
+	refilled, refilledTaken, refilledErr := store.Take(ctx, "user:1", bucket, 2, now.Add(20*time.Second))
+	other, otherTaken, otherErr := store.Take(ctx, "user:2", bucket, 1, now)
+	require.NoError(t, a.DB.Model(&middleware.RateLimitBucket{}).Where("key = ?", "user:2").Update("updated_at", now.Add(-2*time.Minute)).Error)
+	pruneErr := store.PruneOnce()
+
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, deniedErr)
+	require.NoError(t, refilledErr)
+	require.NoError(t, otherErr)
+	require.NoError(t, pruneErr)
+	assert.True(t, firstTaken)
+	assert.InDelta(t, 1.0, first, 1e-6)
+	assert.False(t, deniedTaken)
+	assert.InDelta(t, 1.0, denied, 1e-6)
+	assert.True(t, refilledTaken)
+	assert.InDelta(t, 0.0, refilled, 1e-6)
+	assert.True(t, otherTaken)
+	assert.InDelta(t, 2.0, other, 1e-6)
+	var keys []string
+	require.NoError(t, a.DB.Model(&middleware.RateLimitBucket{}).Pluck("key", &keys).Error)
+	assert.Equal(t, []string{"user:1"}, keys)
+}
+
+func TestAPI_CORS_AllowedOrigins(t *testing.T) {
+	// Arrange
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("OPTIONS", "/urls", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Act
+	first := preflight("http://mock-origin-1")
+	second := preflight("http://mock-origin-2")
+	denied := preflight("http://mock-origin-3")
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, first.Code)
+	assert.Equal(t, "http://mock-origin-1", first.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, first.Header().Get("Access-Control-Allow-Methods"), "POST")
+	assert.Equal(t, http.StatusNoContent, second.Code)
+	assert.Equal(t, "http://mock-origin-2", second.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, http.StatusForbidden, denied.Code)
+	assert.Contains(t, denied.Body.String(), "403_02_034")
+	assert.Empty(t, denied.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Origin", denied.Header().Get("Vary"))
+}
+
 func TestAPI_AllURLs_Unauthorized(t *testing.T) {
 	tests := []struct {
 		method string
@@ -495,9 +2254,45 @@ func TestAPI_AllURLs_Unauthorized(t *testing.T) {
 	}{
 		{"POST", "/urls"},
 		{"GET", "/urls/root-id"},
+		{"GET", "/urls/duplicates"},
+		{"POST", "/urls/duplicates/merge"},
+		{"GET", "/urls/lookup?url=https://example.com"},
+		{"POST", "/urls/lookup"},
+		{"GET", "/urls/broken"},
+		{"GET", "/urls/changes"},
+		{"GET", "/urls/events"},
+		{"POST", "/urls/undo"},
+		{"POST", "/urls/redo"},
+		{"GET", "/urls/shared-with-me"},
+		{"POST", "/urls/shared-with-me/123e4567-e89b-12d3-a456-426614174001/mount"},
 		{"GET", "/urls/123e4567-e89b-12d3-a456-426614174001"},
+		{"GET", "/urls/123e4567-e89b-12d3-a456-426614174001/favicon"},
+		{"GET", "/urls/123e4567-e89b-12d3-a456-426614174001/history"},
+		{"GET", "/urls/123e4567-e89b-12d3-a456-426614174001/revisions"},
+		{"POST", "/urls/123e4567-e89b-12d3-a456-426614174001/revisions/1/revert"},
+		{"GET", "/admin/audit-logs"},
+		{"GET", "/admin/metrics"},
+		{"POST", "/urls/123e4567-e89b-12d3-a456-426614174001/shares"},
+		{"GET", "/urls/123e4567-e89b-12d3-a456-426614174001/shares"},
+		{"DELETE", "/urls/123e4567-e89b-12d3-a456-426614174001/shares/123e4567-e89b-12d3-a456-426614174002"},
+		{"GET", "/urls/123e4567-e89b-12d3-a456-426614174001/collaborators"},
+		{"POST", "/urls/123e4567-e89b-12d3-a456-426614174001/collaborators"},
+		{"PUT", "/urls/123e4567-e89b-12d3-a456-426614174001/collaborators/2"},
+		{"DELETE", "/urls/123e4567-e89b-12d3-a456-426614174001/collaborators/2"},
 		{"PUT", "/urls/123e4567-e89b-12d3-a456-426614174001"},
 		{"DELETE", "/urls/123e4567-e89b-12d3-a456-426614174001"},
+		{"POST", "/webhooks"},
+		{"GET", "/webhooks"},
+		{"DELETE", "/webhooks/123e4567-e89b-12d3-a456-426614174001"},
+		{"GET", "/webhooks/123e4567-e89b-12d3-a456-426614174001/deliveries"},
+		{"POST", "/access-tokens"},
+		{"GET", "/access-tokens"},
+		{"DELETE", "/access-tokens/123e4567-e89b-12d3-a456-426614174001"},
+		{"GET", "/account/export"},
+		{"DELETE", "/account"},
+		{"GET", "/me"},
+		{"GET", "/me/preferences"},
+		{"PUT", "/me/preferences"},
 	}
 
 	for _, tt := range tests {
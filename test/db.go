@@ -3,6 +3,8 @@ package test
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"gorm.io/gorm"
@@ -54,3 +56,25 @@ func CleanupTables(db *gorm.DB) error {
 
 	return nil
 }
+
+// ApplyMigrations runs the up migrations in dir in order. Unlike AutoMigrate,
+// they create the triggers and constraints of the production schema.
+func ApplyMigrations(db *gorm.DB, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	if err != nil {
+		return fmt.Errorf("failed to list migrations: %v", err)
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %v", file, err)
+		}
+		err = db.Exec(string(content)).Error
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %s: %v", file, err)
+		}
+	}
+
+	return nil
+}